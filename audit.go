@@ -0,0 +1,160 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+// AuditEvent 密钥生命周期审计事件
+type AuditEvent string
+
+const (
+	AuditKeyCreated  AuditEvent = "key_created"  // 生成或导入密钥
+	AuditKeyLoaded   AuditEvent = "key_loaded"   // 从已有密钥文件加载密钥
+	AuditFileWritten AuditEvent = "file_written" // 写入密钥文件或 keyinfo 文件
+	AuditKeyRotated  AuditEvent = "key_rotated"  // 轮换器切换到新密钥
+	AuditKeyFetched  AuditEvent = "key_fetched"  // KeyServer 向客户端返回密钥
+	AuditKeyDisposed AuditEvent = "key_disposed" // 清理密钥文件与 keyinfo 文件
+)
+
+// AuditRecord 审计记录，不包含密钥本身
+type AuditRecord struct {
+	Time          time.Time  `json:"time"`
+	Event         AuditEvent `json:"event"`
+	Actor         string     `json:"actor"`                     // 操作主体，密钥请求为鉴权主体或客户端 IP，其余为 WithAuditActor 设置的身份
+	KeyID         string     `json:"key_id,omitempty"`          // 密钥 ID，密钥请求未指定 KeyID 时为空
+	Version       int        `json:"version,omitempty"`         // 密钥版本
+	Stream        string     `json:"stream,omitempty"`          // 流名称
+	Path          string     `json:"path,omitempty"`            // 写入或加载的文件路径
+	PreviousKeyID string     `json:"previous_key_id,omitempty"` // 轮换前的密钥 ID
+	ClientIP      string     `json:"client_ip,omitempty"`       // 密钥请求的远端 IP
+}
+
+// Auditor 审计记录的接收方，可能被并发调用
+type Auditor interface {
+	Audit(rec AuditRecord)
+}
+
+// AuditorFunc 函数形式的 Auditor
+type AuditorFunc func(rec AuditRecord)
+
+// Audit 实现 Auditor 接口
+func (f AuditorFunc) Audit(rec AuditRecord) {
+	f(rec)
+}
+
+// WriterAuditor 将审计记录以 JSON Lines 写入 io.Writer，如以追加方式打开的审计文件
+type WriterAuditor struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// NewWriterAuditor 创建写入 w 的 WriterAuditor
+func NewWriterAuditor(w io.Writer) *WriterAuditor {
+	return &WriterAuditor{w: w}
+}
+
+// Audit 实现 Auditor 接口，每条记录一行，单次写入
+func (a *WriterAuditor) Audit(rec AuditRecord) {
+	b, err := json.Marshal(rec)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err == nil {
+		_, err = a.w.Write(append(b, '\n'))
+	}
+	if err != nil && a.err == nil {
+		a.err = err
+	}
+}
+
+// Err 返回首个写入错误，审计证据不完整时可据此告警
+func (a *WriterAuditor) Err() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// NewSlogAuditor 创建以 slog 输出审计记录的 Auditor，记录为 Info 级别
+func NewSlogAuditor(l *slog.Logger) Auditor {
+	return AuditorFunc(func(rec AuditRecord) {
+		attrs := []slog.Attr{
+			slog.String("event", string(rec.Event)),
+			slog.Time("time", rec.Time),
+			slog.String("actor", rec.Actor),
+		}
+		for _, a := range []struct{ key, value string }{
+			{"key_id", rec.KeyID}, {"stream", rec.Stream}, {"path", rec.Path},
+			{"previous_key_id", rec.PreviousKeyID}, {"client_ip", rec.ClientIP},
+		} {
+			if a.value != "" {
+				attrs = append(attrs, slog.String(a.key, a.value))
+			}
+		}
+		if rec.Version != 0 {
+			attrs = append(attrs, slog.Int("version", rec.Version))
+		}
+		l.LogAttrs(context.Background(), slog.LevelInfo, "hls key audit", attrs...)
+	})
+}
+
+// WithAuditor 设置密钥生命周期审计，记录密钥创建、文件写入与清理；Clone 与轮换生成的新密钥沿用该设置
+func WithAuditor(a Auditor) Option {
+	return func(k *KeyInfo) {
+		k.auditor = a
+	}
+}
+
+// WithAuditActor 设置审计记录中的操作主体，如服务名或操作人，默认为当前用户名@主机名
+func WithAuditActor(actor string) Option {
+	return func(k *KeyInfo) {
+		k.auditActor = actor
+	}
+}
+
+// WithServerAuditor 设置密钥服务的审计，每次成功返回密钥时记录，操作主体为鉴权主体，未鉴权时为客户端 IP
+func WithServerAuditor(a Auditor) ServerOption {
+	return func(s *KeyServer) {
+		s.auditor = a
+	}
+}
+
+// defaultAuditActor 返回当前用户名@主机名
+var defaultAuditActor = sync.OnceValue(func() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	return name + "@" + host
+})
+
+// audit 发送该密钥的审计记录，未设置 Auditor 时不做处理
+func (k *KeyInfo) audit(event AuditEvent, path string) {
+	if k.auditor != nil {
+		k.auditor.Audit(k.auditRecord(event, path))
+	}
+}
+
+// auditRecord 返回该密钥的审计记录
+func (k *KeyInfo) auditRecord(event AuditEvent, path string) AuditRecord {
+	actor := k.auditActor
+	if actor == "" {
+		actor = defaultAuditActor()
+	}
+	return AuditRecord{
+		Time:    time.Now(),
+		Event:   event,
+		Actor:   actor,
+		KeyID:   k.KeyID,
+		Version: k.Version,
+		Stream:  k.Stream,
+		Path:    path,
+	}
+}
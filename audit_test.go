@@ -0,0 +1,152 @@
+package hlskeyinfo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingAuditor 记录收到的审计事件
+type recordingAuditor struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (a *recordingAuditor) Audit(rec AuditRecord) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.records = append(a.records, rec)
+}
+
+func (a *recordingAuditor) events() []AuditEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var out []AuditEvent
+	for _, rec := range a.records {
+		out = append(out, rec.Event)
+	}
+	return out
+}
+
+func TestAuditLifecycle(t *testing.T) {
+	a := &recordingAuditor{}
+	k, err := NewKeyInfo("https://keys.example.com/key", WithTempDir(t.TempDir()), WithAuditor(a), WithAuditActor("packager-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRotator(k, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := k.KeyID
+	next, err := r.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Dispose(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 创建、写入密钥文件、写入 keyinfo 文件，轮换生成新密钥后切换，最后清理两个密钥
+	want := []AuditEvent{
+		AuditKeyCreated, AuditFileWritten, AuditFileWritten,
+		AuditKeyCreated, AuditFileWritten, AuditFileWritten, AuditKeyRotated,
+		AuditKeyDisposed, AuditKeyDisposed,
+	}
+	got := a.events()
+	if strings.Join(toStrings(got), ",") != strings.Join(toStrings(want), ",") {
+		t.Fatalf("审计事件不正确: %v", got)
+	}
+	for _, rec := range a.records {
+		if rec.Actor != "packager-1" || rec.Time.IsZero() {
+			t.Errorf("审计记录缺少操作主体或时间: %+v", rec)
+		}
+	}
+	rotated := a.records[6]
+	if rotated.KeyID != next.KeyID || rotated.PreviousKeyID != first || rotated.Version != 2 {
+		t.Errorf("轮换记录不正确: %+v", rotated)
+	}
+
+	// 重复清理不再记录
+	n := len(a.records)
+	k.Dispose()
+	if len(a.records) != n {
+		t.Error("重复 Dispose 不应记录审计")
+	}
+}
+
+func toStrings(events []AuditEvent) []string {
+	out := make([]string, len(events))
+	for i, e := range events {
+		out[i] = string(e)
+	}
+	return out
+}
+
+func TestKeyServerAudit(t *testing.T) {
+	k, err := NewKeyInfo("https://keys.example.com/key", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Dispose()
+
+	a := &recordingAuditor{}
+	s := NewKeyServer(k, WithServerAuditor(a))
+	for _, target := range []string{"/key", "/key?kid=unknown"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = "203.0.113.7:5000"
+		s.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if len(a.records) != 1 {
+		t.Fatalf("仅成功返回密钥时记录审计，实际: %d", len(a.records))
+	}
+	rec := a.records[0]
+	if rec.Event != AuditKeyFetched || rec.Actor != "203.0.113.7" || rec.ClientIP != "203.0.113.7" {
+		t.Errorf("密钥请求审计记录不正确: %+v", rec)
+	}
+}
+
+func TestWriterAuditor(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriterAuditor(&buf)
+	k, err := NewKeyInfo("https://keys.example.com/key", WithTempDir(t.TempDir()), WithAuditor(w))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.Dispose()
+	if w.Err() != nil {
+		t.Fatal(w.Err())
+	}
+
+	var lines int
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var rec AuditRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			t.Fatalf("审计记录不是 JSON: %s", sc.Text())
+		}
+		if rec.KeyID != k.KeyID || rec.Actor == "" {
+			t.Errorf("审计记录不正确: %s", sc.Text())
+		}
+		if strings.Contains(sc.Text(), k.GetKeyHex()) {
+			t.Error("审计记录不应包含密钥")
+		}
+		lines++
+	}
+	if lines != 3 {
+		t.Errorf("期望 3 条审计记录，实际: %d", lines)
+	}
+
+	// slog 输出
+	buf.Reset()
+	NewSlogAuditor(slog.New(slog.NewTextHandler(&buf, nil))).Audit(AuditRecord{Event: AuditKeyRotated, Actor: "ops", KeyID: "abc", Version: 2})
+	if s := buf.String(); !strings.Contains(s, "event=key_rotated") || !strings.Contains(s, "key_id=abc") || !strings.Contains(s, "version=2") {
+		t.Errorf("slog 输出不正确: %s", s)
+	}
+}
@@ -0,0 +1,140 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DefaultTokenParam 默认的令牌查询参数名
+const DefaultTokenParam = "token"
+
+// requestInfo 密钥请求的上下文信息，由 KeyServer 在进入中间件前放入请求上下文，
+// 内层中间件写入的信息对外层（如访问日志）可见
+type requestInfo struct {
+	mu      sync.Mutex
+	subject string
+}
+
+type requestInfoKey struct{}
+
+// withRequestInfo 确保请求上下文中存在 requestInfo
+func withRequestInfo(r *http.Request) (*http.Request, *requestInfo) {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		return r, info
+	}
+	info := &requestInfo{}
+	return r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)), info
+}
+
+// setSubject 记录通过鉴权的请求主体
+func setSubject(r *http.Request, subject string) *http.Request {
+	r, info := withRequestInfo(r)
+	info.mu.Lock()
+	info.subject = subject
+	info.mu.Unlock()
+	return r
+}
+
+// SubjectFromContext 返回鉴权中间件记录的请求主体，如静态令牌的名称或 JWT 的 sub
+func SubjectFromContext(ctx context.Context) string {
+	info, ok := ctx.Value(requestInfoKey{}).(*requestInfo)
+	if !ok {
+		return ""
+	}
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	return info.subject
+}
+
+// TokenAuth 静态令牌鉴权，从 Authorization: Bearer 请求头或查询参数读取令牌
+// 令牌以常量时间比较，SetTokens 可在运行时轮换令牌
+type TokenAuth struct {
+	mu     sync.RWMutex
+	tokens map[[sha256.Size]byte]string // 令牌摘要 -> 名称
+	param  string
+}
+
+// NewTokenAuth 创建静态令牌鉴权，tokens 为名称到令牌的映射，名称作为请求主体记录
+func NewTokenAuth(tokens map[string]string) *TokenAuth {
+	a := &TokenAuth{param: DefaultTokenParam}
+	a.SetTokens(tokens)
+	return a
+}
+
+// SetTokens 替换全部有效令牌；轮换时可同时保留新旧令牌，待客户端切换后再移除旧令牌
+func (a *TokenAuth) SetTokens(tokens map[string]string) {
+	m := make(map[[sha256.Size]byte]string, len(tokens))
+	for name, token := range tokens {
+		if token != "" {
+			m[sha256.Sum256([]byte(token))] = name
+		}
+	}
+	a.mu.Lock()
+	a.tokens = m
+	a.mu.Unlock()
+}
+
+// SetParam 设置令牌查询参数名，为空时只接受请求头
+func (a *TokenAuth) SetParam(name string) *TokenAuth {
+	a.mu.Lock()
+	a.param = name
+	a.mu.Unlock()
+	return a
+}
+
+// Verify 校验令牌，返回令牌名称
+func (a *TokenAuth) Verify(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+	// 比较固定长度的摘要，避免泄露令牌长度；遍历全部令牌，耗时与匹配位置无关
+	sum := sha256.Sum256([]byte(token))
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	var name string
+	found := 0
+	for digest, n := range a.tokens {
+		if subtle.ConstantTimeCompare(sum[:], digest[:]) == 1 {
+			name = n
+			found = 1
+		}
+	}
+	return name, found == 1
+}
+
+// Middleware 返回鉴权中间件，令牌无效时返回 401
+func (a *TokenAuth) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			a.mu.RLock()
+			param := a.param
+			a.mu.RUnlock()
+
+			name, ok := a.Verify(requestToken(r, param))
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="hls-key"`)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, setSubject(r, name))
+		})
+	}
+}
+
+// requestToken 从 Authorization: Bearer 请求头或查询参数中读取令牌
+func requestToken(r *http.Request, param string) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, token, ok := strings.Cut(auth, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	if param != "" {
+		return r.URL.Query().Get(param)
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package hlskeyinfo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokenAuth(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	auth := NewTokenAuth(map[string]string{"player": "secret-1"})
+	var subject string
+	s := NewKeyServer(k, WithMiddleware(auth.Middleware(), func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			subject = SubjectFromContext(r.Context())
+			next.ServeHTTP(w, r)
+		})
+	}))
+
+	do := func(target, bearer string) int {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := do("/key", ""); code != http.StatusUnauthorized {
+		t.Errorf("缺少令牌应返回 401，实际: %d", code)
+	}
+	if code := do("/key", "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("错误令牌应返回 401，实际: %d", code)
+	}
+	if code := do("/key", "secret-1"); code != http.StatusOK || subject != "player" {
+		t.Errorf("Bearer 令牌应通过鉴权，实际: %d, 主体: %q", code, subject)
+	}
+	if code := do("/key?token=secret-1", ""); code != http.StatusOK {
+		t.Errorf("查询参数令牌应通过鉴权，实际: %d", code)
+	}
+
+	// 轮换令牌后旧令牌失效
+	auth.SetTokens(map[string]string{"player": "secret-2"})
+	if code := do("/key", "secret-1"); code != http.StatusUnauthorized {
+		t.Errorf("轮换后旧令牌应失效，实际: %d", code)
+	}
+	if code := do("/key", "secret-2"); code != http.StatusOK {
+		t.Errorf("轮换后新令牌应通过鉴权，实际: %d", code)
+	}
+}
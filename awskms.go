@@ -0,0 +1,89 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"fmt"
+)
+
+// AWSKMSClient AWS KMS 客户端，由调用方基于 AWS SDK 的 kms.Client 实现
+type AWSKMSClient interface {
+	GenerateDataKey(ctx context.Context, in AWSGenerateDataKeyInput) (plaintext, ciphertextBlob []byte, err error)
+	Encrypt(ctx context.Context, keyID string, plaintext []byte, encryptionContext map[string]string) ([]byte, error)
+	// Decrypt 解封密文，对称 KMS 密钥的密文中已包含 keyID，但仍建议传入以限制可用的密钥
+	Decrypt(ctx context.Context, keyID string, ciphertextBlob []byte, encryptionContext map[string]string) ([]byte, error)
+}
+
+// AWSGenerateDataKeyInput GenerateDataKey 参数
+type AWSGenerateDataKeyInput struct {
+	KeyID             string // KMS 密钥 ID、ARN 或别名，如 alias/hls
+	NumberOfBytes     int
+	EncryptionContext map[string]string
+}
+
+// AWSKMSProvider 基于 AWS KMS 的 KeyProvider，内容密钥由 GenerateDataKey 生成，根密钥保存在 KMS 的 HSM 中
+type AWSKMSProvider struct {
+	client            AWSKMSClient
+	keyID             string
+	encryptionContext map[string]string
+}
+
+var _ KeyProvider = &AWSKMSProvider{}
+
+// AWSKMSOption AWS KMS 选项
+type AWSKMSOption func(*AWSKMSProvider)
+
+// WithEncryptionContext 设置加密上下文，解封时必须提供相同的上下文，并会记录在 CloudTrail 中
+func WithEncryptionContext(ctx map[string]string) AWSKMSOption {
+	return func(p *AWSKMSProvider) {
+		p.encryptionContext = ctx
+	}
+}
+
+// NewAWSKMSProvider 创建基于 AWS KMS 的 KeyProvider
+func NewAWSKMSProvider(client AWSKMSClient, keyID string, opts ...AWSKMSOption) (*AWSKMSProvider, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("KMS 密钥 ID 不能为空")
+	}
+	p := &AWSKMSProvider{client: client, keyID: keyID}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+// GenerateDataKey 实现 KeyProvider 接口
+func (p *AWSKMSProvider) GenerateDataKey(ctx context.Context, size int) (plaintext, wrapped []byte, err error) {
+	if err := validateKeySize(size); err != nil {
+		return nil, nil, err
+	}
+	plaintext, wrapped, err = p.client.GenerateDataKey(ctx, AWSGenerateDataKeyInput{
+		KeyID:             p.keyID,
+		NumberOfBytes:     size,
+		EncryptionContext: p.encryptionContext,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("KMS GenerateDataKey 失败: %w", err)
+	}
+	if len(plaintext) != size {
+		return nil, nil, fmt.Errorf("KMS 返回的密钥长度 %d 与请求的 %d 不一致", len(plaintext), size)
+	}
+	return plaintext, wrapped, nil
+}
+
+// Encrypt 实现 KeyProvider 接口
+func (p *AWSKMSProvider) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	wrapped, err := p.client.Encrypt(ctx, p.keyID, plaintext, p.encryptionContext)
+	if err != nil {
+		return nil, fmt.Errorf("KMS Encrypt 失败: %w", err)
+	}
+	return wrapped, nil
+}
+
+// Decrypt 实现 KeyProvider 接口
+func (p *AWSKMSProvider) Decrypt(ctx context.Context, wrapped []byte) ([]byte, error) {
+	key, err := p.client.Decrypt(ctx, p.keyID, wrapped, p.encryptionContext)
+	if err != nil {
+		return nil, fmt.Errorf("KMS Decrypt 失败: %w", err)
+	}
+	return key, nil
+}
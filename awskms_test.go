@@ -0,0 +1,72 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"maps"
+	"testing"
+)
+
+// fakeAWSKMS 模拟 AWSKMSClient，解封时校验密钥 ID 与加密上下文
+type fakeAWSKMS struct {
+	*fakeProvider
+	keyID string
+	ctx   map[string]string
+}
+
+func (f *fakeAWSKMS) check(keyID string, encCtx map[string]string) error {
+	if keyID != f.keyID || !maps.Equal(encCtx, f.ctx) {
+		return errors.New("InvalidCiphertextException")
+	}
+	return nil
+}
+
+func (f *fakeAWSKMS) GenerateDataKey(ctx context.Context, in AWSGenerateDataKeyInput) ([]byte, []byte, error) {
+	if err := f.check(in.KeyID, in.EncryptionContext); err != nil {
+		return nil, nil, err
+	}
+	return f.fakeProvider.GenerateDataKey(ctx, in.NumberOfBytes)
+}
+
+func (f *fakeAWSKMS) Encrypt(ctx context.Context, keyID string, plaintext []byte, encCtx map[string]string) ([]byte, error) {
+	if err := f.check(keyID, encCtx); err != nil {
+		return nil, err
+	}
+	return gcmSeal(f.kek, plaintext)
+}
+
+func (f *fakeAWSKMS) Decrypt(ctx context.Context, keyID string, blob []byte, encCtx map[string]string) ([]byte, error) {
+	if err := f.check(keyID, encCtx); err != nil {
+		return nil, err
+	}
+	return gcmOpen(f.kek, blob)
+}
+
+func TestAWSKMSProvider(t *testing.T) {
+	ctx := context.Background()
+	encCtx := map[string]string{"stream": "channel-1"}
+	client := &fakeAWSKMS{fakeProvider: newFakeProvider(), keyID: "alias/hls", ctx: encCtx}
+
+	p, err := NewAWSKMSProvider(client, "alias/hls", WithEncryptionContext(encCtx))
+	if err != nil {
+		t.Fatalf("创建 AWSKMSProvider 失败: %v", err)
+	}
+	key, wrapped, err := p.GenerateDataKey(ctx, 16)
+	if err != nil || len(key) != 16 {
+		t.Fatalf("生成数据密钥失败: %v", err)
+	}
+	if got, err := p.Decrypt(ctx, wrapped); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("解封结果不一致: %v", err)
+	}
+
+	// 加密上下文不一致时无法解封
+	other, _ := NewAWSKMSProvider(client, "alias/hls", WithEncryptionContext(map[string]string{"stream": "channel-2"}))
+	if _, err := other.Decrypt(ctx, wrapped); err == nil {
+		t.Error("加密上下文不一致时应解封失败")
+	}
+
+	if _, err := NewAWSKMSProvider(client, ""); err == nil {
+		t.Error("KMS 密钥 ID 为空时应返回错误")
+	}
+}
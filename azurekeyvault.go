@@ -0,0 +1,178 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// azureKeyVaultVersion Key Vault REST API 版本
+const azureKeyVaultVersion = "7.4"
+
+// AzureKeyVaultManagedIdentity 使用托管标识访问 Key Vault，clientID 为空时使用系统分配的标识
+func AzureKeyVaultManagedIdentity(clientID string) AzureCredential {
+	return newAzureManagedIdentity(clientID, "https://vault.azure.net")
+}
+
+// AzureKeyVaultProvider 基于 Azure Key Vault 的 KeyProvider，直接调用 Key Vault REST API
+// 内容密钥在本地生成后以 Key Vault 中的密钥 wrapKey 封装，重启时通过 unwrapKey 恢复
+type AzureKeyVaultProvider struct {
+	vaultURL   string
+	keyName    string
+	keyVersion string
+	alg        string
+	cred       AzureCredential
+	client     *http.Client
+	random     io.Reader
+}
+
+var _ KeyProvider = &AzureKeyVaultProvider{}
+
+// AzureKeyVaultOption Azure Key Vault 选项
+type AzureKeyVaultOption func(*AzureKeyVaultProvider)
+
+// WithKeyVaultKeyVersion 指定封装使用的密钥版本，默认使用最新版本
+// 解封始终使用密文中记录的版本，轮换 Key Vault 密钥后旧密文仍可解封
+func WithKeyVaultKeyVersion(version string) AzureKeyVaultOption {
+	return func(p *AzureKeyVaultProvider) {
+		p.keyVersion = version
+	}
+}
+
+// WithKeyVaultAlgorithm 设置封装算法，默认 RSA-OAEP-256；Managed HSM 中的 AES 密钥使用 A256KW 等
+func WithKeyVaultAlgorithm(alg string) AzureKeyVaultOption {
+	return func(p *AzureKeyVaultProvider) {
+		p.alg = alg
+	}
+}
+
+// NewAzureKeyVaultProvider 创建基于 Key Vault 的 KeyProvider，vaultURL 如 https://myvault.vault.azure.net
+func NewAzureKeyVaultProvider(vaultURL, keyName string, cred AzureCredential, opts ...AzureKeyVaultOption) (*AzureKeyVaultProvider, error) {
+	if keyName == "" {
+		return nil, fmt.Errorf("Key Vault 密钥名称不能为空")
+	}
+	if u, err := url.Parse(vaultURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("无效的 Key Vault 地址: %q", vaultURL)
+	}
+	p := &AzureKeyVaultProvider{
+		vaultURL: strings.TrimSuffix(vaultURL, "/"),
+		keyName:  keyName,
+		alg:      "RSA-OAEP-256",
+		cred:     cred,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		random:   rand.Reader,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+// azureWrappedKey 封装结果，记录封装所用的密钥版本与算法
+type azureWrappedKey struct {
+	KID   string `json:"kid"`
+	Alg   string `json:"alg"`
+	Value string `json:"value"`
+}
+
+// GenerateDataKey 实现 KeyProvider 接口
+func (p *AzureKeyVaultProvider) GenerateDataKey(ctx context.Context, size int) (plaintext, wrapped []byte, err error) {
+	if err := validateKeySize(size); err != nil {
+		return nil, nil, err
+	}
+	plaintext = make([]byte, size)
+	if _, err := io.ReadFull(p.random, plaintext); err != nil {
+		return nil, nil, fmt.Errorf("生成密钥失败: %w", err)
+	}
+	if wrapped, err = p.Encrypt(ctx, plaintext); err != nil {
+		return nil, nil, err
+	}
+	return plaintext, wrapped, nil
+}
+
+// Encrypt 实现 KeyProvider 接口，返回包含密钥版本的 JSON
+func (p *AzureKeyVaultProvider) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	keyURL := p.vaultURL + "/keys/" + url.PathEscape(p.keyName)
+	if p.keyVersion != "" {
+		keyURL += "/" + url.PathEscape(p.keyVersion)
+	}
+	var out struct {
+		KID   string `json:"kid"`
+		Value string `json:"value"`
+	}
+	if err := p.do(ctx, keyURL+"/wrapkey", plaintext, p.alg, &out); err != nil {
+		return nil, fmt.Errorf("Key Vault wrapKey 失败: %w", err)
+	}
+	return json.Marshal(azureWrappedKey{KID: out.KID, Alg: p.alg, Value: out.Value})
+}
+
+// Decrypt 实现 KeyProvider 接口
+func (p *AzureKeyVaultProvider) Decrypt(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var w azureWrappedKey
+	if err := json.Unmarshal(wrapped, &w); err != nil {
+		return nil, fmt.Errorf("解析封装密钥失败: %w", err)
+	}
+	// 只向配置的 Key Vault 发送令牌，防止伪造的 kid 将请求导向其他主机
+	if !strings.HasPrefix(w.KID, p.vaultURL+"/keys/") {
+		return nil, fmt.Errorf("封装密钥不属于 %s: %q", p.vaultURL, w.KID)
+	}
+	value, err := base64.RawURLEncoding.DecodeString(w.Value)
+	if err != nil {
+		return nil, fmt.Errorf("解析封装密钥失败: %w", err)
+	}
+	var out struct {
+		Value string `json:"value"`
+	}
+	if err := p.do(ctx, w.KID+"/unwrapkey", value, w.Alg, &out); err != nil {
+		return nil, fmt.Errorf("Key Vault unwrapKey 失败: %w", err)
+	}
+	key, err := base64.RawURLEncoding.DecodeString(out.Value)
+	if err != nil {
+		return nil, fmt.Errorf("解析解封结果失败: %w", err)
+	}
+	return key, nil
+}
+
+// do 调用密钥操作接口
+func (p *AzureKeyVaultProvider) do(ctx context.Context, opURL string, value []byte, alg string, out any) error {
+	body, _ := json.Marshal(map[string]string{
+		"alg":   alg,
+		"value": base64.RawURLEncoding.EncodeToString(value),
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, opURL+"?api-version="+azureKeyVaultVersion, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := p.cred.Authorize(ctx, req); err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 Key Vault 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var v struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&v)
+		return fmt.Errorf("%s: %s %s", resp.Status, v.Error.Code, v.Error.Message)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("解析 Key Vault 响应失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newFakeKeyVault 模拟 Key Vault 的 wrapKey 与 unwrapKey，“封装”为逐字节异或 0x5a
+func newFakeKeyVault(t *testing.T) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer kv-token" || r.URL.Query().Get("api-version") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]any{"error": map[string]string{"code": "Unauthorized", "message": "invalid token"}})
+			return
+		}
+		var in struct {
+			Alg   string `json:"alg"`
+			Value string `json:"value"`
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		value, _ := base64.RawURLEncoding.DecodeString(in.Value)
+		for i := range value {
+			value[i] ^= 0x5a
+		}
+		// wrapKey 未指定版本时使用最新版本 v2，unwrapKey 使用密文中记录的版本
+		kid := srv.URL + strings.TrimSuffix(r.URL.Path, "/unwrapkey")
+		if base, ok := strings.CutSuffix(r.URL.Path, "/wrapkey"); ok {
+			kid = srv.URL + base + "/v2"
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"kid":   kid,
+			"value": base64.RawURLEncoding.EncodeToString(value),
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAzureKeyVaultProvider(t *testing.T) {
+	ctx := context.Background()
+	srv := newFakeKeyVault(t)
+	cred := AzureTokenFunc(func(ctx context.Context) (string, error) { return "kv-token", nil })
+
+	p, err := NewAzureKeyVaultProvider(srv.URL, "hls", cred)
+	if err != nil {
+		t.Fatalf("创建 AzureKeyVaultProvider 失败: %v", err)
+	}
+	key, wrapped, err := p.GenerateDataKey(ctx, 16)
+	if err != nil || len(key) != 16 {
+		t.Fatalf("生成数据密钥失败: %v", err)
+	}
+	var w azureWrappedKey
+	if err := json.Unmarshal(wrapped, &w); err != nil || w.KID != srv.URL+"/keys/hls/v2" || w.Alg != "RSA-OAEP-256" {
+		t.Errorf("封装结果应记录密钥版本与算法: %s", wrapped)
+	}
+	if got, err := p.Decrypt(ctx, wrapped); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("解封结果不一致: %v", err)
+	}
+
+	// 重启后从密文恢复
+	k, err := NewKeyInfoFromProviderWrapped(ctx, p, "https://example.com/key", wrapped, WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("恢复密钥失败: %v", err)
+	}
+	defer k.Dispose()
+	if !bytes.Equal(k.GetKey(), key) {
+		t.Error("恢复的密钥与原密钥不一致")
+	}
+
+	// 不向其他主机发送令牌
+	w.KID = "https://evil.example.com/keys/hls/v2"
+	forged, _ := json.Marshal(w)
+	if _, err := p.Decrypt(ctx, forged); err == nil {
+		t.Error("kid 不属于配置的 Key Vault 时应返回错误")
+	}
+
+	denied, _ := NewAzureKeyVaultProvider(srv.URL, "hls", AzureTokenFunc(func(ctx context.Context) (string, error) { return "wrong", nil }))
+	if _, err := denied.Encrypt(ctx, key); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("应返回 Key Vault 的错误信息: %v", err)
+	}
+}
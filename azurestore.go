@@ -0,0 +1,395 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// azureStorageVersion Blob REST API 版本，Bearer 令牌鉴权要求 2017-11-09 及以上
+const azureStorageVersion = "2021-08-06"
+
+// AzureBlobClient Azure Blob Storage 客户端，可使用 NewAzureBlobRESTClient，或基于 Azure SDK 自行实现
+// Blob 不存在时 Download 与 Delete 应返回包装了 ErrKeyNotFound 的错误
+type AzureBlobClient interface {
+	Upload(ctx context.Context, in AzureUploadInput) error
+	Download(ctx context.Context, container, blob string) ([]byte, error)
+	Delete(ctx context.Context, container, blob string) error
+	// List 返回前缀下的全部 Blob 名，需自行处理分页
+	List(ctx context.Context, container, prefix string) ([]string, error)
+}
+
+// AzureUploadInput 上传 Blob 参数
+type AzureUploadInput struct {
+	Container       string
+	Blob            string
+	Data            []byte
+	ContentType     string
+	EncryptionScope string // 加密范围，为空时使用容器默认加密
+}
+
+// AzureBlobStore 基于 Azure Blob Storage 的密钥存储，对象布局与 S3Store 一致
+type AzureBlobStore struct {
+	client          AzureBlobClient
+	container       string
+	prefix          string
+	encryptionScope string
+}
+
+var _ KeyStore = &AzureBlobStore{}
+
+// AzureOption Azure Blob 密钥存储选项
+type AzureOption func(*AzureBlobStore)
+
+// WithAzurePrefix 设置 Blob 名前缀，如 "hls/keys/"
+func WithAzurePrefix(prefix string) AzureOption {
+	return func(s *AzureBlobStore) {
+		s.prefix = prefix
+	}
+}
+
+// WithEncryptionScope 使用指定的加密范围加密密钥 Blob，加密范围可绑定 Key Vault 中客户管理的密钥
+func WithEncryptionScope(scope string) AzureOption {
+	return func(s *AzureBlobStore) {
+		s.encryptionScope = scope
+	}
+}
+
+// NewAzureBlobStore 创建基于 Azure Blob Storage 的密钥存储
+func NewAzureBlobStore(client AzureBlobClient, container string, opts ...AzureOption) (*AzureBlobStore, error) {
+	if container == "" {
+		return nil, fmt.Errorf("容器名称不能为空")
+	}
+	s := &AzureBlobStore{client: client, container: container}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Put 实现 KeyStore 接口
+func (s *AzureBlobStore) Put(ctx context.Context, rec KeyRecord) error {
+	name, err := objectName(s.prefix, rec.KeyID)
+	if err != nil {
+		return err
+	}
+	data, err := encodeRecord(rec)
+	if err != nil {
+		return err
+	}
+	err = s.client.Upload(ctx, AzureUploadInput{
+		Container:       s.container,
+		Blob:            name,
+		Data:            data,
+		ContentType:     "application/json",
+		EncryptionScope: s.encryptionScope,
+	})
+	if err != nil {
+		return fmt.Errorf("上传密钥 Blob 失败: %w", err)
+	}
+	return nil
+}
+
+// Get 实现 KeyStore 接口
+func (s *AzureBlobStore) Get(ctx context.Context, keyID string) (KeyRecord, error) {
+	name, err := objectName(s.prefix, keyID)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	data, err := s.client.Download(ctx, s.container, name)
+	if err != nil {
+		return KeyRecord{}, fmt.Errorf("下载密钥 Blob 失败: %w", err)
+	}
+	return decodeRecord(data)
+}
+
+// Delete 实现 KeyStore 接口
+func (s *AzureBlobStore) Delete(ctx context.Context, keyID string) error {
+	name, err := objectName(s.prefix, keyID)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Delete(ctx, s.container, name); err != nil {
+		return fmt.Errorf("删除密钥 Blob 失败: %w", err)
+	}
+	return nil
+}
+
+// List 实现 KeyStore 接口
+func (s *AzureBlobStore) List(ctx context.Context, stream string) ([]KeyRecord, error) {
+	names, err := s.client.List(ctx, s.container, s.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("列出密钥 Blob 失败: %w", err)
+	}
+	return listObjectRecords(ctx, names, stream, func(ctx context.Context, name string) ([]byte, error) {
+		data, err := s.client.Download(ctx, s.container, name)
+		if err != nil {
+			return nil, fmt.Errorf("下载密钥 Blob 失败: %w", err)
+		}
+		return data, nil
+	})
+}
+
+// AzureCredential Azure 请求鉴权
+type AzureCredential interface {
+	// Authorize 为请求添加鉴权信息
+	Authorize(ctx context.Context, req *http.Request) error
+}
+
+// azureSAS SAS 令牌鉴权
+type azureSAS string
+
+// AzureSAS 使用共享访问签名鉴权，token 为 URL 查询字符串（可带前导 ?）
+func AzureSAS(token string) AzureCredential {
+	return azureSAS(strings.TrimPrefix(token, "?"))
+}
+
+// Authorize 实现 AzureCredential 接口
+func (s azureSAS) Authorize(ctx context.Context, req *http.Request) error {
+	sas, err := url.ParseQuery(string(s))
+	if err != nil {
+		return fmt.Errorf("解析 SAS 令牌失败: %w", err)
+	}
+	q := req.URL.Query()
+	for k, v := range sas {
+		q[k] = v
+	}
+	req.URL.RawQuery = q.Encode()
+	return nil
+}
+
+// AzureTokenFunc 由函数提供 Bearer 访问令牌的鉴权，可用于对接 azidentity 等凭据库
+type AzureTokenFunc func(ctx context.Context) (string, error)
+
+// Authorize 实现 AzureCredential 接口
+func (f AzureTokenFunc) Authorize(ctx context.Context, req *http.Request) error {
+	token, err := f(ctx)
+	if err != nil {
+		return fmt.Errorf("获取访问令牌失败: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// azureManagedIdentity 托管标识鉴权，从实例元数据服务获取访问令牌并缓存至过期前
+type azureManagedIdentity struct {
+	clientID string
+	resource string
+	endpoint string // 实例元数据服务令牌端点
+	client   *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// AzureManagedIdentity 使用托管标识鉴权，clientID 为空时使用系统分配的标识
+func AzureManagedIdentity(clientID string) AzureCredential {
+	return newAzureManagedIdentity(clientID, "https://storage.azure.com/")
+}
+
+// newAzureManagedIdentity 创建获取指定资源令牌的托管标识鉴权
+func newAzureManagedIdentity(clientID, resource string) *azureManagedIdentity {
+	return &azureManagedIdentity{
+		clientID: clientID,
+		resource: resource,
+		endpoint: "http://169.254.169.254/metadata/identity/oauth2/token",
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Authorize 实现 AzureCredential 接口
+func (m *azureManagedIdentity) Authorize(ctx context.Context, req *http.Request) error {
+	token, err := m.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// accessToken 返回缓存的访问令牌，过期前 5 分钟刷新
+func (m *azureManagedIdentity) accessToken(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.token != "" && time.Until(m.expires) > 5*time.Minute {
+		return m.token, nil
+	}
+
+	q := url.Values{"api-version": {"2018-02-01"}, "resource": {m.resource}}
+	if m.clientID != "" {
+		q.Set("client_id", m.clientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata", "true")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("获取托管标识令牌失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("获取托管标识令牌失败: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	var v struct {
+		AccessToken string `json:"access_token"`
+		ExpiresOn   string `json:"expires_on"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return "", fmt.Errorf("解析托管标识令牌失败: %w", err)
+	}
+	sec, err := strconv.ParseInt(v.ExpiresOn, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("解析令牌过期时间失败: %w", err)
+	}
+	m.token, m.expires = v.AccessToken, time.Unix(sec, 0)
+	return m.token, nil
+}
+
+// AzureBlobRESTClient 基于 Blob REST API 的 AzureBlobClient 实现，无需依赖 Azure SDK
+type AzureBlobRESTClient struct {
+	accountURL string
+	cred       AzureCredential
+	client     *http.Client
+}
+
+var _ AzureBlobClient = &AzureBlobRESTClient{}
+
+// NewAzureBlobRESTClient 创建 Blob REST 客户端，accountURL 如 https://account.blob.core.windows.net
+func NewAzureBlobRESTClient(accountURL string, cred AzureCredential) *AzureBlobRESTClient {
+	return &AzureBlobRESTClient{
+		accountURL: strings.TrimSuffix(accountURL, "/"),
+		cred:       cred,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Upload 实现 AzureBlobClient 接口
+func (c *AzureBlobRESTClient) Upload(ctx context.Context, in AzureUploadInput) error {
+	resp, err := c.do(ctx, http.MethodPut, in.Container, in.Blob, nil, in.Data, func(h http.Header) {
+		h.Set("x-ms-blob-type", "BlockBlob")
+		if in.ContentType != "" {
+			h.Set("Content-Type", in.ContentType)
+		}
+		if in.EncryptionScope != "" {
+			h.Set("x-ms-encryption-scope", in.EncryptionScope)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Download 实现 AzureBlobClient 接口
+func (c *AzureBlobRESTClient) Download(ctx context.Context, container, blob string) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, container, blob, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取 Blob 失败: %w", err)
+	}
+	return data, nil
+}
+
+// Delete 实现 AzureBlobClient 接口
+func (c *AzureBlobRESTClient) Delete(ctx context.Context, container, blob string) error {
+	resp, err := c.do(ctx, http.MethodDelete, container, blob, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// List 实现 AzureBlobClient 接口，自动处理分页
+func (c *AzureBlobRESTClient) List(ctx context.Context, container, prefix string) ([]string, error) {
+	var names []string
+	marker := ""
+	for {
+		q := url.Values{"restype": {"container"}, "comp": {"list"}}
+		if prefix != "" {
+			q.Set("prefix", prefix)
+		}
+		if marker != "" {
+			q.Set("marker", marker)
+		}
+		resp, err := c.do(ctx, http.MethodGet, container, "", q, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Blobs []struct {
+				Name string `xml:"Name"`
+			} `xml:"Blobs>Blob"`
+			NextMarker string `xml:"NextMarker"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("解析 Blob 列表失败: %w", err)
+		}
+		for _, b := range result.Blobs {
+			names = append(names, b.Name)
+		}
+		if result.NextMarker == "" {
+			return names, nil
+		}
+		marker = result.NextMarker
+	}
+}
+
+// do 发送 Blob REST 请求，404 返回 ErrKeyNotFound，其他非 2xx 状态返回错误
+func (c *AzureBlobRESTClient) do(ctx context.Context, method, container, blob string, query url.Values, body []byte, header func(http.Header)) (*http.Response, error) {
+	u := c.accountURL + "/" + url.PathEscape(container)
+	if blob != "" {
+		u += "/" + escapePath(blob)
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", azureStorageVersion)
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	if header != nil {
+		header(req.Header)
+	}
+	if err := c.cred.Authorize(ctx, req); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 Azure Blob 失败: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound && blob != "" {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, blob)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("请求 Azure Blob 失败: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
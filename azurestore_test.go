@@ -0,0 +1,164 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAzure 模拟 AzureBlobClient
+type fakeAzure struct {
+	*fakeObjects
+	uploads []AzureUploadInput
+}
+
+func (f *fakeAzure) Upload(ctx context.Context, in AzureUploadInput) error {
+	f.put(in.Container+"/"+in.Blob, in.Data)
+	f.mu.Lock()
+	f.uploads = append(f.uploads, in)
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeAzure) Download(ctx context.Context, container, blob string) ([]byte, error) {
+	return f.get(container + "/" + blob)
+}
+
+func (f *fakeAzure) Delete(ctx context.Context, container, blob string) error {
+	return f.delete(container + "/" + blob)
+}
+
+func (f *fakeAzure) List(ctx context.Context, container, prefix string) ([]string, error) {
+	var names []string
+	for _, name := range f.list(container + "/" + prefix) {
+		names = append(names, strings.TrimPrefix(name, container+"/"))
+	}
+	return names, nil
+}
+
+func TestAzureBlobStore(t *testing.T) {
+	client := &fakeAzure{fakeObjects: newFakeObjects()}
+	store, err := NewAzureBlobStore(client, "media-keys", WithAzurePrefix("hls/"), WithEncryptionScope("hls-cmk"))
+	if err != nil {
+		t.Fatalf("创建 AzureBlobStore 失败: %v", err)
+	}
+	testKeyStore(t, store)
+
+	if in := client.uploads[0]; in.Blob != "hls/key-a.json" || in.EncryptionScope != "hls-cmk" {
+		t.Errorf("上传参数不正确: %+v", in)
+	}
+}
+
+// newFakeBlobServer 模拟 Blob REST API，每页最多返回一个 Blob 以覆盖分页
+func newFakeBlobServer(t *testing.T, authorized func(r *http.Request) bool) *httptest.Server {
+	objects := newFakeObjects()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) || r.Header.Get("x-ms-version") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Query().Get("comp") == "list" {
+			container := strings.TrimPrefix(r.URL.Path, "/")
+			names := objects.list(container + "/" + r.URL.Query().Get("prefix"))
+			slices.Sort(names)
+			var result struct {
+				XMLName    xml.Name `xml:"EnumerationResults"`
+				Blobs      []string `xml:"Blobs>Blob>Name"`
+				NextMarker string
+			}
+			for _, name := range names {
+				name = strings.TrimPrefix(name, container+"/")
+				if name > r.URL.Query().Get("marker") && len(result.Blobs) == 0 {
+					result.Blobs = append(result.Blobs, name)
+				} else if len(result.Blobs) == 1 && name > result.Blobs[0] {
+					result.NextMarker = result.Blobs[0]
+				}
+			}
+			xml.NewEncoder(w).Encode(result)
+			return
+		}
+
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		switch r.Method {
+		case http.MethodPut:
+			if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(r.Body)
+			objects.put(name, data)
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodGet:
+			data, err := objects.get(name)
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case http.MethodDelete:
+			if err := objects.delete(name); err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAzureBlobRESTClient(t *testing.T) {
+	t.Run("SAS", func(t *testing.T) {
+		srv := newFakeBlobServer(t, func(r *http.Request) bool {
+			return r.URL.Query().Get("sig") == "secret" && r.URL.Query().Get("sv") != ""
+		})
+		store, err := NewAzureBlobStore(NewAzureBlobRESTClient(srv.URL, AzureSAS("?sv=2021-08-06&sig=secret")), "media-keys")
+		if err != nil {
+			t.Fatalf("创建 AzureBlobStore 失败: %v", err)
+		}
+		testKeyStore(t, store)
+	})
+
+	t.Run("ManagedIdentity", func(t *testing.T) {
+		var tokenRequests int
+		imds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("client_id") != "app-id" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			tokenRequests++
+			fmt.Fprintf(w, `{"access_token":"mi-token","expires_on":"%d"}`, time.Now().Add(time.Hour).Unix())
+		}))
+		t.Cleanup(imds.Close)
+
+		cred := newAzureManagedIdentity("app-id", "https://storage.azure.com/")
+		cred.endpoint = imds.URL
+		srv := newFakeBlobServer(t, func(r *http.Request) bool {
+			return r.Header.Get("Authorization") == "Bearer mi-token"
+		})
+		store, err := NewAzureBlobStore(NewAzureBlobRESTClient(srv.URL, cred), "media-keys")
+		if err != nil {
+			t.Fatalf("创建 AzureBlobStore 失败: %v", err)
+		}
+		testKeyStore(t, store)
+		if tokenRequests != 1 {
+			t.Errorf("令牌应被缓存，实际请求 %d 次", tokenRequests)
+		}
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		srv := newFakeBlobServer(t, func(r *http.Request) bool { return false })
+		_, err := NewAzureBlobRESTClient(srv.URL, AzureSAS("sig=wrong")).Download(context.Background(), "media-keys", "key-a.json")
+		if err == nil || errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("鉴权失败应返回错误而不是密钥不存在: %v", err)
+		}
+	})
+}
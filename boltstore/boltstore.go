@@ -0,0 +1,123 @@
+// Package boltstore 提供基于嵌入式 bbolt 数据库的 hlskeyinfo.KeyStore，
+// 适用于单二进制部署，密钥与轮换历史在重启后仍然保留且无需任何外部服务
+package boltstore
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	hlskeyinfo "github.com/ixugo/hls_keyinfo"
+	bolt "go.etcd.io/bbolt"
+)
+
+// bucketName 密钥记录所在的 bucket
+var bucketName = []byte("hls_keys")
+
+// Store 基于 bbolt 的密钥存储，每个密钥以 KeyID 为键、KeyRecord JSON 为值保存
+type Store struct {
+	db *bolt.DB
+}
+
+var _ hlskeyinfo.KeyStore = &Store{}
+
+// Open 打开或创建数据库文件，文件权限 0600；同一文件同时只能被一个进程打开
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化数据库失败: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close 关闭数据库
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Put 实现 hlskeyinfo.KeyStore 接口
+func (s *Store) Put(ctx context.Context, rec hlskeyinfo.KeyRecord) error {
+	if rec.KeyID == "" {
+		return fmt.Errorf("KeyID 不能为空")
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("序列化密钥记录失败: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Put([]byte(rec.KeyID), data)
+	})
+}
+
+// Get 实现 hlskeyinfo.KeyStore 接口
+func (s *Store) Get(ctx context.Context, keyID string) (hlskeyinfo.KeyRecord, error) {
+	var rec hlskeyinfo.KeyRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketName).Get([]byte(keyID))
+		if data == nil {
+			return fmt.Errorf("%w: %s", hlskeyinfo.ErrKeyNotFound, keyID)
+		}
+		return decode(data, &rec)
+	})
+	return rec, err
+}
+
+// Delete 实现 hlskeyinfo.KeyStore 接口
+func (s *Store) Delete(ctx context.Context, keyID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketName)
+		if b.Get([]byte(keyID)) == nil {
+			return fmt.Errorf("%w: %s", hlskeyinfo.ErrKeyNotFound, keyID)
+		}
+		return b.Delete([]byte(keyID))
+	})
+}
+
+// List 实现 hlskeyinfo.KeyStore 接口
+func (s *Store) List(ctx context.Context, stream string) ([]hlskeyinfo.KeyRecord, error) {
+	var out []hlskeyinfo.KeyRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).ForEach(func(k, v []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var rec hlskeyinfo.KeyRecord
+			if err := decode(v, &rec); err != nil {
+				return fmt.Errorf("密钥 %s: %w", k, err)
+			}
+			if stream == "" || rec.Stream == stream {
+				out = append(out, rec)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(out, func(a, b hlskeyinfo.KeyRecord) int {
+		if c := a.NotBefore.Compare(b.NotBefore); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.KeyID, b.KeyID)
+	})
+	return out, nil
+}
+
+// decode 解码密钥记录，bbolt 返回的数据只在事务内有效，json.Unmarshal 会复制
+func decode(data []byte, rec *hlskeyinfo.KeyRecord) error {
+	if err := json.Unmarshal(data, rec); err != nil {
+		return fmt.Errorf("解析密钥记录失败: %w", err)
+	}
+	return nil
+}
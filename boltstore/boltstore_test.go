@@ -0,0 +1,81 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	hlskeyinfo "github.com/ixugo/hls_keyinfo"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys.db")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	a := hlskeyinfo.KeyRecord{KeyID: "key-a", Stream: "channel-1", Key: bytes.Repeat([]byte{1}, 16), Version: 1, NotBefore: now}
+	b := hlskeyinfo.KeyRecord{KeyID: "key-b", Stream: "channel-2", Key: bytes.Repeat([]byte{2}, 16), Version: 1, NotBefore: now.Add(time.Second)}
+	for _, rec := range []hlskeyinfo.KeyRecord{b, a} {
+		if err := store.Put(ctx, rec); err != nil {
+			t.Fatalf("保存 %s 失败: %v", rec.KeyID, err)
+		}
+	}
+	if list, err := store.List(ctx, ""); err != nil || len(list) != 2 || list[0].KeyID != "key-a" {
+		t.Errorf("列出全部记录不正确: %+v, %v", list, err)
+	}
+	if list, _ := store.List(ctx, "channel-2"); len(list) != 1 || list[0].KeyID != "key-b" {
+		t.Errorf("按流列出记录不正确: %+v", list)
+	}
+
+	// 重新打开后记录仍然存在
+	store.Close()
+	if store, err = Open(path); err != nil {
+		t.Fatalf("重新打开数据库失败: %v", err)
+	}
+	defer store.Close()
+	got, err := store.Get(ctx, "key-a")
+	if err != nil || !bytes.Equal(got.Key, a.Key) || !got.NotBefore.Equal(a.NotBefore) {
+		t.Errorf("重启后读取的密钥记录不正确: %+v, %v", got, err)
+	}
+
+	if err := store.Delete(ctx, "key-a"); err != nil {
+		t.Fatalf("删除密钥失败: %v", err)
+	}
+	if _, err := store.Get(ctx, "key-a"); !errors.Is(err, hlskeyinfo.ErrKeyNotFound) {
+		t.Errorf("删除后应返回 ErrKeyNotFound，实际: %v", err)
+	}
+	if err := store.Delete(ctx, "key-a"); !errors.Is(err, hlskeyinfo.ErrKeyNotFound) {
+		t.Errorf("删除不存在的密钥应返回 ErrKeyNotFound，实际: %v", err)
+	}
+}
+
+func TestStoreRotator(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "keys.db"))
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer store.Close()
+
+	k, err := hlskeyinfo.NewKeyInfo("https://example.com/key", hlskeyinfo.WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建KeyInfo失败: %v", err)
+	}
+	r, err := hlskeyinfo.NewRotator(k, 0, hlskeyinfo.WithKeyStore(store))
+	if err != nil {
+		t.Fatalf("创建轮换器失败: %v", err)
+	}
+	defer r.Dispose()
+	if _, err := r.Rotate(); err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+	if list, _ := store.List(context.Background(), ""); len(list) != 2 || list[0].NotAfter.IsZero() {
+		t.Errorf("应记录轮换历史: %+v", list)
+	}
+}
@@ -0,0 +1,651 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// cbcs 视频按 1:9 模式加密：每 10 个块加密第 1 个；音频加密全部完整的块
+const (
+	cbcsCryptBlocks = 1
+	cbcsSkipBlocks  = 9
+	cbcsClearHeader = 32 // 视频 NAL 开头保持明文的字节数，覆盖 NAL 头与切片头起始部分
+)
+
+// CMAFTracks fMP4 初始化分片中需要加密的轨道信息，用于加密对应的媒体分片
+type CMAFTracks struct {
+	tracks map[uint32]*cmafTrack
+}
+
+// cmafTrack 单个轨道的加密参数
+type cmafTrack struct {
+	video       bool   // 视频按 NAL 子样本模式加密，音频整样本加密
+	format      string // 原始样本描述类型，如 avc1、mp4a
+	nalLength   int    // 视频样本中 NAL 长度字段的字节数
+	hevc        bool   // HEVC 的 NAL 头为 2 字节
+	defaultSize uint32 // trex 中的默认样本大小
+}
+
+// Tracks 返回需要加密的轨道数
+func (t *CMAFTracks) Tracks() int {
+	return len(t.tracks)
+}
+
+// newCMAFTrack 按样本描述条目创建轨道信息，不加密的轨道返回 nil
+func newCMAFTrack(handler string, entry *mp4Box, format string) (*cmafTrack, error) {
+	switch handler {
+	case "vide":
+		tr := &cmafTrack{video: true, format: format}
+		switch format {
+		case "avc1", "avc3":
+			c := entry.child("avcC")
+			if c == nil || len(c.payload) < 5 {
+				return nil, fmt.Errorf("%s 缺少 avcC", format)
+			}
+			tr.nalLength = int(c.payload[4]&3) + 1
+		case "hvc1", "hev1":
+			c := entry.child("hvcC")
+			if c == nil || len(c.payload) < 22 {
+				return nil, fmt.Errorf("%s 缺少 hvcC", format)
+			}
+			tr.nalLength, tr.hevc = int(c.payload[21]&3)+1, true
+		default:
+			return nil, fmt.Errorf("不支持的视频编码: %s", format)
+		}
+		return tr, nil
+	case "soun":
+		switch format {
+		case "mp4a", "ac-3", "ec-3":
+			return &cmafTrack{format: format}, nil
+		}
+		return nil, fmt.Errorf("不支持的音频编码: %s", format)
+	}
+	// 字幕等其他轨道保持明文
+	return nil, nil
+}
+
+// ParseCMAFInit 解析 fMP4 初始化分片中的轨道信息，明文与 EncryptCMAFInit 加密后的初始化分片均可
+// 用于进程重启等场景下，不重新加密初始化分片而继续加密媒体分片
+func ParseCMAFInit(r io.Reader) (*CMAFTracks, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("读取初始化分片失败: %w", err)
+	}
+	boxes, err := parseMP4Boxes(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析初始化分片失败: %w", err)
+	}
+	tracks, _, err := cmafTracks(boxes)
+	return tracks, err
+}
+
+// cmafTracks 返回各轨道的加密参数，以及待加密的样本描述条目
+func cmafTracks(boxes []*mp4Box) (*CMAFTracks, map[*mp4Box]*cmafTrack, error) {
+	var moov *mp4Box
+	for _, b := range boxes {
+		if b.typ == "moov" {
+			moov = b
+		}
+	}
+	if moov == nil {
+		return nil, nil, fmt.Errorf("初始化分片缺少 moov")
+	}
+
+	defaults := make(map[uint32]uint32)
+	if mvex := moov.child("mvex"); mvex != nil {
+		for _, b := range mvex.children {
+			if b.typ == "trex" && len(b.payload) >= 24 {
+				defaults[binary.BigEndian.Uint32(b.payload[4:])] = binary.BigEndian.Uint32(b.payload[16:])
+			}
+		}
+	}
+
+	out := &CMAFTracks{tracks: make(map[uint32]*cmafTrack)}
+	entries := make(map[*mp4Box]*cmafTrack)
+	for _, trak := range moov.children {
+		if trak.typ != "trak" {
+			continue
+		}
+		id, err := trackID(trak)
+		if err != nil {
+			return nil, nil, err
+		}
+		hdlr := trak.path("mdia", "hdlr")
+		stsd := trak.path("mdia", "minf", "stbl", "stsd")
+		if hdlr == nil || len(hdlr.payload) < 12 || stsd == nil || len(stsd.children) == 0 {
+			return nil, nil, fmt.Errorf("轨道 %d 缺少 hdlr 或 stsd", id)
+		}
+		handler := string(hdlr.payload[8:12])
+		for _, entry := range stsd.children {
+			format := entry.typ
+			if format == "encv" || format == "enca" {
+				frma := entry.path("sinf", "frma")
+				if frma == nil || len(frma.payload) < 4 {
+					return nil, nil, fmt.Errorf("轨道 %d 缺少 frma", id)
+				}
+				format = string(frma.payload[:4])
+			}
+			tr, err := newCMAFTrack(handler, entry, format)
+			if err != nil {
+				return nil, nil, fmt.Errorf("轨道 %d: %w", id, err)
+			}
+			if tr == nil {
+				continue
+			}
+			tr.defaultSize = defaults[id]
+			out.tracks[id] = tr
+			if entry.typ != "encv" && entry.typ != "enca" {
+				entries[entry] = tr
+			}
+		}
+	}
+	if len(out.tracks) == 0 {
+		return nil, nil, fmt.Errorf("初始化分片中没有可加密的音视频轨道")
+	}
+	return out, entries, nil
+}
+
+// trackID 返回 tkhd 中的轨道 ID
+func trackID(trak *mp4Box) (uint32, error) {
+	tkhd := trak.child("tkhd")
+	if tkhd == nil || len(tkhd.payload) < 24 {
+		return 0, fmt.Errorf("轨道缺少 tkhd")
+	}
+	if v, _ := boxFlags(tkhd); v == 1 {
+		return binary.BigEndian.Uint32(tkhd.payload[20:]), nil
+	}
+	return binary.BigEndian.Uint32(tkhd.payload[12:]), nil
+}
+
+// cbcsKey 返回 cbcs 加密使用的密钥、常量 IV 与 16 字节 KID
+func (k *KeyInfo) cbcsKey() (cipher.Block, []byte, []byte, error) {
+	if err := k.requireSampleAES(); err != nil {
+		return nil, nil, nil, err
+	}
+	if len(k.key) != 16 {
+		return nil, nil, nil, fmt.Errorf("cbcs 加密需要 16 字节密钥，实际: %d", len(k.key))
+	}
+	if !k.HasIV() {
+		return nil, nil, nil, fmt.Errorf("cbcs 加密使用常量 IV，需要设置显式 IV")
+	}
+	iv, err := k.SegmentIV(0)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	kid := k.KID()
+	block, err := aes.NewCipher(k.key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return block, iv, kid[:], nil
+}
+
+// EncryptCMAFInit 按 CENC cbcs 方案改写 fMP4 初始化分片，从 src 读取明文写入 dst，返回用于加密媒体分片的轨道信息
+// 音视频样本描述改为 encv/enca 并添加 sinf（frma、schm、tenc），tenc 中写入 KID()、
+// 常量 IV 与视频 1:9 的加密模式；pssh 为完整的 pssh box，追加到 moov 中，可用 PSSHBox 生成
+// 要求加密方式为 SAMPLE-AES 且设置了显式 IV；视频支持 H.264 与 HEVC，音频支持 AAC、AC-3 与 E-AC-3，其他轨道保持明文
+func (k *KeyInfo) EncryptCMAFInit(dst io.Writer, src io.Reader, pssh ...[]byte) (*CMAFTracks, error) {
+	_, iv, kid, err := k.cbcsKey()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf("读取初始化分片失败: %w", err)
+	}
+	boxes, err := parseMP4Boxes(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析初始化分片失败: %w", err)
+	}
+	tracks, entries, err := cmafTracks(boxes)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("初始化分片已加密")
+	}
+
+	var psshBoxes []*mp4Box
+	for _, p := range pssh {
+		b, err := parseMP4Boxes(p)
+		if err != nil || len(b) != 1 || b[0].typ != "pssh" {
+			return nil, fmt.Errorf("无效的 pssh box")
+		}
+		psshBoxes = append(psshBoxes, b[0])
+	}
+
+	for entry, tr := range entries {
+		var pattern byte
+		entry.typ = "enca"
+		if tr.video {
+			pattern = cbcsCryptBlocks<<4 | cbcsSkipBlocks
+			entry.typ = "encv"
+		}
+		tenc := append([]byte{0, pattern, 1, 0}, kid...)
+		tenc = append(append(tenc, byte(len(iv))), iv...)
+		schm := append([]byte("cbcs"), 0, 1, 0, 0)
+		entry.children = append(entry.children, &mp4Box{typ: "sinf", children: []*mp4Box{
+			{typ: "frma", payload: []byte(tr.format)},
+			fullBox("schm", 0, 0, schm),
+			{typ: "schi", children: []*mp4Box{fullBox("tenc", 1, 0, tenc)}},
+		}})
+	}
+
+	var buf bytes.Buffer
+	for _, b := range boxes {
+		if b.typ == "moov" {
+			b.children = append(b.children, psshBoxes...)
+		}
+		if err := b.appendTo(&buf); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := dst.Write(buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("写入初始化分片失败: %w", err)
+	}
+	return tracks, nil
+}
+
+// PSSHBox 生成版本 1 的 pssh box，systemID 为 DRM 系统 ID，kids 为其保护的 KID，data 为该系统的私有数据
+func PSSHBox(systemID [16]byte, kids [][16]byte, data []byte) []byte {
+	payload := append([]byte{}, systemID[:]...)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(len(kids)))
+	for _, kid := range kids {
+		payload = append(payload, kid[:]...)
+	}
+	payload = binary.BigEndian.AppendUint32(payload, uint32(len(data)))
+	payload = append(payload, data...)
+
+	var buf bytes.Buffer
+	fullBox("pssh", 1, 0, payload).appendTo(&buf)
+	return buf.Bytes()
+}
+
+// PSSH 生成保护该密钥 KID 的 pssh box，systemID 为 DRM 系统 ID，data 为该系统的私有数据
+func (k *KeyInfo) PSSH(systemID [16]byte, data []byte) []byte {
+	return PSSHBox(systemID, [][16]byte{k.KID()}, data)
+}
+
+// subsample 子样本：先是明文字节，随后是按模式加密的字节
+type subsample struct {
+	clear     uint16
+	protected uint32
+}
+
+// EncryptCMAFSegment 按 cbcs 方案加密 fMP4 媒体分片的样本，从 src 读取明文写入 dst
+// tracks 为 EncryptCMAFInit 或 ParseCMAFInit 返回的轨道信息。视频每个 NAL 保留长度字段与开头 32 字节明文，
+// 其余按 1:9 模式加密并在每个 traf 中添加 senc、saiz、saio 记录子样本；音频整个样本加密，末尾不足一块的字节保持明文；
+// 每个子样本（音频为每个样本）从常量 IV 开始 CBC。同步修正 trun 的 data_offset 与单个 sidx 引用的长度
+// 分片整体读入内存；要求 tfhd 使用 default-base-is-moof（CMAF 的要求），已包含 senc 时返回错误
+func (k *KeyInfo) EncryptCMAFSegment(dst io.Writer, src io.Reader, tracks *CMAFTracks) error {
+	block, iv, _, err := k.cbcsKey()
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return fmt.Errorf("读取分片失败: %w", err)
+	}
+
+	type topBox struct {
+		box        *mp4Box // 需要改写的 box，为空时原样输出
+		start, end int
+	}
+	var (
+		top    []topBox
+		grow   []int // 各 moof 增加的字节数
+		sidx   *mp4Box
+		offset int
+	)
+	for offset < len(data) {
+		typ, header, size, err := nextMP4Box(data[offset:])
+		if err != nil {
+			return fmt.Errorf("解析分片失败: %w", err)
+		}
+		tb := topBox{start: offset, end: offset + size}
+		switch typ {
+		case "moof":
+			moof, err := newMP4Box(typ, data[offset+header:offset+size])
+			if err != nil {
+				return fmt.Errorf("解析分片失败: %w", err)
+			}
+			if err := encryptMoof(moof, data, offset, block, iv, tracks); err != nil {
+				return err
+			}
+			grow = append(grow, moof.size()-size)
+			if err := shiftTrunOffsets(moof, grow[len(grow)-1]); err != nil {
+				return err
+			}
+			tb.box = moof
+		case "sidx":
+			if sidx != nil {
+				return fmt.Errorf("不支持包含多个 sidx 的分片")
+			}
+			sidx = &mp4Box{typ: typ, payload: bytes.Clone(data[offset+header : offset+size])}
+			tb.box = sidx
+		}
+		top = append(top, tb)
+		offset += size
+	}
+	if sidx != nil {
+		if err := growSidx(sidx, grow); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, tb := range top {
+		if tb.box == nil {
+			buf.Write(data[tb.start:tb.end])
+		} else if err := tb.box.appendTo(&buf); err != nil {
+			return err
+		}
+	}
+	if _, err := dst.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("写入分片失败: %w", err)
+	}
+	return nil
+}
+
+// encryptMoof 加密 moof 引用的样本（原地修改 data），并为视频轨道添加 senc、saiz、saio
+// moofStart 为 moof 在 data 中的偏移
+func encryptMoof(moof *mp4Box, data []byte, moofStart int, block cipher.Block, iv []byte, tracks *CMAFTracks) error {
+	type aux struct {
+		traf *mp4Box
+		saio *mp4Box
+	}
+	var auxes []aux
+	for _, traf := range moof.children {
+		if traf.typ != "traf" {
+			continue
+		}
+		tfhd := traf.child("tfhd")
+		if tfhd == nil || len(tfhd.payload) < 8 {
+			return fmt.Errorf("traf 缺少 tfhd")
+		}
+		tr := tracks.tracks[binary.BigEndian.Uint32(tfhd.payload[4:])]
+		if tr == nil {
+			continue
+		}
+		if traf.child("senc") != nil {
+			return fmt.Errorf("分片已加密")
+		}
+		defaultSize, err := tfhdDefaultSize(tfhd, tr.defaultSize)
+		if err != nil {
+			return err
+		}
+
+		var sencData []byte
+		var sizes []byte
+		samples := 0
+		for _, trun := range traf.children {
+			if trun.typ != "trun" {
+				continue
+			}
+			err := trunSamples(trun, defaultSize, func(off, size int) error {
+				start := moofStart + off
+				if off < 0 || size < 0 || start+size > len(data) {
+					return fmt.Errorf("样本超出分片范围")
+				}
+				sample := data[start : start+size]
+				if !tr.video {
+					cbcsEncrypt(block, iv, sample, 0, 0)
+					return nil
+				}
+				subs, err := tr.encryptSample(block, iv, sample)
+				if err != nil {
+					return err
+				}
+				n := len(sencData)
+				sencData = binary.BigEndian.AppendUint16(sencData, uint16(len(subs)))
+				for _, s := range subs {
+					sencData = binary.BigEndian.AppendUint16(sencData, s.clear)
+					sencData = binary.BigEndian.AppendUint32(sencData, s.protected)
+				}
+				if len(sencData)-n > 0xff {
+					return fmt.Errorf("样本的子样本过多")
+				}
+				sizes = append(sizes, byte(len(sencData)-n))
+				samples++
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if !tr.video {
+			continue
+		}
+
+		senc := fullBox("senc", 0, 0x2, append(binary.BigEndian.AppendUint32(nil, uint32(samples)), sencData...))
+		saiz := fullBox("saiz", 0, 0, append(binary.BigEndian.AppendUint32([]byte{0}, uint32(samples)), sizes...))
+		saio := fullBox("saio", 0, 0, binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 1), 0))
+		traf.children = append(traf.children, senc, saiz, saio)
+		auxes = append(auxes, aux{traf: traf, saio: saio})
+	}
+
+	// saio 指向 senc 中第一个样本的数据，相对 moof 起始位置
+	for _, a := range auxes {
+		pos := 8
+		for _, c := range moof.children {
+			if c == a.traf {
+				break
+			}
+			pos += c.size()
+		}
+		pos += 8
+		for _, c := range a.traf.children {
+			if c.typ == "senc" {
+				break
+			}
+			pos += c.size()
+		}
+		binary.BigEndian.PutUint32(a.saio.payload[8:], uint32(pos+16))
+	}
+	return nil
+}
+
+// tfhdDefaultSize 返回 tfhd 的默认样本大小，未设置时使用 trex 中的值，同时检查数据偏移的基准
+func tfhdDefaultSize(tfhd *mp4Box, trex uint32) (uint32, error) {
+	_, flags := boxFlags(tfhd)
+	if flags&0x1 != 0 || flags&0x20000 == 0 {
+		return 0, fmt.Errorf("仅支持 default-base-is-moof 的分片")
+	}
+	pos := 8
+	for _, f := range []uint32{0x2, 0x8} {
+		if flags&f != 0 {
+			pos += 4
+		}
+	}
+	if flags&0x10 == 0 {
+		return trex, nil
+	}
+	if len(tfhd.payload) < pos+4 {
+		return 0, fmt.Errorf("tfhd 长度不足")
+	}
+	return binary.BigEndian.Uint32(tfhd.payload[pos:]), nil
+}
+
+// trunFields 返回 trun 的样本数、data_offset 所在位置（不存在时为 -1）、第一个样本的位置与每个样本的字段数
+func trunFields(trun *mp4Box) (count, dataOffset, first, fields int, err error) {
+	_, flags := boxFlags(trun)
+	if len(trun.payload) < 8 {
+		return 0, 0, 0, 0, fmt.Errorf("trun 长度不足")
+	}
+	count, dataOffset, first = int(binary.BigEndian.Uint32(trun.payload[4:])), -1, 8
+	if flags&0x1 != 0 {
+		dataOffset, first = 8, 12
+	}
+	if flags&0x4 != 0 {
+		first += 4
+	}
+	for _, f := range []uint32{0x100, 0x200, 0x400, 0x800} {
+		if flags&f != 0 {
+			fields++
+		}
+	}
+	if uint64(len(trun.payload)) < uint64(first)+uint64(count)*uint64(fields)*4 {
+		return 0, 0, 0, 0, fmt.Errorf("trun 长度不足")
+	}
+	return count, dataOffset, first, fields, nil
+}
+
+// trunSamples 按顺序以样本相对 moof 的偏移与大小调用 fn
+func trunSamples(trun *mp4Box, defaultSize uint32, fn func(off, size int) error) error {
+	count, dataOffset, first, fields, err := trunFields(trun)
+	if err != nil {
+		return err
+	}
+	if dataOffset < 0 {
+		return fmt.Errorf("trun 缺少 data_offset")
+	}
+	_, flags := boxFlags(trun)
+	off := int(int32(binary.BigEndian.Uint32(trun.payload[dataOffset:])))
+	for i := range count {
+		size := defaultSize
+		if flags&0x200 != 0 {
+			pos := first + i*fields*4
+			if flags&0x100 != 0 {
+				pos += 4
+			}
+			size = binary.BigEndian.Uint32(trun.payload[pos:])
+		} else if defaultSize == 0 {
+			return fmt.Errorf("无法确定样本大小")
+		}
+		if err := fn(off, int(size)); err != nil {
+			return err
+		}
+		off += int(size)
+	}
+	return nil
+}
+
+// shiftTrunOffsets 将 moof 中各 trun 的 data_offset 增加 delta
+func shiftTrunOffsets(moof *mp4Box, delta int) error {
+	for _, traf := range moof.children {
+		if traf.typ != "traf" {
+			continue
+		}
+		for _, trun := range traf.children {
+			if trun.typ != "trun" {
+				continue
+			}
+			_, pos, _, _, err := trunFields(trun)
+			if err != nil {
+				return err
+			}
+			if pos >= 0 {
+				v := int32(binary.BigEndian.Uint32(trun.payload[pos:])) + int32(delta)
+				binary.BigEndian.PutUint32(trun.payload[pos:], uint32(v))
+			}
+		}
+	}
+	return nil
+}
+
+// growSidx 按各 moof 增加的字节数修正 sidx 引用的长度：单个引用时加上总增长量，引用数与 moof 数相同时逐个修正
+func growSidx(sidx *mp4Box, grow []int) error {
+	v, _ := boxFlags(sidx)
+	pos := 4 + 4 + 4 + 8
+	if v == 1 {
+		pos += 8
+	}
+	if len(sidx.payload) < pos+4 {
+		return fmt.Errorf("sidx 长度不足")
+	}
+	count := int(binary.BigEndian.Uint16(sidx.payload[pos+2:]))
+	pos += 4
+	if len(sidx.payload) < pos+count*12 {
+		return fmt.Errorf("sidx 长度不足")
+	}
+	if count == 1 && len(grow) > 1 {
+		total := 0
+		for _, g := range grow {
+			total += g
+		}
+		grow = []int{total}
+	}
+	if count != len(grow) {
+		return fmt.Errorf("sidx 的引用数 %d 与 moof 数 %d 不一致", count, len(grow))
+	}
+	for i, g := range grow {
+		ref := binary.BigEndian.Uint32(sidx.payload[pos+i*12:])
+		size := ref&0x7fffffff + uint32(g)
+		binary.BigEndian.PutUint32(sidx.payload[pos+i*12:], ref&0x80000000|size&0x7fffffff)
+	}
+	return nil
+}
+
+// encryptSample 按 NAL 加密视频样本，返回子样本划分
+// 非 VCL NAL 与过短的 VCL NAL 保持明文，合并到下一个子样本的明文部分
+func (tr *cmafTrack) encryptSample(block cipher.Block, iv []byte, sample []byte) ([]subsample, error) {
+	var (
+		subs  []subsample
+		clear int
+	)
+	for pos := 0; pos < len(sample); {
+		if pos+tr.nalLength > len(sample) {
+			return nil, fmt.Errorf("样本的 NAL 长度字段不完整")
+		}
+		n := 0
+		for _, b := range sample[pos : pos+tr.nalLength] {
+			n = n<<8 | int(b)
+		}
+		start := pos + tr.nalLength
+		end := start + n
+		if n == 0 || end > len(sample) {
+			return nil, fmt.Errorf("样本的 NAL 长度无效: %d", n)
+		}
+		pos = end
+
+		protected := 0
+		if tr.vcl(sample[start]) && n > cbcsClearHeader {
+			// 加密部分为块大小的整数倍，余下的字节归入明文
+			protected = (n - cbcsClearHeader) &^ (aes.BlockSize - 1)
+		}
+		clear += end - start + tr.nalLength - protected
+		if protected == 0 {
+			continue
+		}
+		for clear > 0xffff {
+			subs = append(subs, subsample{clear: 0xffff})
+			clear -= 0xffff
+		}
+		subs = append(subs, subsample{clear: uint16(clear), protected: uint32(protected)})
+		cbcsEncrypt(block, iv, sample[end-protected:end], cbcsCryptBlocks, cbcsSkipBlocks)
+		clear = 0
+	}
+	for clear > 0 {
+		c := min(clear, 0xffff)
+		subs = append(subs, subsample{clear: uint16(c)})
+		clear -= c
+	}
+	return subs, nil
+}
+
+// vcl 判断 NAL 是否为编码切片
+func (tr *cmafTrack) vcl(header byte) bool {
+	if tr.hevc {
+		return (header>>1)&0x3f < 32
+	}
+	t := header & 0x1f
+	return t >= 1 && t <= 5
+}
+
+// cbcsEncrypt 从 iv 开始以 CBC 原地加密 data：crypt 为 0 时加密全部完整的块，
+// 否则每 crypt+skip 个块加密前 crypt 个，跳过的块不参与 CBC 链；末尾不足一块的字节保持明文
+func cbcsEncrypt(block cipher.Block, iv []byte, data []byte, crypt, skip int) {
+	mode := cipher.NewCBCEncrypter(block, iv)
+	if crypt == 0 {
+		n := len(data) &^ (aes.BlockSize - 1)
+		mode.CryptBlocks(data[:n], data[:n])
+		return
+	}
+	for off := 0; off+crypt*aes.BlockSize <= len(data); off += (crypt + skip) * aes.BlockSize {
+		mode.CryptBlocks(data[off:off+crypt*aes.BlockSize], data[off:off+crypt*aes.BlockSize])
+	}
+}
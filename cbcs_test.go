@@ -0,0 +1,282 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+// testBox 序列化测试用的 box
+func testBox(t *testing.T, b *mp4Box) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := b.appendTo(&buf); err != nil {
+		t.Fatalf("序列化失败: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// testCMAFInit 生成包含 H.264 视频（轨道 1）与 AAC 音频（轨道 2）的初始化分片
+func testCMAFInit(t *testing.T) []byte {
+	trak := func(id uint32, handler string, entry *mp4Box) *mp4Box {
+		tkhd := make([]byte, 80)
+		binary.BigEndian.PutUint32(tkhd[12:], id)
+		hdlr := append(make([]byte, 8), handler...)
+		hdlr = append(hdlr, make([]byte, 13)...)
+		stsd := &mp4Box{typ: "stsd", payload: []byte{0, 0, 0, 0, 0, 0, 0, 1}, children: []*mp4Box{entry}}
+		return &mp4Box{typ: "trak", children: []*mp4Box{
+			{typ: "tkhd", payload: tkhd},
+			{typ: "mdia", children: []*mp4Box{
+				{typ: "hdlr", payload: hdlr},
+				{typ: "minf", children: []*mp4Box{{typ: "stbl", children: []*mp4Box{stsd}}}},
+			}},
+		}}
+	}
+	trex := func(id uint32) *mp4Box {
+		p := make([]byte, 24)
+		binary.BigEndian.PutUint32(p[4:], id)
+		return &mp4Box{typ: "trex", payload: p}
+	}
+	video := &mp4Box{typ: "avc1", payload: make([]byte, 78), children: []*mp4Box{
+		{typ: "avcC", payload: []byte{1, 0x64, 0, 0x1f, 0xff, 0xe0}},
+	}}
+	audio := &mp4Box{typ: "mp4a", payload: make([]byte, 28), children: []*mp4Box{{typ: "esds", payload: make([]byte, 20)}}}
+	moov := &mp4Box{typ: "moov", children: []*mp4Box{
+		{typ: "mvhd", payload: make([]byte, 100)},
+		trak(1, "vide", video),
+		trak(2, "soun", audio),
+		{typ: "mvex", children: []*mp4Box{trex(1), trex(2)}},
+	}}
+	ftyp := &mp4Box{typ: "ftyp", payload: []byte("iso6\x00\x00\x00\x00cmfc")}
+	return append(testBox(t, ftyp), testBox(t, moov)...)
+}
+
+// testNAL 生成带 4 字节长度字段的 NAL
+func testNAL(header byte, size int) []byte {
+	nal := binary.BigEndian.AppendUint32(nil, uint32(size))
+	nal = append(nal, header)
+	for i := 1; i < size; i++ {
+		nal = append(nal, byte(i))
+	}
+	return nal
+}
+
+// testCMAFSegment 生成包含 sidx 与一个 moof 的媒体分片，返回分片与各轨道的明文样本
+func testCMAFSegment(t *testing.T) ([]byte, map[uint32][][]byte) {
+	samples := map[uint32][][]byte{
+		1: {
+			append(testNAL(0x67, 10), testNAL(0x65, 400)...),
+			testNAL(0x41, 40),
+			append(testNAL(0x06, 20), testNAL(0x41, 70000)...),
+		},
+		2: {bytes.Repeat([]byte{0xaa}, 100), bytes.Repeat([]byte{0xbb}, 100)},
+	}
+
+	videoTrun := binary.BigEndian.AppendUint32(nil, uint32(len(samples[1])))
+	videoTrun = append(videoTrun, 0, 0, 0, 0)
+	for _, s := range samples[1] {
+		videoTrun = binary.BigEndian.AppendUint32(videoTrun, uint32(len(s)))
+	}
+	audioTfhd := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 2), 100)
+	audioTrun := binary.BigEndian.AppendUint32(nil, uint32(len(samples[2])))
+	audioTrun = append(audioTrun, 0, 0, 0, 0)
+
+	videoRun := fullBox("trun", 0, 0x201, videoTrun)
+	audioRun := fullBox("trun", 0, 0x1, audioTrun)
+	moof := &mp4Box{typ: "moof", children: []*mp4Box{
+		fullBox("mfhd", 0, 0, []byte{0, 0, 0, 1}),
+		{typ: "traf", children: []*mp4Box{fullBox("tfhd", 0, 0x20000, []byte{0, 0, 0, 1}), videoRun}},
+		{typ: "traf", children: []*mp4Box{fullBox("tfhd", 0, 0x20010, audioTfhd), audioRun}},
+	}}
+
+	var mdat []byte
+	for _, id := range []uint32{1, 2} {
+		for _, s := range samples[id] {
+			mdat = append(mdat, s...)
+		}
+	}
+	// data_offset 相对 moof 起始位置
+	off := moof.size() + 8
+	binary.BigEndian.PutUint32(videoRun.payload[8:], uint32(off))
+	binary.BigEndian.PutUint32(audioRun.payload[8:], uint32(off+len(mdat)-200))
+
+	mdatBox := &mp4Box{typ: "mdat", payload: mdat}
+	sidx := make([]byte, 36)
+	binary.BigEndian.PutUint16(sidx[22:], 1)
+	binary.BigEndian.PutUint32(sidx[24:], uint32(moof.size()+mdatBox.size()))
+
+	var seg []byte
+	seg = append(seg, testBox(t, &mp4Box{typ: "styp", payload: []byte("msdh\x00\x00\x00\x00")})...)
+	seg = append(seg, testBox(t, &mp4Box{typ: "sidx", payload: sidx})...)
+	seg = append(seg, testBox(t, moof)...)
+	seg = append(seg, testBox(t, mdatBox)...)
+	return seg, samples
+}
+
+// cbcsDecrypt 以 CBC 解密按模式加密的数据，用于验证
+func cbcsDecrypt(block cipher.Block, iv, data []byte, crypt, skip int) {
+	mode := cipher.NewCBCDecrypter(block, iv)
+	if crypt == 0 {
+		n := len(data) / 16 * 16
+		mode.CryptBlocks(data[:n], data[:n])
+		return
+	}
+	for off := 0; off+16 <= len(data); off += (crypt + skip) * 16 {
+		mode.CryptBlocks(data[off:off+16], data[off:off+16])
+	}
+}
+
+func TestEncryptCMAF(t *testing.T) {
+	key := bytes.Repeat([]byte{9}, 16)
+	k, err := NewKeyInfoWithKey("skd://example.com/key", key, WithMethod(MethodSampleAES), WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+	defer k.Dispose()
+	k.SetIV("000102030405060708090a0b0c0d0e0f")
+	kid, _ := hex.DecodeString(k.KeyID)
+
+	systemID := [16]byte{0x94, 0xce, 0x86, 0xfb}
+	var init bytes.Buffer
+	tracks, err := k.EncryptCMAFInit(&init, bytes.NewReader(testCMAFInit(t)), PSSHBox(systemID, [][16]byte{[16]byte(kid)}, []byte("data")))
+	if err != nil {
+		t.Fatalf("加密初始化分片失败: %v", err)
+	}
+	if tracks.Tracks() != 2 {
+		t.Errorf("应有 2 个加密轨道，实际: %d", tracks.Tracks())
+	}
+
+	boxes, err := parseMP4Boxes(init.Bytes())
+	if err != nil {
+		t.Fatalf("解析加密后的初始化分片失败: %v", err)
+	}
+	moov := boxes[1]
+	for i, want := range []string{"encv", "enca"} {
+		entry := moov.children[1+i].path("mdia", "minf", "stbl", "stsd").children[0]
+		if entry.typ != want {
+			t.Fatalf("样本描述应为 %s，实际: %s", want, entry.typ)
+		}
+		tenc := entry.path("sinf", "schi", "tenc")
+		if string(entry.path("sinf", "schm").payload[4:8]) != "cbcs" || tenc == nil {
+			t.Fatalf("%s 缺少 cbcs 的 schm 或 tenc", want)
+		}
+		if !bytes.Equal(tenc.payload[8:24], kid) || !bytes.Equal(tenc.payload[25:41], []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}) {
+			t.Errorf("tenc 中的 KID 或常量 IV 不正确: %x", tenc.payload)
+		}
+		if want == "encv" && tenc.payload[5] != 0x19 {
+			t.Errorf("视频加密模式应为 1:9，实际: %x", tenc.payload[5])
+		}
+	}
+	if pssh := moov.child("pssh"); pssh == nil || !bytes.Equal(pssh.payload[4:20], systemID[:]) {
+		t.Errorf("moov 中应包含 pssh")
+	}
+
+	// 从加密后的初始化分片解析得到相同的轨道信息
+	parsed, err := ParseCMAFInit(bytes.NewReader(init.Bytes()))
+	if err != nil || parsed.Tracks() != 2 || parsed.tracks[1].nalLength != 4 {
+		t.Fatalf("解析加密后的初始化分片失败: %v", err)
+	}
+	if _, err := k.EncryptCMAFInit(&bytes.Buffer{}, bytes.NewReader(init.Bytes())); err == nil {
+		t.Errorf("重复加密初始化分片应返回错误")
+	}
+
+	seg, samples := testCMAFSegment(t)
+	var out bytes.Buffer
+	if err := k.EncryptCMAFSegment(&out, bytes.NewReader(seg), parsed); err != nil {
+		t.Fatalf("加密分片失败: %v", err)
+	}
+	enc := out.Bytes()
+	if len(enc) <= len(seg) {
+		t.Fatalf("加密后的分片应包含 senc 等 box")
+	}
+
+	top, err := parseMP4Boxes(enc)
+	if err != nil {
+		t.Fatalf("解析加密后的分片失败: %v", err)
+	}
+	sidx, moof := top[1], top[2]
+	if got := binary.BigEndian.Uint32(sidx.payload[24:]); int(got) != moof.size()+top[3].size() {
+		t.Errorf("sidx 引用长度应为 %d，实际: %d", moof.size()+top[3].size(), got)
+	}
+	moofStart := top[0].size() + sidx.size()
+
+	block, _ := aes.NewCipher(key)
+	iv := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	for _, traf := range moof.children[1:] {
+		id := binary.BigEndian.Uint32(traf.child("tfhd").payload[4:])
+		trun := traf.child("trun")
+		off := moofStart + int(binary.BigEndian.Uint32(trun.payload[8:]))
+		senc := traf.child("senc")
+		if id == 2 {
+			if senc != nil {
+				t.Errorf("音频整样本加密不需要 senc")
+			}
+			for i, want := range samples[2] {
+				got := bytes.Clone(enc[off : off+len(want)])
+				if bytes.Equal(got, want) {
+					t.Errorf("音频样本 %d 未加密", i)
+				}
+				cbcsDecrypt(block, iv, got, 0, 0)
+				if !bytes.Equal(got, want) {
+					t.Errorf("音频样本 %d 解密后不一致", i)
+				}
+				off += len(want)
+			}
+			continue
+		}
+
+		if senc == nil || traf.child("saiz") == nil || traf.child("saio") == nil {
+			t.Fatalf("视频 traf 缺少 senc、saiz 或 saio")
+		}
+		saio := traf.child("saio")
+		auxOff := moofStart + int(binary.BigEndian.Uint32(saio.payload[8:]))
+		if !bytes.Equal(enc[auxOff:auxOff+len(senc.payload)-8], senc.payload[8:]) {
+			t.Errorf("saio 未指向 senc 的样本数据")
+		}
+		aux := senc.payload[8:]
+		for i, want := range samples[1] {
+			got := bytes.Clone(enc[off : off+len(want)])
+			n := int(binary.BigEndian.Uint16(aux))
+			aux = aux[2:]
+			pos, total := 0, 0
+			for range n {
+				clear := int(binary.BigEndian.Uint16(aux))
+				protected := int(binary.BigEndian.Uint32(aux[2:]))
+				aux = aux[6:]
+				if protected%16 != 0 {
+					t.Errorf("样本 %d 的加密字节数应为块大小的整数倍: %d", i, protected)
+				}
+				pos += clear
+				cbcsDecrypt(block, iv, got[pos:pos+protected], 1, 9)
+				pos += protected
+				total += clear + protected
+			}
+			if total != len(want) {
+				t.Errorf("样本 %d 的子样本总长应为 %d，实际: %d", i, len(want), total)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("视频样本 %d 解密后不一致", i)
+			}
+			if i == 1 && !bytes.Equal(enc[off:off+len(want)], want) {
+				t.Errorf("过短的 NAL 应保持明文")
+			}
+			if i == 0 && !bytes.Equal(enc[off:off+14+4+cbcsClearHeader], want[:14+4+cbcsClearHeader]) {
+				t.Errorf("参数集与 NAL 开头应保持明文")
+			}
+			off += len(want)
+		}
+	}
+
+	if err := k.EncryptCMAFSegment(&bytes.Buffer{}, bytes.NewReader(enc), parsed); err == nil {
+		t.Errorf("重复加密分片应返回错误")
+	}
+
+	aes128, _ := NewKeyInfoWithKey("https://example.com/key", key, WithTempDir(t.TempDir()))
+	defer aes128.Dispose()
+	if _, err := aes128.EncryptCMAFInit(&bytes.Buffer{}, bytes.NewReader(testCMAFInit(t))); err == nil {
+		t.Errorf("AES-128 密钥加密 cbcs 应返回错误")
+	}
+}
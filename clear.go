@@ -0,0 +1,147 @@
+package hlskeyinfo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// InsertClearRanges 逐行复制媒体播放列表，对 isClear 返回 true 的分片插入 #EXT-X-KEY:METHOD=NONE，
+// 在其后第一个加密分片前重新写入此前生效的 EXT-X-KEY 标签，用于需保持明文的广告或片头
+// isClear 以分片的媒体序列号与 URI 调用；插入的标签位于分片的 EXT-X-PART、EXT-X-MAP 或 EXTINF 之前，其他行按原字节输出
+// LL-HLS 的部分分片与其所属分片一致，末尾尚未完成的分片以第一个部分分片的 URI 调用
+// 仅修改播放列表，明文区间的分片本身须未加密
+func InsertClearRanges(r io.Reader, w io.Writer, isClear func(seq uint64, uri string) bool) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+
+	var (
+		eol      string
+		seq      uint64
+		keys     []KeyTag // 当前生效的密钥标签
+		outClear bool     // 输出中已插入 METHOD=NONE 且尚未恢复
+		block    []string // 上一个分片之后尚未输出的行
+		lastKey  = -1     // block 中最后一个密钥标签的位置
+		insertAt = -1     // block 中第一个 EXT-X-PART、EXT-X-MAP 或 EXTINF 的位置
+		partURI  string   // block 中第一个 EXT-X-PART 的 URI
+	)
+	write := func(lines []string) error {
+		for _, l := range lines {
+			if _, err := bw.WriteString(l); err != nil {
+				return fmt.Errorf("写入播放列表失败: %w", err)
+			}
+		}
+		return nil
+	}
+	// flush 按分片是否为明文插入标签后输出 block；同一分片的部分分片与其一致
+	flush := func(uri string) error {
+		pos := insertAt
+		if pos < 0 || lastKey >= pos {
+			pos = lastKey + 1
+		}
+		var insert []string
+		wantClear := isClear(seq, uri)
+		switch {
+		case wantClear && len(keys) > 0 && (!outClear || lastKey >= 0):
+			insert = []string{"#EXT-X-KEY:METHOD=NONE"}
+		case !wantClear && outClear:
+			for _, k := range keys {
+				insert = append(insert, k.String())
+			}
+		}
+		outClear = wantClear && len(keys) > 0
+
+		if len(insert) > 0 {
+			if eol == "" {
+				eol = "\n"
+			}
+			// 插入位置之前的行缺少换行符时补齐
+			if pos > 0 && !strings.HasSuffix(block[pos-1], "\n") {
+				block[pos-1] += eol
+			}
+			for i := range insert {
+				insert[i] += eol
+			}
+			block = append(block[:pos], append(insert, block[pos:]...)...)
+		}
+		err := write(block)
+		block, lastKey, insertAt, partURI = block[:0], -1, -1, ""
+		return err
+	}
+	for n := 1; ; n++ {
+		line, readErr := br.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return fmt.Errorf("读取播放列表失败: %w", readErr)
+		}
+		if eol == "" && strings.HasSuffix(line, "\n") {
+			eol = "\n"
+			if strings.HasSuffix(line, "\r\n") {
+				eol = "\r\n"
+			}
+		}
+
+		content := strings.TrimSpace(line)
+		switch {
+		case content == "":
+		case strings.HasPrefix(content, "#EXT-X-STREAM-INF:"):
+			return fmt.Errorf("第 %d 行: 不支持主播放列表", n)
+		case strings.HasPrefix(content, "#EXT-X-MEDIA-SEQUENCE:"):
+			v, err := strconv.ParseUint(strings.TrimPrefix(content, "#EXT-X-MEDIA-SEQUENCE:"), 10, 64)
+			if err != nil {
+				return fmt.Errorf("第 %d 行: 无效的媒体序列号: %w", n, err)
+			}
+			seq = v
+		case strings.HasPrefix(content, "#EXT-X-KEY:"):
+			tag, err := ParseKeyTag(content)
+			if err != nil {
+				return fmt.Errorf("第 %d 行: %w", n, err)
+			}
+			keys = activeKeys(keys, tag)
+			lastKey = len(block)
+		case strings.HasPrefix(content, "#EXT-X-MAP:") || strings.HasPrefix(content, "#EXTINF:"):
+			if insertAt < 0 {
+				insertAt = len(block)
+			}
+		case strings.HasPrefix(content, "#EXT-X-PART:"):
+			if insertAt < 0 {
+				insertAt = len(block)
+			}
+			if partURI == "" {
+				attrs, err := parseAttributes(strings.TrimPrefix(content, "#EXT-X-PART:"))
+				if err != nil {
+					return fmt.Errorf("第 %d 行: %w", n, err)
+				}
+				partURI = attrs["URI"]
+			}
+		case !strings.HasPrefix(content, "#"):
+			block = append(block, line)
+			if err := flush(content); err != nil {
+				return err
+			}
+			seq++
+			line = ""
+		}
+		if line != "" {
+			block = append(block, line)
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	// 末尾尚未完成的分片已有部分分片时，按第一个部分分片的 URI 判断
+	var err error
+	if partURI != "" {
+		err = flush(partURI)
+	} else {
+		err = write(block)
+	}
+	if err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("写入播放列表失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package hlskeyinfo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInsertClearRanges(t *testing.T) {
+	playlist := "#EXTM3U\r\n" +
+		"#EXT-X-MEDIA-SEQUENCE:10\r\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"https://example.com/key\"\r\n" +
+		"#EXTINF:4.0,\r\n" +
+		"ad_0.ts\r\n" +
+		"#EXTINF:4.0,\r\n" +
+		"ad_1.ts\r\n" +
+		"#EXT-X-DISCONTINUITY\r\n" +
+		"#EXTINF:4.0,\r\n" +
+		"main_0.ts\r\n" +
+		"#EXTINF:4.0,\r\n" +
+		"mid_0.ts\r\n" +
+		"#EXT-X-ENDLIST\r\n"
+
+	var out strings.Builder
+	var seqs []uint64
+	err := InsertClearRanges(strings.NewReader(playlist), &out, func(seq uint64, uri string) bool {
+		seqs = append(seqs, seq)
+		return strings.HasPrefix(uri, "ad_") || strings.HasPrefix(uri, "mid_")
+	})
+	if err != nil {
+		t.Fatalf("插入明文区间失败: %v", err)
+	}
+	want := "#EXTM3U\r\n" +
+		"#EXT-X-MEDIA-SEQUENCE:10\r\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"https://example.com/key\"\r\n" +
+		"#EXT-X-KEY:METHOD=NONE\r\n" +
+		"#EXTINF:4.0,\r\n" +
+		"ad_0.ts\r\n" +
+		"#EXTINF:4.0,\r\n" +
+		"ad_1.ts\r\n" +
+		"#EXT-X-DISCONTINUITY\r\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"https://example.com/key\"\r\n" +
+		"#EXTINF:4.0,\r\n" +
+		"main_0.ts\r\n" +
+		"#EXT-X-KEY:METHOD=NONE\r\n" +
+		"#EXTINF:4.0,\r\n" +
+		"mid_0.ts\r\n" +
+		"#EXT-X-ENDLIST\r\n"
+	if out.String() != want {
+		t.Errorf("输出不正确:\n%q\n期望\n%q", out.String(), want)
+	}
+	if len(seqs) != 4 || seqs[0] != 10 || seqs[3] != 13 {
+		t.Errorf("媒体序列号不正确: %v", seqs)
+	}
+
+	// 恢复加密时按 KEYFORMAT 写回全部生效的密钥，明文区间内的新密钥同样被覆盖
+	playlist = `#EXTM3U
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://a",KEYFORMAT="com.apple.streamingkeydelivery"
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="data:a",KEYFORMAT="urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed"
+#EXTINF:4.0,
+seg0.ts
+#EXTINF:4.0,
+ad0.ts
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://b",KEYFORMAT="com.apple.streamingkeydelivery"
+#EXTINF:4.0,
+ad1.ts
+#EXTINF:4.0,
+seg1.ts`
+	out.Reset()
+	err = InsertClearRanges(strings.NewReader(playlist), &out, func(seq uint64, uri string) bool {
+		return seq == 1 || seq == 2
+	})
+	if err != nil {
+		t.Fatalf("插入明文区间失败: %v", err)
+	}
+	want = `#EXTM3U
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://a",KEYFORMAT="com.apple.streamingkeydelivery"
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="data:a",KEYFORMAT="urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed"
+#EXTINF:4.0,
+seg0.ts
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:4.0,
+ad0.ts
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://b",KEYFORMAT="com.apple.streamingkeydelivery"
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:4.0,
+ad1.ts
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="data:a",KEYFORMAT="urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed"
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://b",KEYFORMAT="com.apple.streamingkeydelivery"
+#EXTINF:4.0,
+seg1.ts`
+	if out.String() != want {
+		t.Errorf("输出不正确:\n%s\n期望\n%s", out.String(), want)
+	}
+
+	if err := InsertClearRanges(strings.NewReader("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1\nv.m3u8\n"), &out, func(uint64, string) bool { return true }); err == nil {
+		t.Error("主播放列表应返回错误")
+	}
+}
+
+func TestInsertClearRangesParts(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-PART-INF:PART-TARGET=1.0
+#EXT-X-MEDIA-SEQUENCE:1
+#EXT-X-KEY:METHOD=AES-128,URI="https://example.com/key"
+#EXT-X-PART:DURATION=1.0,URI="seg1.0.mp4",INDEPENDENT=YES
+#EXT-X-PART:DURATION=1.0,URI="seg1.1.mp4"
+#EXTINF:2.0,
+seg1.mp4
+#EXT-X-PART:DURATION=1.0,URI="ad2.0.mp4",INDEPENDENT=YES
+#EXT-X-PRELOAD-HINT:TYPE=PART,URI="ad2.1.mp4"
+`
+	var out strings.Builder
+	err := InsertClearRanges(strings.NewReader(playlist), &out, func(seq uint64, uri string) bool {
+		return strings.HasPrefix(uri, "ad")
+	})
+	if err != nil {
+		t.Fatalf("插入明文区间失败: %v", err)
+	}
+	// 尚未完成的分片按其第一个部分分片判断，标签插在所有部分分片之前
+	want := strings.Replace(playlist, "seg1.mp4\n", "seg1.mp4\n#EXT-X-KEY:METHOD=NONE\n", 1)
+	if out.String() != want {
+		t.Errorf("输出不正确:\n%s\n期望\n%s", out.String(), want)
+	}
+}
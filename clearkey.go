@@ -0,0 +1,92 @@
+package hlskeyinfo
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxClearKeyRequest ClearKey 许可证请求体的最大长度
+const maxClearKeyRequest = 64 << 10
+
+// ClearKey W3C ClearKey 的 JSON Web Key，K 与 KID 为无填充的 Base64url
+type ClearKey struct {
+	Kty string `json:"kty"`
+	K   string `json:"k"`
+	KID string `json:"kid"`
+}
+
+// ClearKeyResponse ClearKey 许可证响应，即 {"keys":[{"kty":"oct","k":...,"kid":...}]}
+type ClearKeyResponse struct {
+	Keys []ClearKey `json:"keys"`
+	Type string     `json:"type,omitempty"` // 会话类型，如 temporary
+}
+
+// clearKeyRequest CDM 发出的 ClearKey 许可证请求
+type clearKeyRequest struct {
+	KIDs []string `json:"kids"`
+	Type string   `json:"type"`
+}
+
+// NewClearKey 由 KID 与内容密钥创建 ClearKey
+func NewClearKey(kid [16]byte, key []byte) ClearKey {
+	return ClearKey{
+		Kty: "oct",
+		K:   base64.RawURLEncoding.EncodeToString(key),
+		KID: base64.RawURLEncoding.EncodeToString(kid[:]),
+	}
+}
+
+// ClearKey 返回该密钥的 ClearKey，KID 为 KID()；ClearKey 仅支持 16 字节密钥
+func (k *KeyInfo) ClearKey() (ClearKey, error) {
+	if len(k.key) != 16 {
+		return ClearKey{}, fmt.Errorf("ClearKey 需要 16 字节密钥，实际: %d", len(k.key))
+	}
+	kid := k.KID()
+	return NewClearKey(kid, k.key), nil
+}
+
+// ClearKeyHandler 返回 ClearKey 许可证服务，接受 CDM 以 POST 发送的 {"kids":[...]} 请求，
+// 按 KID 的十六进制小写形式从 source 查找密钥并以 JSON 返回，全部未找到时响应 404
+// 浏览器跨域请求时可用 CORS 等中间件包裹
+func ClearKeyHandler(source KeySource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		var req clearKeyRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxClearKeyRequest)).Decode(&req); err != nil {
+			http.Error(w, "无效的 ClearKey 请求", http.StatusBadRequest)
+			return
+		}
+
+		resp := ClearKeyResponse{Keys: []ClearKey{}, Type: req.Type}
+		for _, s := range req.KIDs {
+			b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+			if err != nil || len(b) != 16 {
+				http.Error(w, "无效的 KID", http.StatusBadRequest)
+				return
+			}
+			key, ok := source.LookupKey(hex.EncodeToString(b))
+			if !ok || len(key) != 16 {
+				continue
+			}
+			resp.Keys = append(resp.Keys, NewClearKey([16]byte(b), key))
+		}
+		if len(resp.Keys) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", "application/json")
+		h.Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
@@ -0,0 +1,62 @@
+package hlskeyinfo
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClearKeyHandler(t *testing.T) {
+	r := newTestRotator(t, 0, WithKeepPrevious(1))
+	first := r.Current()
+	second, err := r.Rotate()
+	if err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+	kid := func(k *KeyInfo) string {
+		b, _ := hex.DecodeString(k.KeyID)
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	h := ClearKeyHandler(r)
+	body := `{"kids":["` + kid(first) + `","` + kid(second) + `","AAAAAAAAAAAAAAAAAAAAAA"],"type":"temporary"}`
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/license", strings.NewReader(body)))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("响应不正确: %d %s", w.Code, w.Body)
+	}
+	var resp ClearKeyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Type != "temporary" || len(resp.Keys) != 2 {
+		t.Fatalf("应返回两把密钥: %s", w.Body)
+	}
+	for i, k := range []*KeyInfo{first, second} {
+		want, _ := k.ClearKey()
+		if resp.Keys[i] != want || want.Kty != "oct" || want.K != base64.RawURLEncoding.EncodeToString(k.GetKey()) {
+			t.Errorf("第 %d 把密钥不正确: %+v", i, resp.Keys[i])
+		}
+	}
+
+	cases := []struct {
+		method, body string
+		status       int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "{", http.StatusBadRequest},
+		{http.MethodPost, `{"kids":["short"]}`, http.StatusBadRequest},
+		{http.MethodPost, `{"kids":["AAAAAAAAAAAAAAAAAAAAAA"]}`, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(c.method, "/license", strings.NewReader(c.body)))
+		if w.Code != c.status {
+			t.Errorf("%s %s 期望状态码 %d，实际: %d", c.method, c.body, c.status, w.Code)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package hlskeyinfo
+
+// Clone 复制密钥与配置到新实例，新实例拥有独立的密钥文件与 keyinfo 文件
+// opts 在复制的配置之后应用，例如通过 WithTempDir 为不同输出目录生成各自的文件；回调不会被复制
+func (k *KeyInfo) Clone(opts ...Option) (*KeyInfo, error) {
+	c := k.cloneSettings()
+	for _, opt := range opts {
+		opt(c)
+	}
+	if k.key == nil {
+		return c, nil
+	}
+	if err := c.SetKey(k.key); err != nil {
+		return nil, err
+	}
+	if c.randomKID && c.autoKeyID {
+		// 相同密钥沿用原 KID
+		c.KeyID = k.KeyID
+	}
+	return c, nil
+}
+
+// cloneSettings 复制配置与元数据，不复制密钥、文件与回调
+func (k *KeyInfo) cloneSettings() *KeyInfo {
+	return &KeyInfo{
+		URL:               k.URL,
+		IV:                k.IV,
+		KeyID:             k.KeyID,
+		Version:           k.Version,
+		Stream:            k.Stream,
+		Method:            k.Method,
+		KeyFormat:         k.KeyFormat,
+		KeyFormatVersions: k.KeyFormatVersions,
+		keySize:           k.keySize,
+		tempDir:           k.tempDir,
+		fileMode:          k.fileMode,
+		random:            k.random,
+		autoKeyID:         k.autoKeyID,
+		randomKID:         k.randomKID,
+		keyIDInURL:        k.keyIDInURL,
+		ivMode:            k.ivMode,
+		ivPrefix:          k.ivPrefix,
+		sequence:          k.sequence,
+		memoryKeyFile:     k.memoryKeyFile,
+		lockedMemory:      k.lockedMemory,
+		auditor:           k.auditor,
+		auditActor:        k.auditActor,
+	}
+}
@@ -0,0 +1,51 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithKeyIDInURL())
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+	k.RandIV()
+
+	dir := t.TempDir()
+	c, err := k.Clone(WithTempDir(dir))
+	if err != nil {
+		t.Fatalf("Clone 失败: %v", err)
+	}
+	defer c.Dispose()
+
+	if !bytes.Equal(c.GetKey(), k.GetKey()) || c.IV != k.IV || c.KeyID != k.KeyID || c.KeyURL() != k.KeyURL() {
+		t.Error("克隆实例的密钥或配置不一致")
+	}
+	if c.KeyFile == k.KeyFile || filepath.Dir(c.KeyFile) != dir {
+		t.Errorf("克隆实例应在 %s 拥有独立密钥文件，实际: %s", dir, c.KeyFile)
+	}
+
+	infoFile, err := c.WriteToTempFile()
+	if err != nil {
+		t.Fatalf("WriteToTempFile 失败: %v", err)
+	}
+	origInfo, err := k.WriteToTempFile()
+	if err != nil {
+		t.Fatalf("WriteToTempFile 失败: %v", err)
+	}
+	if infoFile == origInfo {
+		t.Error("克隆实例的 keyinfo 文件不应与原实例相同")
+	}
+
+	// 清理克隆实例不影响原实例
+	if err := c.Dispose(); err != nil {
+		t.Fatalf("Dispose 失败: %v", err)
+	}
+	if _, err := os.Stat(k.KeyFile); err != nil {
+		t.Error("原实例的密钥文件不应被删除")
+	}
+}
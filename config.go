@@ -0,0 +1,210 @@
+package hlskeyinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config 多路流加密配置，可从 YAML 或 JSON 文件加载
+type Config struct {
+	TempDir string         `json:"temp_dir" yaml:"temp_dir"` // 密钥文件与 keyinfo 文件目录
+	Streams []StreamConfig `json:"streams" yaml:"streams"`
+}
+
+// StreamConfig 单路流的加密配置
+type StreamConfig struct {
+	Name             string   `json:"name" yaml:"name"`                           // 流名称，需唯一
+	URL              string   `json:"url" yaml:"url"`                             // 密钥获取URL，{stream} 会被替换为流名称，支持 {keyID}、{kid}、{version} 占位符
+	KeySize          int      `json:"key_size" yaml:"key_size"`                   // 密钥长度，默认 16
+	KeyFile          string   `json:"key_file" yaml:"key_file"`                   // 已有密钥文件，为空时生成随机密钥
+	IV               string   `json:"iv" yaml:"iv"`                               // random（默认）、none、sequence、derive 或 32 位十六进制
+	Method           string   `json:"method" yaml:"method"`                       // 加密方式，AES-128（默认）或 SAMPLE-AES
+	FairPlay         bool     `json:"fairplay" yaml:"fairplay"`                   // 使用 FairPlay Streaming，url 需为 skd:// 形式
+	RotationInterval Duration `json:"rotation_interval" yaml:"rotation_interval"` // 密钥轮换间隔，如 "10m"
+	RotationSchedule string   `json:"rotation_schedule" yaml:"rotation_schedule"` // cron 轮换计划，如 "0 3 * * *"，优先于轮换间隔
+}
+
+// Duration 支持 "10m"、"1h30m" 格式的时长
+type Duration time.Duration
+
+// UnmarshalText 实现encoding.TextUnmarshaler接口
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("解析时长失败: %w", err)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText 实现encoding.TextMarshaler接口
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// LoadConfig 读取 YAML（.yaml/.yml）或 JSON（.json）配置文件
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	var c Config
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &c)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &c)
+	default:
+		return nil, fmt.Errorf("不支持的配置文件格式: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Validate 校验配置
+func (c *Config) Validate() error {
+	seen := make(map[string]bool, len(c.Streams))
+	for i, s := range c.Streams {
+		if s.Name == "" {
+			return fmt.Errorf("第 %d 路流缺少名称", i+1)
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("流名称重复: %s", s.Name)
+		}
+		seen[s.Name] = true
+		if s.URL == "" {
+			return fmt.Errorf("流 %s 缺少密钥获取URL", s.Name)
+		}
+		if s.KeySize != 0 {
+			if err := validateKeySize(s.KeySize); err != nil {
+				return fmt.Errorf("流 %s: %w", s.Name, err)
+			}
+		}
+		switch s.IV {
+		case "", "random", "none", "sequence", "derive":
+		default:
+			if err := ValidateIV(s.IV); err != nil {
+				return fmt.Errorf("流 %s: %w", s.Name, err)
+			}
+		}
+		if err := validateMethod(s.Method); err != nil {
+			return fmt.Errorf("流 %s: %w", s.Name, err)
+		}
+		if s.FairPlay {
+			if s.Method != "" && s.Method != MethodSampleAES {
+				return fmt.Errorf("流 %s: FairPlay 需要加密方式 %s", s.Name, MethodSampleAES)
+			}
+			if !strings.HasPrefix(s.URL, fairPlayScheme) {
+				return fmt.Errorf("流 %s: FairPlay 密钥获取URL需以 %s 开头", s.Name, fairPlayScheme)
+			}
+		}
+		if s.RotationInterval < 0 {
+			return fmt.Errorf("流 %s 的轮换间隔不能为负数", s.Name)
+		}
+		if _, err := s.Schedule(); err != nil {
+			return fmt.Errorf("流 %s: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// Schedule 返回该流的轮换计划，未配置轮换时返回 nil
+func (s StreamConfig) Schedule() (Schedule, error) {
+	if s.RotationSchedule != "" {
+		return ParseCron(s.RotationSchedule)
+	}
+	if s.RotationInterval > 0 {
+		return Every(time.Duration(s.RotationInterval)), nil
+	}
+	return nil, nil
+}
+
+// KeyInfos 按配置为每路流创建KeyInfo实例，返回以流名称为键的集合
+// 任意一路创建失败时会清理已创建的实例
+func (c *Config) KeyInfos(opts ...Option) (map[string]*KeyInfo, error) {
+	out := make(map[string]*KeyInfo, len(c.Streams))
+	for _, s := range c.Streams {
+		k, err := c.newStream(s, opts)
+		if err != nil {
+			for _, created := range out {
+				created.Dispose()
+			}
+			return nil, fmt.Errorf("创建流 %s 失败: %w", s.Name, err)
+		}
+		out[s.Name] = k
+	}
+	return out, nil
+}
+
+// newStream 按单路流配置创建KeyInfo实例
+func (c *Config) newStream(s StreamConfig, opts []Option) (*KeyInfo, error) {
+	var streamOpts []Option
+	if c.TempDir != "" {
+		streamOpts = append(streamOpts, WithTempDir(c.TempDir))
+	}
+	if s.KeySize != 0 {
+		streamOpts = append(streamOpts, WithKeySize(s.KeySize))
+	}
+	if s.Method != "" {
+		streamOpts = append(streamOpts, WithMethod(s.Method))
+	}
+	if s.FairPlay {
+		streamOpts = append(streamOpts, WithFairPlay())
+	}
+	streamOpts = append(streamOpts, WithStream(s.Name))
+	streamOpts = append(streamOpts, opts...)
+
+	url := strings.ReplaceAll(s.URL, "{stream}", s.Name)
+	var (
+		k   *KeyInfo
+		err error
+	)
+	if s.KeyFile != "" {
+		k, err = NewKeyInfoFromKeyFile(url, s.KeyFile, streamOpts...)
+	} else {
+		k, err = NewKeyInfo(url, streamOpts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch s.IV {
+	case "", "random":
+		err = k.RandIVErr()
+	case "none":
+		k.NoIV()
+	case "sequence":
+		k.UseSequenceIV()
+	case "derive":
+		k.DeriveIV()
+	default:
+		err = k.SetIVStrict(s.IV)
+	}
+	if err != nil {
+		k.Dispose()
+		return nil, err
+	}
+	return k, nil
+}
+
+// Stream 按名称查找流配置
+func (c *Config) Stream(name string) (StreamConfig, error) {
+	for _, s := range c.Streams {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return StreamConfig{}, fmt.Errorf("流不存在: %s", name)
+}
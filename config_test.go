@@ -0,0 +1,79 @@
+package hlskeyinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "streams.yaml")
+	content := `
+temp_dir: ` + dir + `
+streams:
+  - name: channel-1
+    url: https://keys.example.com/{stream}/key
+    rotation_interval: 10m
+  - name: channel-2
+    url: https://keys.example.com/{stream}/key
+    key_size: 32
+    iv: none
+    rotation_schedule: "0 3 * * *"
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig 失败: %v", err)
+	}
+	s, err := c.Stream("channel-1")
+	if err != nil {
+		t.Fatalf("查找流配置失败: %v", err)
+	}
+	if time.Duration(s.RotationInterval) != 10*time.Minute {
+		t.Errorf("轮换间隔不匹配，实际: %v", time.Duration(s.RotationInterval))
+	}
+
+	if sched, err := c.Streams[1].Schedule(); err != nil || sched == nil {
+		t.Errorf("应解析轮换计划: %v", err)
+	}
+
+	keys, err := c.KeyInfos()
+	if err != nil {
+		t.Fatalf("KeyInfos 失败: %v", err)
+	}
+	defer func() {
+		for _, k := range keys {
+			k.Dispose()
+		}
+	}()
+
+	k1, k2 := keys["channel-1"], keys["channel-2"]
+	if k1.URL != "https://keys.example.com/channel-1/key" {
+		t.Errorf("URL 占位符未替换，实际: %s", k1.URL)
+	}
+	if !k1.HasIV() || k2.HasIV() {
+		t.Error("IV 配置未生效")
+	}
+	if len(k2.GetKey()) != 32 {
+		t.Errorf("期望密钥长度为 32 字节，实际: %d", len(k2.GetKey()))
+	}
+	if filepath.Dir(k1.KeyFile) != dir {
+		t.Errorf("密钥文件应位于 %s，实际: %s", dir, k1.KeyFile)
+	}
+}
+
+func TestLoadConfigJSONInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "streams.json")
+	content := `{"streams":[{"name":"a","url":"http://a"},{"name":"a","url":"http://b"}]}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("重复的流名称应返回错误")
+	}
+}
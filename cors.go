@@ -0,0 +1,69 @@
+package hlskeyinfo
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions 跨域配置
+type CORSOptions struct {
+	AllowedOrigins   []string      // 允许的来源，如 https://player.example.com，"*" 表示任意来源
+	AllowedHeaders   []string      // 预检允许的请求头，默认 Authorization
+	AllowedMethods   []string      // 预检允许的方法，默认 GET、HEAD、OPTIONS；ClearKey 许可证服务需包含 POST
+	MaxAge           time.Duration // 预检结果缓存时间
+	AllowCredentials bool          // 允许携带 Cookie 等凭证，此时 "*" 按实际来源回写
+}
+
+// CORS 返回跨域中间件，为 hls.js 等浏览器播放器处理预检请求与跨域响应头
+// 需放在鉴权中间件之前，预检请求不携带令牌
+func CORS(opts CORSOptions) Middleware {
+	headers := opts.AllowedHeaders
+	if len(headers) == 0 {
+		headers = []string{"Authorization"}
+	}
+	allowHeaders := strings.Join(headers, ", ")
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+	}
+	allowMethods := strings.Join(methods, ", ")
+	anyOrigin := slices.Contains(opts.AllowedOrigins, "*")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if origin == "" || !(anyOrigin || slices.Contains(opts.AllowedOrigins, origin)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if anyOrigin && !opts.AllowCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if opts.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			// 预检请求直接应答
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				h.Set("Access-Control-Allow-Methods", allowMethods)
+				h.Set("Access-Control-Allow-Headers", allowHeaders)
+				if opts.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge.Seconds())))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package hlskeyinfo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	handler := CORS(CORSOptions{
+		AllowedOrigins: []string{"https://player.example.com"},
+		MaxAge:         10 * time.Minute,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/key", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodOptions, "https://player.example.com")
+	if w.Code != http.StatusNoContent {
+		t.Errorf("预检请求应返回 204，实际: %d", w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Headers") != "Authorization" || w.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("预检响应头不正确: %v", w.Header())
+	}
+
+	w = do(http.MethodGet, "https://player.example.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://player.example.com" {
+		t.Errorf("允许的来源应回写 Access-Control-Allow-Origin，实际: %v", w.Header())
+	}
+
+	w = do(http.MethodGet, "https://evil.example.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("未允许的来源不应返回 Access-Control-Allow-Origin")
+	}
+}
@@ -0,0 +1,347 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CPIXVersion 生成 CPIX 文档时使用的版本
+const CPIXVersion = "2.3"
+
+// CPIX DASH-IF CPIX 文档，用于与商业 DRM 与加密器交换内容密钥
+// 仅支持明文（PlainValue）内容密钥，不支持以文档密钥加密的内容密钥
+type CPIX struct {
+	ContentID   string
+	Version     string // 为空时写入 CPIXVersion
+	ContentKeys []CPIXContentKey
+	DRMSystems  []CPIXDRMSystem
+	UsageRules  []CPIXUsageRule
+}
+
+// CPIXContentKey CPIX 的 ContentKey 元素
+type CPIXContentKey struct {
+	KID    [16]byte
+	Key    []byte
+	IV     []byte // explicitIV，未指定时为空
+	Scheme string // commonEncryptionScheme，如 cenc、cbcs，未指定时为空
+}
+
+// CPIXDRMSystem CPIX 的 DRMSystem 元素，描述某个 DRM 系统对某个 KID 的信令
+type CPIXDRMSystem struct {
+	KID                    [16]byte
+	SystemID               [16]byte
+	PSSH                   []byte // 完整的 pssh box
+	ContentProtectionData  string // DASH MPD 中 ContentProtection 元素的子元素
+	HLSSignalingData       string // 媒体播放列表的 EXT-X-KEY 标签
+	HLSMasterSignalingData string // 主播放列表的 EXT-X-SESSION-KEY 标签
+}
+
+// CPIXUsageRule CPIX 的 ContentKeyUsageRule 元素，各过滤器为空时不限制；每类过滤器只支持一个
+type CPIXUsageRule struct {
+	KID               [16]byte
+	IntendedTrackType string // 如 SD、HD、AUDIO
+	Video             *CPIXVideoFilter
+	Audio             *CPIXAudioFilter
+	Bitrate           *CPIXBitrateFilter
+	Label             string // LabelFilter 的 label
+	PeriodID          string // KeyPeriodFilter 的 periodId
+}
+
+// CPIXVideoFilter 视频轨道过滤器，像素数为 0 时不限
+type CPIXVideoFilter struct {
+	MinPixels int `xml:"minPixels,attr,omitempty"`
+	MaxPixels int `xml:"maxPixels,attr,omitempty"`
+}
+
+// CPIXAudioFilter 音频轨道过滤器，声道数为 0 时不限
+type CPIXAudioFilter struct {
+	MinChannels int `xml:"minChannels,attr,omitempty"`
+	MaxChannels int `xml:"maxChannels,attr,omitempty"`
+}
+
+// CPIXBitrateFilter 码率过滤器，码率为 0 时不限
+type CPIXBitrateFilter struct {
+	MinBitrate int `xml:"minBitrate,attr,omitempty"`
+	MaxBitrate int `xml:"maxBitrate,attr,omitempty"`
+}
+
+// cpixXML CPIX 文档的 XML 表示，子元素继承根元素的默认命名空间
+type cpixXML struct {
+	XMLName     xml.Name            `xml:"urn:dashif:org:cpix CPIX"`
+	ContentID   string              `xml:"contentId,attr,omitempty"`
+	Version     string              `xml:"version,attr,omitempty"`
+	ContentKeys []cpixContentKeyXML `xml:"ContentKeyList>ContentKey"`
+	DRMSystems  []cpixDRMSystemXML  `xml:"DRMSystemList>DRMSystem"`
+	UsageRules  []cpixUsageRuleXML  `xml:"ContentKeyUsageRuleList>ContentKeyUsageRule"`
+}
+
+type cpixContentKeyXML struct {
+	KID        string       `xml:"kid,attr"`
+	ExplicitIV string       `xml:"explicitIV,attr,omitempty"`
+	Scheme     string       `xml:"commonEncryptionScheme,attr,omitempty"`
+	Data       *cpixDataXML `xml:"Data"`
+}
+
+type cpixDataXML struct {
+	Secret struct {
+		PlainValue     string    `xml:"PlainValue,omitempty"`
+		EncryptedValue *struct{} `xml:"EncryptedValue"`
+	} `xml:"urn:ietf:params:xml:ns:keyprov:pskc Secret"`
+}
+
+type cpixDRMSystemXML struct {
+	KID                   string                `xml:"kid,attr"`
+	SystemID              string                `xml:"systemId,attr"`
+	PSSH                  *string               `xml:"PSSH"`
+	ContentProtectionData *string               `xml:"ContentProtectionData"`
+	HLSSignalingData      []cpixHLSSignalingXML `xml:"HLSSignalingData"`
+}
+
+type cpixHLSSignalingXML struct {
+	Playlist string `xml:"playlist,attr,omitempty"` // media 或 master，未指定时为 media
+	Value    string `xml:",chardata"`
+}
+
+type cpixUsageRuleXML struct {
+	KID               string             `xml:"kid,attr"`
+	IntendedTrackType string             `xml:"intendedTrackType,attr,omitempty"`
+	KeyPeriod         *cpixPeriodXML     `xml:"KeyPeriodFilter"`
+	Label             *cpixLabelXML      `xml:"LabelFilter"`
+	Video             *CPIXVideoFilter   `xml:"VideoFilter"`
+	Audio             *CPIXAudioFilter   `xml:"AudioFilter"`
+	Bitrate           *CPIXBitrateFilter `xml:"BitrateFilter"`
+}
+
+type cpixPeriodXML struct {
+	PeriodID string `xml:"periodId,attr"`
+}
+
+type cpixLabelXML struct {
+	Label string `xml:"label,attr"`
+}
+
+// ParseCPIX 解析 CPIX 文档
+func ParseCPIX(r io.Reader) (*CPIX, error) {
+	var v cpixXML
+	if err := xml.NewDecoder(r).Decode(&v); err != nil {
+		return nil, fmt.Errorf("解析 CPIX 失败: %w", err)
+	}
+
+	c := &CPIX{ContentID: v.ContentID, Version: v.Version}
+	for _, x := range v.ContentKeys {
+		kid, err := parseUUID(x.KID)
+		if err != nil {
+			return nil, err
+		}
+		ck := CPIXContentKey{KID: kid, Scheme: x.Scheme}
+		if x.Data == nil || x.Data.Secret.PlainValue == "" {
+			if x.Data != nil && x.Data.Secret.EncryptedValue != nil {
+				return nil, fmt.Errorf("内容密钥 %s 已加密，不支持文档密钥", x.KID)
+			}
+			return nil, fmt.Errorf("内容密钥 %s 缺少密钥值", x.KID)
+		}
+		if ck.Key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(x.Data.Secret.PlainValue)); err != nil {
+			return nil, fmt.Errorf("解码内容密钥 %s 失败: %w", x.KID, err)
+		}
+		if x.ExplicitIV != "" {
+			if ck.IV, err = base64.StdEncoding.DecodeString(x.ExplicitIV); err != nil || len(ck.IV) != 16 {
+				return nil, fmt.Errorf("内容密钥 %s 的 explicitIV 无效", x.KID)
+			}
+		}
+		c.ContentKeys = append(c.ContentKeys, ck)
+	}
+
+	for _, x := range v.DRMSystems {
+		kid, err := parseUUID(x.KID)
+		if err != nil {
+			return nil, err
+		}
+		sys, err := parseUUID(x.SystemID)
+		if err != nil {
+			return nil, err
+		}
+		d := CPIXDRMSystem{KID: kid, SystemID: sys}
+		if d.PSSH, err = decodeCPIXData(x.PSSH); err != nil {
+			return nil, fmt.Errorf("解码 PSSH 失败: %w", err)
+		}
+		data, err := decodeCPIXData(x.ContentProtectionData)
+		if err != nil {
+			return nil, fmt.Errorf("解码 ContentProtectionData 失败: %w", err)
+		}
+		d.ContentProtectionData = string(data)
+		for _, h := range x.HLSSignalingData {
+			data, err := decodeCPIXData(&h.Value)
+			if err != nil {
+				return nil, fmt.Errorf("解码 HLSSignalingData 失败: %w", err)
+			}
+			if h.Playlist == "master" {
+				d.HLSMasterSignalingData = string(data)
+			} else {
+				d.HLSSignalingData = string(data)
+			}
+		}
+		c.DRMSystems = append(c.DRMSystems, d)
+	}
+
+	for _, x := range v.UsageRules {
+		kid, err := parseUUID(x.KID)
+		if err != nil {
+			return nil, err
+		}
+		u := CPIXUsageRule{KID: kid, IntendedTrackType: x.IntendedTrackType, Video: x.Video, Audio: x.Audio, Bitrate: x.Bitrate}
+		if x.Label != nil {
+			u.Label = x.Label.Label
+		}
+		if x.KeyPeriod != nil {
+			u.PeriodID = x.KeyPeriod.PeriodID
+		}
+		c.UsageRules = append(c.UsageRules, u)
+	}
+	return c, nil
+}
+
+// decodeCPIXData 解码 Base64 元素内容，元素不存在或内容为空时返回 nil
+func decodeCPIXData(s *string) ([]byte, error) {
+	if s == nil {
+		return nil, nil
+	}
+	v := strings.Join(strings.Fields(*s), "")
+	if v == "" {
+		return nil, nil
+	}
+	return base64.StdEncoding.DecodeString(v)
+}
+
+// encodeCPIXData 编码 Base64 元素内容，request 为 true 时以空元素向密钥服务请求该项，否则内容为空时省略元素
+func encodeCPIXData(data []byte, request bool) *string {
+	if len(data) == 0 && !request {
+		return nil
+	}
+	s := base64.StdEncoding.EncodeToString(data)
+	return &s
+}
+
+// WriteTo 实现io.WriterTo接口，写入包含 XML 声明的 CPIX 文档
+func (c *CPIX) WriteTo(w io.Writer) (int64, error) {
+	return writeCPIX(w, c.document(false))
+}
+
+// document 返回文档的 XML 表示；request 为 true 时生成密钥请求：内容密钥不含密钥值，DRM 系统的各项信令为空元素
+func (c *CPIX) document(request bool) cpixXML {
+	v := cpixXML{ContentID: c.ContentID, Version: c.Version}
+	if v.Version == "" {
+		v.Version = CPIXVersion
+	}
+	for _, ck := range c.ContentKeys {
+		x := cpixContentKeyXML{KID: formatUUID(ck.KID), Scheme: ck.Scheme}
+		if !request {
+			x.Data = &cpixDataXML{}
+			x.Data.Secret.PlainValue = base64.StdEncoding.EncodeToString(ck.Key)
+		}
+		if len(ck.IV) > 0 {
+			x.ExplicitIV = base64.StdEncoding.EncodeToString(ck.IV)
+		}
+		v.ContentKeys = append(v.ContentKeys, x)
+	}
+	for _, d := range c.DRMSystems {
+		x := cpixDRMSystemXML{
+			KID:                   formatUUID(d.KID),
+			SystemID:              formatUUID(d.SystemID),
+			PSSH:                  encodeCPIXData(d.PSSH, request),
+			ContentProtectionData: encodeCPIXData([]byte(d.ContentProtectionData), request),
+		}
+		if s := encodeCPIXData([]byte(d.HLSSignalingData), request); s != nil {
+			x.HLSSignalingData = append(x.HLSSignalingData, cpixHLSSignalingXML{Playlist: "media", Value: *s})
+		}
+		if s := encodeCPIXData([]byte(d.HLSMasterSignalingData), request); s != nil {
+			x.HLSSignalingData = append(x.HLSSignalingData, cpixHLSSignalingXML{Playlist: "master", Value: *s})
+		}
+		v.DRMSystems = append(v.DRMSystems, x)
+	}
+	for _, u := range c.UsageRules {
+		x := cpixUsageRuleXML{KID: formatUUID(u.KID), IntendedTrackType: u.IntendedTrackType, Video: u.Video, Audio: u.Audio, Bitrate: u.Bitrate}
+		if u.PeriodID != "" {
+			x.KeyPeriod = &cpixPeriodXML{PeriodID: u.PeriodID}
+		}
+		if u.Label != "" {
+			x.Label = &cpixLabelXML{Label: u.Label}
+		}
+		v.UsageRules = append(v.UsageRules, x)
+	}
+	return v
+}
+
+// writeCPIX 写入包含 XML 声明的 CPIX 文档
+func writeCPIX(w io.Writer, v cpixXML) (int64, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return 0, fmt.Errorf("生成 CPIX 失败: %w", err)
+	}
+	buf.WriteByte('\n')
+	return buf.WriteTo(w)
+}
+
+// CPIXContentKey 返回该密钥的 CPIX 内容密钥，KID 为 KID()；SAMPLE-AES 时加密方案为 cbcs
+func (k *KeyInfo) CPIXContentKey() (CPIXContentKey, error) {
+	if k.key == nil {
+		return CPIXContentKey{}, fmt.Errorf("密钥未初始化")
+	}
+	ck := CPIXContentKey{KID: k.KID(), Key: k.GetKey()}
+	if k.HasIV() {
+		var err error
+		if ck.IV, err = k.SegmentIV(0); err != nil {
+			return CPIXContentKey{}, err
+		}
+	}
+	if k.method() == MethodSampleAES {
+		ck.Scheme = "cbcs"
+	}
+	return ck, nil
+}
+
+// NewCPIX 由本包管理的密钥创建 CPIX 文档，DRM 信令与使用规则可随后追加
+func NewCPIX(contentID string, keys ...*KeyInfo) (*CPIX, error) {
+	c := &CPIX{ContentID: contentID}
+	for _, k := range keys {
+		ck, err := k.CPIXContentKey()
+		if err != nil {
+			return nil, err
+		}
+		c.ContentKeys = append(c.ContentKeys, ck)
+	}
+	return c, nil
+}
+
+// KeyInfos 为文档中的每个内容密钥创建KeyInfo实例，按文档顺序返回
+// KeyID 为 KID 的十六进制形式，explicitIV 作为显式 IV，加密方案为 cbcs 时使用 SAMPLE-AES；任意一个创建失败时会清理已创建的实例
+func (c *CPIX) KeyInfos(url string, opts ...Option) ([]*KeyInfo, error) {
+	out := make([]*KeyInfo, 0, len(c.ContentKeys))
+	for _, ck := range c.ContentKeys {
+		keyOpts := []Option{WithKID(ck.KID)}
+		if ck.Scheme == "cbcs" {
+			keyOpts = append(keyOpts, WithMethod(MethodSampleAES))
+		}
+		k, err := NewKeyInfoWithKey(url, ck.Key, append(keyOpts, opts...)...)
+		if err == nil && len(ck.IV) > 0 {
+			err = k.SetIVRaw(ck.IV)
+		}
+		if err != nil {
+			if k != nil {
+				k.Dispose()
+			}
+			for _, created := range out {
+				created.Dispose()
+			}
+			return nil, fmt.Errorf("导入内容密钥 %s 失败: %w", formatUUID(ck.KID), err)
+		}
+		out = append(out, k)
+	}
+	return out, nil
+}
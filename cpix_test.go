@@ -0,0 +1,112 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testCPIXDocument 商业加密器常见的带前缀命名空间的 CPIX 文档
+const testCPIXDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<cpix:CPIX xmlns:cpix="urn:dashif:org:cpix" xmlns:pskc="urn:ietf:params:xml:ns:keyprov:pskc" contentId="movie-1" version="2.3">
+  <cpix:ContentKeyList>
+    <cpix:ContentKey kid="01234567-89ab-cdef-0123-456789abcdef" explicitIV="AAECAwQFBgcICQoLDA0ODw==" commonEncryptionScheme="cbcs">
+      <cpix:Data><pskc:Secret><pskc:PlainValue>AAAAAAAAAAAAAAAAAAAAAA==</pskc:PlainValue></pskc:Secret></cpix:Data>
+    </cpix:ContentKey>
+  </cpix:ContentKeyList>
+  <cpix:DRMSystemList>
+    <cpix:DRMSystem kid="01234567-89ab-cdef-0123-456789abcdef" systemId="edef8ba9-79d6-4ace-a3c8-27dcd51d21ed">
+      <cpix:PSSH>AAAAIHBzc2g=</cpix:PSSH>
+      <cpix:HLSSignalingData playlist="media">I0VYVC1YLUtFWTpNRVRIT0Q9U0FNUExFLUFFUw==</cpix:HLSSignalingData>
+      <cpix:HLSSignalingData playlist="master">I0VYVC1YLVNFU1NJT04tS0VZOk1FVEhPRD1TQU1QTEUtQUVT</cpix:HLSSignalingData>
+    </cpix:DRMSystem>
+  </cpix:DRMSystemList>
+  <cpix:ContentKeyUsageRuleList>
+    <cpix:ContentKeyUsageRule kid="01234567-89ab-cdef-0123-456789abcdef" intendedTrackType="HD">
+      <cpix:VideoFilter minPixels="921600"/>
+    </cpix:ContentKeyUsageRule>
+  </cpix:ContentKeyUsageRuleList>
+</cpix:CPIX>`
+
+func TestParseCPIX(t *testing.T) {
+	c, err := ParseCPIX(strings.NewReader(testCPIXDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parseUUID("0123456789abcdef0123456789abcdef")
+	if c.ContentID != "movie-1" || len(c.ContentKeys) != 1 || c.ContentKeys[0].KID != kid || c.ContentKeys[0].Scheme != "cbcs" {
+		t.Fatalf("内容密钥解析不正确: %+v", c)
+	}
+	if len(c.DRMSystems) != 1 || c.DRMSystems[0].SystemID != WidevineSystemID ||
+		c.DRMSystems[0].HLSSignalingData != "#EXT-X-KEY:METHOD=SAMPLE-AES" ||
+		c.DRMSystems[0].HLSMasterSignalingData != "#EXT-X-SESSION-KEY:METHOD=SAMPLE-AES" {
+		t.Errorf("DRM 信令解析不正确: %+v", c.DRMSystems)
+	}
+	if len(c.UsageRules) != 1 || c.UsageRules[0].IntendedTrackType != "HD" || c.UsageRules[0].Video == nil || c.UsageRules[0].Video.MinPixels != 921600 {
+		t.Errorf("使用规则解析不正确: %+v", c.UsageRules)
+	}
+
+	keys, err := c.KeyInfos("https://keys.example.com/{keyID}", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keys[0].Dispose()
+	k := keys[0]
+	if k.KeyID != "0123456789abcdef0123456789abcdef" || k.Method != MethodSampleAES || k.IV != "000102030405060708090a0b0c0d0e0f" {
+		t.Errorf("导入的密钥不正确: %#v", k)
+	}
+	if !bytes.Equal(k.GetKey(), make([]byte, 16)) || k.KeyURL() != "https://keys.example.com/0123456789abcdef0123456789abcdef" {
+		t.Errorf("导入的密钥或URL不正确")
+	}
+
+	encrypted := strings.Replace(testCPIXDocument, "<pskc:PlainValue>AAAAAAAAAAAAAAAAAAAAAA==</pskc:PlainValue>", "<pskc:EncryptedValue/>", 1)
+	if _, err := ParseCPIX(strings.NewReader(encrypted)); err == nil {
+		t.Errorf("加密的内容密钥应返回错误")
+	}
+}
+
+func TestCPIXRoundTrip(t *testing.T) {
+	k, err := NewKeyInfo("https://keys.example.com/key", WithTempDir(t.TempDir()), WithMethod(MethodSampleAES))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Dispose()
+	k.RandIV()
+
+	c, err := NewCPIX("live", k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid := c.ContentKeys[0].KID
+	tag, _ := k.WidevineKeyTag(WidevinePSSH{})
+	c.DRMSystems = append(c.DRMSystems, CPIXDRMSystem{KID: kid, SystemID: WidevineSystemID, PSSH: WidevinePSSH{KeyIDs: [][16]byte{kid}}.Box(), HLSSignalingData: tag.String()})
+	c.UsageRules = append(c.UsageRules, CPIXUsageRule{KID: kid, Audio: &CPIXAudioFilter{MaxChannels: 2}, Label: "main", PeriodID: "p1"})
+
+	var buf bytes.Buffer
+	if _, err := c.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{`<CPIX xmlns="urn:dashif:org:cpix" contentId="live" version="2.3">`, `<Secret xmlns="urn:ietf:params:xml:ns:keyprov:pskc">`, `commonEncryptionScheme="cbcs"`, `<HLSSignalingData playlist="media">`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("CPIX 缺少 %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "<ContentProtectionData>") {
+		t.Errorf("空的 ContentProtectionData 不应写入")
+	}
+	if !strings.Contains(out, base64.StdEncoding.EncodeToString(k.GetKey())) {
+		t.Errorf("CPIX 缺少内容密钥")
+	}
+
+	parsed, err := ParseCPIX(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Version = CPIXVersion
+	if !reflect.DeepEqual(parsed, c) {
+		t.Errorf("往返结果不一致:\n%+v\n%+v", parsed, c)
+	}
+}
@@ -0,0 +1,119 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+)
+
+// DRMKeyRequest 向 DRMProvider 请求内容密钥或信令时的参数
+type DRMKeyRequest struct {
+	ContentID string   // 内容 ID，DRM 服务按其归组密钥
+	KID       [16]byte // 内容密钥 ID
+	Method    string   // 加密方式，AES-128 或 SAMPLE-AES
+	KeyFormat string   // 期望的 KEYFORMAT，如 KeyFormatFairPlay，为空时不限
+}
+
+// DRMProvider 商业 DRM 服务（EZDRM、Axinom、BuyDRM 等）的接入点，可在独立模块中实现并通过 RegisterDRMProvider 注册
+type DRMProvider interface {
+	// FetchContentKey 获取 KID 对应的内容密钥，同一 KID 应返回相同的密钥；iv 为空表示由本包决定 IV
+	FetchContentKey(ctx context.Context, req DRMKeyRequest) (key, iv []byte, err error)
+	// SignalingTags 返回该 KID 在播放列表中的密钥标签，可包含多个 DRM 系统的 EXT-X-KEY 与 EXT-X-SESSION-KEY
+	SignalingTags(ctx context.Context, req DRMKeyRequest) ([]KeyTag, error)
+	// LicenseURL 返回播放器向指定 DRM 系统获取许可证的地址，不支持该系统时返回空字符串
+	LicenseURL(systemID [16]byte) string
+}
+
+// DRMProviderFactory 按配置创建 DRMProvider，配置项由各实现自行定义
+type DRMProviderFactory func(config map[string]string) (DRMProvider, error)
+
+var (
+	drmMu        sync.RWMutex
+	drmFactories = map[string]DRMProviderFactory{}
+)
+
+// RegisterDRMProvider 注册 DRMProvider 实现，通常在实现模块的 init 中调用；名称重复或 factory 为 nil 时 panic
+func RegisterDRMProvider(name string, factory DRMProviderFactory) {
+	drmMu.Lock()
+	defer drmMu.Unlock()
+	if factory == nil {
+		panic("hlskeyinfo: DRMProviderFactory 为 nil: " + name)
+	}
+	if _, ok := drmFactories[name]; ok {
+		panic("hlskeyinfo: 重复注册 DRMProvider: " + name)
+	}
+	drmFactories[name] = factory
+}
+
+// OpenDRMProvider 按名称创建已注册的 DRMProvider
+func OpenDRMProvider(name string, config map[string]string) (DRMProvider, error) {
+	drmMu.RLock()
+	factory, ok := drmFactories[name]
+	drmMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未注册的 DRMProvider: %s", name)
+	}
+	p, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("创建 DRMProvider %s 失败: %w", name, err)
+	}
+	return p, nil
+}
+
+// DRMProviders 返回已注册的 DRMProvider 名称，按字母顺序排列
+func DRMProviders() []string {
+	drmMu.RLock()
+	defer drmMu.RUnlock()
+	names := make([]string, 0, len(drmFactories))
+	for name := range drmFactories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// NewKeyInfoFromDRM 以随机 KID 从 DRMProvider 获取内容密钥并创建KeyInfo实例，KeyID 为 KID 的十六进制形式，
+// DRMProvider 返回 IV 时作为显式 IV；播放列表标签可用 DRMKeyTags 获取
+func NewKeyInfoFromDRM(ctx context.Context, p DRMProvider, contentID, url string, opts ...Option) (*KeyInfo, error) {
+	kid, err := randomKID()
+	if err != nil {
+		return nil, err
+	}
+	settings := newKeyInfo(url, opts)
+	key, iv, err := p.FetchContentKey(ctx, DRMKeyRequest{ContentID: contentID, KID: kid, Method: settings.method(), KeyFormat: settings.KeyFormat})
+	if err != nil {
+		return nil, fmt.Errorf("获取内容密钥失败: %w", err)
+	}
+	k, err := NewKeyInfoWithKey(url, key, append([]Option{WithKID(kid)}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) > 0 {
+		if err := k.SetIVRaw(iv); err != nil {
+			k.Dispose()
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// DRMKeyTags 返回 DRMProvider 为该密钥生成的播放列表标签，KID 为 KID()
+func (k *KeyInfo) DRMKeyTags(ctx context.Context, p DRMProvider, contentID string) ([]KeyTag, error) {
+	tags, err := p.SignalingTags(ctx, DRMKeyRequest{ContentID: contentID, KID: k.KID(), Method: k.method(), KeyFormat: k.KeyFormat})
+	if err != nil {
+		return nil, fmt.Errorf("获取 DRM 信令失败: %w", err)
+	}
+	return tags, nil
+}
+
+// randomKID 生成随机 KID
+func randomKID() ([16]byte, error) {
+	var kid [16]byte
+	if _, err := io.ReadFull(rand.Reader, kid[:]); err != nil {
+		return kid, fmt.Errorf("生成 KID 失败: %w", err)
+	}
+	return kid, nil
+}
@@ -0,0 +1,84 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// testDRMProvider 按 KID 派生密钥的 DRMProvider
+type testDRMProvider struct {
+	licenseURL string
+}
+
+func (p *testDRMProvider) FetchContentKey(ctx context.Context, req DRMKeyRequest) ([]byte, []byte, error) {
+	if req.ContentID == "" {
+		return nil, nil, fmt.Errorf("missing content id")
+	}
+	return bytes.Repeat(req.KID[:1], 16), bytes.Repeat([]byte{1}, 16), nil
+}
+
+func (p *testDRMProvider) SignalingTags(ctx context.Context, req DRMKeyRequest) ([]KeyTag, error) {
+	return []KeyTag{{Method: req.Method, URI: "skd://" + hex.EncodeToString(req.KID[:]), KeyFormat: KeyFormatFairPlay, KeyFormatVersions: "1"}}, nil
+}
+
+func (p *testDRMProvider) LicenseURL(systemID [16]byte) string {
+	if systemID == FairPlaySystemID {
+		return p.licenseURL
+	}
+	return ""
+}
+
+func TestDRMProvider(t *testing.T) {
+	RegisterDRMProvider("test", func(config map[string]string) (DRMProvider, error) {
+		return &testDRMProvider{licenseURL: config["license_url"]}, nil
+	})
+	if !slices.Contains(DRMProviders(), "test") || !slices.Contains(DRMProviders(), "speke") {
+		t.Errorf("已注册的 DRMProvider 不正确: %v", DRMProviders())
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("重复注册应 panic")
+			}
+		}()
+		RegisterDRMProvider("test", func(map[string]string) (DRMProvider, error) { return nil, nil })
+	}()
+	if _, err := OpenDRMProvider("missing", nil); err == nil {
+		t.Errorf("未注册的 DRMProvider 应返回错误")
+	}
+	if _, err := OpenDRMProvider("speke", nil); err == nil {
+		t.Errorf("缺少 endpoint 时应返回错误")
+	}
+
+	p, err := OpenDRMProvider("test", map[string]string{"license_url": "https://fps.example.com/license"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.LicenseURL(FairPlaySystemID) != "https://fps.example.com/license" || p.LicenseURL(WidevineSystemID) != "" {
+		t.Errorf("许可证地址不正确")
+	}
+
+	k, err := NewKeyInfoFromDRM(context.Background(), p, "movie-1", "skd://{keyID}", WithTempDir(t.TempDir()), WithFairPlay())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Dispose()
+	kid := k.KID()
+	if !bytes.Equal(k.GetKey(), bytes.Repeat(kid[:1], 16)) || k.IV != "01010101010101010101010101010101" {
+		t.Errorf("密钥或 IV 不正确: %#v", k)
+	}
+	tags, err := k.DRMKeyTags(context.Background(), p, "movie-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].URI != k.KeyURL() || tags[0].Method != MethodSampleAES {
+		t.Errorf("信令不正确: %+v", tags)
+	}
+	if _, err := NewKeyInfoFromDRM(context.Background(), p, "", "skd://{keyID}"); err == nil {
+		t.Errorf("DRMProvider 返回错误时应返回错误")
+	}
+}
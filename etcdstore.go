@@ -0,0 +1,145 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// EtcdClient etcd 客户端，由调用方基于 go.etcd.io/etcd/client/v3 实现
+type EtcdClient interface {
+	Put(ctx context.Context, key string, value []byte) error
+	// Get 读取键值，键不存在时返回包装了 ErrKeyNotFound 的错误
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete 删除键，未删除任何键时返回包装了 ErrKeyNotFound 的错误
+	Delete(ctx context.Context, key string) error
+	// GetPrefix 返回前缀下的全部键值
+	GetPrefix(ctx context.Context, prefix string) (map[string][]byte, error)
+	// Watch 监听前缀下的变更，ctx 取消或监听中断时关闭返回的通道
+	Watch(ctx context.Context, prefix string) (<-chan EtcdEvent, error)
+}
+
+// EtcdEvent etcd 键变更事件
+type EtcdEvent struct {
+	Deleted   bool
+	Key       string
+	Value     []byte // 删除事件中为空
+	PrevValue []byte // 删除前的值，客户端启用 WithPrevKV 时提供，用于确定被删除密钥所属的流
+}
+
+// EtcdStore 基于 etcd 的密钥存储，键为 <prefix><keyID>，值为包含密钥的 KeyRecord JSON
+// 密钥服务的多个副本通过 Watch 监听写入，以一致且低延迟的方式感知轮换
+type EtcdStore struct {
+	client EtcdClient
+	prefix string
+}
+
+var (
+	_ KeyStore   = &EtcdStore{}
+	_ KeyWatcher = &EtcdStore{}
+)
+
+// NewEtcdStore 创建基于 etcd 的密钥存储，prefix 为空时使用 /hls/keys/
+func NewEtcdStore(client EtcdClient, prefix string) *EtcdStore {
+	if prefix == "" {
+		prefix = "/hls/keys/"
+	}
+	return &EtcdStore{client: client, prefix: prefix}
+}
+
+// Put 实现 KeyStore 接口
+func (s *EtcdStore) Put(ctx context.Context, rec KeyRecord) error {
+	key, err := s.key(rec.KeyID)
+	if err != nil {
+		return err
+	}
+	data, err := encodeRecord(rec)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Put(ctx, key, data); err != nil {
+		return fmt.Errorf("写入密钥失败: %w", err)
+	}
+	return nil
+}
+
+// Get 实现 KeyStore 接口
+func (s *EtcdStore) Get(ctx context.Context, keyID string) (KeyRecord, error) {
+	key, err := s.key(keyID)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	data, err := s.client.Get(ctx, key)
+	if err != nil {
+		return KeyRecord{}, fmt.Errorf("读取密钥失败: %w", err)
+	}
+	return decodeRecord(data)
+}
+
+// Delete 实现 KeyStore 接口
+func (s *EtcdStore) Delete(ctx context.Context, keyID string) error {
+	key, err := s.key(keyID)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Delete(ctx, key); err != nil {
+		return fmt.Errorf("删除密钥失败: %w", err)
+	}
+	return nil
+}
+
+// List 实现 KeyStore 接口
+func (s *EtcdStore) List(ctx context.Context, stream string) ([]KeyRecord, error) {
+	kvs, err := s.client.GetPrefix(ctx, s.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("列出密钥失败: %w", err)
+	}
+	var out []KeyRecord
+	for key, data := range kvs {
+		rec, err := decodeRecord(data)
+		if err != nil {
+			return nil, fmt.Errorf("键 %s: %w", key, err)
+		}
+		if stream == "" || rec.Stream == stream {
+			out = append(out, rec)
+		}
+	}
+	sortRecords(out)
+	return out, nil
+}
+
+// Watch 实现 KeyWatcher 接口
+func (s *EtcdStore) Watch(ctx context.Context) (<-chan KeyEvent, error) {
+	changes, err := s.client.Watch(ctx, s.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("监听密钥变更失败: %w", err)
+	}
+	events := make(chan KeyEvent, 16)
+	go func() {
+		defer close(events)
+		for change := range changes {
+			ev := KeyEvent{Type: KeyPut, KeyID: strings.TrimPrefix(change.Key, s.prefix)}
+			value := change.Value
+			if change.Deleted {
+				ev.Type, value = KeyDeleted, change.PrevValue
+			}
+			if rec, err := decodeRecord(value); err == nil {
+				ev.Stream = rec.Stream
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// key 返回 KeyID 对应的 etcd 键
+func (s *EtcdStore) key(keyID string) (string, error) {
+	if err := validatePathName(keyID); err != nil {
+		return "", err
+	}
+	return s.prefix + keyID, nil
+}
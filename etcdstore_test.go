@@ -0,0 +1,93 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEtcd 模拟 EtcdClient，删除事件携带删除前的值
+type fakeEtcd struct {
+	*fakeObjects
+	mu       sync.Mutex
+	watchers []chan EtcdEvent
+}
+
+func (f *fakeEtcd) Put(ctx context.Context, key string, value []byte) error {
+	f.put(key, value)
+	f.notify(EtcdEvent{Key: key, Value: value})
+	return nil
+}
+
+func (f *fakeEtcd) Get(ctx context.Context, key string) ([]byte, error) {
+	return f.get(key)
+}
+
+func (f *fakeEtcd) Delete(ctx context.Context, key string) error {
+	prev, err := f.get(key)
+	if err != nil {
+		return err
+	}
+	if err := f.delete(key); err != nil {
+		return err
+	}
+	f.notify(EtcdEvent{Deleted: true, Key: key, PrevValue: prev})
+	return nil
+}
+
+func (f *fakeEtcd) GetPrefix(ctx context.Context, prefix string) (map[string][]byte, error) {
+	kvs := make(map[string][]byte)
+	for _, key := range f.list(prefix) {
+		kvs[key], _ = f.get(key)
+	}
+	return kvs, nil
+}
+
+func (f *fakeEtcd) Watch(ctx context.Context, prefix string) (<-chan EtcdEvent, error) {
+	ch := make(chan EtcdEvent, 16)
+	f.mu.Lock()
+	f.watchers = append(f.watchers, ch)
+	f.mu.Unlock()
+	return ch, nil
+}
+
+func (f *fakeEtcd) notify(ev EtcdEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ch := range f.watchers {
+		if strings.HasPrefix(ev.Key, "/hls/keys/") {
+			ch <- ev
+		}
+	}
+}
+
+func TestEtcdStore(t *testing.T) {
+	store := NewEtcdStore(&fakeEtcd{fakeObjects: newFakeObjects()}, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := store.Watch(ctx)
+	if err != nil {
+		t.Fatalf("监听密钥变更失败: %v", err)
+	}
+	testKeyStore(t, store)
+
+	want := []KeyEvent{
+		{KeyPut, "key-a", "channel-1"},
+		{KeyPut, "key-b", "channel-2"},
+		{KeyPut, "key-a", "channel-1"},
+		{KeyDeleted, "key-a", "channel-1"},
+	}
+	for i, w := range want {
+		select {
+		case ev := <-events:
+			if ev != w {
+				t.Errorf("第 %d 个事件应为 %+v，实际为 %+v", i, w, ev)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("等待第 %d 个事件超时", i)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package hlskeyinfo
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -217,3 +220,232 @@ func TestWriteToTempFile(t *testing.T) {
 		t.Error("Dispose 后临时文件应该被删除")
 	}
 }
+
+func TestWithKeySize(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithKeySize(32))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	if len(k.GetKey()) != 32 {
+		t.Errorf("期望密钥长度为 32 字节，实际: %d", len(k.GetKey()))
+	}
+
+	// 密钥文件内容应与完整密钥一致
+	content, err := os.ReadFile(k.KeyFile)
+	if err != nil {
+		t.Fatalf("读取密钥文件失败: %v", err)
+	}
+	if !bytes.Equal(content, k.GetKey()) {
+		t.Error("密钥文件内容与密钥不一致")
+	}
+}
+
+func TestInvalidKeySize(t *testing.T) {
+	for _, size := range []int{0, 8, 20, 64} {
+		k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithKeySize(size))
+		if !errors.Is(err, ErrInvalidKeySize) {
+			t.Errorf("密钥长度 %d 应返回 ErrInvalidKeySize，实际: %v", size, err)
+		}
+		if k != nil {
+			k.Dispose()
+		}
+	}
+
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithKeySize(24))
+	if err != nil {
+		t.Fatalf("创建 24 字节密钥失败: %v", err)
+	}
+	defer k.Dispose()
+	if len(k.GetKey()) != 24 {
+		t.Errorf("期望密钥长度为 24 字节，实际: %d", len(k.GetKey()))
+	}
+}
+
+func TestSetKey(t *testing.T) {
+	external := bytes.Repeat([]byte{0xab}, 16)
+	k, err := NewKeyInfoWithKey("http://localhost:4123/keyinfo", external)
+	if err != nil {
+		t.Fatalf("使用外部密钥创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	content, err := os.ReadFile(k.KeyFile)
+	if err != nil {
+		t.Fatalf("读取密钥文件失败: %v", err)
+	}
+	if !bytes.Equal(content, external) {
+		t.Error("密钥文件内容与外部密钥不一致")
+	}
+
+	// 生成 keyinfo 文件后替换密钥，旧文件应失效
+	infoFile, err := k.WriteToTempFile()
+	if err != nil {
+		t.Fatalf("WriteToTempFile 失败: %v", err)
+	}
+	rotated := bytes.Repeat([]byte{0xcd}, 32)
+	if err := k.SetKey(rotated); err != nil {
+		t.Fatalf("SetKey 失败: %v", err)
+	}
+	if _, err := os.Stat(infoFile); !os.IsNotExist(err) {
+		t.Error("SetKey 后旧 keyinfo 文件应被删除")
+	}
+	content, _ = os.ReadFile(k.KeyFile)
+	if !bytes.Equal(content, rotated) {
+		t.Error("SetKey 后密钥文件未被重写")
+	}
+
+	if err := k.SetKey([]byte("short")); !errors.Is(err, ErrInvalidKeySize) {
+		t.Errorf("非法长度应返回 ErrInvalidKeySize，实际: %v", err)
+	}
+}
+
+func TestNewKeyInfoFromKeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "ops.key")
+	want := bytes.Repeat([]byte{0x42}, 16)
+	if err := os.WriteFile(keyFile, want, 0o600); err != nil {
+		t.Fatalf("写入密钥文件失败: %v", err)
+	}
+
+	k, err := NewKeyInfoFromKeyFile("http://localhost:4123/keyinfo", keyFile)
+	if err != nil {
+		t.Fatalf("从密钥文件创建 KeyInfo 失败: %v", err)
+	}
+	if !bytes.Equal(k.GetKey(), want) {
+		t.Error("加载的密钥与文件内容不一致")
+	}
+	if k.KeyFile != keyFile {
+		t.Errorf("期望复用密钥文件路径 %s，实际: %s", keyFile, k.KeyFile)
+	}
+
+	// 外部密钥文件在 Dispose 后应保留
+	if err := k.Dispose(); err != nil {
+		t.Fatalf("Dispose 失败: %v", err)
+	}
+	if _, err := os.Stat(keyFile); err != nil {
+		t.Error("Dispose 不应删除外部密钥文件")
+	}
+
+	if err := os.WriteFile(keyFile, []byte("bad"), 0o600); err != nil {
+		t.Fatalf("写入密钥文件失败: %v", err)
+	}
+	if _, err := NewKeyInfoFromKeyFile("http://localhost:4123/keyinfo", keyFile); !errors.Is(err, ErrInvalidKeySize) {
+		t.Errorf("非法长度的密钥文件应返回 ErrInvalidKeySize，实际: %v", err)
+	}
+}
+
+func TestKeyEncoding(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo")
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	if err := k.SetKeyHex("000102030405060708090a0b0c0d0e0f"); err != nil {
+		t.Fatalf("SetKeyHex 失败: %v", err)
+	}
+	if k.GetKeyBase64() != "AAECAwQFBgcICQoLDA0ODw==" {
+		t.Errorf("Base64 编码不匹配，实际: %s", k.GetKeyBase64())
+	}
+
+	if err := k.SetKeyBase64("Dw4NDAsKCQgHBgUEAwIBAA=="); err != nil {
+		t.Fatalf("SetKeyBase64 失败: %v", err)
+	}
+	if k.GetKeyHex() != "0f0e0d0c0b0a09080706050403020100" {
+		t.Errorf("十六进制编码不匹配，实际: %s", k.GetKeyHex())
+	}
+
+	if err := k.SetKeyHex("zz"); err == nil {
+		t.Error("非法十六进制字符串应返回错误")
+	}
+}
+
+func TestWithRand(t *testing.T) {
+	// 确定性随机源：前 16 字节作为密钥，后 16 字节作为 IV
+	seed := make([]byte, 32)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithRand(bytes.NewReader(seed)))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	if !bytes.Equal(k.GetKey(), seed[:16]) {
+		t.Errorf("密钥应来自自定义随机源，实际: %x", k.GetKey())
+	}
+	k.RandIV()
+	if k.IV != "101112131415161718191a1b1c1d1e1f" {
+		t.Errorf("IV 应来自自定义随机源，实际: %s", k.IV)
+	}
+
+	// 随机源耗尽时创建失败
+	if _, err := NewKeyInfo("http://localhost:4123/keyinfo", WithRand(bytes.NewReader(nil))); err == nil {
+		t.Error("随机源读取失败时应返回错误")
+	}
+}
+
+func TestWithTempDir(t *testing.T) {
+	dir := t.TempDir()
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithTempDir(dir))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+
+	infoFile, err := k.WriteToTempFile()
+	if err != nil {
+		t.Fatalf("WriteToTempFile 失败: %v", err)
+	}
+	if filepath.Dir(k.KeyFile) != dir || filepath.Dir(infoFile) != dir {
+		t.Errorf("临时文件应位于 %s，实际: %s, %s", dir, k.KeyFile, infoFile)
+	}
+
+	if err := k.Dispose(); err != nil {
+		t.Fatalf("Dispose 失败: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Dispose 后目录应为空，实际剩余 %d 个文件", len(entries))
+	}
+}
+
+func TestStringRedacted(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo")
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+	k.RandIV()
+
+	for _, s := range []string{k.String(), fmt.Sprintf("%v", k), fmt.Sprintf("%#v", k), fmt.Sprintf("%+v", k)} {
+		if strings.Contains(s, k.GetKeyHex()) || strings.Contains(s, k.IV) || strings.Contains(s, string(k.GetKey())) {
+			t.Errorf("输出中不应包含密钥或 IV: %s", s)
+		}
+		if !strings.Contains(s, k.URL) || !strings.Contains(s, k.KeyID) || !strings.Contains(s, "IV: set") {
+			t.Errorf("输出应包含 URL、KeyID 与 IV 状态: %s", s)
+		}
+	}
+}
+
+func TestOnDispose(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo")
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+
+	calls := 0
+	k.OnDispose(func(d *KeyInfo) {
+		calls++
+		if _, err := os.Stat(d.KeyFile); err != nil {
+			t.Errorf("清理回调时密钥文件应存在: %v", err)
+		}
+	})
+	k.Dispose()
+	k.Dispose()
+	if calls != 1 {
+		t.Errorf("清理回调应只触发一次，实际: %d", calls)
+	}
+}
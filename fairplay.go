@@ -0,0 +1,104 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// KeyFormatFairPlay FairPlay Streaming 的 KEYFORMAT
+const KeyFormatFairPlay = "com.apple.streamingkeydelivery"
+
+// FairPlaySystemID FairPlay Streaming 的 DRM 系统 ID，用于 CPIX 与 SPEKE
+var FairPlaySystemID = [16]byte{0x94, 0xce, 0x86, 0xfb, 0x07, 0xff, 0x4f, 0x43, 0xad, 0xb8, 0x93, 0xd2, 0xfa, 0x96, 0x8c, 0xa2}
+
+// fairPlayScheme FairPlay 密钥获取URL的协议前缀
+const fairPlayScheme = "skd://"
+
+// WithKeyFormat 设置 EXT-X-KEY 的 KEYFORMAT 与 KEYFORMATVERSIONS，为空时播放器按 identity 处理
+func WithKeyFormat(format, versions string) Option {
+	return func(k *KeyInfo) {
+		k.KeyFormat = format
+		k.KeyFormatVersions = versions
+	}
+}
+
+// WithFairPlay 使用 FairPlay Streaming：METHOD=SAMPLE-AES，KEYFORMAT 为 com.apple.streamingkeydelivery
+// 密钥获取URL需为 skd:// 形式，如 skd://{stream}/{keyID}，播放器以 skd:// 之后的部分作为资产 ID 向 KSM 请求密钥
+func WithFairPlay() Option {
+	return func(k *KeyInfo) {
+		k.Method = MethodSampleAES
+		k.KeyFormat = KeyFormatFairPlay
+		k.KeyFormatVersions = "1"
+	}
+}
+
+// IsFairPlay 是否使用 FairPlay Streaming
+func (k *KeyInfo) IsFairPlay() bool {
+	return k.KeyFormat == KeyFormatFairPlay
+}
+
+// FairPlayAssetID 返回 skd:// 之后的资产 ID，即播放器发给 KSM 的内容标识
+func (k *KeyInfo) FairPlayAssetID() (string, error) {
+	uri := k.KeyURL()
+	if !strings.HasPrefix(uri, fairPlayScheme) {
+		return "", fmt.Errorf("FairPlay 密钥获取URL需以 %s 开头: %s", fairPlayScheme, uri)
+	}
+	id := strings.TrimPrefix(uri, fairPlayScheme)
+	if id == "" {
+		return "", fmt.Errorf("FairPlay 资产 ID 为空")
+	}
+	return id, nil
+}
+
+// FairPlayKSM FairPlay 密钥服务器（Key Security Module）
+// 播放器通过 SPC 请求密钥，KSM 据资产 ID 找到内容密钥与 IV 后以 CKC 返回，本包只负责把密钥交给 KSM
+type FairPlayKSM interface {
+	// PutContentKey 登记资产的内容密钥与 IV，同一资产 ID 重复登记时应覆盖
+	PutContentKey(ctx context.Context, assetID string, key, iv []byte) error
+}
+
+// FairPlayKSMFunc 函数形式的 FairPlayKSM
+type FairPlayKSMFunc func(ctx context.Context, assetID string, key, iv []byte) error
+
+// PutContentKey 实现FairPlayKSM接口
+func (f FairPlayKSMFunc) PutContentKey(ctx context.Context, assetID string, key, iv []byte) error {
+	return f(ctx, assetID, key, iv)
+}
+
+// PublishFairPlay 将内容密钥与 IV 交给 KSM，需在播放列表引用该密钥之前调用
+// FairPlay 的 CKC 需要携带 IV，因此要求显式 IV
+func (k *KeyInfo) PublishFairPlay(ctx context.Context, ksm FairPlayKSM) error {
+	if !k.IsFairPlay() {
+		return fmt.Errorf("KEYFORMAT 不是 %s", KeyFormatFairPlay)
+	}
+	if err := k.requireSampleAES(); err != nil {
+		return err
+	}
+	if k.key == nil {
+		return fmt.Errorf("密钥未初始化")
+	}
+	if !k.HasIV() {
+		return fmt.Errorf("FairPlay 需要显式 IV")
+	}
+	id, err := k.FairPlayAssetID()
+	if err != nil {
+		return err
+	}
+	iv, err := k.SegmentIV(0)
+	if err != nil {
+		return err
+	}
+	if err := ksm.PutContentKey(ctx, id, k.GetKey(), iv); err != nil {
+		return fmt.Errorf("登记 FairPlay 密钥失败: %w", err)
+	}
+	return nil
+}
+
+// WithFairPlayKSM 初始密钥与每次轮换的新密钥在写入 keyinfo 文件前交给 KSM，登记失败时放弃本次轮换
+// 登记在轮换器写锁内进行，KSM 应尽快返回
+func WithFairPlayKSM(ksm FairPlayKSM) RotatorOption {
+	return func(r *Rotator) {
+		r.ksm = ksm
+	}
+}
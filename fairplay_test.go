@@ -0,0 +1,75 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFairPlay(t *testing.T) {
+	k, err := NewKeyInfo("skd://{stream}/{keyID}", WithTempDir(t.TempDir()), WithStream("live"), WithFairPlay())
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.RandIV()
+
+	tag := k.ExtXKey()
+	for _, want := range []string{`METHOD=SAMPLE-AES`, `URI="skd://live/` + k.KeyID + `"`, `KEYFORMAT="com.apple.streamingkeydelivery"`, `KEYFORMATVERSIONS="1"`, `IV=0x`} {
+		if !strings.Contains(tag, want) {
+			t.Errorf("EXT-X-KEY 缺少 %s: %s", want, tag)
+		}
+	}
+	id, err := k.FairPlayAssetID()
+	if err != nil || id != "live/"+k.KeyID {
+		t.Errorf("资产 ID 不正确: %q, %v", id, err)
+	}
+
+	got := map[string][]byte{}
+	ksm := FairPlayKSMFunc(func(ctx context.Context, assetID string, key, iv []byte) error {
+		got[assetID] = append(append([]byte{}, key...), iv...)
+		return nil
+	})
+	r, err := NewRotator(k, 0, WithFairPlayKSM(ksm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Dispose()
+	iv, _ := k.SegmentIV(0)
+	if want := append(k.GetKey(), iv...); !bytes.Equal(got[id], want) {
+		t.Errorf("初始密钥未登记到 KSM")
+	}
+
+	next, err := r.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextID, _ := next.FairPlayAssetID()
+	iv, _ = next.SegmentIV(0)
+	if want := append(next.GetKey(), iv...); nextID == id || !bytes.Equal(got[nextID], want) {
+		t.Errorf("轮换后的密钥未登记到 KSM")
+	}
+
+	// KSM 登记失败时放弃轮换
+	fail := errors.New("ksm down")
+	r.ksm = FairPlayKSMFunc(func(context.Context, string, []byte, []byte) error { return fail })
+	if _, err := r.Rotate(); !errors.Is(err, fail) {
+		t.Errorf("KSM 登记失败应返回错误: %v", err)
+	}
+	if r.Current() != next {
+		t.Errorf("KSM 登记失败后不应切换密钥")
+	}
+
+	plain, err := NewKeyInfo("https://keys.example.com/key", WithTempDir(t.TempDir()), WithFairPlay())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Dispose()
+	if _, err := plain.FairPlayAssetID(); err == nil {
+		t.Errorf("非 skd:// URL 应返回错误")
+	}
+	if err := plain.PublishFairPlay(context.Background(), ksm); err == nil {
+		t.Errorf("缺少 IV 时应返回错误")
+	}
+}
@@ -0,0 +1,478 @@
+package hlskeyinfo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ffmpegStopTimeout 取消后等待 ffmpeg 写完播放列表并退出的时间，超时后强制结束进程
+const ffmpegStopTimeout = 10 * time.Second
+
+// FFmpegEncryption ffmpeg HLS 加密参数的形式
+type FFmpegEncryption int
+
+const (
+	// FFmpegKeyInfoFile 使用 -hls_key_info_file，支持 periodic_rekey 轮换密钥
+	FFmpegKeyInfoFile FFmpegEncryption = iota
+	// FFmpegHLSEnc 使用 -hls_enc、-hls_enc_key、-hls_enc_key_url 与 -hls_enc_iv，仅支持 16 字节密钥，
+	// 密钥明文出现在命令行中，可被同一主机上的其他用户通过进程列表看到
+	FFmpegHLSEnc
+	// FFmpegKeyInfoFD 通过继承的文件描述符（/dev/fd/3）传递 keyinfo 内容，磁盘上不存在 keyinfo 文件，仅 FFmpegCommand 支持
+	// 管道只能读取一次，因此不支持 periodic_rekey 与多路输出（var_stream_map）；Windows 不支持
+	// 如需密钥文件也不落盘，可同时使用 WithMemoryKeyFile
+	FFmpegKeyInfoFD
+)
+
+// ffmpegKeyInfoFD ffmpeg 中继承的 keyinfo 管道的描述符，ExtraFiles 的第一个文件对应 3
+const ffmpegKeyInfoFD = 3
+
+// FFmpegOptions ffmpeg HLS 输出参数
+type FFmpegOptions struct {
+	Input           string           // 输入，为空时不生成 -i，由调用方自行添加输入参数
+	Output          string           // 播放列表路径，如 playlist.m3u8
+	VideoCodec      string           // -c:v，如 copy、libx264，为空时不指定
+	AudioCodec      string           // -c:a，如 copy、aac，为空时不指定
+	SegmentDuration time.Duration    // -hls_time，为 0 时使用 ffmpeg 默认值
+	ListSize        int              // -hls_list_size，0 表示保留全部分片，负数表示使用 ffmpeg 默认值
+	SegmentFilename string           // -hls_segment_filename，如 segment_%d.ts
+	PeriodicRekey   bool             // 在 -hls_flags 中加入 periodic_rekey，每个分片开始时重新读取 keyinfo 文件
+	Flags           []string         // 其他 -hls_flags，如 delete_segments
+	ExtraArgs       []string         // 追加在输出路径之前的其他参数
+	InfoFile        string           // keyinfo 文件路径，为空时使用 WriteToTempFile 生成的文件
+	Encryption      FFmpegEncryption // 加密参数形式，默认 FFmpegKeyInfoFile
+}
+
+// FFmpegArgs 返回使用该密钥生成 HLS 加密流的 ffmpeg 参数（不含 ffmpeg 本身），可直接传给 exec.Command
+func (k *KeyInfo) FFmpegArgs(opts FFmpegOptions) ([]string, error) {
+	if err := k.requireAES128(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFFmpegUnsupported, err)
+	}
+	switch opts.Encryption {
+	case FFmpegKeyInfoFile:
+		if opts.InfoFile == "" {
+			path, err := k.WriteToTempFile()
+			if err != nil {
+				return nil, err
+			}
+			opts.InfoFile = path
+		}
+		return opts.args([]string{"-hls_key_info_file", opts.InfoFile})
+	case FFmpegHLSEnc:
+		encArgs, err := k.hlsEncArgs()
+		if err != nil {
+			return nil, err
+		}
+		if opts.PeriodicRekey {
+			return nil, fmt.Errorf("-hls_enc 不支持 periodic_rekey，请使用 keyinfo 文件")
+		}
+		return opts.args(encArgs)
+	case FFmpegKeyInfoFD:
+		return nil, fmt.Errorf("通过文件描述符传递 keyinfo 需使用 FFmpegCommand")
+	default:
+		return nil, fmt.Errorf("不支持的加密参数形式: %d", opts.Encryption)
+	}
+}
+
+// hlsEncArgs 返回 -hls_enc 形式的加密参数，未设置 IV 时由 ffmpeg 按媒体序列号计算
+func (k *KeyInfo) hlsEncArgs() ([]string, error) {
+	if len(k.key) != 16 {
+		return nil, fmt.Errorf("-hls_enc 仅支持 16 字节密钥，实际: %d", len(k.key))
+	}
+	args := []string{"-hls_enc", "1", "-hls_enc_key", k.GetKeyHex(), "-hls_enc_key_url", k.KeyURL()}
+	if k.HasIV() {
+		if err := ValidateIV(k.IV); err != nil {
+			return nil, err
+		}
+		args = append(args, "-hls_enc_iv", k.IV)
+	}
+	return args, nil
+}
+
+// FFmpegArgs 返回使用轮换器 keyinfo 文件的 ffmpeg 参数，始终开启 periodic_rekey 以便新密钥生效
+// 轮换依赖 keyinfo 文件，不支持 FFmpegHLSEnc
+func (r *Rotator) FFmpegArgs(opts FFmpegOptions) ([]string, error) {
+	if opts.Encryption != FFmpegKeyInfoFile {
+		return nil, fmt.Errorf("轮换器仅支持 keyinfo 文件形式的加密参数")
+	}
+	if err := r.Current().requireAES128(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFFmpegUnsupported, err)
+	}
+	opts.InfoFile = r.InfoFile()
+	opts.PeriodicRekey = true
+	return opts.args([]string{"-hls_key_info_file", opts.InfoFile})
+}
+
+// args 按选项组装参数，keyArgs 为加密参数
+func (o FFmpegOptions) args(keyArgs []string) ([]string, error) {
+	if o.Output == "" {
+		return nil, fmt.Errorf("输出路径不能为空")
+	}
+	if o.SegmentDuration < 0 {
+		return nil, fmt.Errorf("分片时长不能为负数: %v", o.SegmentDuration)
+	}
+
+	var args []string
+	if o.Input != "" {
+		args = append(args, "-i", o.Input)
+	}
+	if o.VideoCodec != "" {
+		args = append(args, "-c:v", o.VideoCodec)
+	}
+	if o.AudioCodec != "" {
+		args = append(args, "-c:a", o.AudioCodec)
+	}
+	args = append(args, "-f", "hls")
+	if o.SegmentDuration > 0 {
+		args = append(args, "-hls_time", strconv.FormatFloat(o.SegmentDuration.Seconds(), 'f', -1, 64))
+	}
+	if o.ListSize >= 0 {
+		args = append(args, "-hls_list_size", strconv.Itoa(o.ListSize))
+	}
+	args = append(args, keyArgs...)
+
+	var flags []string
+	if o.PeriodicRekey {
+		flags = append(flags, "periodic_rekey")
+	}
+	for _, f := range o.Flags {
+		if f != "" && !(f == "periodic_rekey" && o.PeriodicRekey) {
+			flags = append(flags, f)
+		}
+	}
+	if len(flags) > 0 {
+		args = append(args, "-hls_flags", strings.Join(flags, "+"))
+	}
+	if o.SegmentFilename != "" {
+		args = append(args, "-hls_segment_filename", o.SegmentFilename)
+	}
+	args = append(args, o.ExtraArgs...)
+	return append(args, o.Output), nil
+}
+
+// ffmpegBoolOptions 不带参数值的常用 ffmpeg 选项，其余以 - 开头的参数均视为带一个值
+var ffmpegBoolOptions = map[string]bool{
+	"-y": true, "-n": true, "-re": true, "-nostdin": true, "-stdin": true,
+	"-hide_banner": true, "-stats": true, "-nostats": true, "-report": true,
+	"-vn": true, "-an": true, "-sn": true, "-dn": true, "-shortest": true,
+	"-copyts": true, "-start_at_zero": true, "-copytb": true, "-benchmark": true,
+	"-ignore_unknown": true, "-dump": true, "-hex": true, "-xerror": true,
+}
+
+// ffmpegKeyOptions 与 HLS 加密相关、注入时会被替换的选项
+var ffmpegKeyOptions = map[string]bool{
+	"-hls_key_info_file": true, "-hls_enc": true, "-hls_enc_key": true,
+	"-hls_enc_key_url": true, "-hls_enc_iv": true,
+}
+
+// InjectHLSEncryption 在已有的 ffmpeg 参数中为 HLS 输出加入该密钥的 keyinfo 文件，参数中不含 ffmpeg 本身
+// 详见 Rotator.InjectHLSEncryption
+func (k *KeyInfo) InjectHLSEncryption(args []string) ([]string, error) {
+	if err := k.requireAES128(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFFmpegUnsupported, err)
+	}
+	path, err := k.WriteToTempFile()
+	if err != nil {
+		return nil, err
+	}
+	return injectHLSEncryption(args, path, false), nil
+}
+
+// InjectHLSEncryption 在已有的 ffmpeg 参数中为 HLS 输出加入轮换器的 keyinfo 文件并开启 periodic_rekey
+// HLS 输出为带 -f hls 的输出，其次为 .m3u8 结尾的输出，否则为最后一个输出；
+// 该输出原有的 -hls_key_info_file、-hls_enc* 选项被移除，多个 -hls_flags 合并为一个；
+// 找不到输出时原样返回参数副本
+func (r *Rotator) InjectHLSEncryption(args []string) []string {
+	return injectHLSEncryption(args, r.InfoFile(), true)
+}
+
+// injectHLSEncryption 见 Rotator.InjectHLSEncryption
+func injectHLSEncryption(args []string, infoFile string, periodicRekey bool) []string {
+	// 找出所有输出路径的下标，输出选项位于上一个输出（或 -i 输入）之后
+	var outputs []int
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case len(a) < 2 || a[0] != '-':
+			outputs = append(outputs, i)
+		case !ffmpegBoolOptions[a]:
+			i++
+		}
+	}
+	if len(outputs) == 0 {
+		return append([]string(nil), args...)
+	}
+
+	groupStart := func(n int) int {
+		if n == 0 {
+			return 0
+		}
+		return outputs[n-1] + 1
+	}
+	target := -1
+	for n, out := range outputs {
+		for i := groupStart(n); i < out-1; i++ {
+			if args[i] == "-f" && args[i+1] == "hls" {
+				target = n
+			}
+		}
+	}
+	if target < 0 {
+		target = len(outputs) - 1
+		for n, out := range outputs {
+			if strings.HasSuffix(args[out], ".m3u8") {
+				target = n
+			}
+		}
+	}
+	start, out := groupStart(target), outputs[target]
+
+	result := append([]string(nil), args[:start]...)
+	var flags []string
+	if periodicRekey {
+		flags = append(flags, "periodic_rekey")
+	}
+	for i := start; i < out; i++ {
+		a := args[i]
+		if len(a) < 2 || a[0] != '-' || ffmpegBoolOptions[a] || i+1 >= out {
+			result = append(result, a)
+			continue
+		}
+		switch {
+		case ffmpegKeyOptions[a]:
+		case a == "-hls_flags":
+			for _, f := range strings.Split(args[i+1], "+") {
+				if f != "" && !slices.Contains(flags, f) {
+					flags = append(flags, f)
+				}
+			}
+		default:
+			result = append(result, a, args[i+1])
+		}
+		i++
+	}
+	result = append(result, "-hls_key_info_file", infoFile)
+	if len(flags) > 0 {
+		result = append(result, "-hls_flags", strings.Join(flags, "+"))
+	}
+	return append(result, args[out:]...)
+}
+
+// FFmpegCommand 运行一次 ffmpeg HLS 任务，进程退出后清理密钥
+type FFmpegCommand struct {
+	path     string
+	args     []string
+	dispose  func() error
+	onStderr func(line string)
+	keyInfo  []byte        // 通过管道传递给 ffmpeg 的 keyinfo 内容
+	stopWait time.Duration // 取消后等待退出的时间，为 0 时使用 ffmpegStopTimeout
+	once     sync.Once
+}
+
+// FFmpegCommand 创建使用该密钥的 ffmpeg 任务，Run 返回后 KeyInfo 被 Dispose
+func (k *KeyInfo) FFmpegCommand(opts FFmpegOptions) (*FFmpegCommand, error) {
+	if opts.Encryption == FFmpegKeyInfoFD {
+		if err := k.requireAES128(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrFFmpegUnsupported, err)
+		}
+		return k.fdCommand(opts)
+	}
+	args, err := k.FFmpegArgs(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &FFmpegCommand{path: "ffmpeg", args: args, dispose: k.Dispose}, nil
+}
+
+// fdCommand 创建通过继承的文件描述符读取 keyinfo 的 ffmpeg 任务
+func (k *KeyInfo) fdCommand(opts FFmpegOptions) (*FFmpegCommand, error) {
+	if runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("当前平台不支持通过文件描述符传递 keyinfo")
+	}
+	if opts.PeriodicRekey {
+		return nil, fmt.Errorf("通过文件描述符传递的 keyinfo 只能读取一次，不支持 periodic_rekey")
+	}
+	var buf bytes.Buffer
+	if _, err := k.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	args, err := opts.args([]string{"-hls_key_info_file", fmt.Sprintf("/dev/fd/%d", ffmpegKeyInfoFD)})
+	if err != nil {
+		return nil, err
+	}
+	return &FFmpegCommand{path: "ffmpeg", args: args, dispose: k.Dispose, keyInfo: buf.Bytes()}, nil
+}
+
+// FFmpegCommand 创建使用轮换器 keyinfo 文件的 ffmpeg 任务，Run 返回后轮换器被 Dispose
+func (r *Rotator) FFmpegCommand(opts FFmpegOptions) (*FFmpegCommand, error) {
+	args, err := r.FFmpegArgs(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &FFmpegCommand{path: "ffmpeg", args: args, dispose: r.Dispose}, nil
+}
+
+// SetPath 设置 ffmpeg 可执行文件路径，默认从 PATH 中查找 ffmpeg
+func (c *FFmpegCommand) SetPath(path string) *FFmpegCommand {
+	c.path = path
+	return c
+}
+
+// OnStderr 注册 ffmpeg 标准错误输出回调，按行调用（进度行以 \r 分隔，同样逐行回调）
+func (c *FFmpegCommand) OnStderr(fn func(line string)) *FFmpegCommand {
+	c.onStderr = fn
+	return c
+}
+
+// Args 返回 ffmpeg 参数
+func (c *FFmpegCommand) Args() []string {
+	return append([]string(nil), c.args...)
+}
+
+// Run 运行 ffmpeg 并等待退出，只能调用一次
+// ctx 取消时先向 ffmpeg 发送 q 使其写完播放列表后正常退出，并按其退出状态返回（正常退出时为 nil）；
+// 10 秒内未退出则强制结束，仅此时返回 ctx.Err()
+// 无论成功与否，进程退出后都会清理密钥
+func (c *FFmpegCommand) Run(ctx context.Context) (err error) {
+	ran := false
+	c.once.Do(func() { ran = true })
+	if !ran {
+		return fmt.Errorf("ffmpeg 任务已运行")
+	}
+	defer func() {
+		if derr := c.dispose(); derr != nil {
+			err = errors.Join(err, derr)
+		}
+	}()
+
+	cmd := exec.CommandContext(ctx, c.path, c.args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stopWait := c.stopWait
+	if stopWait == 0 {
+		stopWait = ffmpegStopTimeout
+	}
+	// 记录是否走了强制结束的路径，以区分收到 q 后的正常退出；持锁结束进程，Wait 返回后读取时不会错过
+	var (
+		killMu    sync.Mutex
+		killed    bool
+		killTimer atomic.Pointer[time.Timer]
+	)
+	cmd.Cancel = func() error {
+		killTimer.Store(time.AfterFunc(stopWait, func() {
+			killMu.Lock()
+			defer killMu.Unlock()
+			killed = cmd.Process.Kill() == nil
+		}))
+		_, err := io.WriteString(stdin, "q")
+		return err
+	}
+	// 进程退出后仍有子进程占用输出管道时，再等待同样时长后关闭管道
+	cmd.WaitDelay = 2 * stopWait
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if c.keyInfo != nil {
+		pr, err := c.keyInfoPipe()
+		if err != nil {
+			return err
+		}
+		cmd.ExtraFiles = []*os.File{pr}
+		// 子进程已继承读端，父进程持有的副本在启动后关闭
+		defer pr.Close()
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动 ffmpeg 失败: %w", err)
+	}
+
+	// 保留最后一行输出，用于错误信息
+	var last string
+	scanner := bufio.NewScanner(stderr)
+	scanner.Split(scanLines)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		last = line
+		if c.onStderr != nil {
+			c.onStderr(line)
+		}
+	}
+	// 单行超出缓冲区时停止按行读取，继续丢弃剩余输出，避免 ffmpeg 写满管道后阻塞
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		io.Copy(io.Discard, stderr)
+		scanErr = fmt.Errorf("读取 ffmpeg 输出失败: %w", scanErr)
+	}
+
+	err = cmd.Wait()
+	if t := killTimer.Load(); t != nil {
+		t.Stop()
+	}
+	killMu.Lock()
+	forced := killed
+	killMu.Unlock()
+	if forced {
+		return errors.Join(ctx.Err(), scanErr)
+	}
+	if ctx.Err() != nil {
+		// 取消后自行退出时 Wait 返回的是 ctx 的错误，改为按退出状态返回
+		err = nil
+		if st := cmd.ProcessState; st != nil && !st.Success() {
+			err = &exec.ExitError{ProcessState: st}
+		}
+	}
+	if err != nil {
+		if last != "" {
+			err = fmt.Errorf("ffmpeg 退出: %w: %s", err, last)
+		} else {
+			err = fmt.Errorf("ffmpeg 退出: %w", err)
+		}
+	}
+	return errors.Join(err, scanErr)
+}
+
+// keyInfoPipe 创建管道并写入 keyinfo 内容后关闭写端，返回读端
+// keyinfo 内容远小于管道缓冲区，写入不会阻塞
+func (c *FFmpegCommand) keyInfoPipe() (*os.File, error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("创建 keyinfo 管道失败: %w", err)
+	}
+	_, err = pw.Write(c.keyInfo)
+	if cerr := pw.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		pr.Close()
+		return nil, fmt.Errorf("写入 keyinfo 管道失败: %w", err)
+	}
+	return pr, nil
+}
+
+// scanLines 以 \n 或 \r 分隔行的 bufio.SplitFunc
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,332 @@
+package hlskeyinfo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFFmpegArgs(t *testing.T) {
+	k, err := NewKeyInfo("https://example.com/key", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建KeyInfo失败: %v", err)
+	}
+	defer k.Dispose()
+
+	args, err := k.FFmpegArgs(FFmpegOptions{
+		Input:           "input.mp4",
+		Output:          "playlist.m3u8",
+		VideoCodec:      "copy",
+		SegmentDuration: 2500 * time.Millisecond,
+		SegmentFilename: "segment_%d.ts",
+		Flags:           []string{"independent_segments"},
+	})
+	if err != nil {
+		t.Fatalf("生成参数失败: %v", err)
+	}
+	infoFile, _ := k.WriteToTempFile()
+	want := []string{
+		"-i", "input.mp4", "-c:v", "copy", "-f", "hls",
+		"-hls_time", "2.5", "-hls_list_size", "0",
+		"-hls_key_info_file", infoFile,
+		"-hls_flags", "independent_segments",
+		"-hls_segment_filename", "segment_%d.ts",
+		"playlist.m3u8",
+	}
+	if !slices.Equal(args, want) {
+		t.Errorf("参数不正确:\n实际 %q\n期望 %q", args, want)
+	}
+
+	if _, err := k.FFmpegArgs(FFmpegOptions{}); err == nil {
+		t.Error("输出路径为空时应返回错误")
+	}
+}
+
+func TestFFmpegArgsHLSEnc(t *testing.T) {
+	k, err := NewKeyInfo("https://example.com/key", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建KeyInfo失败: %v", err)
+	}
+	defer k.Dispose()
+	k.SetIV("0x0123456789abcdef0123456789abcdef")
+
+	args, err := k.FFmpegArgs(FFmpegOptions{Input: "input.mp4", Output: "playlist.m3u8", ListSize: -1, Encryption: FFmpegHLSEnc})
+	if err != nil {
+		t.Fatalf("生成参数失败: %v", err)
+	}
+	want := []string{
+		"-i", "input.mp4", "-f", "hls",
+		"-hls_enc", "1", "-hls_enc_key", k.GetKeyHex(), "-hls_enc_key_url", "https://example.com/key",
+		"-hls_enc_iv", "0123456789abcdef0123456789abcdef",
+		"playlist.m3u8",
+	}
+	if !slices.Equal(args, want) {
+		t.Errorf("参数不正确:\n实际 %q\n期望 %q", args, want)
+	}
+	if k.infoFile != "" {
+		t.Error("-hls_enc 模式不应生成 keyinfo 文件")
+	}
+
+	k.NoIV()
+	args, _ = k.FFmpegArgs(FFmpegOptions{Output: "playlist.m3u8", ListSize: -1, Encryption: FFmpegHLSEnc})
+	if slices.Contains(args, "-hls_enc_iv") {
+		t.Error("未设置 IV 时不应生成 -hls_enc_iv")
+	}
+	if _, err := k.FFmpegArgs(FFmpegOptions{Output: "playlist.m3u8", PeriodicRekey: true, Encryption: FFmpegHLSEnc}); err == nil {
+		t.Error("-hls_enc 与 periodic_rekey 同时使用时应返回错误")
+	}
+
+	k32, err := NewKeyInfo("https://example.com/key", WithKeySize(32), WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建KeyInfo失败: %v", err)
+	}
+	defer k32.Dispose()
+	if _, err := k32.FFmpegArgs(FFmpegOptions{Output: "playlist.m3u8", Encryption: FFmpegHLSEnc}); err == nil {
+		t.Error("非 16 字节密钥应返回错误")
+	}
+	if _, err := newTestRotator(t, 0).FFmpegArgs(FFmpegOptions{Output: "live.m3u8", Encryption: FFmpegHLSEnc}); err == nil {
+		t.Error("轮换器使用 -hls_enc 时应返回错误")
+	}
+}
+
+func TestRotatorFFmpegArgs(t *testing.T) {
+	r := newTestRotator(t, 0)
+	args, err := r.FFmpegArgs(FFmpegOptions{Output: "live.m3u8", ListSize: -1, Flags: []string{"delete_segments", "periodic_rekey"}})
+	if err != nil {
+		t.Fatalf("生成参数失败: %v", err)
+	}
+	want := []string{"-f", "hls", "-hls_key_info_file", r.InfoFile(), "-hls_flags", "periodic_rekey+delete_segments", "live.m3u8"}
+	if !slices.Equal(args, want) {
+		t.Errorf("参数不正确:\n实际 %q\n期望 %q", args, want)
+	}
+}
+
+func TestInjectHLSEncryption(t *testing.T) {
+	r := newTestRotator(t, 0)
+	info := r.InfoFile()
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "合并已有 hls_flags 并替换旧的 keyinfo",
+			args: []string{"-y", "-i", "in.mp4", "-c", "copy", "-hls_flags", "delete_segments", "-hls_key_info_file", "old.keyinfo", "-hls_flags", "+independent_segments", "out.m3u8"},
+			want: []string{"-y", "-i", "in.mp4", "-c", "copy", "-hls_key_info_file", info, "-hls_flags", "periodic_rekey+delete_segments+independent_segments", "out.m3u8"},
+		},
+		{
+			name: "只修改 -f hls 的输出",
+			args: []string{"-i", "in.mp4", "-f", "hls", "-hls_enc", "1", "-hls_enc_key", "00", "live/index", "-an", "-f", "mp4", "archive.mp4"},
+			want: []string{"-i", "in.mp4", "-f", "hls", "-hls_key_info_file", info, "-hls_flags", "periodic_rekey", "live/index", "-an", "-f", "mp4", "archive.mp4"},
+		},
+		{
+			name: "按扩展名识别输出",
+			args: []string{"-i", "in.mp4", "-nostats", "a.m3u8", "-c", "copy", "b.mkv"},
+			want: []string{"-i", "in.mp4", "-nostats", "-hls_key_info_file", info, "-hls_flags", "periodic_rekey", "a.m3u8", "-c", "copy", "b.mkv"},
+		},
+		{
+			name: "没有输出",
+			args: []string{"-i", "in.mp4"},
+			want: []string{"-i", "in.mp4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := slices.Clone(tt.args)
+			if got := r.InjectHLSEncryption(tt.args); !slices.Equal(got, tt.want) {
+				t.Errorf("参数不正确:\n实际 %q\n期望 %q", got, tt.want)
+			}
+			if !slices.Equal(tt.args, orig) {
+				t.Error("不应修改传入的参数")
+			}
+		})
+	}
+
+	k, err := NewKeyInfo("https://example.com/key", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建KeyInfo失败: %v", err)
+	}
+	defer k.Dispose()
+	got, err := k.InjectHLSEncryption([]string{"-i", "in.mp4", "out.m3u8"})
+	infoFile, _ := k.WriteToTempFile()
+	if want := []string{"-i", "in.mp4", "-hls_key_info_file", infoFile, "out.m3u8"}; err != nil || !slices.Equal(got, want) {
+		t.Errorf("参数不正确: %q, %v", got, err)
+	}
+}
+
+// TestFFmpegHelperProcess 作为假的 ffmpeg 进程运行，由 FFMPEG_HELPER 决定行为
+func TestFFmpegHelperProcess(t *testing.T) {
+	mode := os.Getenv("FFMPEG_HELPER")
+	if mode == "" {
+		return
+	}
+	switch mode {
+	case "ok":
+		fmt.Fprint(os.Stderr, "frame=1\rframe=2\nmuxing done\n")
+		os.Exit(0)
+	case "fail":
+		fmt.Fprintln(os.Stderr, "Invalid argument")
+		os.Exit(1)
+	case "wait":
+		// 与 ffmpeg 一样，读到 q 后退出
+		fmt.Fprintln(os.Stderr, "running")
+		buf := make([]byte, 1)
+		for {
+			if _, err := os.Stdin.Read(buf); err != nil || buf[0] == 'q' {
+				os.Exit(0)
+			}
+		}
+	case "longline":
+		// 输出超出 bufio.Scanner 上限的一行，之后的输出超过管道缓冲区
+		fmt.Fprintln(os.Stderr, strings.Repeat("x", 128*1024))
+		fmt.Fprint(os.Stderr, strings.Repeat("frame=1\n", 64*1024))
+		os.Exit(0)
+	case "hang":
+		// 忽略 q，只能被强制结束
+		fmt.Fprintln(os.Stderr, "running")
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	}
+}
+
+// helperCommand 将 FFmpegCommand 指向测试进程
+func helperCommand(t *testing.T, mode string) (*FFmpegCommand, *KeyInfo) {
+	t.Helper()
+	t.Setenv("FFMPEG_HELPER", mode)
+	k, err := NewKeyInfo("https://example.com/key", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建KeyInfo失败: %v", err)
+	}
+	c, err := k.FFmpegCommand(FFmpegOptions{Output: "playlist.m3u8"})
+	if err != nil {
+		t.Fatalf("创建任务失败: %v", err)
+	}
+	// 保留原参数用于校验，前置 -test.run 让测试进程只运行 helper
+	c.args = append([]string{"-test.run=^TestFFmpegHelperProcess$", "--"}, c.args...)
+	return c.SetPath(os.Args[0]), k
+}
+
+func TestFFmpegCommand(t *testing.T) {
+	ctx := context.Background()
+
+	c, k := helperCommand(t, "ok")
+	infoFile, _ := k.WriteToTempFile()
+	var lines []string
+	c.OnStderr(func(line string) { lines = append(lines, line) })
+	if !slices.Contains(c.Args(), infoFile) {
+		t.Errorf("参数中应包含 keyinfo 文件: %q", c.Args())
+	}
+	if err := c.Run(ctx); err != nil {
+		t.Fatalf("运行失败: %v", err)
+	}
+	if want := []string{"frame=1", "frame=2", "muxing done"}; !slices.Equal(lines, want) {
+		t.Errorf("stderr 回调不正确: %q", lines)
+	}
+	if _, err := os.Stat(infoFile); !os.IsNotExist(err) {
+		t.Error("进程退出后应清理 keyinfo 文件")
+	}
+	if err := c.Run(ctx); err == nil {
+		t.Error("重复运行应返回错误")
+	}
+
+	c, _ = helperCommand(t, "fail")
+	if err := c.Run(ctx); err == nil || !strings.Contains(err.Error(), "Invalid argument") {
+		t.Errorf("错误信息应包含 ffmpeg 最后一行输出: %v", err)
+	}
+
+	c, _ = helperCommand(t, "wait")
+	ctx, cancel := context.WithCancel(ctx)
+	c.OnStderr(func(line string) { cancel() })
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("收到 q 后正常退出应返回 nil: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("取消后 ffmpeg 未退出")
+	}
+
+	// 未响应 q 时强制结束并返回 ctx.Err()
+	c, _ = helperCommand(t, "hang")
+	c.stopWait = 200 * time.Millisecond
+	ctx, cancel = context.WithCancel(context.Background())
+	c.OnStderr(func(line string) { cancel() })
+	go func() { done <- c.Run(ctx) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("强制结束后应返回 context.Canceled: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("超时后 ffmpeg 未被强制结束")
+	}
+
+	// 超长输出行导致停止按行读取后仍需读完输出，否则 ffmpeg 阻塞在写入上
+	c, _ = helperCommand(t, "longline")
+	go func() { done <- c.Run(context.Background()) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, bufio.ErrTooLong) {
+			t.Errorf("应返回读取输出的错误: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("输出行过长时 ffmpeg 未退出")
+	}
+
+	c, _ = helperCommand(t, "ok")
+	c.SetPath("/nonexistent/ffmpeg")
+	if err := c.Run(context.Background()); err == nil {
+		t.Error("可执行文件不存在时应返回错误")
+	}
+}
+
+func TestFFmpegCommandKeyInfoFD(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 不支持通过文件描述符传递 keyinfo")
+	}
+	// 模拟 ffmpeg：将 -hls_key_info_file 指向的内容输出到 stderr
+	script := "#!/bin/sh\nwhile [ $# -gt 0 ]; do [ \"$1\" = -hls_key_info_file ] && cat \"$2\" >&2; shift; done\n"
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(path, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	k, err := NewKeyInfo("https://example.com/key", WithTempDir(dir))
+	if err != nil {
+		t.Fatalf("创建KeyInfo失败: %v", err)
+	}
+	k.RandIV()
+	c, err := k.FFmpegCommand(FFmpegOptions{Output: "playlist.m3u8", Encryption: FFmpegKeyInfoFD})
+	if err != nil {
+		t.Fatalf("创建任务失败: %v", err)
+	}
+	if !slices.Contains(c.Args(), "/dev/fd/3") {
+		t.Errorf("参数应使用 /dev/fd/3: %q", c.Args())
+	}
+	want := []string{"https://example.com/key", k.KeyFile, k.IV}
+	var lines []string
+	if err := c.SetPath(path).OnStderr(func(line string) { lines = append(lines, line) }).Run(context.Background()); err != nil {
+		t.Fatalf("运行失败: %v", err)
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("ffmpeg 读取的 keyinfo 不正确: %q", lines)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*keyinfo*")); len(matches) > 0 {
+		t.Errorf("不应生成 keyinfo 文件: %v", matches)
+	}
+
+	if _, err := k.FFmpegArgs(FFmpegOptions{Output: "playlist.m3u8", Encryption: FFmpegKeyInfoFD}); err == nil {
+		t.Error("FFmpegArgs 不支持文件描述符形式，应返回错误")
+	}
+}
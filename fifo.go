@@ -0,0 +1,116 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// fifoReopenDelay 写完一份内容后重新打开管道前的等待时间，留给读取方读到 EOF 并关闭
+const fifoReopenDelay = 100 * time.Millisecond
+
+// ErrFIFOUnsupported 当前平台不支持命名管道
+var ErrFIFOUnsupported = errors.New("当前平台不支持命名管道")
+
+// fifoServer 以命名管道提供 keyinfo 内容，每次有读取方打开管道时写入一份完整内容
+type fifoServer struct {
+	path    string
+	content atomic.Pointer[[]byte]
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// ServeFIFO 创建命名管道代替普通 keyinfo 文件，返回管道路径
+// 后台 goroutine 在每次读取方（如 ffmpeg）打开管道时写入 keyinfo 内容，内容不会落盘
+// 重复调用返回同一管道，Dispose 时停止后台 goroutine 并删除管道
+func (k *KeyInfo) ServeFIFO() (string, error) {
+	if k.key == nil {
+		return "", fmt.Errorf("密钥未初始化")
+	}
+	if k.fifo != nil {
+		return k.fifo.path, k.refreshFIFO()
+	}
+
+	name := strings.TrimSuffix(k.infoFileName(), ".txt") + ".fifo"
+	path := filepath.Join(k.dir(), name)
+	if err := mkfifo(path, k.fileMode); err != nil {
+		return "", err
+	}
+
+	s := &fifoServer{
+		path: path,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	k.fifo = s
+	if err := k.refreshFIFO(); err != nil {
+		k.fifo = nil
+		os.Remove(path)
+		return "", err
+	}
+
+	go s.serve()
+	return path, nil
+}
+
+// refreshFIFO 更新命名管道后续提供的 keyinfo 内容
+func (k *KeyInfo) refreshFIFO() error {
+	if k.fifo == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	if _, err := k.WriteTo(&buf); err != nil {
+		return err
+	}
+	content := buf.Bytes()
+	k.fifo.content.Store(&content)
+	return nil
+}
+
+// serve 循环等待读取方打开管道并写入内容
+func (s *fifoServer) serve() {
+	defer close(s.done)
+	for {
+		// 以只写方式打开会阻塞直到有读取方
+		f, err := os.OpenFile(s.path, os.O_WRONLY, 0)
+		select {
+		case <-s.stop:
+			if err == nil {
+				f.Close()
+			}
+			return
+		default:
+		}
+		if err != nil {
+			return
+		}
+		f.Write(*s.content.Load())
+		f.Close()
+
+		// 读取方关闭前再次打开会立即成功，导致同一读取方收到重复内容且读不到 EOF
+		// 因此稍作等待，期间新的读取方会阻塞在打开操作上直到下一轮写入
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(fifoReopenDelay):
+		}
+	}
+}
+
+// close 停止后台 goroutine 并删除管道
+func (s *fifoServer) close() error {
+	close(s.stop)
+	// 以非阻塞只读方式打开管道，唤醒阻塞在打开操作上的写入方
+	unblockFIFO(s.path)
+	<-s.done
+
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除命名管道失败: %w", err)
+	}
+	return nil
+}
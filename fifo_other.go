@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd || openbsd || netbsd || dragonfly)
+
+package hlskeyinfo
+
+import "os"
+
+// mkfifo 当前平台不支持命名管道
+func mkfifo(string, os.FileMode) error {
+	return ErrFIFOUnsupported
+}
+
+// unblockFIFO 当前平台不支持命名管道
+func unblockFIFO(string) {}
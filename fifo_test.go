@@ -0,0 +1,81 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly
+
+package hlskeyinfo
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestServeFIFO(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+	k.SetIV("abcdef1234567890abcdef1234567890")
+
+	path, err := k.ServeFIFO()
+	if err != nil {
+		t.Fatalf("ServeFIFO 失败: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("读取管道信息失败: %v", err)
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		t.Error("返回的路径不是命名管道")
+	}
+
+	// 每次读取都能得到完整内容，IV 轮换后读取到新内容
+	for i := 0; i < 2; i++ {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("读取管道失败: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		if len(lines) != 3 || lines[2] != k.IV {
+			t.Errorf("第 %d 次读取内容不匹配: %q", i+1, content)
+		}
+		if err := k.RotateIV(); err != nil {
+			t.Fatalf("RotateIV 失败: %v", err)
+		}
+	}
+
+	if err := k.Dispose(); err != nil {
+		t.Fatalf("Dispose 失败: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Dispose 后命名管道应被删除")
+	}
+}
+
+func TestServeFIFOSetKey(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keys/{keyID}", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	path, err := k.ServeFIFO()
+	if err != nil {
+		t.Fatalf("ServeFIFO 失败: %v", err)
+	}
+	oldURL := k.KeyURL()
+	if err := k.SetKey([]byte("0123456789abcdef")); err != nil {
+		t.Fatalf("SetKey 失败: %v", err)
+	}
+	if k.KeyURL() == oldURL {
+		t.Fatal("SetKey 后 KeyID 应更新")
+	}
+
+	// 替换密钥后管道提供新的密钥获取URL
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取管道失败: %v", err)
+	}
+	if line := strings.SplitN(string(content), "\n", 2)[0]; line != k.KeyURL() {
+		t.Errorf("管道应提供新的密钥获取URL %q，实际: %q", k.KeyURL(), line)
+	}
+}
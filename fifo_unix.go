@@ -0,0 +1,24 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly
+
+package hlskeyinfo
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// mkfifo 创建命名管道
+func mkfifo(path string, mode os.FileMode) error {
+	if err := syscall.Mkfifo(path, uint32(mode.Perm())); err != nil {
+		return fmt.Errorf("创建命名管道失败: %w", err)
+	}
+	return nil
+}
+
+// unblockFIFO 以非阻塞方式打开并关闭管道读端
+func unblockFIFO(path string) {
+	if f, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0); err == nil {
+		f.Close()
+	}
+}
@@ -0,0 +1,77 @@
+package hlskeyinfo
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 不支持 Unix 文件权限")
+	}
+
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	infoFile, err := k.WriteToTempFile()
+	if err != nil {
+		t.Fatalf("WriteToTempFile 失败: %v", err)
+	}
+	for _, path := range []string{k.KeyFile, infoFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("读取文件信息失败: %v", err)
+		}
+		if info.Mode().Perm() != DefaultFileMode {
+			t.Errorf("%s 权限期望 %o，实际: %o", path, DefaultFileMode, info.Mode().Perm())
+		}
+	}
+
+	// 已存在的宽松权限密钥文件会被修复
+	keyFile := filepath.Join(t.TempDir(), "ops.key")
+	if err := os.WriteFile(keyFile, make([]byte, 16), 0o644); err != nil {
+		t.Fatalf("写入密钥文件失败: %v", err)
+	}
+	if err := os.Chmod(keyFile, 0o644); err != nil {
+		t.Fatalf("设置权限失败: %v", err)
+	}
+	loaded, err := NewKeyInfoFromKeyFile("http://localhost:4123/keyinfo", keyFile, WithFileMode(0o640))
+	if err != nil {
+		t.Fatalf("从密钥文件创建 KeyInfo 失败: %v", err)
+	}
+	defer loaded.Dispose()
+	info, _ := os.Stat(keyFile)
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("密钥文件权限未修复，实际: %o", info.Mode().Perm())
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keyinfo.txt")
+	if err := os.WriteFile(path, []byte("old\n"), 0o600); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+
+	if err := writeFileAtomic(path, []byte("new\n"), 0o600); err != nil {
+		t.Fatalf("writeFileAtomic 失败: %v", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取文件失败: %v", err)
+	}
+	if string(content) != "new\n" {
+		t.Errorf("文件内容未被替换，实际: %q", content)
+	}
+
+	// 不应残留临时文件
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("期望目录中只有 1 个文件，实际: %d", len(entries))
+	}
+}
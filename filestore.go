@@ -0,0 +1,264 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// defaultStreamDir 未指定流的密钥所在的目录名
+const defaultStreamDir = "_default"
+
+// FileStore 基于目录的密钥存储，目录结构为 <root>/<stream>/<keyID>.bin，
+// 同目录下的 <keyID>.json 保存不含密钥的元数据；目录权限 0700，文件权限 0600，均原子写入
+// 这些文件只用于持久化，ffmpeg 读取的密钥文件仍由 KeyInfo 另行写入
+type FileStore struct {
+	root      string
+	masterKey []byte
+	mu        sync.RWMutex
+}
+
+var _ KeyStore = &FileStore{}
+
+// fileStoreMeta 元数据文件内容，不包含密钥
+type fileStoreMeta struct {
+	KeyRecord
+	Key       []byte `json:"key,omitempty"`       // 覆盖 KeyRecord.Key，始终为空
+	Encrypted bool   `json:"encrypted,omitempty"` // 密钥文件以主密钥加密
+}
+
+// FileStoreOption 目录密钥存储选项
+type FileStoreOption func(*FileStore)
+
+// WithMasterKey 以 AES-GCM 在主密钥下加密密钥文件，读取时自动解密；未加密的旧文件仍可读取
+// 主密钥可通过 MasterKeyFromEnv 从环境变量读取，使用 KMS 时改用 NewSealedStore 包装 FileStore
+func WithMasterKey(key []byte) FileStoreOption {
+	return func(s *FileStore) {
+		s.masterKey = key
+	}
+}
+
+// NewFileStore 创建基于目录的密钥存储，目录不存在时自动创建
+func NewFileStore(root string, opts ...FileStoreOption) (*FileStore, error) {
+	s := &FileStore{root: root}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.masterKey != nil {
+		if err := validateKeySize(len(s.masterKey)); err != nil {
+			return nil, fmt.Errorf("无效的主密钥: %w", err)
+		}
+	}
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return nil, fmt.Errorf("创建密钥存储目录失败: %w", err)
+	}
+	return s, nil
+}
+
+// MasterKeyFromEnv 从环境变量读取十六进制或 Base64 编码的 16/24/32 字节主密钥
+func MasterKeyFromEnv(name string) ([]byte, error) {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return nil, fmt.Errorf("环境变量 %s 未设置", name)
+	}
+	key, err := hex.DecodeString(v)
+	if err != nil {
+		if key, err = base64.StdEncoding.DecodeString(v); err != nil {
+			return nil, fmt.Errorf("环境变量 %s 不是十六进制或 Base64 编码", name)
+		}
+	}
+	if err := validateKeySize(len(key)); err != nil {
+		return nil, fmt.Errorf("无效的主密钥: %w", err)
+	}
+	return key, nil
+}
+
+// Put 实现 KeyStore 接口
+func (s *FileStore) Put(ctx context.Context, rec KeyRecord) error {
+	dir, err := s.streamDir(rec.Stream)
+	if err != nil {
+		return err
+	}
+	if err := validatePathName(rec.KeyID); err != nil {
+		return err
+	}
+	keyData := rec.Key
+	if s.masterKey != nil {
+		if keyData, err = gcmSeal(s.masterKey, rec.Key); err != nil {
+			return fmt.Errorf("加密密钥文件失败: %w", err)
+		}
+	}
+	meta, err := json.MarshalIndent(fileStoreMeta{KeyRecord: rec, Encrypted: s.masterKey != nil}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化密钥元数据失败: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 同一 KeyID 只保存在一个流目录下
+	if old, err := s.find(rec.KeyID); err == nil && filepath.Dir(old) != dir {
+		removeKeyFiles(old)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("创建流目录失败: %w", err)
+	}
+	base := filepath.Join(dir, rec.KeyID)
+	// 先写密钥再写元数据，元数据存在即表示记录完整
+	if err := writeFileAtomic(base+".bin", keyData, 0o600); err != nil {
+		return fmt.Errorf("写入密钥文件失败: %w", err)
+	}
+	if err := writeFileAtomic(base+".json", meta, 0o600); err != nil {
+		return fmt.Errorf("写入密钥元数据失败: %w", err)
+	}
+	return nil
+}
+
+// Get 实现 KeyStore 接口
+func (s *FileStore) Get(ctx context.Context, keyID string) (KeyRecord, error) {
+	if err := validatePathName(keyID); err != nil {
+		return KeyRecord{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	base, err := s.find(keyID)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	return readKeyFiles(base, s.masterKey)
+}
+
+// Delete 实现 KeyStore 接口
+func (s *FileStore) Delete(ctx context.Context, keyID string) error {
+	if err := validatePathName(keyID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	base, err := s.find(keyID)
+	if err != nil {
+		return err
+	}
+	return removeKeyFiles(base)
+}
+
+// List 实现 KeyStore 接口
+func (s *FileStore) List(ctx context.Context, stream string) ([]KeyRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var dirs []string
+	if stream != "" {
+		dir, err := s.streamDir(stream)
+		if err != nil {
+			return nil, err
+		}
+		dirs = []string{dir}
+	} else {
+		entries, err := os.ReadDir(s.root)
+		if err != nil {
+			return nil, fmt.Errorf("读取密钥存储目录失败: %w", err)
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				dirs = append(dirs, filepath.Join(s.root, e.Name()))
+			}
+		}
+	}
+
+	var out []KeyRecord
+	for _, dir := range dirs {
+		metas, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, meta := range metas {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			rec, err := readKeyFiles(strings.TrimSuffix(meta, ".json"), s.masterKey)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, rec)
+		}
+	}
+	sortRecords(out)
+	return out, nil
+}
+
+// streamDir 返回流对应的目录
+func (s *FileStore) streamDir(stream string) (string, error) {
+	if stream == "" {
+		return filepath.Join(s.root, defaultStreamDir), nil
+	}
+	if err := validatePathName(stream); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, stream), nil
+}
+
+// find 查找 KeyID 对应的文件路径（不含扩展名），调用方需持有锁
+func (s *FileStore) find(keyID string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.root, "*", keyID+".json"))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	return strings.TrimSuffix(matches[0], ".json"), nil
+}
+
+// readKeyFiles 读取密钥文件与元数据，密钥文件已加密时以 masterKey 解密
+func readKeyFiles(base string, masterKey []byte) (KeyRecord, error) {
+	data, err := os.ReadFile(base + ".json")
+	if err != nil {
+		return KeyRecord{}, fmt.Errorf("读取密钥元数据失败: %w", err)
+	}
+	var meta fileStoreMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return KeyRecord{}, fmt.Errorf("解析密钥元数据失败: %w", err)
+	}
+	rec := meta.KeyRecord
+	if rec.Key, err = os.ReadFile(base + ".bin"); err != nil {
+		return KeyRecord{}, fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	if meta.Encrypted {
+		if masterKey == nil {
+			return KeyRecord{}, fmt.Errorf("密钥文件 %s.bin 已加密，需要主密钥", base)
+		}
+		if rec.Key, err = gcmOpen(masterKey, rec.Key); err != nil {
+			return KeyRecord{}, fmt.Errorf("密钥文件 %s.bin: %w", base, err)
+		}
+	}
+	return rec, nil
+}
+
+// removeKeyFiles 删除密钥文件与元数据，先删除元数据使记录立即不可见
+func removeKeyFiles(base string) error {
+	var errs []error
+	for _, ext := range []string{".json", ".bin"} {
+		if err := os.Remove(base + ext); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("删除密钥文件失败: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// validatePathName 校验名称可安全地用作单个路径片段，且不含通配符
+func validatePathName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\*?[`) || strings.ContainsRune(name, 0) {
+		return fmt.Errorf("无效的名称: %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	root := filepath.Join(t.TempDir(), "keys")
+	store, err := NewFileStore(root)
+	if err != nil {
+		t.Fatalf("创建 FileStore 失败: %v", err)
+	}
+
+	k, err := NewKeyInfo("https://keys.example.com/{stream}/{keyID}", WithStream("channel-1"), WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+	k.RandIV()
+	if err := k.SaveTo(ctx, store); err != nil {
+		t.Fatalf("保存密钥失败: %v", err)
+	}
+
+	base := filepath.Join(root, "channel-1", k.KeyID)
+	for _, ext := range []string{".bin", ".json"} {
+		info, err := os.Stat(base + ext)
+		if err != nil {
+			t.Fatalf("密钥文件不存在: %v", err)
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("期望 %s 权限为 0600，实际: %o", ext, info.Mode().Perm())
+		}
+	}
+	meta, _ := os.ReadFile(base + ".json")
+	if strings.Contains(string(meta), `"key"`) {
+		t.Error("元数据文件不应包含密钥")
+	}
+
+	rec, err := store.Get(ctx, k.KeyID)
+	if err != nil {
+		t.Fatalf("读取密钥失败: %v", err)
+	}
+	if !bytes.Equal(rec.Key, k.GetKey()) || rec.IV != k.IV || rec.URL != k.KeyURL() || rec.Stream != "channel-1" {
+		t.Errorf("读取的密钥记录不正确: %+v", rec)
+	}
+
+	other := KeyRecord{KeyID: "other", Key: bytes.Repeat([]byte{1}, 16), NotBefore: time.Now()}
+	if err := store.Put(ctx, other); err != nil {
+		t.Fatalf("保存密钥失败: %v", err)
+	}
+	if all, _ := store.List(ctx, ""); len(all) != 2 {
+		t.Errorf("期望 2 条记录，实际: %d", len(all))
+	}
+	if list, _ := store.List(ctx, "channel-1"); len(list) != 1 || list[0].KeyID != k.KeyID {
+		t.Errorf("按流列出的记录不正确: %+v", list)
+	}
+
+	if err := store.Delete(ctx, k.KeyID); err != nil {
+		t.Fatalf("删除密钥失败: %v", err)
+	}
+	if _, err := store.Get(ctx, k.KeyID); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("删除后应返回 ErrKeyNotFound，实际: %v", err)
+	}
+	if err := store.Put(ctx, KeyRecord{KeyID: "../escape", Key: other.Key}); err == nil {
+		t.Error("包含路径分隔符的 KeyID 应返回错误")
+	}
+}
+
+func TestFileStoreMasterKey(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	t.Setenv("HLS_MASTER_KEY", strings.Repeat("ab", 32))
+	master, err := MasterKeyFromEnv("HLS_MASTER_KEY")
+	if err != nil {
+		t.Fatalf("读取主密钥失败: %v", err)
+	}
+	store, err := NewFileStore(root, WithMasterKey(master))
+	if err != nil {
+		t.Fatalf("创建 FileStore 失败: %v", err)
+	}
+	testKeyStore(t, store)
+
+	k, err := NewKeyInfo("https://example.com/key", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+	if err := k.SaveTo(ctx, store); err != nil {
+		t.Fatalf("保存密钥失败: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(root, defaultStreamDir, k.KeyID+".bin"))
+	if bytes.Contains(data, k.GetKey()) {
+		t.Error("密钥文件不应包含明文密钥")
+	}
+
+	// 重新加载时自动解密
+	restored, err := NewKeyInfoFromStore(ctx, store, k.KeyID, WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("从密钥存储恢复失败: %v", err)
+	}
+	defer restored.Dispose()
+	if !bytes.Equal(restored.GetKey(), k.GetKey()) {
+		t.Error("恢复的密钥与原密钥不一致")
+	}
+
+	plain, _ := NewFileStore(root)
+	if _, err := plain.Get(ctx, k.KeyID); err == nil {
+		t.Error("没有主密钥时读取加密的密钥文件应返回错误")
+	}
+	wrong, _ := NewFileStore(root, WithMasterKey(bytes.Repeat([]byte{1}, 32)))
+	if _, err := wrong.Get(ctx, k.KeyID); err == nil {
+		t.Error("主密钥错误时应返回错误")
+	}
+
+	// 启用加密前写入的明文文件仍可读取
+	old := KeyRecord{KeyID: "plain", Key: bytes.Repeat([]byte{2}, 16), NotBefore: time.Now()}
+	if err := plain.Put(ctx, old); err != nil {
+		t.Fatalf("保存密钥失败: %v", err)
+	}
+	if rec, err := store.Get(ctx, "plain"); err != nil || !bytes.Equal(rec.Key, old.Key) {
+		t.Errorf("应能读取未加密的密钥文件: %v", err)
+	}
+
+	t.Setenv("HLS_MASTER_KEY", "short")
+	if _, err := MasterKeyFromEnv("HLS_MASTER_KEY"); err == nil {
+		t.Error("无效的主密钥应返回错误")
+	}
+}
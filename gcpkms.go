@@ -0,0 +1,102 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GCPKMSClient Google Cloud KMS 客户端，由调用方基于 cloud.google.com/go/kms 实现
+type GCPKMSClient interface {
+	Encrypt(ctx context.Context, keyName string, plaintext, aad []byte) ([]byte, error)
+	Decrypt(ctx context.Context, keyName string, ciphertext, aad []byte) ([]byte, error)
+	// GenerateRandomBytes 以 HSM 保护级别生成随机字节，location 如 projects/p/locations/global
+	GenerateRandomBytes(ctx context.Context, location string, n int) ([]byte, error)
+}
+
+// GCPKMSProvider 基于 Google Cloud KMS 的 KeyProvider
+// Cloud KMS 没有数据密钥接口，内容密钥在本地生成（或由 HSM 生成随机字节）后以 KMS 密钥封装
+type GCPKMSProvider struct {
+	client  GCPKMSClient
+	keyName string
+	aad     []byte
+	hsm     bool
+	random  io.Reader
+}
+
+var _ KeyProvider = &GCPKMSProvider{}
+
+// GCPKMSOption GCP KMS 选项
+type GCPKMSOption func(*GCPKMSProvider)
+
+// WithAdditionalData 设置附加认证数据，解封时必须提供相同的数据
+func WithAdditionalData(aad []byte) GCPKMSOption {
+	return func(p *GCPKMSProvider) {
+		p.aad = aad
+	}
+}
+
+// WithHSMRandom 通过 GenerateRandomBytes 由 Cloud HSM 生成内容密钥，默认使用本地 crypto/rand
+func WithHSMRandom() GCPKMSOption {
+	return func(p *GCPKMSProvider) {
+		p.hsm = true
+	}
+}
+
+// NewGCPKMSProvider 创建基于 Cloud KMS 的 KeyProvider，keyName 为加密密钥资源名，
+// 如 projects/p/locations/global/keyRings/r/cryptoKeys/k
+func NewGCPKMSProvider(client GCPKMSClient, keyName string, opts ...GCPKMSOption) (*GCPKMSProvider, error) {
+	if !strings.Contains(keyName, "/keyRings/") {
+		return nil, fmt.Errorf("无效的 KMS 密钥资源名: %q", keyName)
+	}
+	p := &GCPKMSProvider{client: client, keyName: keyName, random: rand.Reader}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+// GenerateDataKey 实现 KeyProvider 接口
+func (p *GCPKMSProvider) GenerateDataKey(ctx context.Context, size int) (plaintext, wrapped []byte, err error) {
+	if err := validateKeySize(size); err != nil {
+		return nil, nil, err
+	}
+	if p.hsm {
+		location, _, _ := strings.Cut(p.keyName, "/keyRings/")
+		if plaintext, err = p.client.GenerateRandomBytes(ctx, location, size); err != nil {
+			return nil, nil, fmt.Errorf("KMS GenerateRandomBytes 失败: %w", err)
+		}
+		if len(plaintext) != size {
+			return nil, nil, fmt.Errorf("KMS 返回的随机字节长度 %d 与请求的 %d 不一致", len(plaintext), size)
+		}
+	} else {
+		plaintext = make([]byte, size)
+		if _, err := io.ReadFull(p.random, plaintext); err != nil {
+			return nil, nil, fmt.Errorf("生成密钥失败: %w", err)
+		}
+	}
+	if wrapped, err = p.Encrypt(ctx, plaintext); err != nil {
+		return nil, nil, err
+	}
+	return plaintext, wrapped, nil
+}
+
+// Encrypt 实现 KeyProvider 接口
+func (p *GCPKMSProvider) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	wrapped, err := p.client.Encrypt(ctx, p.keyName, plaintext, p.aad)
+	if err != nil {
+		return nil, fmt.Errorf("KMS Encrypt 失败: %w", err)
+	}
+	return wrapped, nil
+}
+
+// Decrypt 实现 KeyProvider 接口
+func (p *GCPKMSProvider) Decrypt(ctx context.Context, wrapped []byte) ([]byte, error) {
+	key, err := p.client.Decrypt(ctx, p.keyName, wrapped, p.aad)
+	if err != nil {
+		return nil, fmt.Errorf("KMS Decrypt 失败: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,78 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+// fakeGCPKMS 模拟 GCPKMSClient，以附加认证数据调用 AES-GCM
+type fakeGCPKMS struct {
+	kek       []byte
+	locations []string
+}
+
+func (f *fakeGCPKMS) gcm() cipher.AEAD {
+	block, _ := aes.NewCipher(f.kek)
+	gcm, _ := cipher.NewGCM(block)
+	return gcm
+}
+
+func (f *fakeGCPKMS) Encrypt(ctx context.Context, keyName string, plaintext, aad []byte) ([]byte, error) {
+	gcm := f.gcm()
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func (f *fakeGCPKMS) Decrypt(ctx context.Context, keyName string, ciphertext, aad []byte) ([]byte, error) {
+	gcm := f.gcm()
+	n := gcm.NonceSize()
+	if len(ciphertext) < n {
+		return nil, errors.New("invalid ciphertext")
+	}
+	return gcm.Open(nil, ciphertext[:n], ciphertext[n:], aad)
+}
+
+func (f *fakeGCPKMS) GenerateRandomBytes(ctx context.Context, location string, n int) ([]byte, error) {
+	f.locations = append(f.locations, location)
+	return bytes.Repeat([]byte{5}, n), nil
+}
+
+func TestGCPKMSProvider(t *testing.T) {
+	ctx := context.Background()
+	const keyName = "projects/p/locations/global/keyRings/r/cryptoKeys/hls"
+	client := &fakeGCPKMS{kek: bytes.Repeat([]byte{3}, 32)}
+
+	p, err := NewGCPKMSProvider(client, keyName, WithAdditionalData([]byte("channel-1")), WithHSMRandom())
+	if err != nil {
+		t.Fatalf("创建 GCPKMSProvider 失败: %v", err)
+	}
+	key, wrapped, err := p.GenerateDataKey(ctx, 16)
+	if err != nil {
+		t.Fatalf("生成数据密钥失败: %v", err)
+	}
+	if !bytes.Equal(key, bytes.Repeat([]byte{5}, 16)) || len(client.locations) != 1 || client.locations[0] != "projects/p/locations/global" {
+		t.Errorf("应在密钥所在位置由 HSM 生成随机字节: %v", client.locations)
+	}
+	if got, err := p.Decrypt(ctx, wrapped); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("解封结果不一致: %v", err)
+	}
+
+	// 附加认证数据不一致时无法解封
+	other, _ := NewGCPKMSProvider(client, keyName, WithAdditionalData([]byte("channel-2")))
+	if _, err := other.Decrypt(ctx, wrapped); err == nil {
+		t.Error("附加认证数据不一致时应解封失败")
+	}
+	if key, _, err := other.GenerateDataKey(ctx, 32); err != nil || len(key) != 32 {
+		t.Errorf("本地生成数据密钥失败: %v", err)
+	}
+
+	if _, err := NewGCPKMSProvider(client, "hls"); err == nil {
+		t.Error("无效的密钥资源名应返回错误")
+	}
+}
@@ -0,0 +1,128 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"fmt"
+)
+
+// GCSClient Google Cloud Storage 客户端，由调用方基于 cloud.google.com/go/storage 等实现
+// 对象不存在时 Download 与 Delete 应返回包装了 ErrKeyNotFound 的错误
+type GCSClient interface {
+	Upload(ctx context.Context, in GCSUploadInput) error
+	Download(ctx context.Context, bucket, object string) ([]byte, error)
+	Delete(ctx context.Context, bucket, object string) error
+	// List 返回前缀下的全部对象名，需自行处理分页
+	List(ctx context.Context, bucket, prefix string) ([]string, error)
+}
+
+// GCSUploadInput 上传对象参数
+type GCSUploadInput struct {
+	Bucket      string
+	Object      string
+	Data        []byte
+	ContentType string
+	KMSKeyName  string // CMEK 密钥资源名，为空时使用存储桶默认加密
+}
+
+// GCSStore 基于 Google Cloud Storage 的密钥存储，对象布局与 S3Store 一致
+type GCSStore struct {
+	client     GCSClient
+	bucket     string
+	prefix     string
+	kmsKeyName string
+}
+
+var _ KeyStore = &GCSStore{}
+
+// GCSOption GCS 密钥存储选项
+type GCSOption func(*GCSStore)
+
+// WithGCSPrefix 设置对象名前缀，如 "hls/keys/"
+func WithGCSPrefix(prefix string) GCSOption {
+	return func(s *GCSStore) {
+		s.prefix = prefix
+	}
+}
+
+// WithCMEK 使用客户管理的加密密钥加密密钥对象，
+// 如 projects/p/locations/global/keyRings/r/cryptoKeys/k
+func WithCMEK(kmsKeyName string) GCSOption {
+	return func(s *GCSStore) {
+		s.kmsKeyName = kmsKeyName
+	}
+}
+
+// NewGCSStore 创建基于 GCS 的密钥存储
+func NewGCSStore(client GCSClient, bucket string, opts ...GCSOption) (*GCSStore, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("存储桶名称不能为空")
+	}
+	s := &GCSStore{client: client, bucket: bucket}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Put 实现 KeyStore 接口
+func (s *GCSStore) Put(ctx context.Context, rec KeyRecord) error {
+	name, err := objectName(s.prefix, rec.KeyID)
+	if err != nil {
+		return err
+	}
+	data, err := encodeRecord(rec)
+	if err != nil {
+		return err
+	}
+	err = s.client.Upload(ctx, GCSUploadInput{
+		Bucket:      s.bucket,
+		Object:      name,
+		Data:        data,
+		ContentType: "application/json",
+		KMSKeyName:  s.kmsKeyName,
+	})
+	if err != nil {
+		return fmt.Errorf("上传密钥对象失败: %w", err)
+	}
+	return nil
+}
+
+// Get 实现 KeyStore 接口
+func (s *GCSStore) Get(ctx context.Context, keyID string) (KeyRecord, error) {
+	name, err := objectName(s.prefix, keyID)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	data, err := s.client.Download(ctx, s.bucket, name)
+	if err != nil {
+		return KeyRecord{}, fmt.Errorf("下载密钥对象失败: %w", err)
+	}
+	return decodeRecord(data)
+}
+
+// Delete 实现 KeyStore 接口
+func (s *GCSStore) Delete(ctx context.Context, keyID string) error {
+	name, err := objectName(s.prefix, keyID)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Delete(ctx, s.bucket, name); err != nil {
+		return fmt.Errorf("删除密钥对象失败: %w", err)
+	}
+	return nil
+}
+
+// List 实现 KeyStore 接口
+func (s *GCSStore) List(ctx context.Context, stream string) ([]KeyRecord, error) {
+	names, err := s.client.List(ctx, s.bucket, s.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("列出密钥对象失败: %w", err)
+	}
+	return listObjectRecords(ctx, names, stream, func(ctx context.Context, name string) ([]byte, error) {
+		data, err := s.client.Download(ctx, s.bucket, name)
+		if err != nil {
+			return nil, fmt.Errorf("下载密钥对象失败: %w", err)
+		}
+		return data, nil
+	})
+}
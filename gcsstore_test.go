@@ -0,0 +1,51 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// fakeGCS 模拟 GCSClient
+type fakeGCS struct {
+	*fakeObjects
+	uploads []GCSUploadInput
+}
+
+func (f *fakeGCS) Upload(ctx context.Context, in GCSUploadInput) error {
+	f.put(in.Bucket+"/"+in.Object, in.Data)
+	f.mu.Lock()
+	f.uploads = append(f.uploads, in)
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeGCS) Download(ctx context.Context, bucket, object string) ([]byte, error) {
+	return f.get(bucket + "/" + object)
+}
+
+func (f *fakeGCS) Delete(ctx context.Context, bucket, object string) error {
+	return f.delete(bucket + "/" + object)
+}
+
+func (f *fakeGCS) List(ctx context.Context, bucket, prefix string) ([]string, error) {
+	var names []string
+	for _, name := range f.list(bucket + "/" + prefix) {
+		names = append(names, strings.TrimPrefix(name, bucket+"/"))
+	}
+	return names, nil
+}
+
+func TestGCSStore(t *testing.T) {
+	const kmsKey = "projects/p/locations/global/keyRings/r/cryptoKeys/hls"
+	client := &fakeGCS{fakeObjects: newFakeObjects()}
+	store, err := NewGCSStore(client, "media-keys", WithGCSPrefix("hls/"), WithCMEK(kmsKey))
+	if err != nil {
+		t.Fatalf("创建 GCSStore 失败: %v", err)
+	}
+	testKeyStore(t, store)
+
+	if in := client.uploads[0]; in.Object != "hls/key-a.json" || in.KMSKeyName != kmsKey {
+		t.Errorf("上传参数不正确: %+v", in)
+	}
+}
@@ -0,0 +1,197 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// RotationGroup 轮换组，在同一时刻为一组轮换器（如同一频道的多个码率）切换到同一个新密钥
+// 轮换要么全部成功，要么全部保持旧密钥，避免不同码率在同一窗口内使用不同密钥
+// 各成员仍使用各自的密钥获取URL、密钥文件与 keyinfo 文件，成员自身的自动轮换不应再启动
+type RotationGroup struct {
+	mu       sync.Mutex
+	members  []*Rotator
+	schedule Schedule
+	onError  func(error)
+
+	runMu  sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewRotationGroup 创建轮换组，schedule 为 nil 时只能手动轮换
+// 所有成员的密钥长度需一致
+func NewRotationGroup(schedule Schedule, members ...*Rotator) (*RotationGroup, error) {
+	if len(members) == 0 {
+		return nil, fmt.Errorf("轮换组至少需要一个成员")
+	}
+	size := members[0].Current().keySize
+	seen := make(map[*Rotator]bool, len(members))
+	for _, m := range members {
+		if seen[m] {
+			return nil, fmt.Errorf("轮换组成员重复")
+		}
+		seen[m] = true
+		if m.Current().keySize != size {
+			return nil, fmt.Errorf("轮换组成员的密钥长度不一致: %d 与 %d", size, m.Current().keySize)
+		}
+	}
+	return &RotationGroup{
+		members:  members,
+		schedule: schedule,
+	}, nil
+}
+
+// OnError 设置后台自动轮换失败时的回调
+func (g *RotationGroup) OnError(fn func(error)) *RotationGroup {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onError = fn
+	return g
+}
+
+// Members 返回轮换组成员
+func (g *RotationGroup) Members() []*Rotator {
+	out := make([]*Rotator, len(g.members))
+	copy(out, g.members)
+	return out
+}
+
+// Rotate 为所有成员生成同一个新密钥并重写各自的 keyinfo 文件
+// 第一个成员设置了 WithKeyProvider 时由其生成密钥，封装后的密钥同样记录到每个成员的新密钥
+// 任一成员失败时已重写的 keyinfo 文件恢复为旧密钥，所有成员保持不变
+func (g *RotationGroup) Rotate() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// 在锁外调用 KeyProvider，避免网络请求阻塞成员的 LookupKey
+	key, wrapped, err := g.members[0].generateKey()
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		first := g.members[0].Current()
+		key = make([]byte, first.keySize)
+		if _, err := io.ReadFull(first.rand(), key); err != nil {
+			return fmt.Errorf("生成密钥失败: %w", err)
+		}
+	}
+
+	for _, m := range g.members {
+		m.mu.Lock()
+	}
+	unlock := func() {
+		for _, m := range g.members {
+			m.mu.Unlock()
+		}
+	}
+	for _, m := range g.members {
+		if m.disposed {
+			unlock()
+			return fmt.Errorf("轮换组轮换失败: %w", ErrRotatorDisposed)
+		}
+	}
+
+	nexts := make([]*KeyInfo, 0, len(g.members))
+	for _, m := range g.members {
+		next, err := m.prepare(key)
+		if err != nil {
+			// 回滚已切换的成员
+			for i, n := range nexts {
+				done := g.members[i]
+				if rerr := done.current.writeInfoFile(done.infoFile); rerr != nil {
+					err = fmt.Errorf("%w; 恢复 keyinfo 文件失败: %v", err, rerr)
+				}
+				n.Dispose()
+			}
+			unlock()
+			return fmt.Errorf("轮换组轮换失败: %w", err)
+		}
+		next.wrappedKey = wrapped
+		nexts = append(nexts, next)
+	}
+
+	olds := make([]*KeyInfo, len(g.members))
+	retired := make([][]*KeyInfo, len(g.members))
+	for i, m := range g.members {
+		olds[i], retired[i] = m.swap(nexts[i])
+	}
+	unlock()
+
+	for i, m := range g.members {
+		m.finish(olds[i], nexts[i], retired[i])
+	}
+	return nil
+}
+
+// Start 启动后台按轮换计划自动轮换，ctx 取消或调用 Stop 时停止；重复调用无效
+func (g *RotationGroup) Start(ctx context.Context) {
+	g.runMu.Lock()
+	defer g.runMu.Unlock()
+	if g.cancel != nil || g.schedule == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	g.cancel = cancel
+	g.done = make(chan struct{})
+	go g.run(ctx, g.done)
+}
+
+// run 按轮换计划执行轮换
+func (g *RotationGroup) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	for {
+		next := g.schedule.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			if err := g.Rotate(); err != nil {
+				g.mu.Lock()
+				onError := g.onError
+				g.mu.Unlock()
+				if onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}
+}
+
+// Stop 停止后台自动轮换并等待其退出
+func (g *RotationGroup) Stop() {
+	g.runMu.Lock()
+	defer g.runMu.Unlock()
+	if g.cancel == nil {
+		return
+	}
+	g.cancel()
+	<-g.done
+	g.cancel = nil
+	g.done = nil
+}
+
+// Dispose 停止轮换并清理所有成员
+func (g *RotationGroup) Dispose() error {
+	g.Stop()
+
+	var errs []error
+	for _, m := range g.members {
+		if err := m.Dispose(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("清理轮换组时发生错误: %v", errs)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotationGroup(t *testing.T) {
+	r1 := newTestRotator(t, 0)
+	r2 := newTestRotator(t, 0)
+	g, err := NewRotationGroup(nil, r1, r2)
+	if err != nil {
+		t.Fatalf("创建 RotationGroup 失败: %v", err)
+	}
+
+	old1, old2 := r1.Current(), r2.Current()
+	if err := g.Rotate(); err != nil {
+		t.Fatalf("轮换组轮换失败: %v", err)
+	}
+	k1, k2 := r1.Current(), r2.Current()
+	if k1 == old1 || k2 == old2 {
+		t.Fatal("所有成员都应轮换")
+	}
+	if !bytes.Equal(k1.GetKey(), k2.GetKey()) || k1.KeyID != k2.KeyID {
+		t.Error("轮换组成员应使用同一个新密钥")
+	}
+	if k1.KeyFile == k2.KeyFile {
+		t.Error("轮换组成员应使用各自的密钥文件")
+	}
+	if lines := readInfoLines(t, r2.InfoFile()); lines[1] != k2.KeyFile {
+		t.Errorf("keyinfo 文件应指向新密钥文件，实际: %s", lines[1])
+	}
+
+	// 任一成员失败时全部回滚
+	os.Remove(r2.InfoFile())
+	if err := os.Mkdir(r2.InfoFile(), 0o700); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	defer os.Remove(r2.InfoFile())
+	if err := g.Rotate(); err == nil {
+		t.Fatal("成员写入失败时应返回错误")
+	}
+	if r1.Current() != k1 || r2.Current() != k2 {
+		t.Error("轮换失败时成员应保持旧密钥")
+	}
+	if lines := readInfoLines(t, r1.InfoFile()); lines[1] != k1.KeyFile {
+		t.Errorf("轮换失败时 keyinfo 文件应恢复为旧密钥，实际: %s", lines[1])
+	}
+
+	if _, err := NewRotationGroup(nil, r1, r1); err == nil {
+		t.Error("重复的成员应返回错误")
+	}
+}
+
+func TestRotationGroupRotateAfterDispose(t *testing.T) {
+	r1 := newTestRotator(t, 0)
+	r2 := newTestRotator(t, 0)
+	g, err := NewRotationGroup(nil, r1, r2)
+	if err != nil {
+		t.Fatalf("创建 RotationGroup 失败: %v", err)
+	}
+	k1 := r1.Current()
+	dir := filepath.Dir(r2.Current().KeyFile)
+	if err := r2.Dispose(); err != nil {
+		t.Fatalf("Dispose 失败: %v", err)
+	}
+
+	if err := g.Rotate(); !errors.Is(err, ErrRotatorDisposed) {
+		t.Errorf("成员已清理时轮换应返回 ErrRotatorDisposed，实际: %v", err)
+	}
+	if r1.Current() != k1 {
+		t.Error("成员已清理时其他成员应保持旧密钥")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("已清理的成员不应写入文件，实际: %v", entries)
+	}
+}
+
+func TestRotationGroupKeyProvider(t *testing.T) {
+	p := newFakeProvider()
+	r1 := newTestRotator(t, 0, WithKeyProvider(p))
+	r2 := newTestRotator(t, 0, WithKeyProvider(p))
+	g, err := NewRotationGroup(nil, r1, r2)
+	if err != nil {
+		t.Fatalf("创建 RotationGroup 失败: %v", err)
+	}
+	if err := g.Rotate(); err != nil {
+		t.Fatalf("轮换组轮换失败: %v", err)
+	}
+
+	// 新密钥由 KeyProvider 生成，每个成员都记录封装后的密钥
+	for i, r := range []*Rotator{r1, r2} {
+		k := r.Current()
+		if len(k.WrappedKey()) == 0 {
+			t.Fatalf("成员 %d 的新密钥应带有封装后的密钥", i)
+		}
+		key, err := p.Decrypt(context.Background(), k.WrappedKey())
+		if err != nil || !bytes.Equal(key, k.GetKey()) {
+			t.Errorf("成员 %d 的封装密钥应能解封为新密钥: %v", i, err)
+		}
+	}
+}
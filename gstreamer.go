@@ -0,0 +1,106 @@
+package hlskeyinfo
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultGStreamerSegmentPattern GStreamer HLS sink 的默认分片文件名模板
+const DefaultGStreamerSegmentPattern = "segment%05d.ts"
+
+// GStreamerOptions GStreamer HLS sink（hlssink2、hlssink3）的输出设置
+type GStreamerOptions struct {
+	Element        string        // hlssink2（默认）或 hlssink3
+	Playlist       string        // 播放列表路径，写入 playlist-location
+	SegmentPattern string        // 分片文件名模板，与播放列表位于同一目录，为空时使用 DefaultGStreamerSegmentPattern
+	TargetDuration time.Duration // target-duration，按秒向上取整，为 0 时使用元素默认值
+	PlaylistLength int           // playlist-length，0 表示保留全部分片，负数表示使用元素默认值
+}
+
+// GStreamerProperty GStreamer 元素属性
+type GStreamerProperty struct {
+	Name  string
+	Value string
+}
+
+// GStreamerSink GStreamer HLS sink 的元素属性与对应的密钥布局
+// hlssink2 与 hlssink3 没有密钥相关属性，输出的分片为明文；输出结束后以 EncryptVOD 加密播放列表，
+// 即写入 KeyFile 中的密钥并在播放列表中插入 ExtXKey 标签
+type GStreamerSink struct {
+	Element    string
+	Properties []GStreamerProperty
+	Playlist   string // 播放列表路径
+	KeyURI     string // 密钥获取URL
+	KeyFile    string // 密钥文件路径
+	IV         string // 显式 IV，无 IV 与序列号 IV 模式下为空
+	ExtXKey    string // EncryptVOD 插入播放列表的 EXT-X-KEY 标签
+}
+
+// GStreamerSink 返回使用该密钥的 GStreamer HLS sink 配置，不删除分片（max-files=0），以便输出结束后由 EncryptVOD 加密
+// 分片与播放列表位于同一目录，sink 在播放列表中只写入分片文件名
+func (k *KeyInfo) GStreamerSink(opts GStreamerOptions) (*GStreamerSink, error) {
+	element := opts.Element
+	if element == "" {
+		element = "hlssink2"
+	}
+	if element != "hlssink2" && element != "hlssink3" {
+		return nil, fmt.Errorf("不支持的 GStreamer 元素: %s", element)
+	}
+	if opts.Playlist == "" {
+		return nil, fmt.Errorf("播放列表路径不能为空")
+	}
+	pattern := opts.SegmentPattern
+	if pattern == "" {
+		pattern = DefaultGStreamerSegmentPattern
+	}
+	if strings.ContainsAny(pattern, `/\`) {
+		return nil, fmt.Errorf("分片文件名模板不能包含目录: %s", pattern)
+	}
+	if strings.Count(pattern, "%") != 1 || strings.Contains(fmt.Sprintf(pattern, 0), "%!") {
+		return nil, fmt.Errorf("分片文件名模板需包含一个 %%d 或 %%0Nd: %s", pattern)
+	}
+
+	s := &GStreamerSink{
+		Element:  element,
+		Playlist: opts.Playlist,
+		KeyURI:   k.KeyURL(),
+		KeyFile:  k.KeyFile,
+		ExtXKey:  k.ExtXKey(),
+	}
+	if k.HasIV() {
+		s.IV = k.formatIV()
+	}
+	s.set("location", filepath.Join(filepath.Dir(opts.Playlist), pattern))
+	s.set("playlist-location", opts.Playlist)
+	if opts.TargetDuration > 0 {
+		s.set("target-duration", strconv.Itoa(int(math.Ceil(opts.TargetDuration.Seconds()))))
+	}
+	if opts.PlaylistLength >= 0 {
+		s.set("playlist-length", strconv.Itoa(opts.PlaylistLength))
+	}
+	s.set("max-files", "0")
+	return s, nil
+}
+
+// set 添加元素属性
+func (s *GStreamerSink) set(name, value string) {
+	s.Properties = append(s.Properties, GStreamerProperty{Name: name, Value: value})
+}
+
+// Args 返回 gst-launch-1.0 中该元素的参数，如 ["hlssink2", "location=...", ...]，可追加在管道描述之后
+func (s *GStreamerSink) Args() []string {
+	args := []string{s.Element}
+	for _, p := range s.Properties {
+		args = append(args, p.Name+"="+p.Value)
+	}
+	return args
+}
+
+// String 实现 fmt.Stringer 接口，返回加引号后的元素描述，可复制到终端执行的 gst-launch-1.0 命令中
+func (s *GStreamerSink) String() string {
+	return CommandLine(s.Args()...)
+}
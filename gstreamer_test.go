@@ -0,0 +1,73 @@
+package hlskeyinfo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGStreamerSink(t *testing.T) {
+	dir := t.TempDir()
+	k, err := NewKeyInfo("https://example.com/key", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建KeyInfo失败: %v", err)
+	}
+	defer k.Dispose()
+	k.RandIV()
+
+	playlist := filepath.Join(dir, "index.m3u8")
+	s, err := k.GStreamerSink(GStreamerOptions{Playlist: playlist, TargetDuration: 3500 * time.Millisecond, PlaylistLength: 0})
+	if err != nil {
+		t.Fatalf("生成 GStreamer 配置失败: %v", err)
+	}
+	want := []string{
+		"hlssink2",
+		"location=" + filepath.Join(dir, DefaultGStreamerSegmentPattern),
+		"playlist-location=" + playlist,
+		"target-duration=4",
+		"playlist-length=0",
+		"max-files=0",
+	}
+	if !slices.Equal(s.Args(), want) {
+		t.Errorf("元素参数不正确: %q", s.Args())
+	}
+	if s.KeyURI != k.KeyURL() || s.KeyFile != k.KeyFile || s.IV != k.IV || s.ExtXKey != k.ExtXKey() {
+		t.Errorf("密钥布局不正确: %+v", s)
+	}
+
+	// 模拟 hlssink2 的输出：播放列表只写入分片文件名，结束后由 EncryptVOD 加密
+	content := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-TARGETDURATION:4\n"
+	for i := 0; i < 2; i++ {
+		name := fmt.Sprintf(DefaultGStreamerSegmentPattern, i)
+		os.WriteFile(filepath.Join(dir, name), []byte("segment"), 0o644)
+		content += "#EXTINF:3.5,\n" + name + "\n"
+	}
+	os.WriteFile(playlist, []byte(content+"#EXT-X-ENDLIST\n"), 0o644)
+	if err := k.EncryptVOD(s.Playlist); err != nil {
+		t.Fatalf("加密 GStreamer 输出失败: %v", err)
+	}
+	if got, _ := os.ReadFile(playlist); !strings.Contains(string(got), s.ExtXKey+"\n") {
+		t.Errorf("播放列表应包含 EXT-X-KEY 标签:\n%s", got)
+	}
+
+	s, err = k.GStreamerSink(GStreamerOptions{Element: "hlssink3", Playlist: playlist, SegmentPattern: "seg_%d.ts", PlaylistLength: -1})
+	if err != nil || s.Args()[0] != "hlssink3" || slices.ContainsFunc(s.Args(), func(a string) bool { return strings.HasPrefix(a, "playlist-length=") }) {
+		t.Errorf("hlssink3 参数不正确: %q, %v", s.Args(), err)
+	}
+
+	for _, opts := range []GStreamerOptions{
+		{},
+		{Playlist: playlist, Element: "hlssink"},
+		{Playlist: playlist, SegmentPattern: "seg/%d.ts"},
+		{Playlist: playlist, SegmentPattern: "seg.ts"},
+		{Playlist: playlist, SegmentPattern: "seg_%s.ts"},
+	} {
+		if _, err := k.GStreamerSink(opts); err == nil {
+			t.Errorf("无效设置应返回错误: %+v", opts)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package hlskeyinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// KeyRecord 密钥历史记录
+type KeyRecord struct {
+	KeyID      string    `json:"key_id"`
+	KID        string    `json:"kid,omitempty"` // 16 字节 KID 的 UUID 形式，DRM 系统按其查找密钥
+	Stream     string    `json:"stream,omitempty"`
+	URL        string    `json:"url"`
+	Key        []byte    `json:"key"`                   // Base64 编码
+	WrappedKey []byte    `json:"wrapped_key,omitempty"` // 由 KeyProvider 封装的密钥，Base64 编码
+	IV         string    `json:"iv,omitempty"`          // 为空时使用媒体序列号作为 IV
+	Version    int       `json:"version"`
+	NotBefore  time.Time `json:"not_before"`         // 开始使用时间
+	NotAfter   time.Time `json:"not_after,omitzero"` // 停止使用时间，仍在使用时为零值
+}
+
+// Active 返回该密钥在 t 时刻是否处于使用期内
+func (r KeyRecord) Active(t time.Time) bool {
+	return !t.Before(r.NotBefore) && (r.NotAfter.IsZero() || t.Before(r.NotAfter))
+}
+
+// KeyHistory 按 KeyID 记录密钥及其有效期，供密钥服务应答仍引用旧密钥的分片请求
+// 指定文件路径时每次变更都以 0600 权限原子地持久化，文件中包含明文密钥
+type KeyHistory struct {
+	mu      sync.RWMutex
+	records map[string]*KeyRecord
+	order   []string // 按记录时间排序的 KeyID
+	path    string
+}
+
+// NewKeyHistory 创建密钥历史，path 为空时仅保存在内存中；path 对应的文件已存在时加载其中的记录
+func NewKeyHistory(path string) (*KeyHistory, error) {
+	h := &KeyHistory{
+		records: make(map[string]*KeyRecord),
+		path:    path,
+	}
+	if path == "" {
+		return h, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥历史文件失败: %w", err)
+	}
+	var records []*KeyRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("解析密钥历史文件失败: %w", err)
+	}
+	for _, rec := range records {
+		if _, ok := h.records[rec.KeyID]; !ok {
+			h.order = append(h.order, rec.KeyID)
+		}
+		h.records[rec.KeyID] = rec
+	}
+	return h, nil
+}
+
+// Add 记录密钥自 notBefore 起开始使用，KeyID 已存在时覆盖原记录
+func (h *KeyHistory) Add(k *KeyInfo, notBefore time.Time) error {
+	if k.key == nil {
+		return fmt.Errorf("密钥未初始化")
+	}
+	rec := k.record(notBefore)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.records[rec.KeyID]; !ok {
+		h.order = append(h.order, rec.KeyID)
+	}
+	h.records[rec.KeyID] = rec
+	return h.save()
+}
+
+// record 生成密钥记录
+func (k *KeyInfo) record(notBefore time.Time) *KeyRecord {
+	rec := &KeyRecord{
+		KeyID:      k.KeyID,
+		KID:        formatUUID(k.KID()),
+		Stream:     k.Stream,
+		URL:        k.KeyURL(),
+		Key:        slices.Clone(k.key),
+		WrappedKey: slices.Clone(k.wrappedKey),
+		Version:    k.Version,
+		NotBefore:  notBefore,
+	}
+	if k.HasIV() {
+		rec.IV = k.IV
+	}
+	return rec
+}
+
+// Retire 记录密钥自 notAfter 起停止使用
+func (h *KeyHistory) Retire(keyID string, notAfter time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	rec, ok := h.records[keyID]
+	if !ok {
+		return fmt.Errorf("密钥不存在: %s", keyID)
+	}
+	rec.NotAfter = notAfter
+	return h.save()
+}
+
+// Lookup 按 KeyID 查找密钥记录
+func (h *KeyHistory) Lookup(keyID string) (KeyRecord, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	rec, ok := h.records[keyID]
+	if !ok {
+		return KeyRecord{}, false
+	}
+	out := *rec
+	out.Key = slices.Clone(rec.Key)
+	return out, true
+}
+
+// LookupKey 按 KeyID 查找密钥
+func (h *KeyHistory) LookupKey(keyID string) ([]byte, bool) {
+	rec, ok := h.Lookup(keyID)
+	return rec.Key, ok
+}
+
+// Records 返回全部记录，按记录时间排序
+func (h *KeyHistory) Records() []KeyRecord {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]KeyRecord, 0, len(h.order))
+	for _, id := range h.order {
+		rec := *h.records[id]
+		rec.Key = slices.Clone(rec.Key)
+		out = append(out, rec)
+	}
+	return out
+}
+
+// Prune 删除在 before 之前已停止使用的记录，返回删除的数量
+func (h *KeyHistory) Prune(before time.Time) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	h.order = slices.DeleteFunc(h.order, func(id string) bool {
+		rec := h.records[id]
+		if rec.NotAfter.IsZero() || !rec.NotAfter.Before(before) {
+			return false
+		}
+		delete(h.records, id)
+		n++
+		return true
+	})
+	if n == 0 {
+		return 0, nil
+	}
+	return n, h.save()
+}
+
+// save 持久化历史记录，调用方需持有写锁
+func (h *KeyHistory) save() error {
+	if h.path == "" {
+		return nil
+	}
+	records := make([]*KeyRecord, 0, len(h.order))
+	for _, id := range h.order {
+		records = append(records, h.records[id])
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化密钥历史失败: %w", err)
+	}
+	if err := writeFileAtomic(h.path, data, 0o600); err != nil {
+		return fmt.Errorf("写入密钥历史文件失败: %w", err)
+	}
+	return nil
+}
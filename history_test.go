@@ -0,0 +1,64 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	h, err := NewKeyHistory(path)
+	if err != nil {
+		t.Fatalf("创建 KeyHistory 失败: %v", err)
+	}
+
+	r := newTestRotator(t, 0, WithKeepPrevious(0), WithKeyHistory(h))
+	first := r.Current()
+	firstKey := first.GetKey()
+	if _, err := r.Rotate(); err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+
+	// 退役密钥已被清理，仍可从历史中查找
+	key, ok := r.LookupKey(first.KeyID)
+	if !ok || !bytes.Equal(key, firstKey) {
+		t.Error("应能从历史中查找已退役的密钥")
+	}
+	if _, ok := r.LookupKey("unknown"); ok {
+		t.Error("未知 KeyID 不应查找到密钥")
+	}
+
+	rec, _ := h.Lookup(first.KeyID)
+	if rec.NotAfter.IsZero() || rec.Active(time.Now()) || rec.IV != first.IV {
+		t.Errorf("退役密钥的记录不正确: %+v", rec)
+	}
+	if rec, _ := h.Lookup(r.Current().KeyID); !rec.Active(time.Now()) || rec.Version != 2 {
+		t.Errorf("当前密钥的记录不正确: %+v", rec)
+	}
+
+	// 持久化后可重新加载
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("密钥历史文件不存在: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("期望密钥历史文件权限为 0600，实际: %o", info.Mode().Perm())
+	}
+	loaded, err := NewKeyHistory(path)
+	if err != nil {
+		t.Fatalf("加载 KeyHistory 失败: %v", err)
+	}
+	if key, ok := loaded.LookupKey(first.KeyID); !ok || !bytes.Equal(key, firstKey) {
+		t.Error("重新加载后应能查找到密钥")
+	}
+	if len(loaded.Records()) != 2 {
+		t.Errorf("期望 2 条记录，实际: %d", len(loaded.Records()))
+	}
+
+	if n, err := loaded.Prune(time.Now().Add(time.Second)); err != nil || n != 1 {
+		t.Errorf("应删除 1 条已停用的记录，实际: %d, %v", n, err)
+	}
+}
@@ -0,0 +1,40 @@
+package hlskeyinfo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestIPFilter(t *testing.T) {
+	f, err := NewIPFilter([]string{"10.0.0.0/8", "192.168.1.10"}, []string{"10.0.5.0/24"})
+	if err != nil {
+		t.Fatalf("创建 IPFilter 失败: %v", err)
+	}
+	for addr, want := range map[string]bool{
+		"10.1.2.3":        true,
+		"192.168.1.10":    true,
+		"::ffff:10.1.2.3": true,
+		"10.0.5.7":        false,
+		"192.168.1.11":    false,
+		"2001:db8::1":     false,
+	} {
+		if got := f.Allowed(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("%s 期望 %v，实际: %v", addr, want, got)
+		}
+	}
+
+	handler := f.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/key", nil)
+	req.RemoteAddr = "10.0.5.7:53211"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("被拒绝的地址应返回 403，实际: %d", w.Code)
+	}
+
+	if _, err := NewIPFilter([]string{"10.0.0.0/33"}, nil); err == nil {
+		t.Error("无效的 CIDR 应返回错误")
+	}
+}
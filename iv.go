@@ -0,0 +1,191 @@
+package hlskeyinfo
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ivMode keyinfo 文件的 IV 写入模式
+type ivMode int
+
+const (
+	ivExplicit ivMode = iota // 写入显式设置的 IV
+	ivNone                   // 不写入 IV 行
+	ivSequence               // 不写入 IV 行，并按媒体序列号跟踪分片 IV
+)
+
+// IVError IV 格式错误
+type IVError struct {
+	IV     string // 原始 IV
+	Reason string // 错误原因
+}
+
+func (e *IVError) Error() string {
+	return fmt.Sprintf("IV 格式无效 %q: %s", e.IV, e.Reason)
+}
+
+// WithIVPrefix 设置 keyinfo 文件中的 IV 是否带 0x 前缀，默认不带
+// ffmpeg 读取的 IV 不应带前缀，仅在下游工具要求 0x 格式时启用
+func WithIVPrefix(enable bool) Option {
+	return func(k *KeyInfo) {
+		k.ivPrefix = enable
+	}
+}
+
+// normalizeIV 去除 IV 的 0x/0X 前缀
+func normalizeIV(iv string) string {
+	if len(iv) >= 2 && iv[0] == '0' && (iv[1] == 'x' || iv[1] == 'X') {
+		return iv[2:]
+	}
+	return iv
+}
+
+// formatIV 按配置格式化写入 keyinfo 文件的 IV
+func (k *KeyInfo) formatIV() string {
+	if k.ivPrefix {
+		return "0x" + k.IV
+	}
+	return k.IV
+}
+
+// ValidateIV 校验 IV 是否为 32 位十六进制字符串（可带 0x 前缀），不合法时返回 *IVError
+func ValidateIV(iv string) error {
+	s := normalizeIV(iv)
+	if len(s) != 32 {
+		return &IVError{IV: iv, Reason: fmt.Sprintf("长度必须为 32 个字符，实际: %d", len(s))}
+	}
+	if _, err := hex.DecodeString(s); err != nil {
+		return &IVError{IV: iv, Reason: "包含非十六进制字符"}
+	}
+	return nil
+}
+
+// SetIVStrict 校验后设置初始化向量，IV 不是 32 位十六进制字符串时返回 *IVError 且不修改当前 IV
+func (k *KeyInfo) SetIVStrict(iv string) error {
+	if err := ValidateIV(iv); err != nil {
+		return err
+	}
+	k.SetIV(iv)
+	return nil
+}
+
+// SequenceIV 按 HLS 规范由媒体序列号计算 IV：序列号以 128 位大端整数表示
+func SequenceIV(seq uint64) [16]byte {
+	var iv [16]byte
+	binary.BigEndian.PutUint64(iv[8:], seq)
+	return iv
+}
+
+// UseSequenceIV 启用媒体序列号 IV 模式，keyinfo 文件省略 IV 行，每个分片的 IV 由其媒体序列号决定
+func (k *KeyInfo) UseSequenceIV() *KeyInfo {
+	k.ivMode = ivSequence
+	k.IV = ""
+	return k
+}
+
+// NoIV 启用无 IV 模式，keyinfo 文件只包含密钥获取URL与密钥文件路径两行，由 ffmpeg 自行计算 IV
+func (k *KeyInfo) NoIV() *KeyInfo {
+	k.ivMode = ivNone
+	k.IV = ""
+	return k
+}
+
+// HasIV 返回 keyinfo 文件是否会写入 IV 行
+// 无 IV 与序列号 IV 模式下，以及 IV 为空或只含空白字符时均不写入，避免产生空的 IV 行
+func (k *KeyInfo) HasIV() bool {
+	return k.ivMode == ivExplicit && strings.TrimSpace(k.IV) != ""
+}
+
+// SetSequence 设置当前分片的媒体序列号
+func (k *KeyInfo) SetSequence(seq uint64) *KeyInfo {
+	k.sequence = seq
+	return k
+}
+
+// Sequence 返回当前分片的媒体序列号
+func (k *KeyInfo) Sequence() uint64 {
+	return k.sequence
+}
+
+// NextSegment 前进到下一个分片，返回新的媒体序列号
+func (k *KeyInfo) NextSegment() uint64 {
+	k.sequence++
+	return k.sequence
+}
+
+// SegmentIV 返回指定媒体序列号分片使用的 IV
+// 设置了显式 IV 时所有分片共用该 IV，否则按媒体序列号计算
+func (k *KeyInfo) SegmentIV(seq uint64) ([]byte, error) {
+	if !k.HasIV() {
+		iv := SequenceIV(seq)
+		return iv[:], nil
+	}
+	if err := ValidateIV(k.IV); err != nil {
+		return nil, err
+	}
+	iv, _ := hex.DecodeString(k.IV)
+	return iv, nil
+}
+
+// CurrentIV 返回当前分片使用的 IV
+func (k *KeyInfo) CurrentIV() ([]byte, error) {
+	return k.SegmentIV(k.sequence)
+}
+
+// SetIVBytes 使用 16 字节原始 IV 设置初始化向量，转换为 32 位十六进制字符串
+func (k *KeyInfo) SetIVBytes(iv [16]byte) *KeyInfo {
+	return k.SetIV(hex.EncodeToString(iv[:]))
+}
+
+// SetIVRaw 使用原始字节设置初始化向量，长度必须为 16 字节
+func (k *KeyInfo) SetIVRaw(iv []byte) error {
+	if len(iv) != 16 {
+		return fmt.Errorf("IV 长度必须为 16 字节，实际: %d", len(iv))
+	}
+	k.SetIVBytes([16]byte(iv))
+	return nil
+}
+
+// DeriveIV 由密钥确定性地计算 IV（SHA-256 截取前 16 字节），重启任务时无需单独保存 IV
+// 密钥未初始化时不做修改
+func (k *KeyInfo) DeriveIV() *KeyInfo {
+	if k.key == nil {
+		return k
+	}
+	sum := sha256.Sum256(append([]byte("hls_keyinfo/iv\x00"), k.key...))
+	return k.SetIVBytes([16]byte(sum[:16]))
+}
+
+// OnIVRotate 注册 IV 轮换回调，每次 RotateIV 成功后以旧 IV 与新 IV 调用
+func (k *KeyInfo) OnIVRotate(fn func(oldIV, newIV string)) *KeyInfo {
+	k.onIVRotate = fn
+	return k
+}
+
+// RotateIV 生成新的随机 IV，若已生成 keyinfo 文件则原子地重写该文件（命名管道同步更新），并触发 IV 轮换回调
+// 适用于长时间直播在两次密钥轮换之间单独轮换 IV
+func (k *KeyInfo) RotateIV() error {
+	oldIV, oldMode := k.IV, k.ivMode
+	if err := k.RandIVErr(); err != nil {
+		return err
+	}
+
+	if k.infoFile != "" {
+		if err := k.writeInfoFile(k.infoFile); err != nil {
+			k.IV, k.ivMode = oldIV, oldMode
+			return err
+		}
+	}
+	if err := k.refreshFIFO(); err != nil {
+		k.IV, k.ivMode = oldIV, oldMode
+		return err
+	}
+
+	if k.onIVRotate != nil {
+		k.onIVRotate(oldIV, k.IV)
+	}
+	return nil
+}
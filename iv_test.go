@@ -0,0 +1,249 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSequenceIV(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo")
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	k.SetIV("12345678901234567890123456789012").UseSequenceIV().SetSequence(41)
+	if k.NextSegment() != 42 {
+		t.Errorf("期望媒体序列号为 42，实际: %d", k.Sequence())
+	}
+
+	iv, err := k.CurrentIV()
+	if err != nil {
+		t.Fatalf("CurrentIV 失败: %v", err)
+	}
+	if hex.EncodeToString(iv) != "0000000000000000000000000000002a" {
+		t.Errorf("序列号 IV 不匹配，实际: %x", iv)
+	}
+
+	// 序列号 IV 模式下 keyinfo 只有两行
+	var buf bytes.Buffer
+	if _, err := k.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo 失败: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 {
+		t.Errorf("期望输出 2 行，实际: %d", len(lines))
+	}
+}
+
+func TestSegmentIVExplicit(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo")
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	k.SetIV("abcdef1234567890abcdef1234567890")
+	iv, err := k.SegmentIV(7)
+	if err != nil {
+		t.Fatalf("SegmentIV 失败: %v", err)
+	}
+	if hex.EncodeToString(iv) != k.IV {
+		t.Errorf("显式 IV 应用于所有分片，实际: %x", iv)
+	}
+
+	k.SetIV("not-hex")
+	if _, err := k.SegmentIV(7); err == nil {
+		t.Error("非法 IV 应返回错误")
+	}
+}
+
+func TestSetIVBytes(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo")
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	k.SetIVBytes([16]byte{0: 0xab, 15: 0x01})
+	if k.IV != "ab000000000000000000000000000001" {
+		t.Errorf("IV 不匹配，实际: %s", k.IV)
+	}
+
+	if err := k.SetIVRaw(bytes.Repeat([]byte{0xff}, 16)); err != nil {
+		t.Fatalf("SetIVRaw 失败: %v", err)
+	}
+	if k.IV != strings.Repeat("ff", 16) {
+		t.Errorf("IV 不匹配，实际: %s", k.IV)
+	}
+
+	if err := k.SetIVRaw([]byte{1, 2, 3}); err == nil {
+		t.Error("非 16 字节的 IV 应返回错误")
+	}
+}
+
+func TestSetIVStrict(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo")
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	if err := k.SetIVStrict("abcdef1234567890abcdef1234567890"); err != nil {
+		t.Fatalf("合法 IV 不应返回错误: %v", err)
+	}
+
+	for _, iv := range []string{"", "1234", "zzcdef1234567890abcdef1234567890"} {
+		err := k.SetIVStrict(iv)
+		var ivErr *IVError
+		if !errors.As(err, &ivErr) {
+			t.Errorf("非法 IV %q 应返回 *IVError，实际: %v", iv, err)
+		}
+	}
+	if k.IV != "abcdef1234567890abcdef1234567890" {
+		t.Errorf("校验失败时不应修改 IV，实际: %s", k.IV)
+	}
+}
+
+func TestDeriveIV(t *testing.T) {
+	key := bytes.Repeat([]byte{0x5a}, 16)
+	k1, err := NewKeyInfoWithKey("http://localhost:4123/keyinfo", key)
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k1.Dispose()
+	k2, err := NewKeyInfoWithKey("http://localhost:4123/keyinfo", key)
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k2.Dispose()
+
+	k1.DeriveIV()
+	k2.DeriveIV()
+	if err := ValidateIV(k1.IV); err != nil {
+		t.Fatalf("派生的 IV 格式无效: %v", err)
+	}
+	if k1.IV != k2.IV {
+		t.Error("相同密钥应派生出相同 IV")
+	}
+	if k1.IV == hex.EncodeToString(key) {
+		t.Error("IV 不应等于密钥")
+	}
+}
+
+func TestNoIV(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo")
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	countLines := func() int {
+		var buf bytes.Buffer
+		if _, err := k.WriteTo(&buf); err != nil {
+			t.Fatalf("WriteTo 失败: %v", err)
+		}
+		return strings.Count(buf.String(), "\n")
+	}
+
+	k.RandIV().NoIV()
+	if k.HasIV() || countLines() != 2 {
+		t.Error("无 IV 模式应只输出 2 行")
+	}
+
+	// 只含空白字符的 IV 不应写出空行
+	k.SetIV("  ")
+	if k.HasIV() || countLines() != 2 {
+		t.Error("空白 IV 不应写入 IV 行")
+	}
+
+	k.RandIV()
+	if !k.HasIV() || countLines() != 3 {
+		t.Error("设置 IV 后应输出 3 行")
+	}
+}
+
+func TestIVPrefix(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithIVPrefix(true))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	// 输入的 0x 前缀会被规范化
+	k.SetIV("0XABCDEF1234567890abcdef1234567890")
+	if k.IV != "ABCDEF1234567890abcdef1234567890" {
+		t.Errorf("IV 前缀未被去除，实际: %s", k.IV)
+	}
+
+	var buf bytes.Buffer
+	if _, err := k.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo 失败: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[2] != "0xABCDEF1234567890abcdef1234567890" {
+		t.Errorf("启用前缀后第三行不匹配，实际: %s", lines[2])
+	}
+
+	if err := ValidateIV("0xabcdef1234567890abcdef1234567890"); err != nil {
+		t.Errorf("带前缀的合法 IV 不应返回错误: %v", err)
+	}
+}
+
+func TestRandIVErr(t *testing.T) {
+	// 仅提供密钥所需的 16 字节，生成 IV 时随机源已耗尽
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithRand(bytes.NewReader(make([]byte, 16))))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	k.SetIV("abcdef1234567890abcdef1234567890")
+	if err := k.RandIVErr(); err == nil {
+		t.Error("随机源耗尽时 RandIVErr 应返回错误")
+	}
+	if k.IV != "abcdef1234567890abcdef1234567890" {
+		t.Errorf("生成失败时不应修改 IV，实际: %s", k.IV)
+	}
+}
+
+func TestRotateIV(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo")
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	var rotated [2]string
+	k.RandIV().OnIVRotate(func(oldIV, newIV string) {
+		rotated = [2]string{oldIV, newIV}
+	})
+	infoFile, err := k.WriteToTempFile()
+	if err != nil {
+		t.Fatalf("WriteToTempFile 失败: %v", err)
+	}
+
+	oldIV := k.IV
+	if err := k.RotateIV(); err != nil {
+		t.Fatalf("RotateIV 失败: %v", err)
+	}
+	if k.IV == oldIV {
+		t.Error("RotateIV 后 IV 应变化")
+	}
+	if rotated != [2]string{oldIV, k.IV} {
+		t.Errorf("轮换回调参数不匹配: %v", rotated)
+	}
+
+	// keyinfo 文件应已更新为新 IV
+	content, err := os.ReadFile(infoFile)
+	if err != nil {
+		t.Fatalf("读取 keyinfo 文件失败: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if lines[2] != k.IV {
+		t.Errorf("keyinfo 文件中的 IV 未更新，实际: %s", lines[2])
+	}
+}
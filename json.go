@@ -0,0 +1,100 @@
+package hlskeyinfo
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+var (
+	_ json.Marshaler   = &KeyInfo{}
+	_ json.Unmarshaler = &KeyInfo{}
+)
+
+// keyInfoJSON KeyInfo 的 JSON 表示
+type keyInfoJSON struct {
+	URL     string `json:"url"`
+	KeyFile string `json:"key_file,omitempty"`
+	IV      string `json:"iv,omitempty"`
+	KeyID   string `json:"key_id,omitempty"`
+	Version int    `json:"version,omitempty"`
+	Stream  string `json:"stream,omitempty"`
+	Method  string `json:"method,omitempty"`
+
+	KeyFormat         string `json:"key_format,omitempty"`
+	KeyFormatVersions string `json:"key_format_versions,omitempty"`
+
+	Key []byte `json:"key,omitempty"` // Base64 编码，仅在显式导出密钥时包含
+}
+
+// MarshalJSON 实现json.Marshaler接口，默认不包含密钥
+func (k *KeyInfo) MarshalJSON() ([]byte, error) {
+	return k.ExportJSON(false)
+}
+
+// ExportJSON 导出 JSON，includeSecrets 为 true 时包含 Base64 编码的密钥
+// 仅在写入受保护的存储或内部接口时包含密钥
+func (k *KeyInfo) ExportJSON(includeSecrets bool) ([]byte, error) {
+	v := keyInfoJSON{
+		URL:     k.URL,
+		KeyFile: k.KeyFile,
+		IV:      k.IV,
+		KeyID:   k.KeyID,
+		Version: k.Version,
+		Stream:  k.Stream,
+		Method:  k.Method,
+
+		KeyFormat:         k.KeyFormat,
+		KeyFormatVersions: k.KeyFormatVersions,
+	}
+	if includeSecrets {
+		v.Key = k.key
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON 实现json.Unmarshaler接口，包含密钥时一并恢复
+// 不会创建或写入任何文件，其中的密钥文件视为外部文件，Dispose 时不会删除
+func (k *KeyInfo) UnmarshalJSON(data []byte) error {
+	var v keyInfoJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("解析 KeyInfo JSON 失败: %w", err)
+	}
+	if v.Key != nil {
+		if err := validateKeySize(len(v.Key)); err != nil {
+			return err
+		}
+	}
+	if err := validateMethod(v.Method); err != nil {
+		return err
+	}
+
+	if k.keySize == 0 {
+		*k = *newKeyInfo("", nil)
+	}
+	k.URL = v.URL
+	k.KeyFile = v.KeyFile
+	k.Stream = v.Stream
+	k.Method = v.Method
+	k.KeyFormat = v.KeyFormat
+	k.KeyFormatVersions = v.KeyFormatVersions
+	k.keepKeyFile = true
+	if v.IV != "" {
+		k.SetIV(v.IV)
+	} else {
+		k.NoIV()
+	}
+	if v.Version != 0 {
+		k.Version = v.Version
+	}
+	if v.Key != nil {
+		if err := k.setKey(v.Key); err != nil {
+			return err
+		}
+		k.keySize = len(v.Key)
+		k.keyChanged()
+	}
+	if v.KeyID != "" {
+		k.SetKeyID(v.KeyID)
+	}
+	return nil
+}
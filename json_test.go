@@ -0,0 +1,56 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo")
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+	k.RandIV()
+
+	// 默认不包含密钥
+	data, err := json.Marshal(k)
+	if err != nil {
+		t.Fatalf("json.Marshal 失败: %v", err)
+	}
+	if strings.Contains(string(data), `"key"`) || strings.Contains(string(data), k.GetKeyBase64()) {
+		t.Errorf("默认 JSON 不应包含密钥: %s", data)
+	}
+
+	var redacted KeyInfo
+	if err := json.Unmarshal(data, &redacted); err != nil {
+		t.Fatalf("json.Unmarshal 失败: %v", err)
+	}
+	if redacted.GetKey() != nil || redacted.URL != k.URL || redacted.IV != k.IV || redacted.KeyID != k.KeyID {
+		t.Error("脱敏 JSON 还原结果不匹配")
+	}
+
+	// 显式导出密钥
+	data, err = k.ExportJSON(true)
+	if err != nil {
+		t.Fatalf("ExportJSON 失败: %v", err)
+	}
+	var restored KeyInfo
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("json.Unmarshal 失败: %v", err)
+	}
+	if !bytes.Equal(restored.GetKey(), k.GetKey()) || restored.KeyID != k.KeyID {
+		t.Error("包含密钥的 JSON 还原结果不匹配")
+	}
+
+	// JSON 中的密钥文件不属于还原出的实例
+	if err := restored.Dispose(); err != nil {
+		t.Fatalf("Dispose 失败: %v", err)
+	}
+	if _, err := os.Stat(k.KeyFile); err != nil {
+		t.Error("还原出的实例 Dispose 不应删除原密钥文件")
+	}
+}
@@ -0,0 +1,274 @@
+package hlskeyinfo
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("无效的令牌")
+	ErrTokenExpired = errors.New("令牌已过期")
+)
+
+// Claims JWT 声明
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	Raw       map[string]any // 全部声明，用于自定义校验
+}
+
+// JWTVerifier JWT 校验器，支持 HS256 与 RS256，校验签名、签发者、受众与有效期
+// 只接受已配置密钥的算法，拒绝 alg 为 none 的令牌
+type JWTVerifier struct {
+	hmacKey    []byte
+	rsaKey     *rsa.PublicKey
+	issuer     string
+	audience   string
+	leeway     time.Duration
+	requireExp bool
+	checks     []func(*Claims) error
+	now        func() time.Time
+	param      string
+}
+
+// JWTOption JWT 校验器选项
+type JWTOption func(*JWTVerifier)
+
+// WithHS256 使用 HMAC-SHA256 共享密钥校验签名
+func WithHS256(secret []byte) JWTOption {
+	return func(v *JWTVerifier) {
+		v.hmacKey = secret
+	}
+}
+
+// WithRS256 使用 RSA 公钥校验 RSASSA-PKCS1-v1_5 SHA-256 签名
+func WithRS256(pub *rsa.PublicKey) JWTOption {
+	return func(v *JWTVerifier) {
+		v.rsaKey = pub
+	}
+}
+
+// WithIssuer 要求 iss 声明与之一致
+func WithIssuer(iss string) JWTOption {
+	return func(v *JWTVerifier) {
+		v.issuer = iss
+	}
+}
+
+// WithAudience 要求 aud 声明包含该受众
+func WithAudience(aud string) JWTOption {
+	return func(v *JWTVerifier) {
+		v.audience = aud
+	}
+}
+
+// WithLeeway 设置校验 exp 与 nbf 时允许的时钟偏差
+func WithLeeway(d time.Duration) JWTOption {
+	return func(v *JWTVerifier) {
+		v.leeway = d
+	}
+}
+
+// WithRequireExp 设置是否要求令牌包含 exp 声明，默认要求，缺少时拒绝；
+// 关闭后不含 exp 的令牌永不过期，仅在令牌另有吊销机制时使用
+func WithRequireExp(require bool) JWTOption {
+	return func(v *JWTVerifier) {
+		v.requireExp = require
+	}
+}
+
+// WithClaimCheck 添加自定义声明校验，返回错误时拒绝请求
+func WithClaimCheck(fn func(*Claims) error) JWTOption {
+	return func(v *JWTVerifier) {
+		v.checks = append(v.checks, fn)
+	}
+}
+
+// WithTokenParam 设置令牌查询参数名，默认 token，为空时只接受请求头
+func WithTokenParam(name string) JWTOption {
+	return func(v *JWTVerifier) {
+		v.param = name
+	}
+}
+
+// NewJWTVerifier 创建 JWT 校验器，至少需要配置 WithHS256 或 WithRS256
+// 默认拒绝不含 exp 声明的令牌，可通过 WithRequireExp(false) 放宽
+func NewJWTVerifier(opts ...JWTOption) (*JWTVerifier, error) {
+	v := &JWTVerifier{
+		now:        time.Now,
+		param:      DefaultTokenParam,
+		requireExp: true,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	if len(v.hmacKey) == 0 && v.rsaKey == nil {
+		return nil, fmt.Errorf("未配置 JWT 校验密钥")
+	}
+	return v, nil
+}
+
+// Verify 校验令牌并返回声明
+func (v *JWTVerifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: 格式错误", ErrInvalidToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: 签名编码错误", ErrInvalidToken)
+	}
+	if err := v.verifySignature(header.Alg, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, err
+	}
+	claims, err := parseClaims(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// verifySignature 按 alg 校验签名
+func (v *JWTVerifier) verifySignature(alg, signingInput string, sig []byte) error {
+	sum := sha256.Sum256([]byte(signingInput))
+	switch {
+	case alg == "HS256" && len(v.hmacKey) > 0:
+		mac := hmac.New(sha256.New, v.hmacKey)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return fmt.Errorf("%w: 签名不匹配", ErrInvalidToken)
+		}
+	case alg == "RS256" && v.rsaKey != nil:
+		if err := rsa.VerifyPKCS1v15(v.rsaKey, crypto.SHA256, sum[:], sig); err != nil {
+			return fmt.Errorf("%w: 签名不匹配", ErrInvalidToken)
+		}
+	default:
+		return fmt.Errorf("%w: 不支持的算法 %q", ErrInvalidToken, alg)
+	}
+	return nil
+}
+
+// validate 校验签发者、受众、有效期与自定义声明
+func (v *JWTVerifier) validate(c *Claims) error {
+	now := v.now()
+	if v.requireExp && c.ExpiresAt.IsZero() {
+		return fmt.Errorf("%w: 缺少 exp 声明", ErrInvalidToken)
+	}
+	if !c.ExpiresAt.IsZero() && now.After(c.ExpiresAt.Add(v.leeway)) {
+		return ErrTokenExpired
+	}
+	if !c.NotBefore.IsZero() && now.Add(v.leeway).Before(c.NotBefore) {
+		return fmt.Errorf("%w: 令牌尚未生效", ErrInvalidToken)
+	}
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return fmt.Errorf("%w: 签发者不匹配", ErrInvalidToken)
+	}
+	if v.audience != "" && !slices.Contains(c.Audience, v.audience) {
+		return fmt.Errorf("%w: 受众不匹配", ErrInvalidToken)
+	}
+	for _, check := range v.checks {
+		if err := check(c); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		}
+	}
+	return nil
+}
+
+// Middleware 返回 JWT 鉴权中间件，令牌无效时返回 401，sub 声明作为请求主体记录
+func (v *JWTVerifier) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := v.Verify(requestToken(r, v.param))
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="hls-key", error="invalid_token"`)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, setSubject(r, claims.Subject))
+		})
+	}
+}
+
+// decodeSegment 解码 Base64URL 编码的 JSON 片段
+func decodeSegment(seg string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return fmt.Errorf("%w: 编码错误", ErrInvalidToken)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: 解析失败", ErrInvalidToken)
+	}
+	return nil
+}
+
+// parseClaims 解析注册声明
+func parseClaims(raw map[string]any) (*Claims, error) {
+	c := &Claims{Raw: raw}
+	var ok bool
+	if v, exists := raw["sub"]; exists {
+		if c.Subject, ok = v.(string); !ok {
+			return nil, fmt.Errorf("%w: sub 声明类型错误", ErrInvalidToken)
+		}
+	}
+	if v, exists := raw["iss"]; exists {
+		if c.Issuer, ok = v.(string); !ok {
+			return nil, fmt.Errorf("%w: iss 声明类型错误", ErrInvalidToken)
+		}
+	}
+	switch aud := raw["aud"].(type) {
+	case nil:
+	case string:
+		c.Audience = []string{aud}
+	case []any:
+		for _, a := range aud {
+			s, ok := a.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: aud 声明类型错误", ErrInvalidToken)
+			}
+			c.Audience = append(c.Audience, s)
+		}
+	default:
+		return nil, fmt.Errorf("%w: aud 声明类型错误", ErrInvalidToken)
+	}
+	for name, dst := range map[string]*time.Time{"exp": &c.ExpiresAt, "nbf": &c.NotBefore, "iat": &c.IssuedAt} {
+		v, exists := raw[name]
+		if !exists {
+			continue
+		}
+		sec, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s 声明类型错误", ErrInvalidToken, name)
+		}
+		*dst = time.Unix(int64(sec), 0)
+	}
+	return c, nil
+}
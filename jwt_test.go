@@ -0,0 +1,132 @@
+package hlskeyinfo
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// signTestJWT 生成测试用 JWT
+func signTestJWT(t *testing.T, alg string, key any, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var sig []byte
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case "RS256":
+		sum := sha256.Sum256([]byte(input))
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, sum[:]); err != nil {
+			t.Fatalf("签名失败: %v", err)
+		}
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWTVerifier(t *testing.T) {
+	secret := []byte("hmac-secret")
+	v, err := NewJWTVerifier(WithHS256(secret), WithIssuer("backend"), WithAudience("hls"),
+		WithClaimCheck(func(c *Claims) error {
+			if c.Raw["channel"] != "channel-1" {
+				return errors.New("无权访问该频道")
+			}
+			return nil
+		}))
+	if err != nil {
+		t.Fatalf("创建 JWTVerifier 失败: %v", err)
+	}
+
+	valid := map[string]any{
+		"sub": "viewer-1", "iss": "backend", "aud": []string{"hls"},
+		"exp": time.Now().Add(time.Hour).Unix(), "channel": "channel-1",
+	}
+	claims, err := v.Verify(signTestJWT(t, "HS256", secret, valid))
+	if err != nil || claims.Subject != "viewer-1" {
+		t.Fatalf("有效令牌应通过校验: %v", err)
+	}
+
+	with := func(name string, value any) map[string]any {
+		c := make(map[string]any, len(valid))
+		for k, v := range valid {
+			c[k] = v
+		}
+		c[name] = value
+		return c
+	}
+	if _, err := v.Verify(signTestJWT(t, "HS256", secret, with("exp", time.Now().Add(-time.Hour).Unix()))); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("过期令牌应返回 ErrTokenExpired，实际: %v", err)
+	}
+	// 默认拒绝不含 exp 的令牌，WithRequireExp(false) 时放行
+	noExp := with("exp", nil)
+	delete(noExp, "exp")
+	if _, err := v.Verify(signTestJWT(t, "HS256", secret, noExp)); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("不含 exp 的令牌应返回 ErrInvalidToken，实际: %v", err)
+	}
+	lenient, _ := NewJWTVerifier(WithHS256(secret), WithRequireExp(false))
+	if _, err := lenient.Verify(signTestJWT(t, "HS256", secret, noExp)); err != nil {
+		t.Errorf("WithRequireExp(false) 时不含 exp 的令牌应通过校验: %v", err)
+	}
+	for name, token := range map[string]string{
+		"签发者不匹配":  signTestJWT(t, "HS256", secret, with("iss", "other")),
+		"受众不匹配":   signTestJWT(t, "HS256", secret, with("aud", "other")),
+		"自定义校验失败": signTestJWT(t, "HS256", secret, with("channel", "channel-2")),
+		"签名错误":    signTestJWT(t, "HS256", []byte("wrong"), valid),
+		"未配置的算法":  signTestJWT(t, "RS256", mustRSAKey(t), valid),
+		"格式错误":    "not-a-jwt",
+	} {
+		if _, err := v.Verify(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s 应返回 ErrInvalidToken，实际: %v", name, err)
+		}
+	}
+
+	// RS256 与中间件
+	priv := mustRSAKey(t)
+	rv, err := NewJWTVerifier(WithRS256(&priv.PublicKey))
+	if err != nil {
+		t.Fatalf("创建 JWTVerifier 失败: %v", err)
+	}
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+	s := NewKeyServer(k, WithMiddleware(rv.Middleware()))
+
+	req := httptest.NewRequest(http.MethodGet, "/key", nil)
+	req.Header.Set("Authorization", "Bearer "+signTestJWT(t, "RS256", priv, valid))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("RS256 令牌应通过鉴权，实际: %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/key?token=invalid", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("无效令牌应返回 401，实际: %d", w.Code)
+	}
+}
+
+func mustRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成 RSA 密钥失败: %v", err)
+	}
+	return priv
+}
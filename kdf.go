@@ -0,0 +1,146 @@
+package hlskeyinfo
+
+import (
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"slices"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// KDFAlgorithm 口令派生密钥算法
+type KDFAlgorithm string
+
+const (
+	KDFPBKDF2 KDFAlgorithm = "pbkdf2" // PBKDF2-HMAC-SHA256
+	KDFScrypt KDFAlgorithm = "scrypt" // scrypt
+)
+
+// 默认派生参数
+const (
+	DefaultPBKDF2Iterations = 600000
+	DefaultScryptN          = 1 << 15
+	DefaultScryptR          = 8
+	DefaultScryptP          = 1
+)
+
+// KDFOptions 口令派生密钥参数，零值字段使用默认值
+type KDFOptions struct {
+	Algorithm  KDFAlgorithm // 派生算法，默认 PBKDF2
+	Iterations int          // PBKDF2 迭代次数
+	N, R, P    int          // scrypt 参数
+}
+
+// deriveKey 按参数从口令派生指定长度的密钥
+func (o KDFOptions) deriveKey(passphrase string, salt []byte, size int) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("口令不能为空")
+	}
+	if len(salt) == 0 {
+		return nil, fmt.Errorf("盐值不能为空")
+	}
+
+	switch o.Algorithm {
+	case "", KDFPBKDF2:
+		iter := o.Iterations
+		if iter <= 0 {
+			iter = DefaultPBKDF2Iterations
+		}
+		key, err := pbkdf2.Key(sha256.New, passphrase, salt, iter, size)
+		if err != nil {
+			return nil, fmt.Errorf("PBKDF2 派生密钥失败: %w", err)
+		}
+		return key, nil
+	case KDFScrypt:
+		n, r, p := o.N, o.R, o.P
+		if n <= 0 {
+			n = DefaultScryptN
+		}
+		if r <= 0 {
+			r = DefaultScryptR
+		}
+		if p <= 0 {
+			p = DefaultScryptP
+		}
+		key, err := scrypt.Key([]byte(passphrase), salt, n, r, p, size)
+		if err != nil {
+			return nil, fmt.Errorf("scrypt 派生密钥失败: %w", err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("不支持的派生算法: %s", o.Algorithm)
+	}
+}
+
+// NewKeyInfoFromPassphrase 从共享口令和盐值派生密钥创建KeyInfo实例
+// 相同的口令、盐值与参数在任意主机上都会得到相同的密钥，无需存储原始密钥
+func NewKeyInfoFromPassphrase(url, passphrase string, salt []byte, kdf KDFOptions, opts ...Option) (*KeyInfo, error) {
+	k := newKeyInfo(url, opts)
+	if err := validateKeySize(k.keySize); err != nil {
+		return nil, err
+	}
+
+	key, err := kdf.deriveKey(passphrase, salt, k.keySize)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.SetKey(key); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// KeyDeriver 基于 HKDF-SHA256 从主密钥派生各路流的密钥
+// 一个主密钥即可驱动任意数量的加密频道，只需记录流 ID 与序号即可复现密钥
+type KeyDeriver struct {
+	master []byte
+	salt   []byte
+	opts   []Option
+}
+
+// NewKeyDeriver 创建密钥派生器，主密钥至少 16 字节，salt 可为空，opts 应用于派生出的每个 KeyInfo
+func NewKeyDeriver(master, salt []byte, opts ...Option) (*KeyDeriver, error) {
+	if len(master) < 16 {
+		return nil, fmt.Errorf("主密钥长度至少 16 字节，实际: %d", len(master))
+	}
+	return &KeyDeriver{
+		master: slices.Clone(master),
+		salt:   slices.Clone(salt),
+		opts:   opts,
+	}, nil
+}
+
+// DeriveKey 派生指定流 ID 与序号（如分片序号或轮换次数）对应的密钥
+func (d *KeyDeriver) DeriveKey(streamID string, index uint64, size int) ([]byte, error) {
+	if err := validateKeySize(size); err != nil {
+		return nil, err
+	}
+
+	// info = streamID || 0x00 || 大端序号，避免不同流 ID 与序号拼接后产生歧义
+	info := make([]byte, 0, len(streamID)+9)
+	info = append(info, streamID...)
+	info = append(info, 0)
+	info = binary.BigEndian.AppendUint64(info, index)
+
+	key, err := hkdf.Key(sha256.New, d.master, d.salt, string(info), size)
+	if err != nil {
+		return nil, fmt.Errorf("HKDF 派生密钥失败: %w", err)
+	}
+	return key, nil
+}
+
+// Derive 派生指定流 ID 与序号对应的KeyInfo实例
+func (d *KeyDeriver) Derive(url, streamID string, index uint64) (*KeyInfo, error) {
+	k := newKeyInfo(url, d.opts)
+	key, err := d.DeriveKey(streamID, index, k.keySize)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.SetKey(key); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
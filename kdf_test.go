@@ -0,0 +1,81 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewKeyInfoFromPassphrase(t *testing.T) {
+	salt := []byte("channel-1")
+	for _, kdf := range []KDFOptions{
+		{Algorithm: KDFPBKDF2, Iterations: 1000},
+		{Algorithm: KDFScrypt, N: 1 << 10},
+	} {
+		k1, err := NewKeyInfoFromPassphrase("http://localhost:4123/keyinfo", "secret", salt, kdf)
+		if err != nil {
+			t.Fatalf("%s 派生密钥失败: %v", kdf.Algorithm, err)
+		}
+		defer k1.Dispose()
+		k2, err := NewKeyInfoFromPassphrase("http://localhost:4123/keyinfo", "secret", salt, kdf)
+		if err != nil {
+			t.Fatalf("%s 派生密钥失败: %v", kdf.Algorithm, err)
+		}
+		defer k2.Dispose()
+
+		// 相同口令与盐值应得到相同密钥
+		if !bytes.Equal(k1.GetKey(), k2.GetKey()) {
+			t.Errorf("%s 相同参数派生的密钥不一致", kdf.Algorithm)
+		}
+		if len(k1.GetKey()) != DefaultKeySize {
+			t.Errorf("期望密钥长度为 %d 字节，实际: %d", DefaultKeySize, len(k1.GetKey()))
+		}
+	}
+
+	k, err := NewKeyInfoFromPassphrase("http://localhost:4123/keyinfo", "secret", []byte("channel-2"),
+		KDFOptions{Iterations: 1000}, WithKeySize(32))
+	if err != nil {
+		t.Fatalf("派生 32 字节密钥失败: %v", err)
+	}
+	defer k.Dispose()
+	if len(k.GetKey()) != 32 {
+		t.Errorf("期望密钥长度为 32 字节，实际: %d", len(k.GetKey()))
+	}
+
+	if _, err := NewKeyInfoFromPassphrase("http://localhost:4123/keyinfo", "secret", nil, KDFOptions{}); err == nil {
+		t.Error("空盐值应返回错误")
+	}
+}
+
+func TestKeyDeriver(t *testing.T) {
+	master := bytes.Repeat([]byte{0x01}, 32)
+	d, err := NewKeyDeriver(master, []byte("salt"))
+	if err != nil {
+		t.Fatalf("创建 KeyDeriver 失败: %v", err)
+	}
+
+	k1, err := d.Derive("http://localhost:4123/keyinfo", "channel-1", 0)
+	if err != nil {
+		t.Fatalf("派生 KeyInfo 失败: %v", err)
+	}
+	defer k1.Dispose()
+
+	// 相同流 ID 与序号派生结果一致
+	again, err := d.DeriveKey("channel-1", 0, DefaultKeySize)
+	if err != nil {
+		t.Fatalf("DeriveKey 失败: %v", err)
+	}
+	if !bytes.Equal(k1.GetKey(), again) {
+		t.Error("相同流 ID 与序号派生的密钥不一致")
+	}
+
+	// 不同流或序号派生结果不同
+	other, _ := d.DeriveKey("channel-2", 0, DefaultKeySize)
+	next, _ := d.DeriveKey("channel-1", 1, DefaultKeySize)
+	if bytes.Equal(again, other) || bytes.Equal(again, next) {
+		t.Error("不同流 ID 或序号应派生出不同密钥")
+	}
+
+	if _, err := NewKeyDeriver([]byte("short"), nil); err == nil {
+		t.Error("过短的主密钥应返回错误")
+	}
+}
@@ -0,0 +1,156 @@
+package hlskeyinfo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// WithKeyID 设置密钥 ID，未设置时由密钥内容派生
+func WithKeyID(id string) Option {
+	return func(k *KeyInfo) {
+		k.KeyID = id
+		k.autoKeyID = false
+	}
+}
+
+// keyIDParam 密钥获取URL中 KeyID 的查询参数名
+const keyIDParam = "kid"
+
+// WithKeyIDInURL 在写入 keyinfo 文件的密钥获取URL中附带 kid 查询参数
+// 便于轮换场景下播放器按 KeyID 获取对应密钥
+func WithKeyIDInURL() Option {
+	return func(k *KeyInfo) {
+		k.keyIDInURL = true
+	}
+}
+
+// SetKeyID 设置密钥 ID，之后密钥变更不再自动更新 KeyID
+func (k *KeyInfo) SetKeyID(id string) *KeyInfo {
+	k.KeyID = id
+	k.autoKeyID = false
+	return k
+}
+
+// SetVersion 设置密钥版本
+func (k *KeyInfo) SetVersion(version int) *KeyInfo {
+	k.Version = version
+	return k
+}
+
+// WithKID 设置 16 字节的 KID，KeyID 为其十六进制形式
+func WithKID(kid [16]byte) Option {
+	return WithKeyID(hex.EncodeToString(kid[:]))
+}
+
+// WithRandomKID 使用随机 KID 代替由密钥派生的 KeyID，轮换时每个新密钥重新生成
+// 相同密钥重启后会得到不同的 KID，需要通过 JSON 或 KeyStore 保存
+func WithRandomKID() Option {
+	return func(k *KeyInfo) {
+		k.randomKID = true
+	}
+}
+
+// SetKID 设置 16 字节的 KID，KeyID 为其十六进制形式，之后密钥变更不再自动更新
+func (k *KeyInfo) SetKID(kid [16]byte) *KeyInfo {
+	return k.SetKeyID(hex.EncodeToString(kid[:]))
+}
+
+// KID 返回 16 字节的密钥 ID，即 CENC 与各 DRM 系统使用的 KID
+// KeyID 为 32 位十六进制（可含 UUID 的连字符）时即其字节形式，否则由 KeyID 经 SHA-256 派生
+func (k *KeyInfo) KID() [16]byte {
+	var kid [16]byte
+	if b, err := hex.DecodeString(strings.ReplaceAll(k.KeyID, "-", "")); err == nil && len(b) == len(kid) {
+		copy(kid[:], b)
+		return kid
+	}
+	sum := sha256.Sum256(append([]byte("hls_keyinfo/kid\x00"), k.KeyID...))
+	copy(kid[:], sum[:])
+	return kid
+}
+
+// parseUUID 解析 UUID 形式的 KID 或 DRM 系统 ID
+func parseUUID(s string) ([16]byte, error) {
+	var id [16]byte
+	b, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(s), "-", ""))
+	if err != nil || len(b) != len(id) {
+		return id, fmt.Errorf("无效的 UUID: %s", s)
+	}
+	copy(id[:], b)
+	return id, nil
+}
+
+// formatUUID 将 16 字节 ID 格式化为 UUID
+func formatUUID(id [16]byte) string {
+	h := hex.EncodeToString(id[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// WithStream 设置流名称，用于展开密钥获取URL中的 {stream} 占位符
+func WithStream(name string) Option {
+	return func(k *KeyInfo) {
+		k.Stream = name
+	}
+}
+
+// KeyURL 返回写入 keyinfo 文件的密钥获取URL
+// URL 可作为模板包含 {stream}、{keyID}、{kid}（KID 的 UUID 形式）与 {version} 占位符，如 https://keys.example.com/{stream}/{keyID}，
+// 轮换后自动得到各密钥唯一的URL；启用 WithKeyIDInURL 时附带 kid 查询参数，URL 无法解析时原样返回
+func (k *KeyInfo) KeyURL() string {
+	raw := k.expandURL(k.URL)
+	if !k.keyIDInURL || k.KeyID == "" {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	q := u.Query()
+	q.Set(keyIDParam, k.KeyID)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// expandURL 按该密钥展开URL模板中的占位符
+func (k *KeyInfo) expandURL(tmpl string) string {
+	return expandURLTemplate(tmpl, k.Stream, k.KeyID, k.KID(), k.Version)
+}
+
+// expandURLTemplate 展开密钥获取URL模板中的占位符，占位符的值按路径片段转义
+func expandURLTemplate(tmpl, stream, keyID string, kid [16]byte, version int) string {
+	if !strings.Contains(tmpl, "{") {
+		return tmpl
+	}
+	return strings.NewReplacer(
+		"{stream}", url.PathEscape(stream),
+		"{keyID}", url.PathEscape(keyID),
+		"{kid}", formatUUID(kid),
+		"{version}", strconv.Itoa(version),
+	).Replace(tmpl)
+}
+
+// keyChanged 密钥变更后同步自动派生的 KeyID，并清除已失效的封装密钥
+func (k *KeyInfo) keyChanged() {
+	k.wrappedKey = nil
+	if !k.autoKeyID {
+		return
+	}
+	if k.randomKID {
+		var kid [16]byte
+		if _, err := io.ReadFull(k.rand(), kid[:]); err == nil {
+			k.KeyID = hex.EncodeToString(kid[:])
+			return
+		}
+	}
+	k.KeyID = deriveKeyID(k.key)
+}
+
+// deriveKeyID 由密钥派生 32 位十六进制 KeyID，相同密钥（如重启后从密钥文件恢复）得到相同 KeyID
+func deriveKeyID(key []byte) string {
+	sum := sha256.Sum256(append([]byte("hls_keyinfo/key-id\x00"), key...))
+	return hex.EncodeToString(sum[:16])
+}
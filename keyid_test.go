@@ -0,0 +1,165 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyID(t *testing.T) {
+	key := bytes.Repeat([]byte{0x11}, 16)
+	k1, err := NewKeyInfoWithKey("http://localhost:4123/keyinfo", key)
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k1.Dispose()
+	k2, err := NewKeyInfoWithKey("http://localhost:4123/keyinfo", key)
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k2.Dispose()
+
+	if len(k1.KeyID) != 32 {
+		t.Errorf("期望 KeyID 长度为 32，实际: %d", len(k1.KeyID))
+	}
+	if k1.KeyID != k2.KeyID {
+		t.Error("相同密钥应派生出相同 KeyID")
+	}
+	if k1.Version != 1 {
+		t.Errorf("期望默认版本为 1，实际: %d", k1.Version)
+	}
+
+	// 自动派生的 KeyID 随密钥更新
+	old := k1.KeyID
+	if err := k1.SetKey(bytes.Repeat([]byte{0x22}, 16)); err != nil {
+		t.Fatalf("SetKey 失败: %v", err)
+	}
+	if k1.KeyID == old {
+		t.Error("密钥变更后 KeyID 应更新")
+	}
+
+	// 手动设置的 KeyID 不随密钥变化
+	k1.SetKeyID("stream-1-v2").SetVersion(2)
+	if err := k1.SetKey(key); err != nil {
+		t.Fatalf("SetKey 失败: %v", err)
+	}
+	if k1.KeyID != "stream-1-v2" || k1.Version != 2 {
+		t.Errorf("手动设置的 KeyID/版本被覆盖: %s/%d", k1.KeyID, k1.Version)
+	}
+}
+
+func TestKeyIDInURL(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo?stream=1", WithKeyID("abc"), WithKeyIDInURL())
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	if got := k.KeyURL(); got != "http://localhost:4123/keyinfo?kid=abc&stream=1" {
+		t.Errorf("KeyURL 不匹配，实际: %s", got)
+	}
+
+	var buf bytes.Buffer
+	if _, err := k.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo 失败: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), k.KeyURL()+"\n") {
+		t.Errorf("keyinfo 第一行应为 KeyURL，实际: %s", buf.String())
+	}
+}
+
+func TestKeyURLTemplate(t *testing.T) {
+	k, err := NewKeyInfo("https://keys.example.com/{stream}/{keyID}?v={version}", WithStream("channel 1"))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	want := "https://keys.example.com/channel%201/" + k.KeyID + "?v=1"
+	if got := k.KeyURL(); got != want {
+		t.Errorf("期望 %s，实际: %s", want, got)
+	}
+
+	var buf bytes.Buffer
+	k.WriteTo(&buf)
+	if !strings.HasPrefix(buf.String(), want+"\n") {
+		t.Errorf("keyinfo 文件应写入展开后的URL，实际: %s", buf.String())
+	}
+
+	// 轮换后得到新密钥对应的URL
+	r, err := NewRotator(k, 0)
+	if err != nil {
+		t.Fatalf("创建 Rotator 失败: %v", err)
+	}
+	defer r.Dispose()
+	next, _ := r.Rotate()
+	if next.KeyURL() == want || !strings.Contains(next.KeyURL(), next.KeyID) {
+		t.Errorf("轮换后URL应包含新的 KeyID，实际: %s", next.KeyURL())
+	}
+}
+
+func TestKID(t *testing.T) {
+	key := bytes.Repeat([]byte{0x11}, 16)
+	k, err := NewKeyInfoWithKey("skd://{kid}", key, WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	// 自动派生的 KeyID 即 KID 的十六进制形式
+	kid := k.KID()
+	if formatUUID(kid) != strings.Join([]string{k.KeyID[:8], k.KeyID[8:12], k.KeyID[12:16], k.KeyID[16:20], k.KeyID[20:]}, "-") {
+		t.Errorf("KID 与 KeyID 不一致: %x %s", kid, k.KeyID)
+	}
+	if k.KeyURL() != "skd://"+formatUUID(kid) {
+		t.Errorf("{kid} 占位符展开不正确: %s", k.KeyURL())
+	}
+	if rec := k.record(time.Now()); rec.KID != formatUUID(kid) {
+		t.Errorf("KeyRecord 应包含 KID: %s", rec.KID)
+	}
+	if box := k.PSSH(WidevineSystemID, nil); !bytes.Equal(box[32:48], kid[:]) {
+		t.Errorf("pssh 应包含 KID")
+	}
+
+	// 显式设置
+	want := [16]byte{0x01, 0x23, 0x45, 0x67}
+	k.SetKID(want)
+	if k.KID() != want || k.KeyID != "01234567000000000000000000000000" {
+		t.Errorf("SetKID 结果不正确: %s", k.KeyID)
+	}
+	k.SetKeyID("01234567-0000-0000-0000-000000000000")
+	if k.KID() != want {
+		t.Errorf("UUID 形式的 KeyID 应解析为 KID")
+	}
+	k.SetKeyID("key-1")
+	if a, b := k.KID(), k.KID(); a != b || a == want {
+		t.Errorf("非十六进制 KeyID 应稳定派生 KID")
+	}
+
+	// 随机 KID：不同于派生值，轮换时重新生成，复制时保留
+	r1, err := NewKeyInfoWithKey("https://keys.example.com/key", key, WithTempDir(t.TempDir()), WithRandomKID())
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer r1.Dispose()
+	if r1.KeyID == deriveKeyID(key) || len(r1.KeyID) != 32 {
+		t.Errorf("应使用随机 KID: %s", r1.KeyID)
+	}
+	c, err := r1.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Dispose()
+	if c.KeyID != r1.KeyID {
+		t.Errorf("复制应保留 KID")
+	}
+	n, err := r1.next(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Dispose()
+	if n.KeyID == r1.KeyID || n.KeyID == deriveKeyID(key) {
+		t.Errorf("轮换应重新生成随机 KID: %s", n.KeyID)
+	}
+}
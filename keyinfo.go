@@ -1,7 +1,12 @@
 package hlskeyinfo
 
 import (
+	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -11,45 +16,258 @@ import (
 
 var _ io.WriterTo = &KeyInfo{}
 
+// DefaultKeySize 默认密钥长度（字节），对应 AES-128
+const DefaultKeySize = 16
+
+// DefaultFileMode 密钥文件与 keyinfo 文件的默认权限，仅当前用户可读写
+const DefaultFileMode os.FileMode = 0o600
+
+// ErrInvalidKeySize 密钥长度不受支持，仅允许 16/24/32 字节
+var ErrInvalidKeySize = errors.New("密钥长度仅支持 16/24/32 字节")
+
+// validateKeySize 校验密钥长度是否为 AES 支持的长度
+func validateKeySize(size int) error {
+	switch size {
+	case 16, 24, 32:
+		return nil
+	}
+	return fmt.Errorf("%w: %d", ErrInvalidKeySize, size)
+}
+
 // KeyInfo HLS加密信息结构
 type KeyInfo struct {
-	URL      string // 密钥获取URL
-	KeyFile  string // 密钥文件路径
-	IV       string // 初始化向量
+	URL     string // 密钥获取URL
+	KeyFile string // 密钥文件路径
+	IV      string // 初始化向量
+	KeyID   string // 密钥 ID，未设置时由密钥派生
+	Version int    // 密钥版本，从 1 开始
+	Stream  string // 流名称，用于展开密钥获取URL中的 {stream} 占位符
+	Method  string // 加密方式，AES-128（默认）或 SAMPLE-AES
+
+	KeyFormat         string // EXT-X-KEY 的 KEYFORMAT，为空时为 identity
+	KeyFormatVersions string // EXT-X-KEY 的 KEYFORMATVERSIONS
+
 	key      []byte // 密钥字节数组（小写私有属性）
 	infoFile string // 临时 keyinfo 文件路径（小写私有属性）
+	keySize  int    // 密钥长度（字节）
+
+	tempDir     string      // 临时文件目录，默认系统临时目录
+	fileMode    os.FileMode // 密钥文件与 keyinfo 文件权限
+	keepKeyFile bool        // 密钥文件由外部提供，Dispose 时不删除
+	random      io.Reader   // 随机数来源，默认 crypto/rand
+	autoKeyID   bool        // KeyID 由密钥自动派生，密钥变更时同步更新
+	randomKID   bool        // 自动派生的 KeyID 改为随机生成
+	keyIDInURL  bool        // 在密钥获取URL中附带 KeyID
+	ivMode      ivMode      // IV 写入模式
+	ivPrefix    bool        // keyinfo 文件中的 IV 带 0x 前缀
+	sequence    uint64      // 当前分片媒体序列号
+
+	memoryKeyFile bool     // 密钥文件存储在内存中
+	memFile       *os.File // 内存密钥文件
+	wrappedKey    []byte   // 由 KeyProvider 封装的密钥，用于重启后恢复
+
+	lockedMemory bool   // 密钥保存在锁定内存中
+	lockedKey    []byte // 保存密钥的锁定内存
+
+	auditor    Auditor // 密钥生命周期审计
+	auditActor string  // 审计记录中的操作主体
+
+	onIVRotate func(oldIV, newIV string) // IV 轮换回调
+	onDispose  func(k *KeyInfo)          // 清理回调
+	fifo       *fifoServer               // 命名管道方式提供 keyinfo
+}
+
+// Option KeyInfo 创建选项
+type Option func(*KeyInfo)
+
+// WithKeySize 设置密钥长度（字节），支持 16/24/32，默认 16 字节（AES-128）
+// 注意 ffmpeg 的 HLS 加密仅支持 AES-128，更长的密钥适用于自行实现加解密的场景
+func WithKeySize(size int) Option {
+	return func(k *KeyInfo) {
+		k.keySize = size
+	}
+}
+
+// WithRand 设置密钥与 IV 生成使用的随机数来源，例如硬件随机数发生器或测试中的确定性数据源
+func WithRand(r io.Reader) Option {
+	return func(k *KeyInfo) {
+		k.random = r
+	}
+}
+
+// WithTempDir 设置密钥文件与 keyinfo 文件所在目录，例如 tmpfs 挂载点或流的工作目录
+// 目录需已存在，Dispose 时仍会清理其中由本实例创建的文件
+func WithTempDir(dir string) Option {
+	return func(k *KeyInfo) {
+		k.tempDir = dir
+	}
+}
+
+// WithFileMode 设置密钥文件与 keyinfo 文件权限，默认 0600
+// 密钥材料不应对其他用户可读，仅在 ffmpeg 以其他用户身份运行时放宽
+func WithFileMode(mode os.FileMode) Option {
+	return func(k *KeyInfo) {
+		k.fileMode = mode.Perm()
+	}
 }
 
 // NewKeyInfo 创建新的KeyInfo实例
-func NewKeyInfo(url string) (*KeyInfo, error) {
-	k := &KeyInfo{
-		URL: url,
+func NewKeyInfo(url string, opts ...Option) (*KeyInfo, error) {
+	k := newKeyInfo(url, opts)
+	if err := validateKeySize(k.keySize); err != nil {
+		return nil, err
 	}
 
-	// 生成16字节的随机密钥
-	key := make([]byte, 16)
-	if _, err := rand.Read(key); err != nil {
+	// 按配置长度生成随机密钥
+	key := make([]byte, k.keySize)
+	if _, err := io.ReadFull(k.rand(), key); err != nil {
 		return nil, fmt.Errorf("生成密钥失败: %w", err)
 	}
-	k.key = key
+	if err := k.setKey(key); err != nil {
+		return nil, err
+	}
+	k.keyChanged()
+	k.audit(AuditKeyCreated, "")
+
+	if err := k.writeKeyFile(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// NewKeyInfoWithKey 使用外部提供的密钥创建KeyInfo实例，密钥长度以传入的密钥为准
+func NewKeyInfoWithKey(url string, key []byte, opts ...Option) (*KeyInfo, error) {
+	k := newKeyInfo(url, opts)
+	if err := k.SetKey(key); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// NewKeyInfoFromKeyFile 从已有密钥文件加载密钥创建KeyInfo实例，直接复用该文件路径
+// 适用于服务重启后使用相同密钥继续推流，Dispose 时不会删除该密钥文件
+func NewKeyInfoFromKeyFile(url, keyFile string, opts ...Option) (*KeyInfo, error) {
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	if err := validateKeySize(len(key)); err != nil {
+		return nil, err
+	}
+
+	k := newKeyInfo(url, opts)
+	if err := ensureFileMode(keyFile, k.fileMode); err != nil {
+		return nil, err
+	}
+	if err := k.setKey(key); err != nil {
+		return nil, err
+	}
+	k.keySize = len(key)
+	k.KeyFile = keyFile
+	k.keepKeyFile = true
+	k.keyChanged()
+	k.audit(AuditKeyLoaded, keyFile)
+	return k, nil
+}
+
+// newKeyInfo 创建并应用选项，不生成密钥
+func newKeyInfo(url string, opts []Option) *KeyInfo {
+	k := &KeyInfo{
+		URL:       url,
+		Version:   1,
+		keySize:   DefaultKeySize,
+		fileMode:  DefaultFileMode,
+		autoKeyID: true,
+	}
+	for _, opt := range opts {
+		opt(k)
+	}
+	return k
+}
+
+// rand 返回随机数来源，未设置时使用 crypto/rand
+func (k *KeyInfo) rand() io.Reader {
+	if k.random == nil {
+		return rand.Reader
+	}
+	return k.random
+}
+
+// dir 返回临时文件目录，未设置时使用系统临时目录
+func (k *KeyInfo) dir() string {
+	if k.tempDir == "" {
+		return os.TempDir()
+	}
+	return k.tempDir
+}
+
+// writeKeyFile 将密钥写入密钥文件并记录审计
+func (k *KeyInfo) writeKeyFile() error {
+	if err := k.storeKeyFile(); err != nil {
+		return err
+	}
+	k.audit(AuditFileWritten, k.KeyFile)
+	return nil
+}
+
+// storeKeyFile 将密钥写入密钥文件，未设置路径时在临时文件目录创建
+func (k *KeyInfo) storeKeyFile() error {
+	if k.memoryKeyFile {
+		return k.writeMemoryKeyFile()
+	}
+	if k.KeyFile != "" {
+		if err := os.WriteFile(k.KeyFile, k.key, k.fileMode); err != nil {
+			return fmt.Errorf("写入密钥文件失败: %w", err)
+		}
+		return ensureFileMode(k.KeyFile, k.fileMode)
+	}
 
-	// 在系统临时目录创建密钥文件
-	tempDir := os.TempDir()
-	tempFile, err := os.CreateTemp(tempDir, "hls_key_*.bin")
+	// 在临时文件目录创建密钥文件
+	tempFile, err := os.CreateTemp(k.dir(), "hls_key_*.bin")
 	if err != nil {
-		return nil, fmt.Errorf("创建临时文件失败: %w", err)
+		return fmt.Errorf("创建临时文件失败: %w", err)
 	}
 	defer tempFile.Close()
 
 	// 写入密钥到临时文件
-	if _, err := tempFile.Write(key); err != nil {
+	if _, err := tempFile.Write(k.key); err != nil {
+		os.Remove(tempFile.Name())
+		return fmt.Errorf("写入密钥文件失败: %w", err)
+	}
+	if err := tempFile.Chmod(k.fileMode); err != nil {
 		os.Remove(tempFile.Name())
-		return nil, fmt.Errorf("写入密钥文件失败: %w", err)
+		return fmt.Errorf("设置密钥文件权限失败: %w", err)
 	}
 
 	k.KeyFile = tempFile.Name()
+	return nil
+}
 
-	return k, nil
+// SetKey 导入外部生成的密钥，校验长度后重写密钥文件，并使已生成的 keyinfo 文件失效，正在提供的命名管道同步更新
+func (k *KeyInfo) SetKey(key []byte) error {
+	if err := validateKeySize(len(key)); err != nil {
+		return err
+	}
+	if err := k.setKey(slices.Clone(key)); err != nil {
+		return err
+	}
+	k.keySize = len(key)
+	k.keyChanged()
+	k.audit(AuditKeyCreated, "")
+
+	if err := k.writeKeyFile(); err != nil {
+		return err
+	}
+
+	// keyinfo 文件与密钥绑定，密钥变更后需重新生成
+	if k.infoFile != "" {
+		if err := os.Remove(k.infoFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("删除临时 keyinfo 文件失败: %w", err)
+		}
+		k.infoFile = ""
+	}
+	// KeyID 与密钥文件路径可能变化，命名管道随之提供新内容
+	return k.refreshFIFO()
 }
 
 // GetKey 获取密钥字节数组
@@ -60,9 +278,39 @@ func (k *KeyInfo) GetKey() []byte {
 	return slices.Clone(k.key)
 }
 
-// SetIV 设置初始化向量
+// GetKeyHex 获取密钥的十六进制字符串
+func (k *KeyInfo) GetKeyHex() string {
+	return hex.EncodeToString(k.key)
+}
+
+// GetKeyBase64 获取密钥的标准 Base64 字符串
+func (k *KeyInfo) GetKeyBase64() string {
+	return base64.StdEncoding.EncodeToString(k.key)
+}
+
+// SetKeyHex 使用十六进制字符串设置密钥
+func (k *KeyInfo) SetKeyHex(s string) error {
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("解析十六进制密钥失败: %w", err)
+	}
+	return k.SetKey(key)
+}
+
+// SetKeyBase64 使用标准 Base64 字符串设置密钥
+func (k *KeyInfo) SetKeyBase64(s string) error {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("解析 Base64 密钥失败: %w", err)
+	}
+	return k.SetKey(key)
+}
+
+// SetIV 设置初始化向量，同时退出无 IV 与媒体序列号 IV 模式
+// 输入中的 0x/0X 前缀会被去除，IV 始终以不带前缀的形式保存
 func (k *KeyInfo) SetIV(iv string) *KeyInfo {
-	k.IV = iv
+	k.IV = normalizeIV(iv)
+	k.ivMode = ivExplicit
 	return k
 }
 
@@ -73,30 +321,72 @@ func (k *KeyInfo) SetKeyFile(keyFile string) *KeyInfo {
 }
 
 // RandIV 生成随机初始化向量
+// 生成失败时回退为全零 IV，需要感知随机数来源故障时请使用 RandIVErr
 func (k *KeyInfo) RandIV() *KeyInfo {
-	iv := make([]byte, 16)
-	if _, err := rand.Read(iv); err != nil {
+	if err := k.RandIVErr(); err != nil {
 		// 如果生成失败，使用默认值
-		k.IV = "00000000000000000000000000000000"
-		return k
+		k.SetIV("00000000000000000000000000000000")
+	}
+	return k
+}
+
+// RandIVErr 生成随机初始化向量，随机数来源读取失败时返回错误且不修改当前 IV
+func (k *KeyInfo) RandIVErr() error {
+	iv := make([]byte, 16)
+	if _, err := io.ReadFull(k.rand(), iv); err != nil {
+		return fmt.Errorf("生成 IV 失败: %w", err)
 	}
 	// 转换为十六进制字符串
-	k.IV = fmt.Sprintf("%032x", iv)
+	k.SetIV(fmt.Sprintf("%032x", iv))
+	return nil
+}
+
+// OnDispose 注册清理回调，在 Dispose 删除密钥文件前调用一次
+func (k *KeyInfo) OnDispose(fn func(k *KeyInfo)) *KeyInfo {
+	k.onDispose = fn
 	return k
 }
 
 // Dispose 清理临时文件
 func (k *KeyInfo) Dispose() error {
+	// 清理回调只触发一次，此时密钥与文件仍可访问
+	if fn := k.onDispose; fn != nil {
+		k.onDispose = nil
+		fn(k)
+	}
+
+	// 重复调用时不再记录审计
+	held := k.KeyFile != "" || k.infoFile != "" || k.fifo != nil || k.lockedKey != nil
+	if held {
+		k.audit(AuditKeyDisposed, k.KeyFile)
+	}
+
 	var errs []error
 
-	// 清理密钥文件
+	// 清理密钥文件，外部提供的密钥文件保留，内存密钥文件关闭即释放
+	if k.memFile != nil {
+		if err := k.closeMemoryKeyFile(); err != nil {
+			errs = append(errs, err)
+		}
+		k.KeyFile = ""
+	}
 	if k.KeyFile != "" {
-		if err := os.Remove(k.KeyFile); err != nil && !os.IsNotExist(err) {
-			errs = append(errs, fmt.Errorf("删除临时密钥文件失败: %w", err))
+		if !k.keepKeyFile {
+			if err := os.Remove(k.KeyFile); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("删除临时密钥文件失败: %w", err))
+			}
 		}
 		k.KeyFile = ""
 	}
 
+	// 停止命名管道
+	if k.fifo != nil {
+		if err := k.fifo.close(); err != nil {
+			errs = append(errs, err)
+		}
+		k.fifo = nil
+	}
+
 	// 清理 keyinfo 文件
 	if k.infoFile != "" {
 		if err := os.Remove(k.infoFile); err != nil && !os.IsNotExist(err) {
@@ -105,6 +395,11 @@ func (k *KeyInfo) Dispose() error {
 		k.infoFile = ""
 	}
 
+	// 锁定内存中的密钥清零后释放
+	if err := k.releaseKey(); err != nil {
+		errs = append(errs, err)
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("清理临时文件时发生错误: %v", errs)
 	}
@@ -113,27 +408,26 @@ func (k *KeyInfo) Dispose() error {
 }
 
 // WriteToTempFile 将 keyinfo 信息写入临时文件，返回临时文件路径
-// 使用密钥的十六进制表示作为文件名，确保唯一性和可识别性
+// 使用密钥与密钥文件路径的哈希作为文件名，相同实例多次调用返回相同路径，不同实例互不覆盖
 func (k *KeyInfo) WriteToTempFile() (string, error) {
 	if k.key == nil {
 		return "", fmt.Errorf("密钥未初始化")
 	}
 
-	// 使用密钥的十六进制表示作为文件名
-	fileName := "hls_keyinfo_*.txt"
-	filePath := filepath.Join(os.TempDir(), fileName)
+	// 文件名不直接包含密钥，避免通过文件名泄露密钥
+	filePath := filepath.Join(k.dir(), k.infoFileName())
 
-	// 创建文件（如果已存在则覆盖）
-	tempFile, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
-	if err != nil {
-		return "", fmt.Errorf("创建临时文件失败: %w", err)
+	// 密钥文件路径变更后旧 keyinfo 文件不再使用
+	if k.infoFile != "" && k.infoFile != filePath {
+		if err := os.Remove(k.infoFile); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("删除临时 keyinfo 文件失败: %w", err)
+		}
+		k.infoFile = ""
 	}
-	defer tempFile.Close()
 
-	// 写入 keyinfo 内容
-	_, err = k.WriteTo(tempFile)
-	if err != nil {
-		return "", fmt.Errorf("写入临时文件失败: %w", err)
+	// 原子写入，读取方不会看到写了一半的文件
+	if err := k.writeInfoFile(filePath); err != nil {
+		return "", err
 	}
 
 	// 记录临时文件路径
@@ -141,6 +435,28 @@ func (k *KeyInfo) WriteToTempFile() (string, error) {
 	return filePath, nil
 }
 
+// infoFileName 返回由密钥与密钥文件路径哈希得到的 keyinfo 文件名
+func (k *KeyInfo) infoFileName() string {
+	h := sha256.New()
+	h.Write(k.key)
+	h.Write([]byte{0})
+	h.Write([]byte(k.KeyFile))
+	return fmt.Sprintf("hls_keyinfo_%x.txt", h.Sum(nil)[:8])
+}
+
+// writeInfoFile 渲染 keyinfo 内容并原子地写入指定路径
+func (k *KeyInfo) writeInfoFile(path string) error {
+	var buf bytes.Buffer
+	if _, err := k.WriteTo(&buf); err != nil {
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := writeFileAtomic(path, buf.Bytes(), k.fileMode); err != nil {
+		return fmt.Errorf("写入 keyinfo 文件失败: %w", err)
+	}
+	k.audit(AuditFileWritten, path)
+	return nil
+}
+
 // WriteTo 实现io.WriterTo接口，按照ffmpeg hls_key_info_file格式写入三行数据
 func (k *KeyInfo) WriteTo(w io.Writer) (n int64, err error) {
 	// ffmpeg hls_key_info_file格式：
@@ -151,24 +467,24 @@ func (k *KeyInfo) WriteTo(w io.Writer) (n int64, err error) {
 	var written int64
 
 	// 写入URL
-	urlLine := k.URL + "\n"
+	urlLine := k.KeyURL() + "\n"
 	wrote, err := w.Write([]byte(urlLine))
 	if err != nil {
 		return written, fmt.Errorf("写入URL失败: %w", err)
 	}
 	written += int64(wrote)
 
-	// 写入密钥文件路径
-	keyFileLine := k.KeyFile + "\n"
+	// 写入密钥文件路径，按 ffmpeg 的要求规范化
+	keyFileLine := NormalizeFFmpegPath(k.KeyFile) + "\n"
 	wrote, err = w.Write([]byte(keyFileLine))
 	if err != nil {
 		return written, fmt.Errorf("写入密钥文件路径失败: %w", err)
 	}
 	written += int64(wrote)
 
-	// 写入IV（如果存在）
-	if k.IV != "" {
-		ivLine := k.IV + "\n"
+	// 写入IV（如果存在），无 IV 与序列号 IV 模式下省略，由 ffmpeg 按媒体序列号计算
+	if k.HasIV() {
+		ivLine := k.formatIV() + "\n"
 		wrote, err = w.Write([]byte(ivLine))
 		if err != nil {
 			return written, fmt.Errorf("写入IV失败: %w", err)
@@ -178,3 +494,21 @@ func (k *KeyInfo) WriteTo(w io.Writer) (n int64, err error) {
 
 	return written, nil
 }
+
+// String 实现fmt.Stringer接口，输出不包含密钥与完整 IV，可安全写入日志
+func (k *KeyInfo) String() string {
+	iv := "none"
+	switch {
+	case k.ivMode == ivSequence:
+		iv = "sequence"
+	case k.HasIV():
+		iv = "set"
+	}
+	return fmt.Sprintf("KeyInfo{URL: %q, KeyFile: %q, KeyID: %q, Version: %d, Key: [%d bytes], IV: %s}",
+		k.URL, k.KeyFile, k.KeyID, k.Version, len(k.key), iv)
+}
+
+// GoString 实现fmt.GoStringer接口，%#v 输出同样不包含密钥
+func (k *KeyInfo) GoString() string {
+	return "&" + k.String()
+}
@@ -0,0 +1,148 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// DefaultProviderTimeout 轮换器调用 KeyProvider 的默认超时时间
+const DefaultProviderTimeout = 30 * time.Second
+
+// KeyProvider 外部密钥管理服务（KMS、HSM、Vault 等），生成内容密钥并以服务端保管的主密钥封装
+// 明文密钥只保存在内存与密钥文件中，持久化时只需保存封装后的密文
+type KeyProvider interface {
+	// GenerateDataKey 生成 size 字节的内容密钥，返回明文与密文
+	GenerateDataKey(ctx context.Context, size int) (plaintext, wrapped []byte, err error)
+	// Encrypt 封装已有的内容密钥
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+	// Decrypt 解封密文，返回内容密钥
+	Decrypt(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// NewKeyInfoFromProvider 由 KeyProvider 生成内容密钥并创建KeyInfo实例，WrappedKey 返回密文
+// 密钥长度由 WithKeySize 指定
+func NewKeyInfoFromProvider(ctx context.Context, p KeyProvider, url string, opts ...Option) (*KeyInfo, error) {
+	k := newKeyInfo(url, opts)
+	if err := validateKeySize(k.keySize); err != nil {
+		return nil, err
+	}
+	key, wrapped, err := p.GenerateDataKey(ctx, k.keySize)
+	if err != nil {
+		return nil, fmt.Errorf("生成密钥失败: %w", err)
+	}
+	if err := k.SetKey(key); err != nil {
+		return nil, err
+	}
+	k.wrappedKey = wrapped
+	return k, nil
+}
+
+// NewKeyInfoFromProviderWrapped 通过 KeyProvider 解封密文并创建KeyInfo实例，用于重启后恢复密钥
+func NewKeyInfoFromProviderWrapped(ctx context.Context, p KeyProvider, url string, wrapped []byte, opts ...Option) (*KeyInfo, error) {
+	key, err := p.Decrypt(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("解封密钥失败: %w", err)
+	}
+	k, err := NewKeyInfoWithKey(url, key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	k.wrappedKey = slices.Clone(wrapped)
+	return k, nil
+}
+
+// WrappedKey 返回由 KeyProvider 封装的密钥，密钥不是由 KeyProvider 生成或已被 SetKey 替换时返回 nil
+func (k *KeyInfo) WrappedKey() []byte {
+	return slices.Clone(k.wrappedKey)
+}
+
+// WithKeyProvider 轮换时由 KeyProvider 生成新密钥，单次调用超时时间为 DefaultProviderTimeout
+func WithKeyProvider(p KeyProvider) RotatorOption {
+	return func(r *Rotator) {
+		r.provider = p
+	}
+}
+
+// generateKey 通过 KeyProvider 生成下一个密钥，未设置时返回 nil 表示使用随机密钥
+func (r *Rotator) generateKey() (key, wrapped []byte, err error) {
+	if r.provider == nil {
+		return nil, nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultProviderTimeout)
+	defer cancel()
+	key, wrapped, err = r.provider.GenerateDataKey(ctx, r.Current().keySize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("生成密钥失败: %w", err)
+	}
+	return key, wrapped, nil
+}
+
+// SealedStore 将明文密钥替换为 KeyProvider 封装的密文后再写入底层存储，读取时自动解封
+// 后端中只保存密文，泄露存储本身不会暴露内容密钥
+type SealedStore struct {
+	store    KeyStore
+	provider KeyProvider
+}
+
+var _ KeyStore = &SealedStore{}
+
+// NewSealedStore 创建封装密钥的存储
+func NewSealedStore(store KeyStore, p KeyProvider) *SealedStore {
+	return &SealedStore{store: store, provider: p}
+}
+
+// Put 实现 KeyStore 接口，记录没有 WrappedKey 时先封装明文密钥
+func (s *SealedStore) Put(ctx context.Context, rec KeyRecord) error {
+	if len(rec.WrappedKey) == 0 {
+		wrapped, err := s.provider.Encrypt(ctx, rec.Key)
+		if err != nil {
+			return fmt.Errorf("封装密钥失败: %w", err)
+		}
+		rec.WrappedKey = wrapped
+	}
+	rec.Key = nil
+	return s.store.Put(ctx, rec)
+}
+
+// Get 实现 KeyStore 接口
+func (s *SealedStore) Get(ctx context.Context, keyID string) (KeyRecord, error) {
+	rec, err := s.store.Get(ctx, keyID)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	return s.unseal(ctx, rec)
+}
+
+// Delete 实现 KeyStore 接口
+func (s *SealedStore) Delete(ctx context.Context, keyID string) error {
+	return s.store.Delete(ctx, keyID)
+}
+
+// List 实现 KeyStore 接口
+func (s *SealedStore) List(ctx context.Context, stream string) ([]KeyRecord, error) {
+	records, err := s.store.List(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+	for i, rec := range records {
+		if records[i], err = s.unseal(ctx, rec); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// unseal 解封记录中的密钥
+func (s *SealedStore) unseal(ctx context.Context, rec KeyRecord) (KeyRecord, error) {
+	if len(rec.WrappedKey) == 0 {
+		return KeyRecord{}, fmt.Errorf("密钥 %s 未封装", rec.KeyID)
+	}
+	key, err := s.provider.Decrypt(ctx, rec.WrappedKey)
+	if err != nil {
+		return KeyRecord{}, fmt.Errorf("解封密钥 %s 失败: %w", rec.KeyID, err)
+	}
+	rec.Key = key
+	return rec, nil
+}
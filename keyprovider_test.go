@@ -0,0 +1,98 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"testing"
+)
+
+// fakeProvider 以本地 KEK 模拟 KeyProvider
+type fakeProvider struct {
+	kek []byte
+}
+
+func newFakeProvider() *fakeProvider {
+	return &fakeProvider{kek: bytes.Repeat([]byte{0x42}, 32)}
+}
+
+func (p *fakeProvider) GenerateDataKey(ctx context.Context, size int) ([]byte, []byte, error) {
+	key := make([]byte, size)
+	rand.Read(key)
+	wrapped, err := p.Encrypt(ctx, key)
+	return key, wrapped, err
+}
+
+func (p *fakeProvider) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	return gcmSeal(p.kek, plaintext)
+}
+
+func (p *fakeProvider) Decrypt(ctx context.Context, wrapped []byte) ([]byte, error) {
+	return gcmOpen(p.kek, wrapped)
+}
+
+func TestKeyProvider(t *testing.T) {
+	ctx := context.Background()
+	p := newFakeProvider()
+
+	k, err := NewKeyInfoFromProvider(ctx, p, "https://example.com/key", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建KeyInfo失败: %v", err)
+	}
+	defer k.Dispose()
+	wrapped := k.WrappedKey()
+	if len(wrapped) == 0 {
+		t.Fatal("应返回封装后的密钥")
+	}
+
+	restored, err := NewKeyInfoFromProviderWrapped(ctx, p, "https://example.com/key", wrapped, WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("恢复密钥失败: %v", err)
+	}
+	defer restored.Dispose()
+	if !bytes.Equal(restored.GetKey(), k.GetKey()) || restored.KeyID != k.KeyID {
+		t.Error("恢复的密钥与原密钥不一致")
+	}
+
+	// 替换密钥后原密文失效
+	if err := restored.SetKey(bytes.Repeat([]byte{1}, 16)); err != nil {
+		t.Fatalf("设置密钥失败: %v", err)
+	}
+	if restored.WrappedKey() != nil {
+		t.Error("SetKey 后应清除封装的密钥")
+	}
+}
+
+func TestRotatorKeyProvider(t *testing.T) {
+	ctx := context.Background()
+	p := newFakeProvider()
+	backend := NewMemoryStore()
+	store := NewSealedStore(backend, p)
+	r := newTestRotator(t, 0, WithKeyProvider(p), WithKeyStore(store))
+
+	next, err := r.Rotate()
+	if err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+	key, err := p.Decrypt(ctx, next.WrappedKey())
+	if err != nil || !bytes.Equal(key, next.GetKey()) {
+		t.Errorf("新密钥应由 KeyProvider 生成: %v", err)
+	}
+
+	// 底层存储只保存密文，通过 SealedStore 读取时自动解封
+	raw, err := backend.Get(ctx, next.KeyID)
+	if err != nil {
+		t.Fatalf("读取密钥记录失败: %v", err)
+	}
+	if len(raw.Key) != 0 || len(raw.WrappedKey) == 0 {
+		t.Error("底层存储不应保存明文密钥")
+	}
+	rec, err := store.Get(ctx, next.KeyID)
+	if err != nil || !bytes.Equal(rec.Key, next.GetKey()) {
+		t.Errorf("解封的密钥不正确: %v", err)
+	}
+}
+
+func TestSealedStore(t *testing.T) {
+	testKeyStore(t, NewSealedStore(NewMemoryStore(), newFakeProvider()))
+}
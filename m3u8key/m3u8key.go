@@ -0,0 +1,78 @@
+// Package m3u8key 在 hlskeyinfo.KeyInfo 与 github.com/grafov/m3u8 的 Key 之间转换，
+// 便于已使用该库生成播放列表的应用直接使用本包的密钥
+package m3u8key
+
+import (
+	"strings"
+
+	"github.com/grafov/m3u8"
+	hlskeyinfo "github.com/ixugo/hls_keyinfo"
+)
+
+// FromTag 将密钥标签转换为 m3u8.Key，IV 带 0x 前缀
+func FromTag(t hlskeyinfo.KeyTag) *m3u8.Key {
+	key := &m3u8.Key{
+		Method:            t.Method,
+		URI:               t.URI,
+		Keyformat:         t.KeyFormat,
+		Keyformatversions: t.KeyFormatVersions,
+	}
+	if t.IV != "" {
+		key.IV = "0x" + t.IV
+	}
+	return key
+}
+
+// ToTag 将 m3u8.Key 转换为密钥标签
+func ToTag(key *m3u8.Key) hlskeyinfo.KeyTag {
+	iv := strings.TrimSpace(key.IV)
+	if len(iv) >= 2 && (iv[:2] == "0x" || iv[:2] == "0X") {
+		iv = iv[2:]
+	}
+	return hlskeyinfo.KeyTag{
+		Method:            key.Method,
+		URI:               key.URI,
+		IV:                iv,
+		KeyFormat:         key.Keyformat,
+		KeyFormatVersions: key.Keyformatversions,
+	}
+}
+
+// Key 返回该密钥对应的 m3u8.Key，与 KeyInfo.ExtXKey 生成的标签一致
+func Key(k *hlskeyinfo.KeyInfo) *m3u8.Key {
+	return FromTag(k.KeyTag())
+}
+
+// SetDefaultKey 将该密钥设为播放列表头部的 EXT-X-KEY，适用于密钥不变的点播
+func SetDefaultKey(p *m3u8.MediaPlaylist, k *hlskeyinfo.KeyInfo) error {
+	key := Key(k)
+	return p.SetDefaultKey(key.Method, key.URI, key.IV, key.Keyformat, key.Keyformatversions)
+}
+
+// SetKey 为播放列表最后一个分片设置 EXT-X-KEY，表示自该分片起使用该密钥，如密钥轮换后
+func SetKey(p *m3u8.MediaPlaylist, k *hlskeyinfo.KeyInfo) error {
+	key := Key(k)
+	return p.SetKey(key.Method, key.URI, key.IV, key.Keyformat, key.Keyformatversions)
+}
+
+// KeyInfo 使用 m3u8.Key 中的URL、IV 与加密方式以及外部提供的密钥创建KeyInfo实例，
+// 适用于从已有播放列表恢复密钥；未指定 IV 时按媒体序列号计算
+func KeyInfo(key *m3u8.Key, secret []byte, opts ...hlskeyinfo.Option) (*hlskeyinfo.KeyInfo, error) {
+	t := ToTag(key)
+	if t.Method != "" {
+		opts = append([]hlskeyinfo.Option{hlskeyinfo.WithMethod(t.Method)}, opts...)
+	}
+	k, err := hlskeyinfo.NewKeyInfoWithKey(t.URI, secret, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if t.IV == "" {
+		k.UseSequenceIV()
+		return k, nil
+	}
+	if err := k.SetIVStrict("0x" + t.IV); err != nil {
+		k.Dispose()
+		return nil, err
+	}
+	return k, nil
+}
@@ -0,0 +1,47 @@
+package m3u8key
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/grafov/m3u8"
+	hlskeyinfo "github.com/ixugo/hls_keyinfo"
+)
+
+func TestSetKey(t *testing.T) {
+	k, err := hlskeyinfo.NewKeyInfoWithKey("https://keys.example.com/1", bytes.Repeat([]byte{1}, 16), hlskeyinfo.WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+	defer k.Dispose()
+	k.SetIV("0x0123456789abcdef0123456789abcdef")
+
+	p, err := m3u8.NewMediaPlaylist(0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetDefaultKey(p, k); err != nil {
+		t.Fatalf("设置默认密钥失败: %v", err)
+	}
+	p.Append("seg0.ts", 4, "")
+	p.Close()
+	if !strings.Contains(p.String(), k.ExtXKey()+"\n") {
+		t.Errorf("生成的播放列表应包含与 ExtXKey 一致的标签:\n%s", p.String())
+	}
+
+	// 从 m3u8.Key 恢复 KeyInfo
+	restored, err := KeyInfo(p.Key, k.GetKey(), hlskeyinfo.WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("恢复失败: %v", err)
+	}
+	defer restored.Dispose()
+	if restored.ExtXKey() != k.ExtXKey() || !bytes.Equal(restored.GetKey(), k.GetKey()) {
+		t.Errorf("恢复的密钥不一致: %s", restored.ExtXKey())
+	}
+
+	tag := ToTag(&m3u8.Key{Method: "SAMPLE-AES", URI: "skd://a", IV: "0X00", Keyformat: "com.apple.streamingkeydelivery"})
+	if tag.IV != "00" || *FromTag(tag) != (m3u8.Key{Method: "SAMPLE-AES", URI: "skd://a", IV: "0x00", Keyformat: "com.apple.streamingkeydelivery"}) {
+		t.Errorf("标签转换不正确: %+v", tag)
+	}
+}
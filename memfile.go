@@ -0,0 +1,68 @@
+package hlskeyinfo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrMemoryKeyFileUnsupported 当前平台不支持内存密钥文件
+var ErrMemoryKeyFileUnsupported = errors.New("当前平台不支持内存密钥文件")
+
+// WithMemoryKeyFile 使用内存作为密钥文件的存储，原始密钥不会写入持久化存储
+// Linux 上优先使用 memfd_create，密钥文件路径形如 /proc/<pid>/fd/<fd>，ffmpeg 需以相同用户运行才能读取；
+// memfd 不可用时回退到自动检测的 tmpfs（/dev/shm），其他平台创建时返回 ErrMemoryKeyFileUnsupported
+func WithMemoryKeyFile() Option {
+	return func(k *KeyInfo) {
+		k.memoryKeyFile = true
+	}
+}
+
+// memFileCreator 创建内存文件的函数，测试中可替换以模拟 memfd 不可用
+var memFileCreator = createMemFile
+
+// writeMemoryKeyFile 创建或重写内存密钥文件
+func (k *KeyInfo) writeMemoryKeyFile() error {
+	if k.memFile != nil {
+		if err := k.memFile.Truncate(0); err != nil {
+			return fmt.Errorf("写入密钥文件失败: %w", err)
+		}
+		if _, err := k.memFile.WriteAt(k.key, 0); err != nil {
+			return fmt.Errorf("写入密钥文件失败: %w", err)
+		}
+		return nil
+	}
+
+	f, path, err := memFileCreator(k.fileMode)
+	if errors.Is(err, errMemfdUnavailable) {
+		// 回退到 tmpfs，按普通临时文件处理
+		dir, ok := tmpfsDir()
+		if !ok {
+			return ErrMemoryKeyFileUnsupported
+		}
+		k.tempDir = dir
+		k.memoryKeyFile = false
+		// 由外层 writeKeyFile 统一审计，此处只写入文件
+		return k.storeKeyFile()
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(k.key); err != nil {
+		f.Close()
+		return fmt.Errorf("写入密钥文件失败: %w", err)
+	}
+	k.memFile = f
+	k.KeyFile = path
+	return nil
+}
+
+// closeMemoryKeyFile 关闭内存密钥文件，关闭后内容即被释放
+func (k *KeyInfo) closeMemoryKeyFile() error {
+	err := k.memFile.Close()
+	k.memFile = nil
+	if err != nil && !errors.Is(err, os.ErrClosed) {
+		return fmt.Errorf("关闭内存密钥文件失败: %w", err)
+	}
+	return nil
+}
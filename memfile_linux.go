@@ -0,0 +1,41 @@
+package hlskeyinfo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// errMemfdUnavailable 内核不支持 memfd_create
+var errMemfdUnavailable = errors.New("memfd_create 不可用")
+
+// createMemFile 使用 memfd_create 创建匿名内存文件，返回其他进程可访问的 /proc 路径
+func createMemFile(mode os.FileMode) (*os.File, string, error) {
+	fd, err := unix.MemfdCreate("hls_key", unix.MFD_CLOEXEC)
+	if err != nil {
+		if errors.Is(err, unix.ENOSYS) {
+			return nil, "", errMemfdUnavailable
+		}
+		return nil, "", fmt.Errorf("创建内存密钥文件失败: %w", err)
+	}
+	if err := unix.Fchmod(fd, uint32(mode.Perm())); err != nil {
+		unix.Close(fd)
+		return nil, "", fmt.Errorf("设置密钥文件权限失败: %w", err)
+	}
+
+	// /proc/self 对 ffmpeg 而言指向 ffmpeg 自身，因此使用当前进程 pid
+	path := fmt.Sprintf("/proc/%d/fd/%d", os.Getpid(), fd)
+	return os.NewFile(uintptr(fd), path), path, nil
+}
+
+// tmpfsDir 检测 /dev/shm 是否为 tmpfs
+func tmpfsDir() (string, bool) {
+	const dir = "/dev/shm"
+	var st unix.Statfs_t
+	if err := unix.Statfs(dir, &st); err != nil {
+		return "", false
+	}
+	return dir, st.Type == unix.TMPFS_MAGIC
+}
@@ -0,0 +1,75 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWithMemoryKeyFile(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithMemoryKeyFile())
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	if !strings.HasPrefix(k.KeyFile, "/proc/") && !strings.HasPrefix(k.KeyFile, "/dev/shm/") {
+		t.Errorf("密钥文件应位于内存中，实际: %s", k.KeyFile)
+	}
+	content, err := os.ReadFile(k.KeyFile)
+	if err != nil {
+		t.Fatalf("读取密钥文件失败: %v", err)
+	}
+	if !bytes.Equal(content, k.GetKey()) {
+		t.Error("密钥文件内容与密钥不一致")
+	}
+
+	// 替换密钥后内存文件同步更新
+	key := bytes.Repeat([]byte{0x33}, 16)
+	if err := k.SetKey(key); err != nil {
+		t.Fatalf("SetKey 失败: %v", err)
+	}
+	content, _ = os.ReadFile(k.KeyFile)
+	if !bytes.Equal(content, key) {
+		t.Error("SetKey 后内存密钥文件未更新")
+	}
+
+	keyFile := k.KeyFile
+	if err := k.Dispose(); err != nil {
+		t.Fatalf("Dispose 失败: %v", err)
+	}
+	if _, err := os.Stat(keyFile); !os.IsNotExist(err) {
+		t.Error("Dispose 后内存密钥文件应不可访问")
+	}
+}
+
+func TestWithMemoryKeyFileTmpfsFallback(t *testing.T) {
+	if _, ok := tmpfsDir(); !ok {
+		t.Skip("/dev/shm 不是 tmpfs")
+	}
+	memFileCreator = func(os.FileMode) (*os.File, string, error) {
+		return nil, "", errMemfdUnavailable
+	}
+	defer func() { memFileCreator = createMemFile }()
+
+	a := &recordingAuditor{}
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithMemoryKeyFile(), WithAuditor(a))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	if !strings.HasPrefix(k.KeyFile, "/dev/shm/") {
+		t.Errorf("memfd 不可用时密钥文件应位于 /dev/shm，实际: %s", k.KeyFile)
+	}
+	var written int
+	for _, ev := range a.events() {
+		if ev == AuditFileWritten {
+			written++
+		}
+	}
+	if written != 1 {
+		t.Errorf("回退到 tmpfs 时应只记录一次 %s 事件，实际 %d 次", AuditFileWritten, written)
+	}
+}
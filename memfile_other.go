@@ -0,0 +1,21 @@
+//go:build !linux
+
+package hlskeyinfo
+
+import (
+	"errors"
+	"os"
+)
+
+// errMemfdUnavailable 非 Linux 平台没有 memfd_create
+var errMemfdUnavailable = errors.New("memfd_create 不可用")
+
+// createMemFile 非 Linux 平台不支持内存文件
+func createMemFile(os.FileMode) (*os.File, string, error) {
+	return nil, "", errMemfdUnavailable
+}
+
+// tmpfsDir 非 Linux 平台不做 tmpfs 检测
+func tmpfsDir() (string, bool) {
+	return "", false
+}
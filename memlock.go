@@ -0,0 +1,54 @@
+package hlskeyinfo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLockedMemoryUnsupported 当前平台不支持锁定内存
+var ErrLockedMemoryUnsupported = errors.New("当前平台不支持锁定内存")
+
+// WithLockedMemory 将持有的密钥保存在锁定的内存页中（Unix 使用 mlock，Windows 使用 VirtualLock），内存紧张时不会被换出到磁盘
+// 锁定内存不受 GC 管理，密钥变更与 Dispose 时清零并释放，因此 Dispose 后不再持有密钥；
+// GetKey 等返回的副本位于普通内存。锁定失败（如超出 RLIMIT_MEMLOCK）时创建返回错误，其他平台返回 ErrLockedMemoryUnsupported
+func WithLockedMemory() Option {
+	return func(k *KeyInfo) {
+		k.lockedMemory = true
+	}
+}
+
+// setKey 保存密钥，启用锁定内存时复制到新的锁定内存并清零 key，原有锁定内存随即释放
+func (k *KeyInfo) setKey(key []byte) error {
+	if !k.lockedMemory {
+		k.key = key
+		return nil
+	}
+	buf, err := lockedAlloc(len(key))
+	if err != nil {
+		return fmt.Errorf("锁定密钥内存失败: %w", err)
+	}
+	copy(buf, key)
+	clear(key)
+	if err := k.releaseKey(); err != nil {
+		lockedFree(buf)
+		return err
+	}
+	k.lockedKey = buf
+	k.key = buf[:len(key)]
+	return nil
+}
+
+// releaseKey 清零并释放锁定内存中的密钥，未启用锁定内存时不做处理
+func (k *KeyInfo) releaseKey() error {
+	if k.lockedKey == nil {
+		return nil
+	}
+	clear(k.lockedKey)
+	err := lockedFree(k.lockedKey)
+	k.lockedKey = nil
+	k.key = nil
+	if err != nil {
+		return fmt.Errorf("释放锁定内存失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd || openbsd || netbsd || dragonfly || windows)
+
+package hlskeyinfo
+
+// lockedAlloc 当前平台不支持锁定内存
+func lockedAlloc(int) ([]byte, error) {
+	return nil, ErrLockedMemoryUnsupported
+}
+
+// lockedFree 当前平台不支持锁定内存
+func lockedFree([]byte) error {
+	return ErrLockedMemoryUnsupported
+}
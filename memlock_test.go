@@ -0,0 +1,47 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWithLockedMemory(t *testing.T) {
+	k, err := NewKeyInfo("https://keys.example.com/key", WithTempDir(t.TempDir()), WithLockedMemory())
+	if errors.Is(err, ErrLockedMemoryUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+	if k.lockedKey == nil || len(k.GetKey()) != DefaultKeySize {
+		t.Fatal("密钥应保存在锁定内存中")
+	}
+
+	// 替换密钥后换用新的锁定内存，传入的密钥不受影响
+	key := bytes.Repeat([]byte{0x44}, 16)
+	if err := k.SetKey(key); err != nil {
+		t.Fatalf("SetKey 失败: %v", err)
+	}
+	if !bytes.Equal(k.GetKey(), key) || !bytes.Equal(key, bytes.Repeat([]byte{0x44}, 16)) {
+		t.Error("SetKey 后密钥不正确")
+	}
+
+	// 复制的实例同样使用锁定内存
+	c, err := k.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.lockedKey == nil || !bytes.Equal(c.GetKey(), key) {
+		t.Error("Clone 应沿用锁定内存")
+	}
+	c.Dispose()
+
+	if err := k.Dispose(); err != nil {
+		t.Fatalf("Dispose 失败: %v", err)
+	}
+	if k.lockedKey != nil || k.GetKey() != nil {
+		t.Error("Dispose 后应释放锁定内存")
+	}
+}
@@ -0,0 +1,27 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly
+
+package hlskeyinfo
+
+import "golang.org/x/sys/unix"
+
+// lockedAlloc 以匿名映射分配 size 字节并使用 mlock 锁定
+func lockedAlloc(size int) ([]byte, error) {
+	b, err := unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.Mlock(b); err != nil {
+		unix.Munmap(b)
+		return nil, err
+	}
+	return b, nil
+}
+
+// lockedFree 解除锁定并释放 lockedAlloc 分配的内存
+func lockedFree(b []byte) error {
+	if err := unix.Munlock(b); err != nil {
+		unix.Munmap(b)
+		return err
+	}
+	return unix.Munmap(b)
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package hlskeyinfo
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockedAlloc 以 VirtualAlloc 分配 size 字节并使用 VirtualLock 锁定
+func lockedAlloc(size int) ([]byte, error) {
+	addr, err := windows.VirtualAlloc(0, uintptr(size), windows.MEM_COMMIT|windows.MEM_RESERVE, windows.PAGE_READWRITE)
+	if err != nil {
+		return nil, err
+	}
+	if err := windows.VirtualLock(addr, uintptr(size)); err != nil {
+		windows.VirtualFree(addr, 0, windows.MEM_RELEASE)
+		return nil, err
+	}
+	return unsafe.Slice(*(**byte)(unsafe.Pointer(&addr)), size), nil
+}
+
+// lockedFree 解除锁定并释放 lockedAlloc 分配的内存
+func lockedFree(b []byte) error {
+	addr := uintptr(unsafe.Pointer(unsafe.SliceData(b)))
+	if err := windows.VirtualUnlock(addr, uintptr(len(b))); err != nil {
+		windows.VirtualFree(addr, 0, windows.MEM_RELEASE)
+		return err
+	}
+	return windows.VirtualFree(addr, 0, windows.MEM_RELEASE)
+}
@@ -0,0 +1,52 @@
+package hlskeyinfo
+
+import (
+	"fmt"
+)
+
+// HLS 加密方式，对应 EXT-X-KEY 的 METHOD 属性
+const (
+	MethodAES128    = "AES-128"    // 整个分片以 AES-128-CBC 加密，默认
+	MethodSampleAES = "SAMPLE-AES" // 仅加密音视频样本，FairPlay 与部分电视平台要求使用
+)
+
+// WithMethod 设置加密方式，默认 MethodAES128
+// SAMPLE-AES 需要在音视频样本层加密，ffmpeg 的 hls 复用器不支持，可使用 EncryptSampleAES 或 EncryptCMAFSegment
+func WithMethod(method string) Option {
+	return func(k *KeyInfo) {
+		k.Method = method
+	}
+}
+
+// method 返回加密方式，未设置时为 AES-128
+func (k *KeyInfo) method() string {
+	if k.Method == "" {
+		return MethodAES128
+	}
+	return k.Method
+}
+
+// validateMethod 校验加密方式
+func validateMethod(method string) error {
+	switch method {
+	case "", MethodAES128, MethodSampleAES:
+		return nil
+	}
+	return fmt.Errorf("不支持的加密方式: %s", method)
+}
+
+// requireAES128 检查加密方式是否为 AES-128，ffmpeg 与整分片加密仅支持该方式
+func (k *KeyInfo) requireAES128() error {
+	if m := k.method(); m != MethodAES128 {
+		return fmt.Errorf("不支持加密方式 %s，仅支持 %s", m, MethodAES128)
+	}
+	return nil
+}
+
+// requireSampleAES 检查加密方式是否为 SAMPLE-AES，样本级加密仅支持该方式
+func (k *KeyInfo) requireSampleAES() error {
+	if m := k.method(); m != MethodSampleAES {
+		return fmt.Errorf("样本级加密需要加密方式 %s，实际: %s", MethodSampleAES, m)
+	}
+	return nil
+}
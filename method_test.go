@@ -0,0 +1,50 @@
+package hlskeyinfo
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMethod(t *testing.T) {
+	k, err := NewKeyInfo("skd://key-1", WithTempDir(t.TempDir()), WithMethod(MethodSampleAES))
+	if err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+	defer k.Dispose()
+
+	if got := k.ExtXKey(); !strings.HasPrefix(got, "#EXT-X-KEY:METHOD=SAMPLE-AES,") {
+		t.Errorf("EXT-X-KEY 应使用 SAMPLE-AES: %s", got)
+	}
+	if _, err := k.FFmpegArgs(FFmpegOptions{Output: "out.m3u8"}); !errors.Is(err, ErrFFmpegUnsupported) {
+		t.Errorf("ffmpeg 不支持 SAMPLE-AES，应返回 ErrFFmpegUnsupported: %v", err)
+	}
+	if err := k.EncryptSegment(io.Discard, strings.NewReader("segment"), 0); err == nil {
+		t.Error("SAMPLE-AES 不应按整分片加密")
+	}
+
+	data, err := json.Marshal(k)
+	if err != nil {
+		t.Fatalf("序列化失败: %v", err)
+	}
+	var restored KeyInfo
+	if err := json.Unmarshal(data, &restored); err != nil || restored.Method != MethodSampleAES {
+		t.Errorf("JSON 应保留加密方式: %q, %v", restored.Method, err)
+	}
+	if c, err := k.Clone(WithTempDir(t.TempDir())); err != nil || c.Method != MethodSampleAES {
+		t.Errorf("Clone 应保留加密方式: %v", err)
+	} else {
+		c.Dispose()
+	}
+	if err := json.Unmarshal([]byte(`{"url":"a","method":"AES-256"}`), &restored); err == nil {
+		t.Error("不支持的加密方式应返回错误")
+	}
+
+	// 未设置时默认 AES-128
+	k = &KeyInfo{URL: "https://example.com/key"}
+	if got := k.ExtXKey(); !strings.HasPrefix(got, "#EXT-X-KEY:METHOD=AES-128,") {
+		t.Errorf("默认应为 AES-128: %s", got)
+	}
+}
@@ -0,0 +1,142 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// mp4Prefix 容器 box 在子 box 之前的固定字段长度，不在表中的 box 视为叶子
+// 样本描述条目按 ISO/IEC 14496-12 的 VisualSampleEntry 与 AudioSampleEntry（版本 0）计算
+var mp4Prefix = map[string]int{
+	"moov": 0, "trak": 0, "mdia": 0, "minf": 0, "stbl": 0, "mvex": 0,
+	"moof": 0, "traf": 0, "sinf": 0, "schi": 0,
+	"stsd": 8,
+	"avc1": 78, "avc3": 78, "hvc1": 78, "hev1": 78, "encv": 78,
+	"mp4a": 28, "ac-3": 28, "ec-3": 28, "enca": 28,
+}
+
+// mp4Box 解析后的 ISO BMFF box
+type mp4Box struct {
+	typ      string
+	payload  []byte    // 叶子 box 的内容，或容器 box 子 box 之前的固定字段
+	children []*mp4Box // 容器 box 的子 box
+}
+
+// parseMP4Boxes 解析连续的 box，容器 box 递归解析
+func parseMP4Boxes(data []byte) ([]*mp4Box, error) {
+	var boxes []*mp4Box
+	for len(data) > 0 {
+		typ, header, size, err := nextMP4Box(data)
+		if err != nil {
+			return nil, err
+		}
+		b, err := newMP4Box(typ, data[header:size])
+		if err != nil {
+			return nil, err
+		}
+		boxes = append(boxes, b)
+		data = data[size:]
+	}
+	return boxes, nil
+}
+
+// nextMP4Box 读取 data 开头 box 的类型、头长度与总长度
+func nextMP4Box(data []byte) (typ string, header, size int, err error) {
+	if len(data) < 8 {
+		return "", 0, 0, fmt.Errorf("box 头不完整")
+	}
+	n := uint64(binary.BigEndian.Uint32(data))
+	typ, header = string(data[4:8]), 8
+	switch n {
+	case 0:
+		n = uint64(len(data))
+	case 1:
+		if len(data) < 16 {
+			return "", 0, 0, fmt.Errorf("box %s 头不完整", typ)
+		}
+		n, header = binary.BigEndian.Uint64(data[8:]), 16
+	}
+	if n < uint64(header) || n > uint64(len(data)) {
+		return "", 0, 0, fmt.Errorf("box %s 长度无效: %d", typ, n)
+	}
+	return typ, header, int(n), nil
+}
+
+// newMP4Box 按类型创建 box，容器 box 解析其子 box
+func newMP4Box(typ string, body []byte) (*mp4Box, error) {
+	prefix, ok := mp4Prefix[typ]
+	if !ok {
+		return &mp4Box{typ: typ, payload: body}, nil
+	}
+	if len(body) < prefix {
+		return nil, fmt.Errorf("box %s 长度不足", typ)
+	}
+	children, err := parseMP4Boxes(body[prefix:])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", typ, err)
+	}
+	return &mp4Box{typ: typ, payload: body[:prefix], children: children}, nil
+}
+
+// size 返回序列化后的长度
+func (b *mp4Box) size() int {
+	n := 8 + len(b.payload)
+	for _, c := range b.children {
+		n += c.size()
+	}
+	return n
+}
+
+// child 返回第一个指定类型的子 box
+func (b *mp4Box) child(typ string) *mp4Box {
+	for _, c := range b.children {
+		if c.typ == typ {
+			return c
+		}
+	}
+	return nil
+}
+
+// path 按类型逐级查找子 box
+func (b *mp4Box) path(types ...string) *mp4Box {
+	for _, t := range types {
+		if b = b.child(t); b == nil {
+			return nil
+		}
+	}
+	return b
+}
+
+// appendTo 将 box 序列化追加到 buf
+func (b *mp4Box) appendTo(buf *bytes.Buffer) error {
+	size := b.size()
+	if size > math.MaxUint32 {
+		return fmt.Errorf("box %s 过大", b.typ)
+	}
+	buf.Write(binary.BigEndian.AppendUint32(nil, uint32(size)))
+	buf.WriteString(b.typ)
+	buf.Write(b.payload)
+	for _, c := range b.children {
+		if err := c.appendTo(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fullBox 创建 FullBox 叶子，payload 为 version 与 flags 之后的内容
+func fullBox(typ string, version uint8, flags uint32, payload []byte) *mp4Box {
+	head := binary.BigEndian.AppendUint32(nil, uint32(version)<<24|flags&0xffffff)
+	return &mp4Box{typ: typ, payload: append(head, payload...)}
+}
+
+// boxFlags 返回 FullBox 的 version 与 flags
+func boxFlags(b *mp4Box) (uint8, uint32) {
+	if len(b.payload) < 4 {
+		return 0, 0
+	}
+	v := binary.BigEndian.Uint32(b.payload)
+	return uint8(v >> 24), v & 0xffffff
+}
@@ -0,0 +1,72 @@
+package hlskeyinfo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MultiDRM 同一内容密钥需要输出的 DRM 系统，未设置的系统不输出
+// URL 支持与密钥获取URL相同的占位符，按该密钥展开
+type MultiDRM struct {
+	FallbackURL string         // identity 回退的密钥获取URL，如 KeyServer 地址，为空时不输出
+	FairPlayURL string         // FairPlay 的 skd:// 地址，如 skd://{kid}，为空时不输出
+	Widevine    *WidevinePSSH  // Widevine 的 pssh 数据，为 nil 时不输出
+	PlayReady   *PlayReadyPSSH // PlayReady 的 PRO 数据，为 nil 时不输出
+	Session     bool           // 生成主播放列表的 EXT-X-SESSION-KEY
+}
+
+// MultiDRMKeyTags 为同一内容密钥生成所有启用系统的密钥标签，依次为 FairPlay、Widevine、PlayReady 与 identity 回退
+// 支持 KEYFORMAT 的播放器按其选择标签；不识别 KEYFORMAT 的旧播放器以最后一个 EXT-X-KEY 为准，因此回退标签放在最后
+// DRM 系统要求 SAMPLE-AES，回退标签与其使用相同的加密方式
+func (k *KeyInfo) MultiDRMKeyTags(m MultiDRM) ([]KeyTag, error) {
+	var tags []KeyTag
+	if m.FairPlayURL != "" {
+		if err := k.requireSampleAES(); err != nil {
+			return nil, fmt.Errorf("FairPlay: %w", err)
+		}
+		uri := k.expandURL(m.FairPlayURL)
+		if !strings.HasPrefix(uri, fairPlayScheme) {
+			return nil, fmt.Errorf("FairPlay 密钥获取URL需以 %s 开头: %s", fairPlayScheme, uri)
+		}
+		tags = append(tags, k.drmKeyTag(KeyFormatFairPlay, uri))
+	}
+	if m.Widevine != nil {
+		t, err := k.WidevineKeyTag(*m.Widevine)
+		if err != nil {
+			return nil, fmt.Errorf("Widevine: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	if m.PlayReady != nil {
+		t, err := k.PlayReadyKeyTag(*m.PlayReady)
+		if err != nil {
+			return nil, fmt.Errorf("PlayReady: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	if m.FallbackURL != "" {
+		t := k.drmKeyTag("", k.expandURL(m.FallbackURL))
+		t.KeyFormatVersions = ""
+		tags = append(tags, t)
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("未启用任何 DRM 系统")
+	}
+	for i := range tags {
+		tags[i].Session = m.Session
+	}
+	return tags, nil
+}
+
+// MultiDRMExtXKey 返回 MultiDRMKeyTags 生成的标签行，以换行分隔，可直接写入播放列表
+func (k *KeyInfo) MultiDRMExtXKey(m MultiDRM) (string, error) {
+	tags, err := k.MultiDRMKeyTags(m)
+	if err != nil {
+		return "", err
+	}
+	lines := make([]string, len(tags))
+	for i, t := range tags {
+		lines[i] = t.String()
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,81 @@
+package hlskeyinfo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMultiDRMKeyTags(t *testing.T) {
+	k, err := NewKeyInfo("https://keys.example.com/key", WithTempDir(t.TempDir()), WithMethod(MethodSampleAES))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Dispose()
+
+	m := MultiDRM{
+		FallbackURL: "https://keys.example.com/{keyID}",
+		FairPlayURL: "skd://{kid}",
+		Widevine:    &WidevinePSSH{Provider: "example"},
+		PlayReady:   &PlayReadyPSSH{LicenseURL: "https://pr.example.com/rightsmanager.asmx"},
+	}
+	tags, err := k.MultiDRMKeyTags(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formats := []string{KeyFormatFairPlay, KeyFormatWidevine, KeyFormatPlayReady, ""}
+	if len(tags) != len(formats) {
+		t.Fatalf("标签数量不正确: %d", len(tags))
+	}
+	for i, tag := range tags {
+		if tag.KeyFormat != formats[i] || tag.Method != MethodSampleAES || tag.Session {
+			t.Errorf("第 %d 个标签不正确: %+v", i, tag)
+		}
+	}
+	kid := k.KID()
+	if tags[0].URI != "skd://"+formatUUID(kid) {
+		t.Errorf("FairPlay URI 不正确: %s", tags[0].URI)
+	}
+	if tags[3].URI != "https://keys.example.com/"+k.KeyID || tags[3].KeyFormatVersions != "" {
+		t.Errorf("回退标签不正确: %+v", tags[3])
+	}
+
+	// 主播放列表使用 EXT-X-SESSION-KEY
+	m.Session = true
+	s, err := k.MultiDRMExtXKey(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(s, "\n")
+	if len(lines) != 4 {
+		t.Fatalf("标签行数不正确: %q", s)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "#EXT-X-SESSION-KEY:") {
+			t.Errorf("应为 EXT-X-SESSION-KEY: %s", line)
+		}
+	}
+
+	if _, err := k.MultiDRMKeyTags(MultiDRM{}); err == nil {
+		t.Error("未启用任何系统时应返回错误")
+	}
+	if _, err := k.MultiDRMKeyTags(MultiDRM{FairPlayURL: "https://fps.example.com"}); err == nil {
+		t.Error("FairPlay URL 非 skd:// 时应返回错误")
+	}
+}
+
+func TestMultiDRMKeyTagsAES128(t *testing.T) {
+	k, err := NewKeyInfo("https://keys.example.com/key", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Dispose()
+
+	// AES-128 只能输出 identity 回退
+	tags, err := k.MultiDRMKeyTags(MultiDRM{FallbackURL: "https://keys.example.com/key"})
+	if err != nil || len(tags) != 1 || tags[0].Method != MethodAES128 {
+		t.Errorf("AES-128 回退标签不正确: %+v, %v", tags, err)
+	}
+	if _, err := k.MultiDRMKeyTags(MultiDRM{FallbackURL: "https://keys.example.com/key", Widevine: &WidevinePSSH{}}); err == nil {
+		t.Error("AES-128 不应输出 Widevine 标签")
+	}
+}
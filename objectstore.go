@@ -0,0 +1,73 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// 对象存储类 KeyStore（S3、GCS、Azure Blob）共用的对象布局：每个密钥一个对象，
+// 对象名为 <prefix><keyID>.json，内容为包含密钥的 KeyRecord JSON，静态加密由存储服务负责
+
+// objectSuffix 密钥对象名后缀
+const objectSuffix = ".json"
+
+// objectName 返回 KeyID 对应的对象名
+func objectName(prefix, keyID string) (string, error) {
+	if err := validatePathName(keyID); err != nil {
+		return "", err
+	}
+	return prefix + keyID + objectSuffix, nil
+}
+
+// encodeRecord 编码密钥对象
+func encodeRecord(rec KeyRecord) ([]byte, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, fmt.Errorf("序列化密钥记录失败: %w", err)
+	}
+	return data, nil
+}
+
+// decodeRecord 解码密钥对象
+func decodeRecord(data []byte) (KeyRecord, error) {
+	var rec KeyRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return KeyRecord{}, fmt.Errorf("解析密钥记录失败: %w", err)
+	}
+	return rec, nil
+}
+
+// listObjectRecords 读取列出的密钥对象并按流过滤
+func listObjectRecords(ctx context.Context, names []string, stream string, get func(ctx context.Context, name string) ([]byte, error)) ([]KeyRecord, error) {
+	var out []KeyRecord
+	for _, name := range names {
+		if !strings.HasSuffix(name, objectSuffix) {
+			continue
+		}
+		data, err := get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		rec, err := decodeRecord(data)
+		if err != nil {
+			return nil, fmt.Errorf("对象 %s: %w", name, err)
+		}
+		if stream == "" || rec.Stream == stream {
+			out = append(out, rec)
+		}
+	}
+	sortRecords(out)
+	return out, nil
+}
+
+// escapePath 按路径片段转义对象名，保留目录分隔符
+func escapePath(name string) string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,102 @@
+package hlskeyinfo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var _ io.ReaderFrom = &KeyInfo{}
+
+// ReadFrom 实现io.ReaderFrom接口，按照ffmpeg hls_key_info_file格式读取 URL、密钥文件路径与可选的 IV
+// 读取到的密钥文件视为外部文件，Dispose 时不会删除；密钥本身不会被加载
+func (k *KeyInfo) ReadFrom(r io.Reader) (n int64, err error) {
+	cr := &countingReader{r: r}
+	var lines []string
+	scanner := bufio.NewScanner(cr)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return cr.n, fmt.Errorf("读取 keyinfo 失败: %w", err)
+	}
+
+	switch {
+	case len(lines) < 2:
+		return cr.n, fmt.Errorf("keyinfo 至少需要 URL 与密钥文件路径两行，实际: %d", len(lines))
+	case len(lines) > 3:
+		return cr.n, fmt.Errorf("keyinfo 最多包含三行，实际: %d", len(lines))
+	}
+
+	k.URL = lines[0]
+	k.KeyFile = strings.TrimPrefix(lines[1], "file:")
+	k.keepKeyFile = true
+	if len(lines) == 3 {
+		if err := k.SetIVStrict(lines[2]); err != nil {
+			return cr.n, err
+		}
+	} else {
+		k.NoIV()
+	}
+	return cr.n, nil
+}
+
+// ParseKeyInfo 解析已有的 keyinfo 内容，返回的实例不包含密钥
+func ParseKeyInfo(r io.Reader, opts ...Option) (*KeyInfo, error) {
+	k := newKeyInfo("", opts)
+	if _, err := k.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// LoadKeyInfoFile 读取已有的 keyinfo 文件，loadKey 为 true 时同时加载其引用的密钥文件
+// 适用于检查、校验或接管其他系统生成的 keyinfo 文件，Dispose 不会删除这些文件
+func LoadKeyInfoFile(path string, loadKey bool, opts ...Option) (*KeyInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开 keyinfo 文件失败: %w", err)
+	}
+	defer f.Close()
+
+	k, err := ParseKeyInfo(f, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !loadKey {
+		return k, nil
+	}
+
+	key, err := os.ReadFile(k.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	if err := validateKeySize(len(key)); err != nil {
+		return nil, err
+	}
+	if err := k.setKey(key); err != nil {
+		return nil, err
+	}
+	k.keySize = len(key)
+	k.keyChanged()
+	k.audit(AuditKeyLoaded, k.KeyFile)
+	return k, nil
+}
+
+// countingReader 统计已读取的字节数
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,70 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseKeyInfo(t *testing.T) {
+	k, err := ParseKeyInfo(strings.NewReader("http://localhost:4123/keyinfo\r\n/tmp/key.bin\r\n0xabcdef1234567890abcdef1234567890\r\n"))
+	if err != nil {
+		t.Fatalf("ParseKeyInfo 失败: %v", err)
+	}
+	if k.URL != "http://localhost:4123/keyinfo" || k.KeyFile != "/tmp/key.bin" {
+		t.Errorf("解析结果不匹配: %s, %s", k.URL, k.KeyFile)
+	}
+	if k.IV != "abcdef1234567890abcdef1234567890" {
+		t.Errorf("IV 不匹配，实际: %s", k.IV)
+	}
+
+	// 两行格式表示无 IV
+	k, err = ParseKeyInfo(strings.NewReader("http://localhost:4123/keyinfo\n/tmp/key.bin\n"))
+	if err != nil {
+		t.Fatalf("ParseKeyInfo 失败: %v", err)
+	}
+	if k.HasIV() {
+		t.Error("两行 keyinfo 不应包含 IV")
+	}
+
+	for _, bad := range []string{"", "only-url\n", "a\nb\nc\nd\n", "a\nb\nnot-hex\n"} {
+		if _, err := ParseKeyInfo(strings.NewReader(bad)); err == nil {
+			t.Errorf("非法 keyinfo %q 应返回错误", bad)
+		}
+	}
+}
+
+func TestLoadKeyInfoFile(t *testing.T) {
+	src, err := NewKeyInfo("http://localhost:4123/keyinfo", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer src.Dispose()
+	src.RandIV()
+	infoFile, err := src.WriteToTempFile()
+	if err != nil {
+		t.Fatalf("WriteToTempFile 失败: %v", err)
+	}
+
+	k, err := LoadKeyInfoFile(infoFile, true)
+	if err != nil {
+		t.Fatalf("LoadKeyInfoFile 失败: %v", err)
+	}
+	if !bytes.Equal(k.GetKey(), src.GetKey()) || k.IV != src.IV {
+		t.Error("加载的密钥或 IV 与原实例不一致")
+	}
+
+	// 接管的文件在 Dispose 后保留
+	if err := k.Dispose(); err != nil {
+		t.Fatalf("Dispose 失败: %v", err)
+	}
+	if _, err := os.Stat(src.KeyFile); err != nil {
+		t.Error("Dispose 不应删除接管的密钥文件")
+	}
+
+	if _, err := LoadKeyInfoFile(filepath.Join(t.TempDir(), "missing.txt"), false); err == nil {
+		t.Error("不存在的文件应返回错误")
+	}
+}
@@ -0,0 +1,254 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// PipelineState 转码任务状态
+type PipelineState int
+
+const (
+	PipelineIdle    PipelineState = iota // 未启动
+	PipelineRunning                      // ffmpeg 运行中
+	PipelineStopped                      // 调用 Stop 或 ffmpeg 正常退出
+	PipelineFailed                       // ffmpeg 异常退出或启动失败
+)
+
+// String 实现 fmt.Stringer 接口
+func (s PipelineState) String() string {
+	switch s {
+	case PipelineIdle:
+		return "idle"
+	case PipelineRunning:
+		return "running"
+	case PipelineStopped:
+		return "stopped"
+	case PipelineFailed:
+		return "failed"
+	}
+	return fmt.Sprintf("PipelineState(%d)", int(s))
+}
+
+// PipelineStatus 转码任务状态快照
+type PipelineStatus struct {
+	State      PipelineState
+	KeyID      string    // 当前密钥 KeyID
+	KeyVersion int       // 当前密钥版本号
+	StartedAt  time.Time // 启动时间
+	StoppedAt  time.Time // 退出时间，运行中为零值
+	LastLog    string    // ffmpeg 最后一行输出
+	Err        error     // 异常退出的原因
+}
+
+// Pipeline 由一个输入地址生成加密 HLS 流：持有密钥、轮换器、ffmpeg 进程与密钥服务
+// 只能启动一次，ffmpeg 退出后密钥被清理；需要在停止后继续提供密钥时使用 WithKeyHistory 或 WithKeyStore 持久化
+type Pipeline struct {
+	rotator *Rotator
+	server  *KeyServer
+	command *FFmpegCommand
+	onLog   func(line string)
+
+	mu      sync.Mutex
+	status  PipelineStatus
+	started bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// pipelineConfig NewPipeline 的配置
+type pipelineConfig struct {
+	interval   time.Duration
+	ffmpeg     FFmpegOptions
+	ffmpegPath string
+	keyOpts    []Option
+	rotateOpts []RotatorOption
+	serverOpts []ServerOption
+	onLog      func(line string)
+}
+
+// PipelineOption 转码任务创建选项
+type PipelineOption func(*pipelineConfig)
+
+// WithPipelineInterval 设置密钥轮换间隔，默认 1 小时，为 0 时不自动轮换
+func WithPipelineInterval(d time.Duration) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.interval = d
+	}
+}
+
+// WithPipelineFFmpeg 设置 ffmpeg 输出参数，Input 与 Output 由 NewPipeline 的参数覆盖
+func WithPipelineFFmpeg(opts FFmpegOptions) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.ffmpeg = opts
+	}
+}
+
+// WithPipelineFFmpegPath 设置 ffmpeg 可执行文件路径，默认从 PATH 中查找 ffmpeg
+func WithPipelineFFmpegPath(path string) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.ffmpegPath = path
+	}
+}
+
+// WithPipelineKeyOptions 设置创建初始密钥的选项
+func WithPipelineKeyOptions(opts ...Option) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.keyOpts = append(c.keyOpts, opts...)
+	}
+}
+
+// WithPipelineRotatorOptions 设置轮换器选项
+func WithPipelineRotatorOptions(opts ...RotatorOption) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.rotateOpts = append(c.rotateOpts, opts...)
+	}
+}
+
+// WithPipelineServerOptions 设置密钥服务选项
+func WithPipelineServerOptions(opts ...ServerOption) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.serverOpts = append(c.serverOpts, opts...)
+	}
+}
+
+// WithPipelineLog 设置 ffmpeg 标准错误输出回调，按行调用
+func WithPipelineLog(fn func(line string)) PipelineOption {
+	return func(c *pipelineConfig) {
+		c.onLog = fn
+	}
+}
+
+// NewPipeline 创建转码任务，input 为 ffmpeg 输入地址，output 为播放列表路径，keyURL 为写入播放列表的密钥获取URL
+// 密钥服务通过 Handler 取得，需由调用方挂载到 keyURL 对应的路径
+func NewPipeline(input, output, keyURL string, opts ...PipelineOption) (*Pipeline, error) {
+	if input == "" {
+		return nil, fmt.Errorf("输入地址不能为空")
+	}
+	c := pipelineConfig{interval: time.Hour, ffmpeg: FFmpegOptions{ListSize: -1}}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	c.ffmpeg.Input, c.ffmpeg.Output = input, output
+
+	k, err := NewKeyInfo(keyURL, c.keyOpts...)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewRotator(k, c.interval, c.rotateOpts...)
+	if err != nil {
+		k.Dispose()
+		return nil, err
+	}
+	cmd, err := r.FFmpegCommand(c.ffmpeg)
+	if err != nil {
+		r.Dispose()
+		return nil, err
+	}
+	if c.ffmpegPath != "" {
+		cmd.SetPath(c.ffmpegPath)
+	}
+
+	p := &Pipeline{
+		rotator: r,
+		server:  NewKeyServer(r, c.serverOpts...),
+		command: cmd,
+		onLog:   c.onLog,
+		done:    make(chan struct{}),
+	}
+	cmd.OnStderr(p.log)
+	return p, nil
+}
+
+// Rotator 返回任务使用的轮换器，可用于注册 OnRotate 等回调或手动轮换
+func (p *Pipeline) Rotator() *Rotator {
+	return p.rotator
+}
+
+// Handler 返回密钥服务
+func (p *Pipeline) Handler() http.Handler {
+	return p.server
+}
+
+// Start 启动轮换器与 ffmpeg，立即返回；ctx 取消等同于调用 Stop
+// 重复调用返回错误
+func (p *Pipeline) Start(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started {
+		return fmt.Errorf("转码任务已启动")
+	}
+	p.started = true
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.status.State = PipelineRunning
+	p.status.StartedAt = time.Now()
+	p.rotator.Start(ctx)
+	go p.run(ctx)
+	return nil
+}
+
+// run 运行 ffmpeg 并记录退出状态
+func (p *Pipeline) run(ctx context.Context) {
+	defer close(p.done)
+	err := p.command.Run(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.StoppedAt = time.Now()
+	if err != nil && ctx.Err() == nil {
+		p.status.State = PipelineFailed
+		p.status.Err = err
+		return
+	}
+	p.status.State = PipelineStopped
+}
+
+// log 记录并转发 ffmpeg 输出
+func (p *Pipeline) log(line string) {
+	p.mu.Lock()
+	p.status.LastLog = line
+	p.mu.Unlock()
+	if p.onLog != nil {
+		p.onLog(line)
+	}
+}
+
+// Stop 停止 ffmpeg（先发送 q 使其写完播放列表）并等待退出，清理密钥；返回 ffmpeg 异常退出的错误
+// 未启动时直接清理密钥
+func (p *Pipeline) Stop() error {
+	p.mu.Lock()
+	if !p.started {
+		p.started = true
+		p.status.State = PipelineStopped
+		p.mu.Unlock()
+		close(p.done)
+		return p.rotator.Dispose()
+	}
+	cancel := p.cancel
+	p.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	<-p.done
+	return p.Status().Err
+}
+
+// Done 返回在 ffmpeg 退出且密钥清理完成后关闭的 channel
+func (p *Pipeline) Done() <-chan struct{} {
+	return p.done
+}
+
+// Status 返回任务状态快照
+func (p *Pipeline) Status() PipelineStatus {
+	p.mu.Lock()
+	s := p.status
+	p.mu.Unlock()
+	k := p.rotator.Current()
+	s.KeyID, s.KeyVersion = k.KeyID, k.Version
+	return s
+}
@@ -0,0 +1,98 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// writeFakeFFmpeg 写入模拟 ffmpeg 的 shell 脚本，读到 q 后退出，fail 为 true 时立即以错误退出
+func writeFakeFFmpeg(t *testing.T, fail bool) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("使用 shell 脚本模拟 ffmpeg")
+	}
+	script := "#!/bin/sh\necho 'Press [q] to stop' >&2\ndd bs=1 count=1 2>/dev/null >/dev/null\n"
+	if fail {
+		script = "#!/bin/sh\necho 'Connection refused' >&2\nexit 1\n"
+	}
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(path, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPipeline(t *testing.T) {
+	dir := t.TempDir()
+	p, err := NewPipeline("rtmp://localhost/live", filepath.Join(dir, "live.m3u8"), "http://localhost/key",
+		WithPipelineFFmpegPath(writeFakeFFmpeg(t, false)),
+		WithPipelineKeyOptions(WithTempDir(dir)),
+		WithPipelineInterval(0))
+	if err != nil {
+		t.Fatalf("创建转码任务失败: %v", err)
+	}
+	if s := p.Status(); s.State != PipelineIdle || s.KeyID == "" {
+		t.Errorf("初始状态不正确: %+v", s)
+	}
+	key := p.Rotator().Current().GetKey()
+
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("启动失败: %v", err)
+	}
+	if err := p.Start(context.Background()); err == nil {
+		t.Error("重复启动应返回错误")
+	}
+
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/key", nil))
+	if body, _ := io.ReadAll(rec.Body); string(body) != string(key) {
+		t.Error("密钥服务应返回当前密钥")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for p.Status().LastLog == "" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if s := p.Status(); s.State != PipelineRunning || s.LastLog != "Press [q] to stop" {
+		t.Errorf("运行状态不正确: %+v", s)
+	}
+
+	infoFile := p.Rotator().InfoFile()
+	if err := p.Stop(); err != nil {
+		t.Fatalf("停止失败: %v", err)
+	}
+	if s := p.Status(); s.State != PipelineStopped || s.StoppedAt.IsZero() {
+		t.Errorf("停止后状态不正确: %+v", s)
+	}
+	if _, err := os.Stat(infoFile); !os.IsNotExist(err) {
+		t.Error("停止后应清理 keyinfo 文件")
+	}
+}
+
+func TestPipelineFailed(t *testing.T) {
+	dir := t.TempDir()
+	p, err := NewPipeline("rtmp://localhost/live", filepath.Join(dir, "live.m3u8"), "http://localhost/key",
+		WithPipelineFFmpegPath(writeFakeFFmpeg(t, true)),
+		WithPipelineKeyOptions(WithTempDir(dir)))
+	if err != nil {
+		t.Fatalf("创建转码任务失败: %v", err)
+	}
+	p.Start(context.Background())
+	select {
+	case <-p.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("ffmpeg 退出后 Done 应关闭")
+	}
+	if s := p.Status(); s.State != PipelineFailed || s.Err == nil {
+		t.Errorf("异常退出后状态不正确: %+v", s)
+	}
+	if err := p.Stop(); err == nil {
+		t.Error("Stop 应返回 ffmpeg 异常退出的错误")
+	}
+}
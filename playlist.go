@@ -0,0 +1,260 @@
+package hlskeyinfo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxPlaylistLine 播放列表单行的最大长度
+const maxPlaylistLine = 1 << 20
+
+// KeyTag 播放列表中的 EXT-X-KEY 或 EXT-X-SESSION-KEY 标签
+type KeyTag struct {
+	Session           bool   // 是否为主播放列表中的 EXT-X-SESSION-KEY
+	Method            string // NONE、AES-128 或 SAMPLE-AES 等
+	URI               string // 密钥获取URL，METHOD=NONE 时为空
+	IV                string // 十六进制 IV，不含 0x 前缀，未指定时为空
+	KeyID             string // 十六进制 KEYID，不含 0x 前缀，Widevine 等 DRM 系统使用
+	KeyFormat         string // KEYFORMAT，未指定时为空（即 identity）
+	KeyFormatVersions string // KEYFORMATVERSIONS
+	Line              int    // 所在行号，从 1 开始，由 ParseKeyTags 设置
+}
+
+// ExtXKey 返回写入媒体播放列表的 EXT-X-KEY 标签，与 ffmpeg 根据 keyinfo 文件生成的标签一致
+// 如 #EXT-X-KEY:METHOD=AES-128,URI="https://example.com/key",IV=0x0123...；
+// 无 IV 与序列号 IV 模式下省略 IV 属性，由播放器按媒体序列号计算
+func (k *KeyInfo) ExtXKey() string {
+	return k.KeyTag().String()
+}
+
+// KeyTag 返回该密钥对应的 EXT-X-KEY 标签，可用于自行组装播放列表
+func (k *KeyInfo) KeyTag() KeyTag {
+	t := KeyTag{Method: k.method(), URI: k.KeyURL(), KeyFormat: k.KeyFormat, KeyFormatVersions: k.KeyFormatVersions}
+	if k.HasIV() {
+		t.IV = normalizeIV(strings.TrimSpace(k.IV))
+	}
+	return t
+}
+
+// drmKeyTag 返回 DRM 系统的 EXT-X-KEY 标签，URI 为该系统的密钥数据，KEYFORMATVERSIONS 为 1
+func (k *KeyInfo) drmKeyTag(format, uri string) KeyTag {
+	t := KeyTag{Method: k.method(), URI: uri, KeyFormat: format, KeyFormatVersions: "1"}
+	if k.HasIV() {
+		t.IV = normalizeIV(strings.TrimSpace(k.IV))
+	}
+	return t
+}
+
+// String 实现 fmt.Stringer 接口，返回标签行，为 ParseKeyTag 的逆操作
+func (t KeyTag) String() string {
+	var b strings.Builder
+	if t.Session {
+		b.WriteString("#EXT-X-SESSION-KEY:METHOD=")
+	} else {
+		b.WriteString("#EXT-X-KEY:METHOD=")
+	}
+	b.WriteString(t.Method)
+	if t.URI != "" {
+		b.WriteString(",URI=")
+		b.WriteString(quotedString(t.URI))
+	}
+	if t.IV != "" {
+		b.WriteString(",IV=0x")
+		b.WriteString(t.IV)
+	}
+	if t.KeyID != "" {
+		b.WriteString(",KEYID=0x")
+		b.WriteString(t.KeyID)
+	}
+	if t.KeyFormat != "" {
+		b.WriteString(",KEYFORMAT=")
+		b.WriteString(quotedString(t.KeyFormat))
+	}
+	if t.KeyFormatVersions != "" {
+		b.WriteString(",KEYFORMATVERSIONS=")
+		b.WriteString(quotedString(t.KeyFormatVersions))
+	}
+	return b.String()
+}
+
+// ParseKeyTag 解析一行 EXT-X-KEY 或 EXT-X-SESSION-KEY 标签
+func ParseKeyTag(line string) (KeyTag, error) {
+	line = strings.TrimSpace(line)
+	var t KeyTag
+	attrs, ok := strings.CutPrefix(line, "#EXT-X-KEY:")
+	if !ok {
+		if attrs, ok = strings.CutPrefix(line, "#EXT-X-SESSION-KEY:"); !ok {
+			return KeyTag{}, fmt.Errorf("不是密钥标签: %q", line)
+		}
+		t.Session = true
+	}
+
+	values, err := parseAttributes(attrs)
+	if err != nil {
+		return KeyTag{}, fmt.Errorf("解析密钥标签失败: %w", err)
+	}
+	t.Method = values["METHOD"]
+	t.URI = values["URI"]
+	t.IV = normalizeIV(values["IV"])
+	t.KeyID = normalizeIV(values["KEYID"])
+	t.KeyFormat = values["KEYFORMAT"]
+	t.KeyFormatVersions = values["KEYFORMATVERSIONS"]
+	if t.Method == "" {
+		return KeyTag{}, fmt.Errorf("密钥标签缺少 METHOD: %q", line)
+	}
+	return t, nil
+}
+
+// ParseKeyTags 读取播放列表中所有的 EXT-X-KEY 与 EXT-X-SESSION-KEY 标签，按出现顺序返回
+// 可用于审计播放列表引用了哪些密钥
+func ParseKeyTags(r io.Reader) ([]KeyTag, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxPlaylistLine)
+	var tags []KeyTag
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "#EXT-X-KEY:") && !strings.HasPrefix(line, "#EXT-X-SESSION-KEY:") {
+			continue
+		}
+		t, err := ParseKeyTag(line)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %w", n, err)
+		}
+		t.Line = n
+		tags = append(tags, t)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取播放列表失败: %w", err)
+	}
+	return tags, nil
+}
+
+// parseAttributes 解析 m3u8 属性列表，如 METHOD=AES-128,URI="a,b"；quoted-string 取引号内的内容
+func parseAttributes(s string) (map[string]string, error) {
+	attrs := make(map[string]string)
+	err := walkAttributes(s, func(name string, start, end int) {
+		attrs[name] = s[start:end]
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
+
+// walkAttributes 依次以属性名与值在 s 中的起止位置调用 fn，quoted-string 的位置不含引号
+func walkAttributes(s string, fn func(name string, start, end int)) error {
+	i := 0
+	for i < len(s) {
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			return fmt.Errorf("属性缺少值: %q", s[i:])
+		}
+		name := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+
+		var start, end int
+		if i < len(s) && s[i] == '"' {
+			close := strings.IndexByte(s[i+1:], '"')
+			if close < 0 {
+				return fmt.Errorf("属性 %s 的引号未闭合", name)
+			}
+			start, end = i+1, i+1+close
+			i = end + 1
+		} else {
+			start = i
+			if comma := strings.IndexByte(s[i:], ','); comma >= 0 {
+				end = i + comma
+			} else {
+				end = len(s)
+			}
+			i = end
+		}
+		fn(name, start, end)
+
+		for i < len(s) && s[i] == ' ' {
+			i++
+		}
+		if i < len(s) {
+			if s[i] != ',' {
+				return fmt.Errorf("属性 %s 之后应为逗号: %q", name, s[i:])
+			}
+			i++
+		}
+	}
+	return nil
+}
+
+// RewriteKeyURIs 逐行复制播放列表，将带 URI 的 EXT-X-KEY 与 EXT-X-SESSION-KEY 标签的 URI 替换为 fn 的返回值，
+// 如将内网密钥地址替换为 CDN 签名地址；其他行以及标签中的其他属性按原字节输出，fn 返回原 URI 时该行不变
+// fn 返回错误时停止并返回该错误
+func RewriteKeyURIs(r io.Reader, w io.Writer, fn func(tag KeyTag) (string, error)) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+	for n := 1; ; n++ {
+		line, readErr := br.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return fmt.Errorf("读取播放列表失败: %w", readErr)
+		}
+		if line != "" {
+			out, err := rewriteKeyLine(line, n, fn)
+			if err != nil {
+				return fmt.Errorf("第 %d 行: %w", n, err)
+			}
+			if _, err := bw.WriteString(out); err != nil {
+				return fmt.Errorf("写入播放列表失败: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("写入播放列表失败: %w", err)
+	}
+	return nil
+}
+
+// rewriteKeyLine 替换单行密钥标签中的 URI，line 包含行尾换行符
+func rewriteKeyLine(line string, n int, fn func(tag KeyTag) (string, error)) (string, error) {
+	content := strings.TrimRight(line, "\r\n")
+	trimmed := strings.TrimLeft(content, " \t")
+	if !strings.HasPrefix(trimmed, "#EXT-X-KEY:") && !strings.HasPrefix(trimmed, "#EXT-X-SESSION-KEY:") {
+		return line, nil
+	}
+	_, attrs, _ := strings.Cut(trimmed, ":")
+	tag, err := ParseKeyTag(content)
+	if err != nil {
+		return "", err
+	}
+	if tag.URI == "" {
+		return line, nil
+	}
+	tag.Line = n
+	uri, err := fn(tag)
+	if err != nil {
+		return "", err
+	}
+	if uri == tag.URI {
+		return line, nil
+	}
+
+	// 在原始行中定位 URI 的值，只替换这一段
+	offset := len(line) - len(strings.TrimLeft(line, " \t")) + len(trimmed) - len(attrs)
+	start, end := -1, -1
+	walkAttributes(attrs, func(name string, s, e int) {
+		if name == "URI" {
+			start, end = offset+s, offset+e
+		}
+	})
+	quoted := quotedString(uri)
+	return line[:start] + quoted[1:len(quoted)-1] + line[end:], nil
+}
+
+// quotedString 将 s 格式化为 m3u8 属性的 quoted-string
+// 规范不允许其中出现双引号与换行且没有转义方式，因此按 URI 的百分号编码替换
+func quotedString(s string) string {
+	s = strings.NewReplacer(`"`, "%22", "\r", "%0D", "\n", "%0A").Replace(s)
+	return `"` + s + `"`
+}
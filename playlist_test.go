@@ -0,0 +1,116 @@
+package hlskeyinfo
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtXKey(t *testing.T) {
+	k := &KeyInfo{URL: "https://example.com/key?stream=\"a\"\n"}
+	k.SetIV("0x0123456789abcdef0123456789abcdef")
+	want := `#EXT-X-KEY:METHOD=AES-128,URI="https://example.com/key?stream=%22a%22%0A",IV=0x0123456789abcdef0123456789abcdef`
+	if got := k.ExtXKey(); got != want {
+		t.Errorf("EXT-X-KEY 不正确:\n实际 %s\n期望 %s", got, want)
+	}
+
+	k.UseSequenceIV()
+	if got := k.ExtXKey(); got != `#EXT-X-KEY:METHOD=AES-128,URI="https://example.com/key?stream=%22a%22%0A"` {
+		t.Errorf("序列号 IV 模式下不应包含 IV: %s", got)
+	}
+
+	k = &KeyInfo{URL: "https://keys.example.com/{keyID}", KeyID: "k1", keyIDInURL: true}
+	if got := k.ExtXKey(); got != `#EXT-X-KEY:METHOD=AES-128,URI="https://keys.example.com/k1?kid=k1"` {
+		t.Errorf("应使用展开后的密钥URL: %s", got)
+	}
+}
+
+func TestParseKeyTags(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:5
+#EXT-X-SESSION-KEY:METHOD=SAMPLE-AES,URI="skd://key-1",KEYFORMAT="com.apple.streamingkeydelivery",KEYFORMATVERSIONS="1"
+#EXT-X-KEY:METHOD=AES-128,URI="https://example.com/key?a=1,b=2",IV=0X0123456789ABCDEF0123456789ABCDEF
+#EXTINF:4.0,
+segment_0.ts
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:4.0,
+ad_0.ts
+`
+	tags, err := ParseKeyTags(strings.NewReader(playlist))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	want := []KeyTag{
+		{Session: true, Method: "SAMPLE-AES", URI: "skd://key-1", KeyFormat: "com.apple.streamingkeydelivery", KeyFormatVersions: "1", Line: 3},
+		{Method: "AES-128", URI: "https://example.com/key?a=1,b=2", IV: "0123456789ABCDEF0123456789ABCDEF", Line: 4},
+		{Method: "NONE", Line: 7},
+	}
+	if !slices.Equal(tags, want) {
+		t.Errorf("解析结果不正确:\n实际 %+v\n期望 %+v", tags, want)
+	}
+
+	// 生成与解析互逆
+	k := &KeyInfo{URL: "https://example.com/key"}
+	k.RandIV()
+	tag, err := ParseKeyTag(k.ExtXKey())
+	if err != nil || tag.String() != k.ExtXKey() || tag.IV != k.IV {
+		t.Errorf("生成的标签解析后不一致: %+v, %v", tag, err)
+	}
+
+	for _, bad := range []string{
+		`#EXT-X-KEY:URI="https://example.com/key"`,
+		`#EXT-X-KEY:METHOD=AES-128,URI="https://example.com/key`,
+		`#EXT-X-KEY:METHOD=AES-128,URI`,
+	} {
+		if _, err := ParseKeyTags(strings.NewReader(bad)); err == nil {
+			t.Errorf("应返回错误: %s", bad)
+		}
+	}
+}
+
+func TestRewriteKeyURIs(t *testing.T) {
+	playlist := "#EXTM3U\r\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"http://localhost:8080/key/1\",IV=0x0123456789abcdef0123456789abcdef\r\n" +
+		"#EXTINF:4.0,\r\n" +
+		"segment_0.ts\r\n" +
+		"  #EXT-X-KEY:METHOD=AES-128, URI=\"http://localhost:8080/key/2\" ,KEYFORMAT=\"identity\"\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"https://cdn.example.com/key/3\"\n" +
+		"#EXT-X-KEY:METHOD=NONE\n" +
+		"segment_1.ts"
+	var out strings.Builder
+	var seen []int
+	err := RewriteKeyURIs(strings.NewReader(playlist), &out, func(tag KeyTag) (string, error) {
+		seen = append(seen, tag.Line)
+		uri, ok := strings.CutPrefix(tag.URI, "http://localhost:8080/")
+		if !ok {
+			return tag.URI, nil
+		}
+		return "https://cdn.example.com/" + uri + "?sig=\"x\"", nil
+	})
+	if err != nil {
+		t.Fatalf("重写失败: %v", err)
+	}
+	want := "#EXTM3U\r\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"https://cdn.example.com/key/1?sig=%22x%22\",IV=0x0123456789abcdef0123456789abcdef\r\n" +
+		"#EXTINF:4.0,\r\n" +
+		"segment_0.ts\r\n" +
+		"  #EXT-X-KEY:METHOD=AES-128, URI=\"https://cdn.example.com/key/2?sig=%22x%22\" ,KEYFORMAT=\"identity\"\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"https://cdn.example.com/key/3\"\n" +
+		"#EXT-X-KEY:METHOD=NONE\n" +
+		"segment_1.ts"
+	if out.String() != want {
+		t.Errorf("重写结果不正确:\n实际 %q\n期望 %q", out.String(), want)
+	}
+	if !slices.Equal(seen, []int{2, 5, 6}) {
+		t.Errorf("应只对带 URI 的密钥标签调用映射函数: %v", seen)
+	}
+
+	err = RewriteKeyURIs(strings.NewReader(playlist), io.Discard, func(tag KeyTag) (string, error) {
+		return "", errors.New("签名服务不可用")
+	})
+	if err == nil || !strings.Contains(err.Error(), "签名服务不可用") {
+		t.Errorf("应返回映射函数的错误: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package hlskeyinfo
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestPlayReadyKeyTag(t *testing.T) {
+	k, err := NewKeyInfo("https://keys.example.com/key", WithTempDir(t.TempDir()), WithMethod(MethodSampleAES))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Dispose()
+	k.SetKeyID("0123456789abcdef0123456789abcdef")
+
+	tag, err := k.PlayReadyKeyTag(PlayReadyPSSH{LicenseURL: "https://pr.example.com/rightsmanager.asmx?a=1&b=2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag.KeyFormat != KeyFormatPlayReady || tag.KeyFormatVersions != "1" || tag.Method != MethodSampleAES {
+		t.Errorf("标签属性不正确: %+v", tag)
+	}
+	const prefix = "data:text/plain;charset=UTF-16;base64,"
+	if !strings.HasPrefix(tag.URI, prefix) {
+		t.Fatalf("URI 前缀不正确: %s", tag.URI)
+	}
+	pro, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(tag.URI, prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(binary.LittleEndian.Uint32(pro)) != len(pro) || binary.LittleEndian.Uint16(pro[4:]) != 1 ||
+		binary.LittleEndian.Uint16(pro[6:]) != 1 || int(binary.LittleEndian.Uint16(pro[8:])) != len(pro)-10 {
+		t.Fatalf("PlayReady Object 头不正确: %x", pro[:10])
+	}
+	u := make([]uint16, (len(pro)-10)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(pro[10+2*i:])
+	}
+	header := string(utf16.Decode(u))
+	// 0123456789abcdef 的前三段按小端排列为 67452301 ab89 efcd
+	kid := base64.StdEncoding.EncodeToString([]byte{0x67, 0x45, 0x23, 0x01, 0xab, 0x89, 0xef, 0xcd, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef})
+	for _, want := range []string{`version="4.3.0.0"`, `<KID ALGID="AESCBC" VALUE="` + kid + `">`, `<LA_URL>https://pr.example.com/rightsmanager.asmx?a=1&amp;b=2</LA_URL>`} {
+		if !strings.Contains(header, want) {
+			t.Errorf("WRMHEADER 缺少 %s: %s", want, header)
+		}
+	}
+
+	box, err := parseMP4Boxes(PlayReadyPSSH{KeyIDs: [][16]byte{{1}}}.Box())
+	if err != nil || len(box) != 1 || string(box[0].payload[4:20]) != string(PlayReadySystemID[:]) {
+		t.Errorf("pssh box 不正确: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrFFmpegUnsupported ffmpeg 不支持所需的 HLS 加密功能
+var ErrFFmpegUnsupported = errors.New("ffmpeg 不支持所需的 HLS 加密功能")
+
+// probeTimeout 探测 ffmpeg 的超时时间
+const probeTimeout = 10 * time.Second
+
+// hlsEncOption 匹配 hls 复用器帮助中的 -hls_enc 选项，排除 -hls_enc_key 等
+var hlsEncOption = regexp.MustCompile(`(?m)^\s*-hls_enc\s`)
+
+// FFmpegCapabilities ffmpeg 的 HLS 加密能力
+type FFmpegCapabilities struct {
+	Path          string // 可执行文件路径
+	Version       string // 版本号，如 6.1.1 或 N-113000-g1234abcd
+	KeyInfoFile   bool   // 支持 -hls_key_info_file
+	PeriodicRekey bool   // -hls_flags 支持 periodic_rekey
+	HLSEnc        bool   // 支持 -hls_enc 系列选项
+	// SampleAES hls 复用器支持 SAMPLE-AES 加密
+	// 官方 ffmpeg 的 hls 复用器只支持 AES-128（SAMPLE-AES 仅用于解密输入），仅定制版本在帮助中声明时为 true
+	SampleAES bool
+}
+
+// ProbeFFmpeg 运行 ffmpeg -version 与 ffmpeg -h muxer=hls，解析版本与 hls 复用器支持的加密选项
+// path 为空时从 PATH 中查找 ffmpeg；不支持 hls 复用器时返回包装 ErrFFmpegUnsupported 的错误
+func ProbeFFmpeg(path string) (*FFmpegCapabilities, error) {
+	if path == "" {
+		path = "ffmpeg"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	version, err := exec.CommandContext(ctx, path, "-hide_banner", "-version").Output()
+	if err != nil {
+		return nil, fmt.Errorf("运行 ffmpeg 失败: %w", err)
+	}
+	// 帮助输出在部分版本中写入 stderr，且未知格式时退出码可能为 0
+	help, err := exec.CommandContext(ctx, path, "-hide_banner", "-h", "muxer=hls").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("读取 hls 复用器帮助失败: %w", err)
+	}
+	return parseFFmpegCapabilities(path, string(version), string(help))
+}
+
+// parseFFmpegCapabilities 解析 -version 与 -h muxer=hls 的输出
+func parseFFmpegCapabilities(path, version, help string) (*FFmpegCapabilities, error) {
+	c := &FFmpegCapabilities{Path: path}
+	first, _, _ := strings.Cut(version, "\n")
+	if v, ok := strings.CutPrefix(strings.TrimSpace(first), "ffmpeg version "); ok {
+		c.Version, _, _ = strings.Cut(v, " ")
+	}
+	if c.Version == "" {
+		return nil, fmt.Errorf("无法识别 ffmpeg 版本: %q", first)
+	}
+	if !strings.Contains(help, "hls muxer AVOptions") && !strings.Contains(help, "Muxer hls") {
+		return nil, fmt.Errorf("%w: %s %s 未包含 hls 复用器", ErrFFmpegUnsupported, path, c.Version)
+	}
+
+	lower := strings.ToLower(help)
+	c.KeyInfoFile = strings.Contains(help, "-hls_key_info_file")
+	c.PeriodicRekey = strings.Contains(help, "periodic_rekey")
+	c.HLSEnc = hlsEncOption.MatchString(help)
+	c.SampleAES = strings.Contains(lower, "sample-aes") || strings.Contains(lower, "sample_aes")
+	return c, nil
+}
+
+// Require 检查是否支持 opts 所需的加密功能，不支持时返回包装 ErrFFmpegUnsupported 的错误，
+// 避免 ffmpeg 忽略未知选项或退回不加密输出
+// rotate 为 true 表示使用轮换器，需要 periodic_rekey
+func (c *FFmpegCapabilities) Require(opts FFmpegOptions, rotate bool) error {
+	var missing []string
+	switch opts.Encryption {
+	case FFmpegKeyInfoFile, FFmpegKeyInfoFD:
+		if !c.KeyInfoFile {
+			missing = append(missing, "-hls_key_info_file")
+		}
+	case FFmpegHLSEnc:
+		if !c.HLSEnc {
+			missing = append(missing, "-hls_enc")
+		}
+	}
+	if (rotate || opts.PeriodicRekey) && !c.PeriodicRekey {
+		missing = append(missing, "periodic_rekey")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s %s 缺少 %s", ErrFFmpegUnsupported, c.Path, c.Version, strings.Join(missing, ", "))
+	}
+	return nil
+}
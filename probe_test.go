@@ -0,0 +1,104 @@
+package hlskeyinfo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+const testHLSMuxerHelp = `Muxer hls [Apple HTTP Live Streaming]:
+    Common extensions: m3u8.
+    Default video codec: h264.
+hls muxer AVOptions:
+  -start_number      <int64>      E.......... set first number in the sequence (from 0 to I64_MAX) (default 0)
+  -hls_time          <duration>   E.......... set segment length (default 2)
+  -hls_key_info_file <string>     E.......... file with key URI and key file path
+  -hls_enc           <boolean>    E.......... enable AES128 encryption support (default false)
+  -hls_enc_key       <string>     E.......... hex-coded 16 byte key to encrypt the segments
+  -hls_flags         <flags>      E.......... set flags affecting HLS playlist and media file generation (default 0)
+     single_file                  E.......... generate a single media file indexed with byte ranges
+     periodic_rekey               E.......... reload keyinfo file periodically for re-keying
+`
+
+func TestParseFFmpegCapabilities(t *testing.T) {
+	c, err := parseFFmpegCapabilities("ffmpeg", "ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers\nbuilt with gcc 13", testHLSMuxerHelp)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	want := FFmpegCapabilities{Path: "ffmpeg", Version: "6.1.1-3ubuntu5", KeyInfoFile: true, PeriodicRekey: true, HLSEnc: true}
+	if *c != want {
+		t.Errorf("解析结果不正确: %+v", *c)
+	}
+	if err := c.Require(FFmpegOptions{}, true); err != nil {
+		t.Errorf("应满足轮换需求: %v", err)
+	}
+
+	// 旧版本 ffmpeg 只支持 -hls_enc
+	old, err := parseFFmpegCapabilities("ffmpeg", "ffmpeg version 2.8.1", "hls muxer AVOptions:\n  -hls_enc_key <string>\n")
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if old.HLSEnc || old.KeyInfoFile {
+		t.Errorf("不应识别 -hls_enc_key 为 -hls_enc: %+v", *old)
+	}
+	if err := old.Require(FFmpegOptions{}, true); !errors.Is(err, ErrFFmpegUnsupported) {
+		t.Errorf("缺少功能时应返回 ErrFFmpegUnsupported: %v", err)
+	}
+
+	if _, err := parseFFmpegCapabilities("ffmpeg", "ffmpeg version 6.0", "Unknown format 'hls'."); !errors.Is(err, ErrFFmpegUnsupported) {
+		t.Errorf("缺少 hls 复用器时应返回 ErrFFmpegUnsupported: %v", err)
+	}
+	if _, err := parseFFmpegCapabilities("ffmpeg", "avconv version 12", testHLSMuxerHelp); err == nil {
+		t.Error("无法识别版本时应返回错误")
+	}
+}
+
+func TestFFmpegCapabilitiesRequire(t *testing.T) {
+	hlsEncOnly := &FFmpegCapabilities{Path: "ffmpeg", Version: "2.8.1", HLSEnc: true}
+	tests := []struct {
+		name    string
+		opts    FFmpegOptions
+		rotate  bool
+		wantErr bool
+	}{
+		{name: "keyinfo 文件", opts: FFmpegOptions{Encryption: FFmpegKeyInfoFile}, wantErr: true},
+		{name: "keyinfo 文件描述符", opts: FFmpegOptions{Encryption: FFmpegKeyInfoFD}, wantErr: true},
+		{name: "hls_enc", opts: FFmpegOptions{Encryption: FFmpegHLSEnc}},
+		{name: "轮换", opts: FFmpegOptions{Encryption: FFmpegHLSEnc}, rotate: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := hlsEncOnly.Require(tt.opts, tt.rotate)
+		if tt.wantErr != errors.Is(err, ErrFFmpegUnsupported) {
+			t.Errorf("%s: 期望返回错误 %v，实际: %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestProbeFFmpeg(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("使用 shell 脚本模拟 ffmpeg")
+	}
+	dir := t.TempDir()
+	help := filepath.Join(dir, "help.txt")
+	if err := os.WriteFile(help, []byte(testHLSMuxerHelp), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\nif [ \"$2\" = -version ]; then echo 'ffmpeg version 7.0 Copyright'; else cat " + help + " >&2; fi\n"
+	path := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(path, []byte(script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ProbeFFmpeg(path)
+	if err != nil {
+		t.Fatalf("探测失败: %v", err)
+	}
+	if c.Version != "7.0" || !c.KeyInfoFile || !c.PeriodicRekey {
+		t.Errorf("探测结果不正确: %+v", *c)
+	}
+	if _, err := ProbeFFmpeg(filepath.Join(dir, "missing")); err == nil {
+		t.Error("可执行文件不存在时应返回错误")
+	}
+}
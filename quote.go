@@ -0,0 +1,106 @@
+package hlskeyinfo
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// NormalizeFFmpegPath 规范化传给 ffmpeg 的本地文件路径，keyinfo 文件中的密钥文件路径同样经过该处理
+// Windows 上将反斜杠替换为 ffmpeg 同样接受的正斜杠（保留 UNC 路径的 // 开头），其他平台清理多余的分隔符；
+// 形如 "name:..." 的相对路径会被 ffmpeg 当作协议，此时加上 file: 前缀
+// 仅用于本地路径，不要传入 URL 或 pipe: 等协议地址
+func NormalizeFFmpegPath(path string) string {
+	return normalizeFFmpegPath(path, runtime.GOOS == "windows")
+}
+
+// normalizeFFmpegPath 按目标平台规范化路径
+func normalizeFFmpegPath(path string, windows bool) string {
+	if path == "" || path == "-" || strings.HasPrefix(path, "file:") {
+		return path
+	}
+	if windows {
+		path = strings.ReplaceAll(path, `\`, "/")
+	} else {
+		path = filepath.Clean(path)
+	}
+	if looksLikeProtocol(path, windows) {
+		return "file:" + path
+	}
+	return path
+}
+
+// looksLikeProtocol 报告 ffmpeg 是否会将路径开头的 "name:" 识别为协议
+// ffmpeg 的协议名由字母、数字与 +-. 组成，Windows 上单个字母加冒号视为盘符
+func looksLikeProtocol(path string, windows bool) bool {
+	i := strings.IndexByte(path, ':')
+	if i <= 0 || (windows && i == 1) {
+		return false
+	}
+	for _, c := range path[:i] {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '+' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// QuoteArg 按当前平台的规则为命令行参数加引号，不需要时原样返回
+// Windows 上按 CreateProcess（CommandLineToArgvW）的规则，其他平台按 POSIX shell 的规则
+func QuoteArg(arg string) string {
+	if runtime.GOOS == "windows" {
+		return quoteWindowsArg(arg)
+	}
+	return quotePOSIXArg(arg)
+}
+
+// CommandLine 将参数逐个加引号后以空格连接，可用于日志或复制到终端执行
+// Windows 上生成的命令行适用于 cmd.exe，但参数中的 % 仍会被展开为环境变量
+func CommandLine(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = QuoteArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// String 实现 fmt.Stringer 接口，返回可复制到终端执行的命令行
+// 使用 FFmpegHLSEnc 时其中包含明文密钥，不应写入日志
+func (c *FFmpegCommand) String() string {
+	return CommandLine(append([]string{c.path}, c.args...)...)
+}
+
+// quotePOSIXArg 使用单引号包裹参数，参数中的单引号写为 '\''
+func quotePOSIXArg(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// quoteWindowsArg 按 CommandLineToArgvW 的规则加双引号：引号前的反斜杠与结尾的反斜杠需要加倍
+// 同时为含有 cmd.exe 元字符的参数加引号，避免被解释为重定向或管道
+func quoteWindowsArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"&|<>^()") {
+		return arg
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	backslashes := 0
+	for i := 0; i < len(arg); i++ {
+		switch c := arg[i]; c {
+		case '\\':
+			backslashes++
+			continue
+		case '"':
+			b.WriteString(strings.Repeat(`\`, backslashes*2+1))
+		default:
+			b.WriteString(strings.Repeat(`\`, backslashes))
+		}
+		backslashes = 0
+		b.WriteByte(arg[i])
+	}
+	b.WriteString(strings.Repeat(`\`, backslashes*2))
+	b.WriteByte('"')
+	return b.String()
+}
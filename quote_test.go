@@ -0,0 +1,80 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeFFmpegPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		windows bool
+		want    string
+	}{
+		{`C:\Users\Media User\key.bin`, true, "C:/Users/Media User/key.bin"},
+		{`\\server\share\key.bin`, true, "//server/share/key.bin"},
+		{`live:1\key.bin`, true, "file:live:1/key.bin"},
+		{"/tmp//hls/../keys/key.bin", false, "/tmp/keys/key.bin"},
+		{"stream:1.bin", false, "file:stream:1.bin"},
+		{"/data/stream:1.bin", false, "/data/stream:1.bin"},
+		{"file:stream:1.bin", false, "file:stream:1.bin"},
+		{"-", false, "-"},
+	}
+	for _, tt := range tests {
+		if got := normalizeFFmpegPath(tt.path, tt.windows); got != tt.want {
+			t.Errorf("normalizeFFmpegPath(%q, %v) = %q，期望 %q", tt.path, tt.windows, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteArg(t *testing.T) {
+	posix := map[string]string{
+		"segment_%d.ts":  "segment_%d.ts",
+		"/tmp/my key":    "'/tmp/my key'",
+		"it's":           `'it'\''s'`,
+		"":               "''",
+		"a;rm -rf /":     "'a;rm -rf /'",
+		"-hls_flags":     "-hls_flags",
+		"C:/media/x.mp4": "C:/media/x.mp4",
+	}
+	for in, want := range posix {
+		if got := quotePOSIXArg(in); got != want {
+			t.Errorf("quotePOSIXArg(%q) = %s，期望 %s", in, got, want)
+		}
+	}
+
+	windows := map[string]string{
+		`C:\media\in.mp4`:     `C:\media\in.mp4`,
+		`C:\Program Files\`:   `"C:\Program Files\\"`,
+		`say "hi"`:            `"say \"hi\""`,
+		`a\"b`:                `"a\\\"b"`,
+		"":                    `""`,
+		"a&b":                 `"a&b"`,
+		`C:\My Media\key.bin`: `"C:\My Media\key.bin"`,
+	}
+	for in, want := range windows {
+		if got := quoteWindowsArg(in); got != want {
+			t.Errorf("quoteWindowsArg(%q) = %s，期望 %s", in, got, want)
+		}
+	}
+}
+
+func TestKeyInfoNormalizedKeyFile(t *testing.T) {
+	k := &KeyInfo{URL: "https://example.com/key", KeyFile: "stream:1.bin"}
+	var buf bytes.Buffer
+	if _, err := k.WriteTo(&buf); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	if lines := strings.Split(buf.String(), "\n"); lines[1] != "file:stream:1.bin" {
+		t.Errorf("密钥文件路径应加 file: 前缀: %q", lines[1])
+	}
+
+	var parsed KeyInfo
+	if _, err := parsed.ReadFrom(&buf); err != nil {
+		t.Fatalf("读取失败: %v", err)
+	}
+	if parsed.KeyFile != "stream:1.bin" {
+		t.Errorf("读取时应去除 file: 前缀: %q", parsed.KeyFile)
+	}
+}
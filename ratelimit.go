@@ -0,0 +1,135 @@
+package hlskeyinfo
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval 清理空闲令牌桶的间隔
+const rateLimitSweepInterval = time.Minute
+
+// RateLimiter 按客户端限流的令牌桶，默认按客户端 IP 区分
+// 用于抑制针对密钥端点的批量抓取与凭证撞库
+type RateLimiter struct {
+	rate  float64 // 每秒补充的令牌数
+	burst float64 // 桶容量
+	key   func(*http.Request) string
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// tokenBucket 单个客户端的令牌桶
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimitOption 限流器选项
+type RateLimitOption func(*RateLimiter)
+
+// RateLimitBySubject 按鉴权中间件记录的请求主体限流，未鉴权的请求按客户端 IP 限流
+// 需放在鉴权中间件之后
+func RateLimitBySubject() RateLimitOption {
+	return func(l *RateLimiter) {
+		l.key = func(r *http.Request) string {
+			if sub := SubjectFromContext(r.Context()); sub != "" {
+				return "sub:" + sub
+			}
+			return clientIPKey(r)
+		}
+	}
+}
+
+// WithRateLimitKey 自定义限流维度
+func WithRateLimitKey(fn func(*http.Request) string) RateLimitOption {
+	return func(l *RateLimiter) {
+		l.key = fn
+	}
+}
+
+// NewRateLimiter 创建限流器，每个客户端每秒最多 rate 次请求，允许 burst 次突发
+func NewRateLimiter(rate float64, burst int, opts ...RateLimitOption) *RateLimiter {
+	l := &RateLimiter{
+		rate:    rate,
+		burst:   float64(max(burst, 1)),
+		key:     clientIPKey,
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Allow 消耗 key 对应令牌桶中的一个令牌，未被限流时返回 true；被限流时同时返回建议的重试等待时间
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if l.rate <= 0 {
+		return false, time.Duration(math.MaxInt64)
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep 清理已补满的空闲令牌桶，调用方需持有锁
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Middleware 返回限流中间件，被限流时返回 429 与 Retry-After
+func (l *RateLimiter) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, wait := l.Allow(l.key(r))
+			if !ok {
+				secs := int64(math.Ceil(wait.Seconds()))
+				if wait < 0 || secs <= 0 {
+					secs = 1
+				}
+				w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIPKey 以客户端 IP 作为限流维度
+func clientIPKey(r *http.Request) string {
+	if addr, ok := clientIP(r); ok {
+		return "ip:" + addr.String()
+	}
+	return "ip:" + r.RemoteAddr
+}
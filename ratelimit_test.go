@@ -0,0 +1,56 @@
+package hlskeyinfo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewRateLimiter(1, 2)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("突发范围内第 %d 次请求应允许", i+1)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok || wait != time.Second {
+		t.Errorf("超出突发应被限流并等待 1s，实际: %v, %v", ok, wait)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("不同客户端应独立限流")
+	}
+
+	now = now.Add(time.Second)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("令牌补充后应允许请求")
+	}
+
+	// 中间件按客户端 IP 限流
+	handler := l.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	var codes []int
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/key", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Error("被限流时应返回 Retry-After")
+		}
+	}
+	if codes[2] != http.StatusTooManyRequests {
+		t.Errorf("第 3 次请求应被限流，实际状态码: %v", codes)
+	}
+
+	// 空闲的令牌桶会被清理
+	now = now.Add(2 * rateLimitSweepInterval)
+	l.Allow("c")
+	if len(l.buckets) != 1 {
+		t.Errorf("空闲令牌桶应被清理，剩余: %d", len(l.buckets))
+	}
+}
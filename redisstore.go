@@ -0,0 +1,196 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RedisClient Redis 客户端，由调用方基于 go-redis 等实现
+type RedisClient interface {
+	// Set 写入键值，ttl 为 0 时不过期
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Get 读取键值，键不存在时返回包装了 ErrKeyNotFound 的错误
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Del 删除键，键不存在时返回包装了 ErrKeyNotFound 的错误
+	Del(ctx context.Context, key string) error
+	// Scan 返回匹配 pattern 的全部键，需自行处理游标
+	Scan(ctx context.Context, pattern string) ([]string, error)
+	Publish(ctx context.Context, channel string, msg []byte) error
+	// Subscribe 订阅频道，ctx 取消或连接断开时关闭返回的通道
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+}
+
+// RedisStore 基于 Redis 的密钥存储，键为 <prefix><keyID>，值为包含密钥的 KeyRecord JSON
+// 每次 Put 与 Delete 在频道上发布 KeyEvent，水平扩展的密钥服务可通过 Watch 在毫秒级感知轮换
+type RedisStore struct {
+	client    RedisClient
+	prefix    string
+	channel   string
+	retention time.Duration
+}
+
+var (
+	_ KeyStore   = &RedisStore{}
+	_ KeyWatcher = &RedisStore{}
+)
+
+// RedisOption Redis 密钥存储选项
+type RedisOption func(*RedisStore)
+
+// WithRedisPrefix 设置键前缀，默认 hls:key:
+func WithRedisPrefix(prefix string) RedisOption {
+	return func(s *RedisStore) {
+		s.prefix = prefix
+	}
+}
+
+// WithRedisChannel 设置发布密钥变更事件的频道，默认 hls:key:events
+func WithRedisChannel(channel string) RedisOption {
+	return func(s *RedisStore) {
+		s.channel = channel
+	}
+}
+
+// WithRedisRetention 设置密钥停用后的保留时间，到期后由 Redis 自动删除
+// 每个键的 TTL 按各自的 NotAfter 计算，仍在使用的密钥不过期；默认 0 表示永久保留
+func WithRedisRetention(d time.Duration) RedisOption {
+	return func(s *RedisStore) {
+		s.retention = d
+	}
+}
+
+// NewRedisStore 创建基于 Redis 的密钥存储
+func NewRedisStore(client RedisClient, opts ...RedisOption) *RedisStore {
+	s := &RedisStore{
+		client:  client,
+		prefix:  "hls:key:",
+		channel: "hls:key:events",
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Put 实现 KeyStore 接口，已超过保留期的记录直接删除
+func (s *RedisStore) Put(ctx context.Context, rec KeyRecord) error {
+	key, err := s.key(rec.KeyID)
+	if err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if s.retention > 0 && !rec.NotAfter.IsZero() {
+		if ttl = time.Until(rec.NotAfter.Add(s.retention)); ttl <= 0 {
+			if err := s.client.Del(ctx, key); err != nil && !errors.Is(err, ErrKeyNotFound) {
+				return fmt.Errorf("删除过期密钥失败: %w", err)
+			}
+			return s.publish(ctx, KeyEvent{Type: KeyDeleted, KeyID: rec.KeyID, Stream: rec.Stream})
+		}
+	}
+	data, err := encodeRecord(rec)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Set(ctx, key, data, ttl); err != nil {
+		return fmt.Errorf("写入密钥失败: %w", err)
+	}
+	return s.publish(ctx, KeyEvent{Type: KeyPut, KeyID: rec.KeyID, Stream: rec.Stream})
+}
+
+// Get 实现 KeyStore 接口
+func (s *RedisStore) Get(ctx context.Context, keyID string) (KeyRecord, error) {
+	key, err := s.key(keyID)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	data, err := s.client.Get(ctx, key)
+	if err != nil {
+		return KeyRecord{}, fmt.Errorf("读取密钥失败: %w", err)
+	}
+	return decodeRecord(data)
+}
+
+// Delete 实现 KeyStore 接口
+func (s *RedisStore) Delete(ctx context.Context, keyID string) error {
+	rec, err := s.Get(ctx, keyID)
+	if err != nil {
+		return err
+	}
+	key, _ := s.key(keyID)
+	if err := s.client.Del(ctx, key); err != nil {
+		return fmt.Errorf("删除密钥失败: %w", err)
+	}
+	return s.publish(ctx, KeyEvent{Type: KeyDeleted, KeyID: keyID, Stream: rec.Stream})
+}
+
+// List 实现 KeyStore 接口，列出与读取之间过期的键会被跳过
+func (s *RedisStore) List(ctx context.Context, stream string) ([]KeyRecord, error) {
+	keys, err := s.client.Scan(ctx, s.prefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("列出密钥失败: %w", err)
+	}
+	var out []KeyRecord
+	for _, key := range keys {
+		data, err := s.client.Get(ctx, key)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取密钥失败: %w", err)
+		}
+		rec, err := decodeRecord(data)
+		if err != nil {
+			return nil, fmt.Errorf("键 %s: %w", key, err)
+		}
+		if stream == "" || rec.Stream == stream {
+			out = append(out, rec)
+		}
+	}
+	sortRecords(out)
+	return out, nil
+}
+
+// Watch 实现 KeyWatcher 接口，无法解析的消息会被忽略
+func (s *RedisStore) Watch(ctx context.Context) (<-chan KeyEvent, error) {
+	msgs, err := s.client.Subscribe(ctx, s.channel)
+	if err != nil {
+		return nil, fmt.Errorf("订阅密钥变更失败: %w", err)
+	}
+	events := make(chan KeyEvent, 16)
+	go func() {
+		defer close(events)
+		for msg := range msgs {
+			var ev KeyEvent
+			if err := json.Unmarshal(msg, &ev); err != nil || ev.KeyID == "" {
+				continue
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// key 返回 KeyID 对应的 Redis 键，KeyID 不能包含 glob 通配符以免干扰 Scan
+func (s *RedisStore) key(keyID string) (string, error) {
+	if keyID == "" || strings.ContainsAny(keyID, `*?[\`) {
+		return "", fmt.Errorf("无效的 KeyID: %q", keyID)
+	}
+	return s.prefix + keyID, nil
+}
+
+// publish 发布密钥变更事件
+func (s *RedisStore) publish(ctx context.Context, ev KeyEvent) error {
+	msg, _ := json.Marshal(ev)
+	if err := s.client.Publish(ctx, s.channel, msg); err != nil {
+		return fmt.Errorf("发布密钥变更失败: %w", err)
+	}
+	return nil
+}
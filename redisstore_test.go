@@ -0,0 +1,129 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis 模拟 RedisClient
+type fakeRedis struct {
+	*fakeObjects
+	mu   sync.Mutex
+	ttls map[string]time.Duration
+	subs []chan []byte
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{fakeObjects: newFakeObjects(), ttls: make(map[string]time.Duration)}
+}
+
+func (f *fakeRedis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	f.put(key, value)
+	f.mu.Lock()
+	f.ttls[key] = ttl
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeRedis) Get(ctx context.Context, key string) ([]byte, error) {
+	return f.get(key)
+}
+
+func (f *fakeRedis) Del(ctx context.Context, key string) error {
+	return f.delete(key)
+}
+
+func (f *fakeRedis) Scan(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	for _, key := range f.list("") {
+		if ok, _ := path.Match(pattern, key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (f *fakeRedis) Publish(ctx context.Context, channel string, msg []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ch := range f.subs {
+		ch <- msg
+	}
+	return nil
+}
+
+func (f *fakeRedis) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	ch := make(chan []byte, 16)
+	f.mu.Lock()
+	f.subs = append(f.subs, ch)
+	f.mu.Unlock()
+	return ch, nil
+}
+
+func TestRedisStore(t *testing.T) {
+	client := newFakeRedis()
+	store := NewRedisStore(client, WithRedisPrefix("test:key:"), WithRedisRetention(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := store.Watch(ctx)
+	if err != nil {
+		t.Fatalf("订阅密钥变更失败: %v", err)
+	}
+	testKeyStore(t, store)
+
+	// testKeyStore 依次 Put key-a、key-b，更新 key-a 后删除 key-a
+	want := []KeyEvent{
+		{KeyPut, "key-a", "channel-1"},
+		{KeyPut, "key-b", "channel-2"},
+		{KeyPut, "key-a", "channel-1"},
+		{KeyDeleted, "key-a", "channel-1"},
+	}
+	for i, w := range want {
+		select {
+		case ev := <-events:
+			if ev != w {
+				t.Errorf("第 %d 个事件应为 %+v，实际为 %+v", i, w, ev)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("等待第 %d 个事件超时", i)
+		}
+	}
+
+	client.mu.Lock()
+	ttl := client.ttls["test:key:key-b"]
+	client.mu.Unlock()
+	if ttl != 0 {
+		t.Errorf("仍在使用的密钥不应过期，实际 TTL: %v", ttl)
+	}
+}
+
+func TestRedisStoreRetention(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeRedis()
+	store := NewRedisStore(client, WithRedisRetention(time.Hour))
+
+	now := time.Now()
+	retired := KeyRecord{KeyID: "retired", Key: make([]byte, 16), NotBefore: now.Add(-time.Hour), NotAfter: now}
+	if err := store.Put(ctx, retired); err != nil {
+		t.Fatalf("保存密钥失败: %v", err)
+	}
+	client.mu.Lock()
+	ttl := client.ttls["hls:key:retired"]
+	client.mu.Unlock()
+	if ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("TTL 应为停用时间加保留时间，实际: %v", ttl)
+	}
+
+	// 已超过保留期的记录直接删除
+	retired.NotAfter = now.Add(-2 * time.Hour)
+	if err := store.Put(ctx, retired); err != nil {
+		t.Fatalf("保存密钥失败: %v", err)
+	}
+	if _, err := store.Get(ctx, "retired"); err == nil {
+		t.Error("超过保留期的密钥应被删除")
+	}
+}
@@ -0,0 +1,55 @@
+package hlskeyinfo
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RefererPolicy 来源校验策略，只向从指定播放器域名发起的请求返回密钥，用于简单的防盗链
+// 优先校验 Origin 请求头，不存在时校验 Referer；两者都可由客户端伪造，应与令牌鉴权配合使用
+type RefererPolicy struct {
+	AllowedHosts []string // 允许的主机名，如 player.example.com，*.example.com 匹配所有子域名
+	AllowEmpty   bool     // 允许不携带 Origin 与 Referer 的请求，如原生播放器
+}
+
+// Allowed 判断请求来源是否允许
+func (p RefererPolicy) Allowed(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" || source == "null" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return p.AllowEmpty
+	}
+
+	u, err := url.Parse(source)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range p.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware 返回来源校验中间件，来源不允许时返回 403
+func (p RefererPolicy) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !p.Allowed(r) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package hlskeyinfo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRefererPolicy(t *testing.T) {
+	p := RefererPolicy{AllowedHosts: []string{"player.example.com", "*.cdn.example.com"}}
+	cases := []struct {
+		origin, referer string
+		want            bool
+	}{
+		{"https://player.example.com", "", true},
+		{"", "https://edge1.cdn.example.com/live/index.html", true},
+		{"https://evil.example.com", "https://player.example.com/", false},
+		{"", "https://cdn.example.com.evil.com/", false},
+		{"", "", false},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/key", nil)
+		if c.origin != "" {
+			req.Header.Set("Origin", c.origin)
+		}
+		if c.referer != "" {
+			req.Header.Set("Referer", c.referer)
+		}
+		if got := p.Allowed(req); got != c.want {
+			t.Errorf("Origin=%q Referer=%q 期望 %v，实际: %v", c.origin, c.referer, c.want, got)
+		}
+	}
+
+	p.AllowEmpty = true
+	w := httptest.NewRecorder()
+	p.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).
+		ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/key", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("AllowEmpty 时应允许无来源的请求，实际: %d", w.Code)
+	}
+}
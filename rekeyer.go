@@ -0,0 +1,170 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrRekeyNotConfirmed 新密钥在确认超时内未出现在 ffmpeg 输出的播放列表中
+var ErrRekeyNotConfirmed = errors.New("ffmpeg 未使用新密钥")
+
+// DefaultRekeyConfirmTimeout 默认确认超时，应大于两个分片时长
+const DefaultRekeyConfirmTimeout = 30 * time.Second
+
+// LiveRekeyer 配合开启 periodic_rekey 运行中的 ffmpeg：由轮换器按计划原子地重写 keyinfo 文件，
+// 并轮询输出的媒体播放列表，确认最新的 EXT-X-KEY URI 变为新密钥的 URL
+// 密钥获取URL需对每个密钥唯一（如使用 {keyID} 占位符或 WithKeyIDInURL），否则无法确认
+type LiveRekeyer struct {
+	rotator  *Rotator
+	playlist string
+	poll     time.Duration
+	timeout  time.Duration
+
+	mu          sync.Mutex
+	onConfirmed func(k *KeyInfo, delay time.Duration)
+	onFailure   func(error)
+}
+
+// LiveRekeyOption LiveRekeyer 创建选项
+type LiveRekeyOption func(*LiveRekeyer)
+
+// WithConfirmTimeout 设置新密钥出现在播放列表中的超时时间，默认 DefaultRekeyConfirmTimeout
+// ffmpeg 在下一个分片开始时读取 keyinfo 文件，分片写完后才写入播放列表，因此应大于两个分片时长
+func WithConfirmTimeout(d time.Duration) LiveRekeyOption {
+	return func(l *LiveRekeyer) {
+		l.timeout = d
+	}
+}
+
+// WithPlaylistPoll 设置轮询播放列表的间隔，默认 1 秒
+func WithPlaylistPoll(d time.Duration) LiveRekeyOption {
+	return func(l *LiveRekeyer) {
+		l.poll = d
+	}
+}
+
+// NewLiveRekeyer 创建 LiveRekeyer，playlist 为 ffmpeg 输出的媒体播放列表路径
+func NewLiveRekeyer(r *Rotator, playlist string, opts ...LiveRekeyOption) *LiveRekeyer {
+	l := &LiveRekeyer{rotator: r, playlist: playlist, poll: time.Second, timeout: DefaultRekeyConfirmTimeout}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.poll <= 0 {
+		l.poll = time.Second
+	}
+	if l.timeout <= 0 {
+		l.timeout = DefaultRekeyConfirmTimeout
+	}
+	return l
+}
+
+// OnConfirmed 注册确认回调，密钥出现在播放列表中时以该密钥与自轮换起的延迟调用
+func (l *LiveRekeyer) OnConfirmed(fn func(k *KeyInfo, delay time.Duration)) *LiveRekeyer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onConfirmed = fn
+	return l
+}
+
+// OnFailure 注册失败回调，密钥超时未确认或轮换前后URL相同时调用，错误包装 ErrRekeyNotConfirmed
+// 此时 ffmpeg 可能未开启 periodic_rekey 或已停止输出，需由调用方决定是否重启任务
+func (l *LiveRekeyer) OnFailure(fn func(error)) *LiveRekeyer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onFailure = fn
+	return l
+}
+
+// pendingKey 等待确认的密钥
+type pendingKey struct {
+	key   *KeyInfo
+	uri   string
+	since time.Time
+}
+
+// Run 启动轮换器并轮询播放列表，阻塞直到 ctx 取消，返回时停止轮换器
+// 当前密钥同样需要确认，可据此发现 ffmpeg 未加密输出
+func (l *LiveRekeyer) Run(ctx context.Context) error {
+	l.rotator.Start(ctx)
+	defer l.rotator.Stop()
+
+	ticker := time.NewTicker(l.poll)
+	defer ticker.Stop()
+
+	var current *KeyInfo
+	var prevURI string
+	var pending *pendingKey
+	for {
+		if k := l.rotator.Current(); k != current {
+			current = k
+			if pending != nil {
+				l.fail(fmt.Errorf("%w: 密钥 %s 未确认即被轮换", ErrRekeyNotConfirmed, pending.key.KeyID))
+			}
+			pending = &pendingKey{key: k, uri: k.KeyURL(), since: time.Now()}
+			if pending.uri == prevURI {
+				l.fail(fmt.Errorf("%w: 密钥 %s 的URL与上一个密钥相同，无法确认", ErrRekeyNotConfirmed, k.KeyID))
+				pending = nil
+			}
+			prevURI = k.KeyURL()
+		}
+
+		if pending != nil {
+			if uri, err := lastKeyURI(l.playlist); err == nil && uri == pending.uri {
+				l.confirmed(pending.key, time.Since(pending.since))
+				pending = nil
+			} else if time.Since(pending.since) > l.timeout {
+				l.fail(fmt.Errorf("%w: 密钥 %s 在 %v 内未出现在 %s 中", ErrRekeyNotConfirmed, pending.key.KeyID, l.timeout, l.playlist))
+				pending = nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// confirmed 调用确认回调
+func (l *LiveRekeyer) confirmed(k *KeyInfo, delay time.Duration) {
+	l.mu.Lock()
+	fn := l.onConfirmed
+	l.mu.Unlock()
+	if fn != nil {
+		fn(k, delay)
+	}
+}
+
+// fail 调用失败回调
+func (l *LiveRekeyer) fail(err error) {
+	l.mu.Lock()
+	fn := l.onFailure
+	l.mu.Unlock()
+	if fn != nil {
+		fn(err)
+	}
+}
+
+// lastKeyURI 返回媒体播放列表中最后一个 EXT-X-KEY 的 URI，即最新分片使用的密钥
+func lastKeyURI(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	tags, err := ParseKeyTags(f)
+	if err != nil {
+		return "", err
+	}
+	for i := len(tags) - 1; i >= 0; i-- {
+		if !tags[i].Session {
+			return tags[i].URI, nil
+		}
+	}
+	return "", nil
+}
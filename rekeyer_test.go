@@ -0,0 +1,76 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLiveRekeyer(t *testing.T) {
+	k, err := NewKeyInfo("https://keys.example.com/{keyID}", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	r, err := NewRotator(k, 0)
+	if err != nil {
+		t.Fatalf("创建 Rotator 失败: %v", err)
+	}
+	defer r.Dispose()
+
+	playlist := filepath.Join(t.TempDir(), "live.m3u8")
+	writePlaylist := func(k *KeyInfo) {
+		content := fmt.Sprintf("#EXTM3U\n#EXT-X-KEY:METHOD=AES-128,URI=\"%s\"\n#EXTINF:2.0,\nsegment_0.ts\n", k.KeyURL())
+		if err := os.WriteFile(playlist, []byte(content), 0o600); err != nil {
+			t.Fatalf("写入播放列表失败: %v", err)
+		}
+	}
+	writePlaylist(r.Current())
+
+	confirmed := make(chan *KeyInfo, 4)
+	failures := make(chan error, 4)
+	l := NewLiveRekeyer(r, playlist, WithPlaylistPoll(5*time.Millisecond), WithConfirmTimeout(100*time.Millisecond)).
+		OnConfirmed(func(k *KeyInfo, delay time.Duration) { confirmed <- k }).
+		OnFailure(func(err error) { failures <- err })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.Run(ctx)
+
+	expectConfirmed := func(want *KeyInfo) {
+		t.Helper()
+		select {
+		case k := <-confirmed:
+			if k != want {
+				t.Errorf("确认的密钥不正确: %s", k.KeyID)
+			}
+		case err := <-failures:
+			t.Fatalf("不应确认失败: %v", err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("未确认密钥")
+		}
+	}
+	expectConfirmed(r.Current())
+
+	// ffmpeg 仍使用旧密钥
+	if _, err := r.Rotate(); err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+	select {
+	case err := <-failures:
+		if !errors.Is(err, ErrRekeyNotConfirmed) {
+			t.Errorf("错误应包装 ErrRekeyNotConfirmed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("超时未确认时应调用失败回调")
+	}
+
+	next, err := r.Rotate()
+	if err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+	writePlaylist(next)
+	expectConfirmed(next)
+}
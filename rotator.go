@@ -0,0 +1,548 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultKeepPrevious 默认保留的历史密钥数量
+const DefaultKeepPrevious = 2
+
+// ErrRotatorDisposed 轮换器已清理，不能再轮换
+var ErrRotatorDisposed = errors.New("轮换器已清理")
+
+// Rotator 密钥轮换器，按固定间隔轮换密钥
+// 轮换时生成新的密钥文件，并原子地重写路径固定的 keyinfo 文件，供开启 periodic_rekey 的 ffmpeg 读取；
+// 同时保留最近 N 个历史密钥，供仍持有旧播放列表的播放器获取
+type Rotator struct {
+	mu       sync.RWMutex
+	current  *KeyInfo
+	previous []*KeyInfo // 历史密钥，最新的在前
+
+	schedule Schedule
+	keep     int
+	infoFile string
+	onError  func(error)
+
+	onRotate  func(old, new *KeyInfo) // 密钥轮换回调
+	onDispose func(k *KeyInfo)        // 密钥清理回调
+	disposed  bool
+	history   *KeyHistory
+	store     KeyStore
+	provider  KeyProvider
+	ksm       FairPlayKSM
+
+	grace    time.Duration
+	retiring map[*KeyInfo]*time.Timer // 宽限期内等待清理的退役密钥
+
+	runMu  sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	segMu         sync.Mutex
+	everySegments int // 每完成多少个分片轮换一次
+	segments      int // 自上次轮换以来完成的分片数
+}
+
+// RotatorOption 轮换器创建选项
+type RotatorOption func(*Rotator)
+
+// WithSchedule 设置轮换计划，优先于 NewRotator 的 interval 参数，如 ParseCron("0 3 * * *") 表示每天 03:00 轮换
+func WithSchedule(s Schedule) RotatorOption {
+	return func(r *Rotator) {
+		r.schedule = s
+	}
+}
+
+// WithKeepPrevious 设置保留的历史密钥数量，默认 2
+func WithKeepPrevious(n int) RotatorOption {
+	return func(r *Rotator) {
+		r.keep = n
+	}
+}
+
+// WithRotatorInfoFile 设置 keyinfo 文件路径，默认位于初始密钥的临时文件目录
+func WithRotatorInfoFile(path string) RotatorOption {
+	return func(r *Rotator) {
+		r.infoFile = path
+	}
+}
+
+// WithRotateErrorHandler 设置后台自动轮换失败时的回调
+func WithRotateErrorHandler(fn func(error)) RotatorOption {
+	return func(r *Rotator) {
+		r.onError = fn
+	}
+}
+
+// WithRetireGrace 设置退役密钥的宽限期：超出保留数量的密钥在宽限期结束后才清理密钥文件，
+// 宽限期内仍可通过 LookupKey 查找，适用于旧密钥尚未滑出播放列表窗口的场景
+func WithRetireGrace(d time.Duration) RotatorOption {
+	return func(r *Rotator) {
+		r.grace = d
+	}
+}
+
+// WithKeyHistory 设置密钥历史，轮换器记录每个密钥的使用期，已清理的旧密钥仍可通过 LookupKey 查找
+func WithKeyHistory(h *KeyHistory) RotatorOption {
+	return func(r *Rotator) {
+		r.history = h
+	}
+}
+
+// NewRotator 创建密钥轮换器并写入初始 keyinfo 文件，interval 为 0 且未设置 WithSchedule 时只能手动轮换
+// 轮换器接管 k 的生命周期，Dispose 时一并清理
+func NewRotator(k *KeyInfo, interval time.Duration, opts ...RotatorOption) (*Rotator, error) {
+	if k.key == nil {
+		return nil, fmt.Errorf("密钥未初始化")
+	}
+	if interval < 0 {
+		return nil, fmt.Errorf("轮换间隔不能为负数: %v", interval)
+	}
+
+	r := &Rotator{
+		current: k,
+		keep:    DefaultKeepPrevious,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.schedule == nil && interval > 0 {
+		r.schedule = Every(interval)
+	}
+	if r.keep < 0 {
+		r.keep = 0
+	}
+	if r.infoFile == "" {
+		// 与 WriteToTempFile 的文件区分，避免被单个密钥的 Dispose 删除
+		name := strings.Replace(k.infoFileName(), "hls_keyinfo_", "hls_keyinfo_rotator_", 1)
+		r.infoFile = filepath.Join(k.dir(), name)
+	}
+
+	if r.ksm != nil {
+		if err := k.PublishFairPlay(context.Background(), r.ksm); err != nil {
+			return nil, err
+		}
+	}
+	if err := k.writeInfoFile(r.infoFile); err != nil {
+		return nil, err
+	}
+	if r.history != nil {
+		if err := r.history.Add(k, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if r.store != nil {
+		if err := r.store.Put(context.Background(), *k.record(time.Now())); err != nil {
+			return nil, fmt.Errorf("保存密钥失败: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// InfoFile 返回路径固定的 keyinfo 文件，作为 ffmpeg 的 -hls_key_info_file 参数
+func (r *Rotator) InfoFile() string {
+	return r.infoFile
+}
+
+// Current 返回当前密钥
+func (r *Rotator) Current() *KeyInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+// Previous 返回保留的历史密钥，最新的在前
+func (r *Rotator) Previous() []*KeyInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]*KeyInfo, len(r.previous))
+	copy(out, r.previous)
+	return out
+}
+
+// Rotate 立即轮换密钥，返回新的当前密钥，轮换器已清理时返回 ErrRotatorDisposed
+func (r *Rotator) Rotate() (*KeyInfo, error) {
+	// 在锁外调用 KeyProvider，避免网络请求阻塞 LookupKey
+	key, wrapped, err := r.generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if r.disposed {
+		r.mu.Unlock()
+		return nil, ErrRotatorDisposed
+	}
+	next, err := r.prepare(key)
+	if err != nil {
+		r.mu.Unlock()
+		return nil, err
+	}
+	next.wrappedKey = wrapped
+	old, retired := r.swap(next)
+	r.mu.Unlock()
+
+	r.finish(old, next, retired)
+	return next, nil
+}
+
+// prepare 生成新密钥实例，登记到 KSM 后重写 keyinfo 文件，key 为空时生成随机密钥；调用方需持有写锁
+func (r *Rotator) prepare(key []byte) (*KeyInfo, error) {
+	next, err := r.current.next(key)
+	if err != nil {
+		return nil, err
+	}
+	if r.ksm != nil {
+		if err := next.PublishFairPlay(context.Background(), r.ksm); err != nil {
+			next.Dispose()
+			return nil, err
+		}
+	}
+	if err := next.writeInfoFile(r.infoFile); err != nil {
+		next.Dispose()
+		return nil, err
+	}
+	return next, nil
+}
+
+// swap 将新密钥设为当前密钥，返回旧密钥与超出保留数量的退役密钥；调用方需持有写锁
+func (r *Rotator) swap(next *KeyInfo) (old *KeyInfo, retired []*KeyInfo) {
+	old = r.current
+	r.previous = append([]*KeyInfo{old}, r.previous...)
+	r.current = next
+	if len(r.previous) > r.keep {
+		retired = r.previous[r.keep:]
+		r.previous = r.previous[:r.keep:r.keep]
+	}
+	return old, retired
+}
+
+// finish 在锁外完成轮换后的收尾：记录密钥历史、触发轮换回调并清理退役密钥
+func (r *Rotator) finish(old, next *KeyInfo, retired []*KeyInfo) {
+	// 密钥已生效，历史记录与密钥存储写入失败不回滚轮换，通过错误回调报告
+	now := time.Now()
+	if r.history != nil {
+		if err := errors.Join(r.history.Retire(old.KeyID, now), r.history.Add(next, now)); err != nil && r.onError != nil {
+			r.onError(err)
+		}
+	}
+	if r.store != nil {
+		if err := r.storeRotation(old, next, now); err != nil && r.onError != nil {
+			r.onError(err)
+		}
+	}
+
+	if next.auditor != nil {
+		rec := next.auditRecord(AuditKeyRotated, r.infoFile)
+		rec.Time = now
+		rec.PreviousKeyID = old.KeyID
+		next.auditor.Audit(rec)
+	}
+
+	// 回调在锁外执行，回调中可安全访问轮换器
+	r.mu.RLock()
+	onRotate := r.onRotate
+	r.mu.RUnlock()
+	if onRotate != nil {
+		onRotate(old, next)
+	}
+	for _, k := range retired {
+		r.retire(k)
+	}
+}
+
+// LookupKey 按 KeyID 查找当前密钥、保留的历史密钥、宽限期内的退役密钥或密钥历史中的记录，keyID 为空时返回当前密钥
+func (r *Rotator) LookupKey(keyID string) ([]byte, bool) {
+	r.mu.RLock()
+	if keyID == "" {
+		defer r.mu.RUnlock()
+		return r.current.GetKey(), true
+	}
+	for _, k := range append([]*KeyInfo{r.current}, r.previous...) {
+		if k.KeyID == keyID {
+			r.mu.RUnlock()
+			return k.GetKey(), true
+		}
+	}
+	for k := range r.retiring {
+		if k.KeyID == keyID {
+			r.mu.RUnlock()
+			return k.GetKey(), true
+		}
+	}
+	r.mu.RUnlock()
+
+	if r.history != nil {
+		return r.history.LookupKey(keyID)
+	}
+	return nil, false
+}
+
+// OnRotate 注册密钥轮换回调，每次轮换成功并写入 keyinfo 文件后以旧密钥与新密钥调用
+// 可用于向密钥服务发布新密钥、更新数据库或刷新 CDN 缓存
+func (r *Rotator) OnRotate(fn func(old, new *KeyInfo)) *Rotator {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onRotate = fn
+	return r
+}
+
+// OnDispose 注册密钥清理回调，轮换器清理退役密钥或自身 Dispose 时，在删除密钥文件前调用
+func (r *Rotator) OnDispose(fn func(k *KeyInfo)) *Rotator {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onDispose = fn
+	return r
+}
+
+// retire 清理退役密钥，设置了宽限期时延迟清理
+func (r *Rotator) retire(k *KeyInfo) {
+	if r.grace <= 0 {
+		r.disposeKey(k)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.disposed {
+		return
+	}
+	if r.retiring == nil {
+		r.retiring = make(map[*KeyInfo]*time.Timer)
+	}
+	r.retiring[k] = time.AfterFunc(r.grace, func() {
+		r.mu.Lock()
+		_, ok := r.retiring[k]
+		delete(r.retiring, k)
+		r.mu.Unlock()
+		// Dispose 已提前清理时不再重复清理
+		if ok {
+			r.disposeKey(k)
+		}
+	})
+}
+
+// disposeKey 触发清理回调并清理密钥
+func (r *Rotator) disposeKey(k *KeyInfo) error {
+	r.mu.RLock()
+	onDispose := r.onDispose
+	r.mu.RUnlock()
+	if onDispose != nil {
+		onDispose(k)
+	}
+	return k.Dispose()
+}
+
+// Start 启动后台按间隔或轮换计划自动轮换，ctx 取消或调用 Stop 时停止；重复调用无效
+func (r *Rotator) Start(ctx context.Context) {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+	if r.cancel != nil || r.schedule == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go r.run(ctx, r.done)
+}
+
+// run 按轮换计划执行轮换
+func (r *Rotator) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+	for {
+		next := r.schedule.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			if _, err := r.Rotate(); err != nil && r.onError != nil {
+				r.onError(err)
+			}
+		}
+	}
+}
+
+// Stop 停止后台自动轮换并等待其退出
+func (r *Rotator) Stop() {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+	r.cancel = nil
+	r.done = nil
+}
+
+// Dispose 停止轮换并清理所有密钥文件与 keyinfo 文件，重复调用无效
+func (r *Rotator) Dispose() error {
+	r.Stop()
+
+	r.mu.Lock()
+	if r.disposed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.disposed = true
+	keys := append([]*KeyInfo{r.current}, r.previous...)
+	// 从 retiring 中移除的密钥由此处清理，已触发的定时器将跳过
+	for k, timer := range r.retiring {
+		timer.Stop()
+		keys = append(keys, k)
+		delete(r.retiring, k)
+	}
+	r.previous = nil
+	r.mu.Unlock()
+
+	var errs []error
+	for _, k := range keys {
+		if err := r.disposeKey(k); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := os.Remove(r.infoFile); err != nil && !os.IsNotExist(err) {
+		errs = append(errs, fmt.Errorf("删除 keyinfo 文件失败: %w", err))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("清理轮换器时发生错误: %v", errs)
+	}
+	return nil
+}
+
+// next 生成用于轮换的新实例：沿用配置，使用 key（为空时生成新的随机密钥）与独立密钥文件，版本号加一
+// 显式 IV 模式下同时生成新的随机 IV
+func (k *KeyInfo) next(key []byte) (*KeyInfo, error) {
+	n := k.cloneSettings()
+	n.KeyID = ""
+	n.autoKeyID = true
+	n.Version = k.Version + 1
+
+	if key == nil {
+		key = make([]byte, n.keySize)
+		if _, err := io.ReadFull(n.rand(), key); err != nil {
+			return nil, fmt.Errorf("生成密钥失败: %w", err)
+		}
+	}
+	if err := n.SetKey(key); err != nil {
+		return nil, err
+	}
+	if n.ivMode == ivExplicit && n.HasIV() {
+		if err := n.RandIVErr(); err != nil {
+			n.Dispose()
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// WithRotateEverySegments 每完成 n 个分片轮换一次密钥，配合 SegmentDone 或 WatchPlaylist 使用
+// ffmpeg 的 periodic_rekey 在每个分片开始时重新读取 keyinfo 文件，因此新密钥从下一个分片起生效
+func WithRotateEverySegments(n int) RotatorOption {
+	return func(r *Rotator) {
+		r.everySegments = n
+	}
+}
+
+// SegmentDone 通知轮换器完成了一个分片，达到 WithRotateEverySegments 设置的数量时轮换密钥
+// 返回本次是否发生了轮换
+func (r *Rotator) SegmentDone() (bool, error) {
+	r.segMu.Lock()
+	defer r.segMu.Unlock()
+	if r.everySegments <= 0 {
+		return false, nil
+	}
+
+	r.segments++
+	if r.segments < r.everySegments {
+		return false, nil
+	}
+	if _, err := r.Rotate(); err != nil {
+		return false, err
+	}
+	r.segments = 0
+	return true, nil
+}
+
+// WatchPlaylist 轮询 ffmpeg 输出的媒体播放列表，每出现一个新分片调用一次 SegmentDone
+// 开始监听时播放列表中已有的分片不计入，重启监听不会立即轮换；播放列表尚不存在时其后出现的分片均计入
+// LL-HLS 的 EXT-X-PART 部分分片不计入，密钥只在完整分片边界切换，同一分片的部分分片始终使用同一密钥
+// 阻塞直到 ctx 取消，轮换失败时通过 WithRotateErrorHandler 设置的回调报告
+func (r *Rotator) WatchPlaylist(ctx context.Context, path string, poll time.Duration) error {
+	if poll <= 0 {
+		poll = time.Second
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	var last string // 已处理的最后一个分片 URI
+	for first := true; ; first = false {
+		segments, err := readPlaylistSegments(path)
+		switch {
+		case err != nil:
+		case first:
+			// 以开始监听时的最后一个分片为起点
+			if len(segments) > 0 {
+				last = segments[len(segments)-1]
+			}
+		default:
+			for _, uri := range newSegments(segments, last) {
+				if _, err := r.SegmentDone(); err != nil && r.onError != nil {
+					r.onError(err)
+				}
+				last = uri
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// readPlaylistSegments 读取媒体播放列表中的分片 URI
+func readPlaylistSegments(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var segments []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		segments = append(segments, line)
+	}
+	return segments, nil
+}
+
+// newSegments 返回 last 之后新增的分片；last 不在列表中（首次读取或已滑出窗口）时返回全部分片
+func newSegments(segments []string, last string) []string {
+	if last == "" {
+		return segments
+	}
+	for i := len(segments) - 1; i >= 0; i-- {
+		if segments[i] == last {
+			return segments[i+1:]
+		}
+	}
+	return segments
+}
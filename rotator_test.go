@@ -0,0 +1,270 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRotator(t *testing.T, interval time.Duration, opts ...RotatorOption) *Rotator {
+	t.Helper()
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	k.RandIV()
+	r, err := NewRotator(k, interval, opts...)
+	if err != nil {
+		t.Fatalf("创建 Rotator 失败: %v", err)
+	}
+	t.Cleanup(func() { r.Dispose() })
+	return r
+}
+
+func readInfoLines(t *testing.T, path string) []string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取 keyinfo 文件失败: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(content)), "\n")
+}
+
+func TestRotatorRotate(t *testing.T) {
+	r := newTestRotator(t, 0, WithKeepPrevious(1))
+	first := r.Current()
+
+	if lines := readInfoLines(t, r.InfoFile()); lines[1] != first.KeyFile {
+		t.Errorf("初始 keyinfo 文件应指向当前密钥文件，实际: %s", lines[1])
+	}
+
+	second, err := r.Rotate()
+	if err != nil {
+		t.Fatalf("Rotate 失败: %v", err)
+	}
+	if bytes.Equal(second.GetKey(), first.GetKey()) || second.IV == first.IV || second.KeyID == first.KeyID {
+		t.Error("轮换后应生成新的密钥、IV 与 KeyID")
+	}
+	if second.Version != first.Version+1 {
+		t.Errorf("期望版本号为 %d，实际: %d", first.Version+1, second.Version)
+	}
+	lines := readInfoLines(t, r.InfoFile())
+	if lines[1] != second.KeyFile || lines[2] != second.IV {
+		t.Errorf("keyinfo 文件未更新为新密钥: %v", lines)
+	}
+
+	// 只保留 1 个历史密钥，更早的密钥文件被清理
+	if _, err := r.Rotate(); err != nil {
+		t.Fatalf("Rotate 失败: %v", err)
+	}
+	prev := r.Previous()
+	if len(prev) != 1 || prev[0] != second {
+		t.Errorf("历史密钥不匹配: %v", prev)
+	}
+	if first.KeyFile != "" {
+		t.Error("超出保留数量的密钥应被清理")
+	}
+}
+
+func TestRotatorRotateAfterDispose(t *testing.T) {
+	r := newTestRotator(t, 0)
+	dir := filepath.Dir(r.Current().KeyFile)
+	if err := r.Dispose(); err != nil {
+		t.Fatalf("Dispose 失败: %v", err)
+	}
+
+	if _, err := r.Rotate(); !errors.Is(err, ErrRotatorDisposed) {
+		t.Errorf("清理后轮换应返回 ErrRotatorDisposed，实际: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("清理后轮换不应写入文件，实际: %v", entries)
+	}
+}
+
+func TestRotatorStart(t *testing.T) {
+	r := newTestRotator(t, 20*time.Millisecond)
+	first := r.Current()
+
+	r.Start(context.Background())
+	deadline := time.After(2 * time.Second)
+	for r.Current() == first {
+		select {
+		case <-deadline:
+			t.Fatal("自动轮换未发生")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	r.Stop()
+
+	infoFile := r.InfoFile()
+	if err := r.Dispose(); err != nil {
+		t.Fatalf("Dispose 失败: %v", err)
+	}
+	if _, err := os.Stat(infoFile); !os.IsNotExist(err) {
+		t.Error("Dispose 后 keyinfo 文件应被删除")
+	}
+}
+
+func TestRotatorSegments(t *testing.T) {
+	r := newTestRotator(t, 0, WithRotateEverySegments(2))
+	first := r.Current()
+
+	rotated, err := r.SegmentDone()
+	if err != nil || rotated {
+		t.Fatalf("第 1 个分片不应轮换: %v, %v", rotated, err)
+	}
+	rotated, err = r.SegmentDone()
+	if err != nil || !rotated {
+		t.Fatalf("第 2 个分片应轮换: %v, %v", rotated, err)
+	}
+	if r.Current() == first {
+		t.Error("轮换后当前密钥应变化")
+	}
+}
+
+func TestRotatorWatchPlaylist(t *testing.T) {
+	r := newTestRotator(t, 0, WithRotateEverySegments(2))
+	first := r.Current()
+
+	// 先开始监听，ffmpeg 随后创建播放列表，其中的分片均计入
+	playlist := filepath.Join(t.TempDir(), "index.m3u8")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go r.WatchPlaylist(ctx, playlist, 10*time.Millisecond)
+
+	time.Sleep(30 * time.Millisecond)
+	content := "#EXTM3U\n#EXT-X-TARGETDURATION:2\n#EXTINF:2,\nseg0.ts\n#EXTINF:2,\nseg1.ts\n"
+	if err := os.WriteFile(playlist, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入播放列表失败: %v", err)
+	}
+
+	for r.Current() == first {
+		select {
+		case <-ctx.Done():
+			t.Fatal("监听播放列表后未发生轮换")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestRotatorWatchPlaylistExisting(t *testing.T) {
+	r := newTestRotator(t, 0, WithRotateEverySegments(2))
+	first := r.Current()
+
+	// 监听已有分片的直播播放列表，已有分片不计入
+	playlist := filepath.Join(t.TempDir(), "index.m3u8")
+	content := "#EXTM3U\n#EXT-X-TARGETDURATION:2\n"
+	for i := 0; i < 5; i++ {
+		content += fmt.Sprintf("#EXTINF:2,\nseg%d.ts\n", i)
+	}
+	if err := os.WriteFile(playlist, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入播放列表失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go r.WatchPlaylist(ctx, playlist, 10*time.Millisecond)
+
+	time.Sleep(100 * time.Millisecond)
+	if r.Current() != first {
+		t.Fatal("开始监听时已有的分片不应触发轮换")
+	}
+
+	content += "#EXTINF:2,\nseg5.ts\n#EXTINF:2,\nseg6.ts\n"
+	if err := os.WriteFile(playlist, []byte(content), 0o600); err != nil {
+		t.Fatalf("写入播放列表失败: %v", err)
+	}
+	for r.Current() == first {
+		select {
+		case <-ctx.Done():
+			t.Fatal("新增分片后未发生轮换")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if next := r.Current(); next.Version != first.Version+1 {
+		t.Errorf("新增两个分片应只轮换一次，版本: %d", next.Version)
+	}
+}
+
+func TestRotatorCallbacks(t *testing.T) {
+	var rotated [][2]*KeyInfo
+	var disposed []*KeyInfo
+	r := newTestRotator(t, 0, WithKeepPrevious(0))
+	r.OnRotate(func(old, new *KeyInfo) {
+		// 回调中可访问轮换器
+		if r.Current() != new {
+			t.Error("回调时当前密钥应为新密钥")
+		}
+		rotated = append(rotated, [2]*KeyInfo{old, new})
+	}).OnDispose(func(k *KeyInfo) {
+		if k.KeyFile == "" {
+			t.Error("清理回调时密钥文件应仍可访问")
+		}
+		disposed = append(disposed, k)
+	})
+
+	first := r.Current()
+	next, err := r.Rotate()
+	if err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+	if len(rotated) != 1 || rotated[0][0] != first || rotated[0][1] != next {
+		t.Errorf("轮换回调参数不正确: %v", rotated)
+	}
+	if len(disposed) != 1 || disposed[0] != first {
+		t.Errorf("退役密钥应触发清理回调: %v", disposed)
+	}
+
+	r.Dispose()
+	if len(disposed) != 2 || disposed[1] != next {
+		t.Errorf("Dispose 应对当前密钥触发清理回调: %v", disposed)
+	}
+}
+
+func TestRotatorRetireGrace(t *testing.T) {
+	r := newTestRotator(t, 0, WithKeepPrevious(0), WithRetireGrace(50*time.Millisecond))
+	first := r.Current()
+	keyFile := first.KeyFile // 退役后由定时器清理，提前读取避免数据竞争
+	if _, err := r.Rotate(); err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+
+	// 宽限期内密钥文件保留且可查找
+	if _, err := os.Stat(keyFile); err != nil {
+		t.Errorf("宽限期内退役密钥文件应保留: %v", err)
+	}
+	if _, ok := r.LookupKey(first.KeyID); !ok {
+		t.Error("宽限期内应能查找到退役密钥")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(keyFile); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("宽限期结束后退役密钥文件应被删除")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := r.LookupKey(first.KeyID); ok {
+		t.Error("宽限期结束后不应查找到退役密钥")
+	}
+
+	// Dispose 立即清理宽限期内的密钥
+	second := r.Current()
+	if _, err := r.Rotate(); err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+	keyFile = second.KeyFile
+	r.Dispose()
+	if _, err := os.Stat(keyFile); !os.IsNotExist(err) {
+		t.Error("Dispose 后宽限期内的密钥文件应被删除")
+	}
+}
@@ -0,0 +1,131 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"fmt"
+)
+
+// S3Client S3 客户端，由调用方基于 AWS SDK 等实现，使本包无需依赖具体 SDK
+// 对象不存在时 GetObject 与 DeleteObject 应返回包装了 ErrKeyNotFound 的错误
+type S3Client interface {
+	PutObject(ctx context.Context, in S3PutObjectInput) error
+	GetObject(ctx context.Context, bucket, key string) ([]byte, error)
+	DeleteObject(ctx context.Context, bucket, key string) error
+	// ListObjects 返回前缀下的全部对象名，需自行处理分页
+	ListObjects(ctx context.Context, bucket, prefix string) ([]string, error)
+}
+
+// S3PutObjectInput 上传对象参数
+type S3PutObjectInput struct {
+	Bucket               string
+	Key                  string
+	Body                 []byte
+	ContentType          string
+	ServerSideEncryption string // 服务端加密方式，如 aws:kms，为空时使用存储桶默认设置
+	SSEKMSKeyID          string // SSE-KMS 使用的 KMS 密钥，为空时使用 AWS 托管密钥
+}
+
+// S3Store 基于 S3 的密钥存储，密钥在主机重启后仍然保留，并可在转码集群与密钥服务集群之间共享
+type S3Store struct {
+	client   S3Client
+	bucket   string
+	prefix   string
+	sse      string
+	kmsKeyID string
+}
+
+var _ KeyStore = &S3Store{}
+
+// S3Option S3 密钥存储选项
+type S3Option func(*S3Store)
+
+// WithS3Prefix 设置对象名前缀，如 "hls/keys/"
+func WithS3Prefix(prefix string) S3Option {
+	return func(s *S3Store) {
+		s.prefix = prefix
+	}
+}
+
+// WithSSEKMS 使用 SSE-KMS 加密密钥对象，kmsKeyID 为空时使用 AWS 托管密钥
+func WithSSEKMS(kmsKeyID string) S3Option {
+	return func(s *S3Store) {
+		s.sse = "aws:kms"
+		s.kmsKeyID = kmsKeyID
+	}
+}
+
+// NewS3Store 创建基于 S3 的密钥存储
+func NewS3Store(client S3Client, bucket string, opts ...S3Option) (*S3Store, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("存储桶名称不能为空")
+	}
+	s := &S3Store{client: client, bucket: bucket}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Put 实现 KeyStore 接口
+func (s *S3Store) Put(ctx context.Context, rec KeyRecord) error {
+	name, err := objectName(s.prefix, rec.KeyID)
+	if err != nil {
+		return err
+	}
+	data, err := encodeRecord(rec)
+	if err != nil {
+		return err
+	}
+	err = s.client.PutObject(ctx, S3PutObjectInput{
+		Bucket:               s.bucket,
+		Key:                  name,
+		Body:                 data,
+		ContentType:          "application/json",
+		ServerSideEncryption: s.sse,
+		SSEKMSKeyID:          s.kmsKeyID,
+	})
+	if err != nil {
+		return fmt.Errorf("上传密钥对象失败: %w", err)
+	}
+	return nil
+}
+
+// Get 实现 KeyStore 接口
+func (s *S3Store) Get(ctx context.Context, keyID string) (KeyRecord, error) {
+	name, err := objectName(s.prefix, keyID)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	data, err := s.client.GetObject(ctx, s.bucket, name)
+	if err != nil {
+		return KeyRecord{}, fmt.Errorf("下载密钥对象失败: %w", err)
+	}
+	return decodeRecord(data)
+}
+
+// Delete 实现 KeyStore 接口
+func (s *S3Store) Delete(ctx context.Context, keyID string) error {
+	name, err := objectName(s.prefix, keyID)
+	if err != nil {
+		return err
+	}
+	if err := s.client.DeleteObject(ctx, s.bucket, name); err != nil {
+		return fmt.Errorf("删除密钥对象失败: %w", err)
+	}
+	return nil
+}
+
+// List 实现 KeyStore 接口
+func (s *S3Store) List(ctx context.Context, stream string) ([]KeyRecord, error) {
+	names, err := s.client.ListObjects(ctx, s.bucket, s.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("列出密钥对象失败: %w", err)
+	}
+	return listObjectRecords(ctx, names, stream, func(ctx context.Context, name string) ([]byte, error) {
+		data, err := s.client.GetObject(ctx, s.bucket, name)
+		if err != nil {
+			return nil, fmt.Errorf("下载密钥对象失败: %w", err)
+		}
+		return data, nil
+	})
+}
@@ -0,0 +1,102 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeObjects 内存对象存储，用于模拟各云存储客户端
+type fakeObjects struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeObjects() *fakeObjects {
+	return &fakeObjects{objects: make(map[string][]byte)}
+}
+
+func (f *fakeObjects) put(name string, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[name] = slices.Clone(data)
+}
+
+func (f *fakeObjects) get(name string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.objects[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, name)
+	}
+	return slices.Clone(data), nil
+}
+
+func (f *fakeObjects) delete(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.objects[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, name)
+	}
+	delete(f.objects, name)
+	return nil
+}
+
+func (f *fakeObjects) list(prefix string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var names []string
+	for name := range f.objects {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// fakeS3 模拟 S3Client
+type fakeS3 struct {
+	*fakeObjects
+	puts []S3PutObjectInput
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, in S3PutObjectInput) error {
+	f.put(in.Bucket+"/"+in.Key, in.Body)
+	f.mu.Lock()
+	f.puts = append(f.puts, in)
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	return f.get(bucket + "/" + key)
+}
+
+func (f *fakeS3) DeleteObject(ctx context.Context, bucket, key string) error {
+	return f.delete(bucket + "/" + key)
+}
+
+func (f *fakeS3) ListObjects(ctx context.Context, bucket, prefix string) ([]string, error) {
+	var keys []string
+	for _, name := range f.list(bucket + "/" + prefix) {
+		keys = append(keys, strings.TrimPrefix(name, bucket+"/"))
+	}
+	return keys, nil
+}
+
+func TestS3Store(t *testing.T) {
+	client := &fakeS3{fakeObjects: newFakeObjects()}
+	store, err := NewS3Store(client, "media-keys", WithS3Prefix("hls/"), WithSSEKMS("alias/hls"))
+	if err != nil {
+		t.Fatalf("创建 S3Store 失败: %v", err)
+	}
+	testKeyStore(t, store)
+
+	in := client.puts[0]
+	if in.Key != "hls/key-a.json" || in.ServerSideEncryption != "aws:kms" || in.SSEKMSKeyID != "alias/hls" {
+		t.Errorf("上传参数不正确: %+v", in)
+	}
+}
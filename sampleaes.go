@@ -0,0 +1,577 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const tsPacketSize = 188
+
+// MPEG-TS 流类型，加密后按 Apple SAMPLE-AES 规范改为对应的私有类型
+const (
+	streamTypeAAC           = 0x0f
+	streamTypeH264          = 0x1b
+	streamTypeAACSampleAES  = 0xcf
+	streamTypeH264SampleAES = 0xdb
+)
+
+// sampleAESClearTypes 保持明文的流类型：私有数据、ID3 元数据与 SCTE-35
+var sampleAESClearTypes = map[byte]bool{0x06: true, 0x15: true, 0x86: true}
+
+// sampleAESLeader H.264 NAL 开头与 AAC 帧头之后保持明文的字节数
+const (
+	sampleAESVideoLeader = 32
+	sampleAESAudioLeader = 16
+)
+
+// tsPES 分片中的一个 PES 包及其占用的 TS 包
+type tsPES struct {
+	pid   uint16
+	slots []int    // 各 TS 包在分片中的位置
+	afs   [][]byte // 各 TS 包自适应字段中需保留的内容，第一个包保留全部字段，其余只保留 PCR
+	data  []byte
+}
+
+// EncryptSampleAES 按 Apple SAMPLE-AES 规范加密 MPEG-TS 分片中的 H.264 视频与 ADTS AAC 音频，从 src 读取明文写入 dst
+// 视频只加密长度超过 48 字节的切片 NAL：开头 32 字节保持明文，其后每 10 个块加密第 1 个，加密后重新插入防竞争字节；
+// 音频每个 ADTS 帧头与其后 16 字节保持明文，其余完整的块全部加密；每个 NAL 或音频帧从该分片的 IV 开始 CBC
+// seq 与 IV 规则同 EncryptSegment。PMT 中的流类型改为 0xdb/0xcf 并添加 zavc、aacd 与 apad 描述符，
+// ID3 等元数据流保持明文，其他音视频编码返回错误。要求加密方式为 SAMPLE-AES，分片整体读入内存
+func (k *KeyInfo) EncryptSampleAES(dst io.Writer, src io.Reader, seq uint64) error {
+	if err := k.requireSampleAES(); err != nil {
+		return err
+	}
+	block, iv, err := k.blockKey(seq)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return fmt.Errorf("读取分片失败: %w", err)
+	}
+	if len(data)%tsPacketSize != 0 {
+		return fmt.Errorf("分片长度不是 %d 的整数倍", tsPacketSize)
+	}
+
+	var (
+		pmtPIDs = make(map[uint16]bool)
+		streams map[uint16]byte // 第一个 PMT 中各 PID 的流类型
+		pes     []*tsPES
+		open    = make(map[uint16]*tsPES) // 各 PID 尚未结束的 PES
+	)
+	n := len(data) / tsPacketSize
+	for i := range n {
+		pkt := data[i*tsPacketSize : (i+1)*tsPacketSize]
+		if pkt[0] != 0x47 {
+			return fmt.Errorf("第 %d 个 TS 包缺少同步字节", i)
+		}
+		pid, pusi := uint16(pkt[1]&0x1f)<<8|uint16(pkt[2]), pkt[1]&0x40 != 0
+		af, payload, err := tsPacketFields(pkt)
+		if err != nil {
+			return fmt.Errorf("第 %d 个 TS 包: %w", i, err)
+		}
+		switch {
+		case pid == 0 && pusi:
+			if err := parsePAT(payload, pmtPIDs); err != nil {
+				return err
+			}
+		case pmtPIDs[pid]:
+			if streams == nil && pusi {
+				if streams, err = parsePMTStreams(payload); err != nil {
+					return err
+				}
+			}
+		case streams != nil && (streams[pid] == streamTypeH264 || streams[pid] == streamTypeAAC):
+			if payload == nil {
+				continue
+			}
+			p := open[pid]
+			if pusi {
+				p = &tsPES{pid: pid}
+				open[pid] = p
+				pes = append(pes, p)
+				af = tsAFFields(af)
+			} else if p == nil {
+				// 分片开头不完整的 PES 无法加密，保持原样
+				continue
+			} else {
+				af = tsPCRField(af)
+			}
+			p.slots = append(p.slots, i)
+			p.afs = append(p.afs, af)
+			p.data = append(p.data, payload...)
+		}
+	}
+	if streams == nil {
+		return fmt.Errorf("分片中没有 PMT")
+	}
+	var setup []byte
+	encrypt := false
+	for pid, typ := range streams {
+		switch {
+		case typ == streamTypeH264SampleAES || typ == streamTypeAACSampleAES:
+			return fmt.Errorf("分片已加密")
+		case typ == streamTypeH264 || typ == streamTypeAAC:
+			encrypt = true
+		case !sampleAESClearTypes[typ]:
+			return fmt.Errorf("SAMPLE-AES 不支持 PID %d 的流类型 0x%02x", pid, typ)
+		}
+	}
+	if !encrypt {
+		return fmt.Errorf("分片中没有 H.264 或 AAC 流")
+	}
+
+	repl := make(map[int][][]byte)
+	for _, p := range pes {
+		head, es, err := splitPES(p.data)
+		if err != nil {
+			return fmt.Errorf("PID %d: %w", p.pid, err)
+		}
+		if streams[p.pid] == streamTypeH264 {
+			if es, err = encryptH264(block, iv, es); err != nil {
+				return fmt.Errorf("PID %d: %w", p.pid, err)
+			}
+		} else {
+			if setup == nil && len(es) >= 7 {
+				setup = aacSetupInfo(es)
+			}
+			if err := encryptADTS(block, iv, es); err != nil {
+				return fmt.Errorf("PID %d: %w", p.pid, err)
+			}
+		}
+		out := append(head, es...)
+		if l := binary.BigEndian.Uint16(out[4:]); l != 0 {
+			if len(out)-6 > 0xffff {
+				return fmt.Errorf("PID %d: PES 过长", p.pid)
+			}
+			binary.BigEndian.PutUint16(out[4:], uint16(len(out)-6))
+		}
+		pkts := packetizePES(p.pid, out, p.afs)
+		for j, slot := range p.slots {
+			switch {
+			case j == len(p.slots)-1 && j < len(pkts):
+				repl[slot] = pkts[j:]
+			case j < len(pkts):
+				repl[slot] = pkts[j : j+1]
+			default:
+				repl[slot] = nil
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	cc := make(map[uint16]byte)
+	for i := range n {
+		pkt := data[i*tsPacketSize : (i+1)*tsPacketSize]
+		pid := uint16(pkt[1]&0x1f)<<8 | uint16(pkt[2])
+		pkts, ok := repl[i]
+		if !ok {
+			if pmtPIDs[pid] && pkt[1]&0x40 != 0 {
+				p, err := sampleAESPMT(pkt, setup)
+				if err != nil {
+					return err
+				}
+				pkt = p
+			}
+			pkts = [][]byte{pkt}
+		}
+		for _, p := range pkts {
+			// 重新分包的 PID 连续计数器按输出顺序重新编号
+			if t := streams[pid]; t == streamTypeH264 || t == streamTypeAAC {
+				c, seen := cc[pid]
+				if !seen {
+					c = (p[3] - 1) & 0x0f
+				}
+				if p[3]&0x10 != 0 {
+					c = (c + 1) & 0x0f
+				}
+				cc[pid] = c
+				p = append([]byte(nil), p...)
+				p[3] = p[3]&0xf0 | c
+			}
+			buf.Write(p)
+		}
+	}
+	if _, err := dst.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("写入分片失败: %w", err)
+	}
+	return nil
+}
+
+// tsPacketFields 返回 TS 包的自适应字段（不含长度字节）与负载，不存在时为 nil
+func tsPacketFields(pkt []byte) (af, payload []byte, err error) {
+	afc := pkt[3] >> 4 & 3
+	pos := 4
+	if afc&2 != 0 {
+		l := int(pkt[4])
+		if 5+l > tsPacketSize {
+			return nil, nil, fmt.Errorf("自适应字段长度无效")
+		}
+		af, pos = pkt[5:5+l], 5+l
+	}
+	if afc&1 != 0 && pos < tsPacketSize {
+		payload = pkt[pos:]
+	}
+	return af, payload, nil
+}
+
+// tsAFFields 返回自适应字段中去除填充字节后的内容
+func tsAFFields(af []byte) []byte {
+	if len(af) == 0 {
+		return nil
+	}
+	n := 1
+	flags := af[0]
+	for _, f := range []struct {
+		flag byte
+		size int
+	}{{0x10, 6}, {0x08, 6}, {0x04, 1}} {
+		if flags&f.flag != 0 {
+			n += f.size
+		}
+	}
+	for _, flag := range []byte{0x02, 0x01} {
+		if flags&flag != 0 && n < len(af) {
+			n += 1 + int(af[n])
+		}
+	}
+	return bytes.Clone(af[:min(n, len(af))])
+}
+
+// tsPCRField 返回只包含 PCR 的自适应字段内容，af 中没有 PCR 时返回 nil
+func tsPCRField(af []byte) []byte {
+	if len(af) < 7 || af[0]&0x10 == 0 {
+		return nil
+	}
+	return append([]byte{0x10}, af[1:7]...)
+}
+
+// parsePAT 记录 PAT 中的 PMT PID
+func parsePAT(payload []byte, pmt map[uint16]bool) error {
+	section, err := psiSection(payload)
+	if err != nil {
+		return fmt.Errorf("PAT: %w", err)
+	}
+	for p := section[8 : len(section)-4]; len(p) >= 4; p = p[4:] {
+		if binary.BigEndian.Uint16(p) != 0 {
+			pmt[binary.BigEndian.Uint16(p[2:])&0x1fff] = true
+		}
+	}
+	return nil
+}
+
+// parsePMTStreams 返回 PMT 中各基本流的流类型
+func parsePMTStreams(payload []byte) (map[uint16]byte, error) {
+	section, err := psiSection(payload)
+	if err != nil {
+		return nil, fmt.Errorf("PMT: %w", err)
+	}
+	streams := make(map[uint16]byte)
+	err = pmtStreams(section, func(es []byte) {
+		streams[binary.BigEndian.Uint16(es[1:])&0x1fff] = es[0]
+	})
+	return streams, err
+}
+
+// pmtStreams 按顺序以每个基本流的条目（含描述符）调用 fn
+func pmtStreams(section []byte, fn func(es []byte)) error {
+	if len(section) < 16 {
+		return fmt.Errorf("PMT 长度不足")
+	}
+	pos := 12 + int(binary.BigEndian.Uint16(section[10:])&0x0fff)
+	for end := len(section) - 4; pos < end; {
+		if pos+5 > end {
+			return fmt.Errorf("PMT 条目不完整")
+		}
+		l := 5 + int(binary.BigEndian.Uint16(section[pos+3:])&0x0fff)
+		if pos+l > end {
+			return fmt.Errorf("PMT 条目不完整")
+		}
+		fn(section[pos : pos+l])
+		pos += l
+	}
+	return nil
+}
+
+// psiSection 返回 PSI 负载中的第一个完整段（含 CRC），段须位于同一个 TS 包内
+func psiSection(payload []byte) ([]byte, error) {
+	if len(payload) < 1 || 1+int(payload[0])+3 > len(payload) {
+		return nil, fmt.Errorf("段不完整")
+	}
+	s := payload[1+int(payload[0]):]
+	l := 3 + int(binary.BigEndian.Uint16(s[1:])&0x0fff)
+	if l > len(s) || l < 12 {
+		return nil, fmt.Errorf("段不完整或跨越多个 TS 包")
+	}
+	return s[:l], nil
+}
+
+// sampleAESPMT 改写 PMT 包中 H.264 与 AAC 的流类型并添加 SAMPLE-AES 描述符
+// setup 为 AAC 的 audio_setup_information，没有音频时为空
+func sampleAESPMT(pkt []byte, setup []byte) ([]byte, error) {
+	af, payload, err := tsPacketFields(pkt)
+	if err != nil {
+		return nil, err
+	}
+	section, err := psiSection(payload)
+	if err != nil {
+		return nil, fmt.Errorf("PMT: %w", err)
+	}
+	// 节头与节目描述符不变，逐个改写基本流条目
+	head := 12 + int(binary.BigEndian.Uint16(section[10:])&0x0fff)
+	if head > len(section)-4 {
+		return nil, fmt.Errorf("PMT 节目描述符长度无效")
+	}
+	out := bytes.Clone(section[:head])
+	err = pmtStreams(section, func(es []byte) {
+		entry := bytes.Clone(es)
+		var desc []byte
+		switch es[0] {
+		case streamTypeH264:
+			entry[0] = streamTypeH264SampleAES
+			desc = append([]byte{0x0f, 4}, "zavc"...)
+		case streamTypeAAC:
+			entry[0] = streamTypeAACSampleAES
+			desc = append([]byte{0x0f, 4}, "aacd"...)
+			if setup != nil {
+				desc = append(desc, 0x05, byte(4+len(setup)))
+				desc = append(append(desc, "apad"...), setup...)
+			}
+		}
+		entry = append(entry, desc...)
+		binary.BigEndian.PutUint16(entry[3:], 0xf000|uint16(len(entry)-5))
+		out = append(out, entry...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint16(out[1:], 0xb000|uint16(len(out)+4-3))
+	out = binary.BigEndian.AppendUint32(out, crc32MPEG(out))
+
+	// 去除自适应字段中的填充，PSI 段之后以 0xff 填充
+	p := append([]byte(nil), pkt[:4]...)
+	if af = tsAFFields(af); len(af) > 1 || len(af) == 1 && af[0] != 0 {
+		p = append(append(p, byte(len(af))), af...)
+	} else {
+		p[3] &^= 0x20
+	}
+	p = append(append(p, 0), out...) // pointer_field 为 0
+	if len(p) > tsPacketSize {
+		return nil, fmt.Errorf("添加描述符后 PMT 超出一个 TS 包")
+	}
+	for len(p) < tsPacketSize {
+		p = append(p, 0xff)
+	}
+	return p, nil
+}
+
+// crc32MPEG 计算 PSI 段使用的 CRC-32/MPEG-2
+func crc32MPEG(data []byte) uint32 {
+	crc := uint32(0xffffffff)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for range 8 {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// splitPES 将 PES 包分为包头与基本流数据
+func splitPES(data []byte) (head, es []byte, err error) {
+	if len(data) < 9 || data[0] != 0 || data[1] != 0 || data[2] != 1 {
+		return nil, nil, fmt.Errorf("无效的 PES 包头")
+	}
+	n := 9 + int(data[8])
+	if n > len(data) {
+		return nil, nil, fmt.Errorf("PES 包头不完整")
+	}
+	if l := int(binary.BigEndian.Uint16(data[4:])); l != 0 && 6+l < len(data) {
+		data = data[:6+l]
+	}
+	return bytes.Clone(data[:n]), data[n:], nil
+}
+
+// packetizePES 将 PES 包分为 TS 包，afs 为各包需携带的自适应字段内容，最后一个包以自适应字段填充
+// 连续计数器由调用方设置
+func packetizePES(pid uint16, pes []byte, afs [][]byte) [][]byte {
+	var pkts [][]byte
+	for i := 0; len(pes) > 0; i++ {
+		var af []byte
+		if i < len(afs) {
+			af = afs[i]
+		}
+		room := tsPacketSize - 4
+		if af != nil {
+			room -= 1 + len(af)
+		}
+		n := min(room, len(pes))
+		pkts = append(pkts, tsPacket(pid, i == 0, af, pes[:n]))
+		pes = pes[n:]
+	}
+	return pkts
+}
+
+// tsPacket 生成带负载的 TS 包，负载不足时在自适应字段中填充
+func tsPacket(pid uint16, pusi bool, af, payload []byte) []byte {
+	p := []byte{0x47, byte(pid >> 8 & 0x1f), byte(pid), 0x10}
+	if pusi {
+		p[1] |= 0x40
+	}
+	stuff := tsPacketSize - 4 - len(payload)
+	if af != nil {
+		stuff -= 1 + len(af)
+	}
+	if af != nil || stuff > 0 {
+		p[3] |= 0x20
+		if af == nil {
+			stuff--
+			if stuff > 0 {
+				af = []byte{0}
+				stuff--
+			}
+		}
+		p = append(p, byte(len(af)+stuff))
+		p = append(p, af...)
+		p = append(p, bytes.Repeat([]byte{0xff}, stuff)...)
+	}
+	return append(p, payload...)
+}
+
+// encryptH264 加密 Annex B 字节流中的切片 NAL，返回新的字节流；不以起始码开头的负载返回错误
+func encryptH264(block cipher.Block, iv []byte, es []byte) ([]byte, error) {
+	if len(es) == 0 {
+		return es, nil
+	}
+	// 起始码前只允许零字节
+	first := bytes.Index(es, []byte{0, 0, 1})
+	if first < 0 || len(bytes.TrimLeft(es[:first], "\x00")) > 0 {
+		return nil, fmt.Errorf("H.264 负载不是 Annex B 字节流")
+	}
+	out := make([]byte, 0, len(es)+len(es)/64)
+	pos := 0
+	for pos < len(es) {
+		start, next := nextNAL(es, pos)
+		out = append(out, es[pos:start]...)
+		nal := es[start:next]
+		// 下一个起始码前的零字节不属于 NAL
+		end := len(nal)
+		for end > 0 && nal[end-1] == 0 {
+			end--
+		}
+		// 末尾单独的起始码或相邻的起始码之间没有 NAL
+		if end == 0 {
+			out = append(out, nal...)
+			pos = next
+			continue
+		}
+		if t := nal[0] & 0x1f; end > 48 && (t == 1 || t == 5) {
+			out = append(out, encryptNAL(block, iv, nal[:end])...)
+		} else {
+			out = append(out, nal[:end]...)
+		}
+		out = append(out, nal[end:]...)
+		pos = next
+	}
+	return out, nil
+}
+
+// nextNAL 返回 pos 之后第一个 NAL 的起始位置与下一个起始码的位置；pos 之后没有起始码时返回 len(es)
+func nextNAL(es []byte, pos int) (start, next int) {
+	i := bytes.Index(es[pos:], []byte{0, 0, 1})
+	if i < 0 || pos+i+3 >= len(es) {
+		return len(es), len(es)
+	}
+	start = pos + i + 3
+	j := bytes.Index(es[start:], []byte{0, 0, 1})
+	if j < 0 {
+		return start, len(es)
+	}
+	return start, start + j
+}
+
+// encryptNAL 去除防竞争字节后按 1:9 模式加密 NAL，再重新插入防竞争字节
+func encryptNAL(block cipher.Block, iv []byte, nal []byte) []byte {
+	raw := make([]byte, 0, len(nal))
+	zeros := 0
+	for _, b := range nal {
+		if zeros >= 2 && b == 3 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		raw = append(raw, b)
+	}
+
+	mode := cipher.NewCBCEncrypter(block, iv)
+	// 剩余不超过一个块时不再加密
+	for pos := sampleAESVideoLeader; len(raw)-pos > aes.BlockSize; pos += 10 * aes.BlockSize {
+		mode.CryptBlocks(raw[pos:pos+aes.BlockSize], raw[pos:pos+aes.BlockSize])
+	}
+
+	out := make([]byte, 0, len(raw)+len(raw)/64+1)
+	zeros = 0
+	for _, b := range raw {
+		if zeros >= 2 && b <= 3 {
+			out = append(out, 3)
+			zeros = 0
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		out = append(out, b)
+	}
+	if out[len(out)-1] == 0 {
+		out = append(out, 3)
+	}
+	return out
+}
+
+// encryptADTS 原地加密 ADTS 帧：每帧的帧头与其后 16 字节保持明文，其余完整的块以 CBC 加密
+func encryptADTS(block cipher.Block, iv []byte, es []byte) error {
+	for len(es) > 0 {
+		if len(es) < 7 || es[0] != 0xff || es[1]&0xf0 != 0xf0 {
+			return fmt.Errorf("无效的 ADTS 帧头")
+		}
+		header := 7
+		if es[1]&1 == 0 {
+			header = 9 // 带 CRC
+		}
+		size := int(es[3]&3)<<11 | int(es[4])<<3 | int(es[5])>>5
+		if size < header || size > len(es) {
+			return fmt.Errorf("ADTS 帧长度无效: %d", size)
+		}
+		if body := es[header:size]; len(body) > sampleAESAudioLeader {
+			body = body[sampleAESAudioLeader:]
+			n := len(body) &^ (aes.BlockSize - 1)
+			cipher.NewCBCEncrypter(block, iv).CryptBlocks(body[:n], body[:n])
+		}
+		es = es[size:]
+	}
+	return nil
+}
+
+// aacSetupInfo 由第一个 ADTS 帧头生成 apad 描述符中的 audio_setup_information：
+// audio_type 为 zaac，priming 为 0，版本 1，setup_data 为 AudioSpecificConfig
+func aacSetupInfo(adts []byte) []byte {
+	objectType := adts[2]>>6 + 1
+	freq := adts[2] >> 2 & 0x0f
+	channels := adts[2]&1<<2 | adts[3]>>6
+	asc := uint16(objectType)<<11 | uint16(freq)<<7 | uint16(channels)<<3
+	info := append([]byte("zaac"), 0, 0, 1, 2)
+	return binary.BigEndian.AppendUint16(info, asc)
+}
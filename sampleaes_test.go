@@ -0,0 +1,346 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPSI 生成携带单个 PSI 段的 TS 包
+func testPSI(pid uint16, tableID byte, body []byte) []byte {
+	s := []byte{tableID, 0, 0, 0, 1, 0xc1, 0, 0}
+	s = append(s, body...)
+	binary.BigEndian.PutUint16(s[1:], 0xb000|uint16(len(s)+4-3))
+	s = binary.BigEndian.AppendUint32(s, crc32MPEG(s))
+	return tsPacket(pid, true, nil, append([]byte{0}, s...))
+}
+
+// testRBSPNAL 生成指定类型与长度的 NAL，内容包含需要防竞争字节的零序列
+func testRBSPNAL(header byte, size int) []byte {
+	raw := []byte{header}
+	for i := 1; i < size; i++ {
+		if i%50 < 3 {
+			raw = append(raw, 0)
+		} else {
+			raw = append(raw, byte(i))
+		}
+	}
+	var nal []byte
+	zeros := 0
+	for _, b := range raw {
+		if zeros >= 2 && b <= 3 {
+			nal = append(nal, 3)
+			zeros = 0
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		nal = append(nal, b)
+	}
+	return nal
+}
+
+// testADTS 生成带指定负载长度的 ADTS 帧
+func testADTS(payload int, crc bool) []byte {
+	header := 7
+	if crc {
+		header = 9
+	}
+	size := header + payload
+	h := []byte{0xff, 0xf1, 0x50, 0x80, 0, 0, 0xfc}
+	if crc {
+		h[1] = 0xf0
+		h = append(h, 0, 0)
+	}
+	h[3] |= byte(size >> 11 & 3)
+	h[4] = byte(size >> 3)
+	h[5] = byte(size<<5) | 0x1f
+	return append(h, bytes.Repeat([]byte{0x5a}, payload)...)
+}
+
+// testPESPackets 将基本流数据封装为 PES 并分为 TS 包，第一个包携带 PCR
+func testPESPackets(pid uint16, streamID byte, es []byte, pcr bool) [][]byte {
+	pes := []byte{0, 0, 1, streamID, 0, 0, 0x80, 0x80, 5, 0x21, 0, 1, 0, 1}
+	pes = append(pes, es...)
+	if streamID != 0xe0 {
+		binary.BigEndian.PutUint16(pes[4:], uint16(len(pes)-6))
+	}
+	afs := [][]byte{{0x40}}
+	if pcr {
+		afs = [][]byte{{0x50, 0, 0, 0, 0, 0x7e, 0}}
+	}
+	return packetizePES(pid, pes, afs)
+}
+
+// testSampleAESSegment 生成包含 H.264（PID 0x100）与 AAC（PID 0x101）的 TS 分片，返回分片与两路基本流
+func testSampleAESSegment(t *testing.T) ([]byte, []byte, []byte) {
+	t.Helper()
+	video := []byte{0, 0, 0, 1, 0x09, 0xf0}
+	video = append(append(video, 0, 0, 0, 1), testRBSPNAL(0x67, 20)...)
+	video = append(append(video, 0, 0, 1), testRBSPNAL(0x65, 900)...)
+	video = append(append(video, 0, 0, 1), testRBSPNAL(0x41, 40)...)
+	video = append(append(video, 0, 0, 1), testRBSPNAL(0x41, 300)...)
+	audio := append(append(testADTS(100, false), testADTS(12, true)...), testADTS(200, false)...)
+
+	pat := testPSI(0, 0, []byte{0, 1, 0xe0, 0x20})
+	pmt := testPSI(0x20, 2, []byte{0xe1, 0x00, 0xf0, 0x00, 0x1b, 0xe1, 0x00, 0xf0, 0x00, 0x0f, 0xe1, 0x01, 0xf0, 0x00, 0x15, 0xe1, 0x02, 0xf0, 0x00})
+
+	var seg []byte
+	seg = append(seg, pat...)
+	seg = append(seg, pmt...)
+	vp := testPESPackets(0x100, 0xe0, video, true)
+	ap := testPESPackets(0x101, 0xc0, audio, false)
+	// 音视频 TS 包交错排列
+	for i := 0; i < len(vp) || i < len(ap); i++ {
+		if i < len(vp) {
+			vp[i][3] |= byte(i & 0x0f)
+			seg = append(seg, vp[i]...)
+		}
+		if i < len(ap) {
+			ap[i][3] |= byte(i & 0x0f)
+			seg = append(seg, ap[i]...)
+		}
+	}
+	return seg, video, audio
+}
+
+// testDemux 解析测试分片，返回 PMT 段与各 PID 的 PES 数据，并检查连续计数器
+func testDemux(t *testing.T, seg []byte) ([]byte, map[uint16][]byte) {
+	t.Helper()
+	if len(seg)%tsPacketSize != 0 {
+		t.Fatalf("分片长度应为 188 的整数倍: %d", len(seg))
+	}
+	var pmt []byte
+	pes := make(map[uint16][]byte)
+	cc := make(map[uint16]byte)
+	for i := 0; i < len(seg); i += tsPacketSize {
+		pkt := seg[i : i+tsPacketSize]
+		pid := uint16(pkt[1]&0x1f)<<8 | uint16(pkt[2])
+		_, payload, err := tsPacketFields(pkt)
+		if err != nil {
+			t.Fatalf("解析 TS 包失败: %v", err)
+		}
+		if c, ok := cc[pid]; ok && pid >= 0x100 && pkt[3]&0x0f != (c+1)&0x0f {
+			t.Errorf("PID %d 的连续计数器不连续", pid)
+		}
+		cc[pid] = pkt[3] & 0x0f
+		switch {
+		case pid == 0x20:
+			if pmt, err = psiSection(payload); err != nil {
+				t.Fatalf("解析 PMT 失败: %v", err)
+			}
+		case pid >= 0x100:
+			pes[pid] = append(pes[pid], payload...)
+		}
+	}
+	return pmt, pes
+}
+
+// testUnescape 去除 NAL 中的防竞争字节
+func testUnescape(nal []byte) []byte {
+	var out []byte
+	zeros := 0
+	for _, b := range nal {
+		if zeros >= 2 && b == 3 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// testNALs 按起始码拆分 Annex B 字节流
+func testNALs(es []byte) [][]byte {
+	var nals [][]byte
+	for _, part := range bytes.Split(es, []byte{0, 0, 1}) {
+		part = bytes.TrimRight(part, "\x00")
+		if len(part) > 0 {
+			nals = append(nals, part)
+		}
+	}
+	return nals
+}
+
+func TestEncryptSampleAES(t *testing.T) {
+	key := bytes.Repeat([]byte{5}, 16)
+	k, err := NewKeyInfoWithKey("skd://example.com/key", key, WithMethod(MethodSampleAES), WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+	defer k.Dispose()
+	k.UseSequenceIV()
+
+	seg, video, audio := testSampleAESSegment(t)
+	var out bytes.Buffer
+	if err := k.EncryptSampleAES(&out, bytes.NewReader(seg), 7); err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	pmt, pes := testDemux(t, out.Bytes())
+	for i := 0; i < out.Len(); i += tsPacketSize {
+		if pkt := out.Bytes()[i:]; pkt[1]&0x1f == 1 && pkt[2] == 0 {
+			if pkt[3]&0x20 == 0 || pkt[5]&0x50 != 0x50 {
+				t.Errorf("第一个视频 TS 包应保留 PCR 与随机访问标志")
+			}
+			break
+		}
+	}
+
+	if crc32MPEG(pmt) != 0 {
+		t.Errorf("PMT 的 CRC 不正确")
+	}
+	types := make(map[uint16][]byte)
+	pmtStreams(pmt, func(es []byte) {
+		types[binary.BigEndian.Uint16(es[1:])&0x1fff] = es
+	})
+	if v := types[0x100]; v[0] != streamTypeH264SampleAES || !bytes.Contains(v, []byte("zavc")) {
+		t.Errorf("视频流类型应为 0xdb 并带 zavc 描述符: %x", v)
+	}
+	if a := types[0x101]; a[0] != streamTypeAACSampleAES || !bytes.Contains(a, []byte("aacd")) || !bytes.Contains(a, []byte("apadzaac")) {
+		t.Errorf("音频流类型应为 0xcf 并带 aacd 与 apad 描述符: %x", a)
+	}
+	if types[0x102][0] != 0x15 {
+		t.Errorf("ID3 流应保持不变")
+	}
+
+	iv := SequenceIV(7)
+	block, _ := aes.NewCipher(key)
+
+	_, encVideo, err := splitPES(pes[0x100])
+	if err != nil {
+		t.Fatalf("解析视频 PES 失败: %v", err)
+	}
+	want, got := testNALs(video), testNALs(encVideo)
+	if len(got) != len(want) {
+		t.Fatalf("NAL 数量应为 %d，实际: %d", len(want), len(got))
+	}
+	for i := range want {
+		w := testUnescape(want[i])
+		g := testUnescape(got[i])
+		encrypted := len(want[i]) > 48 && (w[0]&0x1f == 1 || w[0]&0x1f == 5)
+		if encrypted == bytes.Equal(g, w) {
+			t.Errorf("NAL %d 的加密状态不正确", i)
+		}
+		if encrypted {
+			mode := cipher.NewCBCDecrypter(block, iv[:])
+			for pos := 32; len(g)-pos > 16; pos += 160 {
+				mode.CryptBlocks(g[pos:pos+16], g[pos:pos+16])
+			}
+		}
+		if !bytes.Equal(g, w) {
+			t.Errorf("NAL %d 解密后不一致", i)
+		}
+		if bytes.Contains(got[i], []byte{0, 0, 1}) || bytes.Contains(got[i], []byte{0, 0, 0}) {
+			t.Errorf("NAL %d 缺少防竞争字节", i)
+		}
+	}
+
+	head, encAudio, err := splitPES(pes[0x101])
+	if err != nil {
+		t.Fatalf("解析音频 PES 失败: %v", err)
+	}
+	if int(binary.BigEndian.Uint16(head[4:])) != len(head)+len(encAudio)-6 {
+		t.Errorf("音频 PES 长度不正确")
+	}
+	if bytes.Equal(encAudio, audio) {
+		t.Fatalf("音频未加密")
+	}
+	plain := bytes.Clone(encAudio)
+	for _, f := range []struct{ off, header, size int }{{0, 7, 107}, {107, 9, 21}, {128, 7, 207}} {
+		if f.header+16 >= f.size {
+			continue
+		}
+		body := plain[f.off+f.header+16 : f.off+f.size]
+		n := len(body) / 16 * 16
+		cipher.NewCBCDecrypter(block, iv[:]).CryptBlocks(body[:n], body[:n])
+	}
+	if !bytes.Equal(plain, audio) {
+		t.Errorf("音频解密后不一致")
+	}
+	if !bytes.Equal(encAudio[107:128], audio[107:128]) {
+		t.Errorf("过短的音频帧应保持明文")
+	}
+
+	if err := k.EncryptSampleAES(&bytes.Buffer{}, bytes.NewReader(out.Bytes()), 7); err == nil {
+		t.Errorf("重复加密应返回错误")
+	}
+	aes128, _ := NewKeyInfoWithKey("https://example.com/key", key, WithTempDir(t.TempDir()))
+	defer aes128.Dispose()
+	if err := aes128.EncryptSampleAES(&bytes.Buffer{}, bytes.NewReader(seg), 7); err == nil {
+		t.Errorf("AES-128 密钥进行 SAMPLE-AES 加密应返回错误")
+	}
+}
+
+func TestEncryptVODSampleAES(t *testing.T) {
+	dir := t.TempDir()
+	playlist := filepath.Join(dir, "index.m3u8")
+	seg, _, _ := testSampleAESSegment(t)
+	os.WriteFile(playlist, []byte("#EXTM3U\n#EXT-X-VERSION:3\n#EXTINF:2.0,\na.ts\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "a.ts"), seg, 0o644)
+
+	k, err := NewKeyInfo("skd://example.com/key", WithMethod(MethodSampleAES), WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建KeyInfo失败: %v", err)
+	}
+	defer k.Dispose()
+	if err := k.EncryptVOD(playlist); err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	got, _ := os.ReadFile(playlist)
+	if !strings.Contains(string(got), "#EXT-X-VERSION:5\n") || !strings.Contains(string(got), "METHOD=SAMPLE-AES") {
+		t.Errorf("应升级到 EXT-X-VERSION:5 并写入 METHOD=SAMPLE-AES:\n%s", got)
+	}
+	enc, _ := os.ReadFile(filepath.Join(dir, "a.ts"))
+	if pmt, _ := testDemux(t, enc); !bytes.Contains(pmt, []byte("zavc")) {
+		t.Errorf("分片应按 SAMPLE-AES 加密")
+	}
+
+	os.WriteFile(playlist, []byte("#EXTM3U\n#EXT-X-MAP:URI=\"init.mp4\"\n#EXTINF:2.0,\na.m4s\n"), 0o644)
+	if err := k.EncryptVOD(playlist); err == nil {
+		t.Errorf("SAMPLE-AES 加密 fMP4 点播应返回错误")
+	}
+}
+
+func TestEncryptH264Malformed(t *testing.T) {
+	block, _ := aes.NewCipher(make([]byte, 16))
+	iv := make([]byte, 16)
+	slice := append([]byte{0x65}, bytes.Repeat([]byte{0x11}, 64)...)
+
+	cases := []struct {
+		name    string
+		es      []byte
+		wantErr bool
+	}{
+		{"无起始码", append([]byte{0x65}, bytes.Repeat([]byte{0x11}, 64)...), true},
+		{"末尾单独的起始码", append(append([]byte{0, 0, 1}, slice...), 0, 0, 1), false},
+		{"相邻的起始码", append([]byte{0, 0, 1, 0, 0, 1}, slice...), false},
+	}
+	for _, c := range cases {
+		out, err := encryptH264(block, iv, c.es)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: 应返回错误", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if len(out) != len(c.es) || bytes.Equal(out, c.es) {
+			t.Errorf("%s: 切片 NAL 应被加密且长度不变", c.name)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package hlskeyinfo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule 轮换计划
+type Schedule interface {
+	// Next 返回 t 之后的下一次轮换时间，返回零值表示不再轮换
+	Next(t time.Time) time.Time
+}
+
+// intervalSchedule 固定间隔的轮换计划
+type intervalSchedule time.Duration
+
+// Every 返回按固定间隔轮换的计划
+func Every(d time.Duration) Schedule {
+	return intervalSchedule(d)
+}
+
+// Next 实现 Schedule 接口
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	if s <= 0 {
+		return time.Time{}
+	}
+	return t.Add(time.Duration(s))
+}
+
+// cronSchedule 由 cron 表达式描述的轮换计划，每个字段以位集合表示
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool // 日期与星期字段是否为 *
+}
+
+// cronDescriptors 常用的预定义表达式
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField cron 字段的取值范围
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{"分钟", 0, 59},
+	{"小时", 0, 23},
+	{"日期", 1, 31},
+	{"月份", 1, 12},
+	{"星期", 0, 7}, // 0 与 7 均表示星期日
+}
+
+// ParseCron 解析标准 5 字段 cron 表达式（分 时 日 月 周），如 "0 3 * * *" 表示每天 03:00
+// 支持 *、列表（1,15）、范围（1-5）、步长（*/10、0-30/5）以及 @daily、@hourly 等预定义表达式
+// 与 Vixie cron 一致，日期与星期字段均不以 * 开头时满足其一即触发，否则需同时满足（如 "0 0 */2 * 1" 为单数日且为星期一）；
+// 时间按传入 Next 的时间所在时区计算
+func ParseCron(expr string) (Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if d, ok := cronDescriptors[spec]; ok {
+		spec = d
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron 表达式应包含 %d 个字段: %q", len(cronFields), expr)
+	}
+
+	var bits [5]uint64
+	for i, f := range fields {
+		b, err := parseCronField(f, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("解析 cron 表达式 %q 失败: %w", expr, err)
+		}
+		bits[i] = b
+	}
+	// 星期 7 与 0 同为星期日
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return &cronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: strings.HasPrefix(fields[2], "*"),
+		dowAny: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronField 解析单个 cron 字段
+func parseCronField(s string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s字段步长无效: %q", f.name, part)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(loStr); err != nil {
+				return 0, fmt.Errorf("%s字段取值无效: %q", f.name, part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiStr); err != nil {
+					return 0, fmt.Errorf("%s字段取值无效: %q", f.name, part)
+				}
+			} else if hasStep {
+				// "5/10" 表示从 5 开始每 10 个单位
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%s字段超出范围 %d-%d: %q", f.name, f.min, f.max, part)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next 实现 Schedule 接口
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// 最多向后查找 5 年，无法匹配的表达式（如 2 月 30 日）返回零值
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay 判断日期是否匹配，日期与星期字段均受限时满足其一即可
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,61 @@
+package hlskeyinfo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	base := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC) // 星期五
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 3 * * *", time.Date(2024, 3, 16, 3, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 3, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2024, 3, 18, 9, 30, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, 3, 17, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 3, 15, 11, 0, 0, 0, time.UTC)},
+		// 日期与星期均受限时满足其一即可
+		{"0 0 20 * 6", time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+		// 以 * 开头的字段视为不受限，与另一字段同时满足：单数日且为星期一
+		{"0 0 */2 * 1", time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * */2", time.Date(2024, 4, 13, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		s, err := ParseCron(c.expr)
+		if err != nil {
+			t.Fatalf("解析 %q 失败: %v", c.expr, err)
+		}
+		if got := s.Next(base); !got.Equal(c.want) {
+			t.Errorf("%q 的下一次时间期望 %v，实际: %v", c.expr, c.want, got)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("无效表达式 %q 应返回错误", expr)
+		}
+	}
+
+	if s, _ := ParseCron("0 0 30 2 *"); !s.Next(base).IsZero() {
+		t.Error("无法匹配的表达式应返回零值")
+	}
+}
+
+func TestRotatorSchedule(t *testing.T) {
+	r := newTestRotator(t, 0, WithSchedule(Every(20*time.Millisecond)))
+	first := r.Current()
+	r.Start(t.Context())
+	defer r.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for r.Current() == first {
+		if time.Now().After(deadline) {
+			t.Fatal("按轮换计划应自动轮换")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,215 @@
+package hlskeyinfo
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// segmentChunk 流式加解密每次处理的字节数，为 AES 块大小的整数倍
+const segmentChunk = 32 * 1024
+
+// segmentKey 返回 AES-128 分片加密使用的密钥与指定媒体序列号分片的 IV
+func (k *KeyInfo) segmentKey(seq uint64) (cipher.Block, []byte, error) {
+	if err := k.requireAES128(); err != nil {
+		return nil, nil, err
+	}
+	return k.blockKey(seq)
+}
+
+// blockKey 返回 16 字节密钥的 AES 块密码与指定媒体序列号分片的 IV
+func (k *KeyInfo) blockKey(seq uint64) (cipher.Block, []byte, error) {
+	if len(k.key) != 16 {
+		return nil, nil, fmt.Errorf("%s 加密需要 16 字节密钥，实际: %d", k.method(), len(k.key))
+	}
+	iv, err := k.SegmentIV(seq)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(k.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, iv, nil
+}
+
+// EncryptSegment 按 HLS AES-128 规则（CBC 模式，PKCS#7 填充）加密整个分片，从 src 读取明文写入 dst
+// seq 为分片的媒体序列号，IV 按该密钥的 IV 规则确定：显式 IV 模式使用该 IV，否则由序列号计算；
+// LL-HLS 的部分分片各自单独加密，seq 取其所属分片的序列号。按块流式处理，内存占用与分片大小无关
+func (k *KeyInfo) EncryptSegment(dst io.Writer, src io.Reader, seq uint64) error {
+	w, err := NewEncryptWriter(dst, k, seq)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return fmt.Errorf("加密分片失败: %w", err)
+	}
+	return w.Close()
+}
+
+// ErrInvalidPadding 解密后的 PKCS#7 填充无效，通常表示密钥或 IV 与分片不匹配
+var ErrInvalidPadding = errors.New("分片填充无效，密钥或 IV 可能不正确")
+
+// DecryptSegment 解密 EncryptSegment 或 ffmpeg 以 AES-128 加密的分片，从 src 读取密文写入去除填充后的明文
+// seq 与 IV 规则同 EncryptSegment；密文长度不是块大小的整数倍时返回错误，填充无效时返回 ErrInvalidPadding，
+// 此时 dst 中可能已写入部分数据
+func (k *KeyInfo) DecryptSegment(dst io.Writer, src io.Reader, seq uint64) error {
+	r, err := NewDecryptReader(src, k, seq)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, r); err != nil {
+		return fmt.Errorf("解密分片失败: %w", err)
+	}
+	return nil
+}
+
+// encryptWriter 见 NewEncryptWriter
+type encryptWriter struct {
+	w      io.Writer
+	mode   cipher.BlockMode
+	buf    []byte // 尚未加密的明文，不超过 segmentChunk
+	closed bool
+	err    error
+}
+
+// NewEncryptWriter 返回加密写入器，写入的明文按 EncryptSegment 的规则加密后写入 w，适用于边加密边上传对象存储
+// 内存占用固定为一个缓冲块；Close 写入最后的填充块，但不关闭 w，未调用 Close 时密文不完整
+func NewEncryptWriter(w io.Writer, k *KeyInfo, seq uint64) (io.WriteCloser, error) {
+	block, iv, err := k.segmentKey(seq)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, mode: cipher.NewCBCEncrypter(block, iv), buf: make([]byte, 0, segmentChunk)}, nil
+}
+
+// Write 实现 io.Writer 接口，缓冲满一块时加密并写入
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, fmt.Errorf("加密写入器已关闭")
+	}
+	if e.err != nil {
+		return 0, e.err
+	}
+	written := 0
+	for len(p) > 0 {
+		n := copy(e.buf[len(e.buf):cap(e.buf)], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+		if len(e.buf) == cap(e.buf) {
+			if err := e.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// flush 加密并写出缓冲区，长度须为块大小的整数倍
+func (e *encryptWriter) flush() error {
+	e.mode.CryptBlocks(e.buf, e.buf)
+	if _, err := e.w.Write(e.buf); err != nil {
+		e.err = fmt.Errorf("写入加密分片失败: %w", err)
+		return e.err
+	}
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// Close 实现 io.Closer 接口，按 PKCS#7 填充并写出最后的数据，长度恰为块大小整数倍时补一个整块
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return e.err
+	}
+	e.closed = true
+	if e.err != nil {
+		return e.err
+	}
+	// 缓冲区满时已在 Write 中写出，此处剩余不足 segmentChunk，追加填充不会超出容量
+	pad := aes.BlockSize - len(e.buf)%aes.BlockSize
+	for range pad {
+		e.buf = append(e.buf, byte(pad))
+	}
+	return e.flush()
+}
+
+// decryptReader 见 NewDecryptReader
+type decryptReader struct {
+	r     io.Reader
+	mode  cipher.BlockMode
+	raw   []byte // 尚未解密的密文
+	n     int    // raw 中的字节数
+	plain []byte // 已解密的明文
+	out   []byte // plain 中尚未读取的部分
+	eof   bool
+	err   error
+}
+
+// NewDecryptReader 返回解密读取器，从 r 读取按 EncryptSegment 规则加密的密文并返回去除填充后的明文
+// 内存占用固定；读到结尾时校验填充，无效时返回 ErrInvalidPadding
+func NewDecryptReader(r io.Reader, k *KeyInfo, seq uint64) (io.Reader, error) {
+	block, iv, err := k.segmentKey(seq)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{
+		r:     r,
+		mode:  cipher.NewCBCDecrypter(block, iv),
+		raw:   make([]byte, segmentChunk+aes.BlockSize),
+		plain: make([]byte, segmentChunk+aes.BlockSize),
+	}, nil
+}
+
+// Read 实现 io.Reader 接口
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.eof {
+			return 0, io.EOF
+		}
+		d.fill()
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// fill 读取并解密下一块；始终保留最后一块密文，读到结尾后才能确定填充
+func (d *decryptReader) fill() {
+	m, err := io.ReadFull(d.r, d.raw[d.n:])
+	d.n += m
+	switch {
+	case err == nil:
+		d.mode.CryptBlocks(d.plain[:segmentChunk], d.raw[:segmentChunk])
+		d.out = d.plain[:segmentChunk]
+		d.n = copy(d.raw, d.raw[segmentChunk:d.n])
+		return
+	case err != io.EOF && err != io.ErrUnexpectedEOF:
+		d.err = fmt.Errorf("读取分片失败: %w", err)
+		return
+	}
+
+	d.eof = true
+	if d.n == 0 || d.n%aes.BlockSize != 0 {
+		d.err = fmt.Errorf("密文长度应为 %d 字节的整数倍", aes.BlockSize)
+		return
+	}
+	d.mode.CryptBlocks(d.plain[:d.n], d.raw[:d.n])
+	pad := int(d.plain[d.n-1])
+	if pad == 0 || pad > aes.BlockSize {
+		d.err = ErrInvalidPadding
+		return
+	}
+	for _, b := range d.plain[d.n-pad : d.n] {
+		if int(b) != pad {
+			d.err = ErrInvalidPadding
+			return
+		}
+	}
+	d.out = d.plain[:d.n-pad]
+}
@@ -0,0 +1,113 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestEncryptSegment(t *testing.T) {
+	k, err := NewKeyInfoWithKey("https://example.com/key", bytes.Repeat([]byte{7}, 16), WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+	defer k.Dispose()
+	k.UseSequenceIV()
+
+	for _, size := range []int{0, 15, 16, segmentChunk, segmentChunk + 1, 3*segmentChunk + 17} {
+		plain := bytes.Repeat([]byte{0x47, 0x01, 0x02}, size/3+1)[:size]
+		var out bytes.Buffer
+		// 每次只读一个字节，覆盖短读的情况
+		if err := k.EncryptSegment(&out, iotest.OneByteReader(bytes.NewReader(plain)), 5); err != nil {
+			t.Fatalf("加密 %d 字节失败: %v", size, err)
+		}
+		if want := size + 16 - size%16; out.Len() != want {
+			t.Errorf("%d 字节明文的密文长度应为 %d，实际 %d", size, want, out.Len())
+		}
+		if got := decryptTestSegment(t, k, out.Bytes(), 5); !bytes.Equal(got, plain) {
+			t.Errorf("%d 字节分片解密后不一致", size)
+		}
+		var dec bytes.Buffer
+		if err := k.DecryptSegment(&dec, iotest.HalfReader(bytes.NewReader(out.Bytes())), 5); err != nil || !bytes.Equal(dec.Bytes(), plain) {
+			t.Errorf("DecryptSegment 解密 %d 字节分片不一致: %v", size, err)
+		}
+	}
+
+	readErr := errors.New("read failed")
+	if err := k.EncryptSegment(&bytes.Buffer{}, iotest.ErrReader(readErr), 0); !errors.Is(err, readErr) {
+		t.Errorf("应返回读取错误: %v", err)
+	}
+
+	// 序列号不同则 IV 不同，填充校验失败
+	var enc bytes.Buffer
+	k.EncryptSegment(&enc, bytes.NewReader([]byte("segment")), 1)
+	if err := k.DecryptSegment(io.Discard, bytes.NewReader(enc.Bytes()), 2); !errors.Is(err, ErrInvalidPadding) {
+		t.Errorf("IV 不正确时应返回 ErrInvalidPadding: %v", err)
+	}
+	if err := k.DecryptSegment(io.Discard, bytes.NewReader(enc.Bytes()[:15]), 1); err == nil {
+		t.Error("密文长度不是块大小的整数倍时应返回错误")
+	}
+}
+
+func TestEncryptWriter(t *testing.T) {
+	k, err := NewKeyInfoWithKey("https://example.com/key", bytes.Repeat([]byte{9}, 16), WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+	defer k.Dispose()
+	k.SetIV("0x000102030405060708090a0b0c0d0e0f")
+
+	plain := bytes.Repeat([]byte("0123456789"), segmentChunk/5)
+	var enc bytes.Buffer
+	w, err := NewEncryptWriter(&enc, k, 0)
+	if err != nil {
+		t.Fatalf("创建加密写入器失败: %v", err)
+	}
+	// 以不对齐的长度分多次写入
+	for rest := plain; len(rest) > 0; {
+		n := min(len(rest), 1000)
+		if _, err := w.Write(rest[:n]); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+		rest = rest[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("关闭失败: %v", err)
+	}
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Error("关闭后写入应返回错误")
+	}
+
+	var want bytes.Buffer
+	k.EncryptSegment(&want, bytes.NewReader(plain), 0)
+	if !bytes.Equal(enc.Bytes(), want.Bytes()) {
+		t.Error("流式加密结果应与 EncryptSegment 一致")
+	}
+
+	r, err := NewDecryptReader(iotest.OneByteReader(bytes.NewReader(enc.Bytes())), k, 0)
+	if err != nil {
+		t.Fatalf("创建解密读取器失败: %v", err)
+	}
+	if err := iotest.TestReader(r, plain); err != nil {
+		t.Errorf("解密读取器不正确: %v", err)
+	}
+
+	// 下游写入失败时返回错误
+	writeErr := errors.New("upload failed")
+	w, _ = NewEncryptWriter(&failWriter{err: writeErr}, k, 0)
+	w.Write(plain)
+	if err := w.Close(); !errors.Is(err, writeErr) {
+		t.Errorf("应返回写入错误: %v", err)
+	}
+}
+
+// failWriter 总是返回错误的 io.Writer
+type failWriter struct {
+	err error
+}
+
+func (f *failWriter) Write([]byte) (int, error) {
+	return 0, f.err
+}
@@ -0,0 +1,211 @@
+package hlskeyinfo
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// KeySource 密钥来源，KeyServer 按 KeyID 查找密钥；keyID 为空表示请求未指定 KeyID，支持的来源返回当前密钥
+type KeySource interface {
+	LookupKey(keyID string) ([]byte, bool)
+}
+
+// KeySourceFunc 函数形式的 KeySource
+type KeySourceFunc func(keyID string) ([]byte, bool)
+
+// LookupKey 实现 KeySource 接口
+func (f KeySourceFunc) LookupKey(keyID string) ([]byte, bool) {
+	return f(keyID)
+}
+
+var (
+	_ KeySource    = &KeyInfo{}
+	_ KeySource    = &Rotator{}
+	_ KeySource    = &KeyHistory{}
+	_ http.Handler = &KeyServer{}
+)
+
+// LookupKey 实现 KeySource 接口，keyID 为空或与 KeyID 一致时返回密钥
+func (k *KeyInfo) LookupKey(keyID string) ([]byte, bool) {
+	if k.key == nil || (keyID != "" && keyID != k.KeyID) {
+		return nil, false
+	}
+	return k.GetKey(), true
+}
+
+// Middleware HTTP 中间件
+type Middleware func(http.Handler) http.Handler
+
+// KeyServer 密钥服务，实现 http.Handler，以 application/octet-stream 返回原始密钥字节
+// 即 keyinfo 文件中密钥获取URL所指向的端点
+type KeyServer struct {
+	source    KeySource
+	keyID     func(*http.Request) string
+	transform func(*http.Request, []byte) ([]byte, error)
+	accessLog func(AccessLogEntry)
+	auditor   Auditor
+	handler   http.Handler
+}
+
+// AccessLogEntry 密钥请求访问日志
+type AccessLogEntry struct {
+	Time     time.Time
+	KeyID    string        // 请求的 KeyID，未指定时为空
+	ClientIP string        // 连接的远端 IP
+	Subject  string        // 鉴权中间件记录的请求主体，未鉴权时为空
+	Status   int           // 响应状态码，包括被中间件拒绝的请求
+	Duration time.Duration // 处理耗时
+	Request  *http.Request
+}
+
+// ServerOption 密钥服务创建选项
+type ServerOption func(*KeyServer)
+
+// WithKeyIDFunc 设置从请求中提取 KeyID 的方式，默认读取 kid 查询参数（与 WithKeyIDInURL 对应），
+// 其次读取 http.ServeMux 路由模式中的 {keyID} 路径参数
+func WithKeyIDFunc(fn func(*http.Request) string) ServerOption {
+	return func(s *KeyServer) {
+		s.keyID = fn
+	}
+}
+
+// WithKeyTransform 设置响应前对密钥的变换，如以会话密钥封装内容密钥；返回错误时响应 500
+func WithKeyTransform(fn func(r *http.Request, key []byte) ([]byte, error)) ServerOption {
+	return func(s *KeyServer) {
+		s.transform = fn
+	}
+}
+
+// WithAccessLog 设置访问日志回调，每个请求处理完成后调用，可用于统计分析与发现泄露的播放列表
+func WithAccessLog(fn func(AccessLogEntry)) ServerOption {
+	return func(s *KeyServer) {
+		s.accessLog = fn
+	}
+}
+
+// WithMiddleware 添加中间件，按传入顺序由外到内包裹密钥处理器
+func WithMiddleware(mw ...Middleware) ServerOption {
+	return func(s *KeyServer) {
+		for i := len(mw) - 1; i >= 0; i-- {
+			s.handler = mw[i](s.handler)
+		}
+	}
+}
+
+// NewKeyServer 创建密钥服务
+func NewKeyServer(source KeySource, opts ...ServerOption) *KeyServer {
+	s := &KeyServer{
+		source: source,
+		keyID: func(r *http.Request) string {
+			if id := r.URL.Query().Get(keyIDParam); id != "" {
+				return id
+			}
+			return r.PathValue("keyID")
+		},
+	}
+	s.handler = http.HandlerFunc(s.serveKey)
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ServeHTTP 实现 http.Handler 接口
+func (s *KeyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r, _ = withRequestInfo(r)
+	if s.accessLog == nil {
+		s.handler.ServeHTTP(w, r)
+		return
+	}
+
+	start := time.Now()
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	s.handler.ServeHTTP(sw, r)
+
+	entry := AccessLogEntry{
+		Time:     start,
+		KeyID:    s.keyID(r),
+		Subject:  SubjectFromContext(r.Context()),
+		Status:   sw.status,
+		Duration: time.Since(start),
+		Request:  r,
+	}
+	if addr, ok := clientIP(r); ok {
+		entry.ClientIP = addr.String()
+	}
+	s.accessLog(entry)
+}
+
+// statusWriter 记录响应状态码
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader 实现 http.ResponseWriter 接口
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap 供 http.ResponseController 访问底层 ResponseWriter
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// serveKey 返回请求的密钥
+func (s *KeyServer) serveKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	key, ok := s.source.LookupKey(s.keyID(r))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if s.transform != nil {
+		var err error
+		if key, err = s.transform(r, key); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if s.auditor != nil {
+		s.audit(r)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "application/octet-stream")
+	h.Set("Content-Length", strconv.Itoa(len(key)))
+	h.Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write(key)
+	}
+}
+
+// audit 记录一次成功的密钥请求
+func (s *KeyServer) audit(r *http.Request) {
+	rec := AuditRecord{
+		Time:  time.Now(),
+		Event: AuditKeyFetched,
+		Actor: SubjectFromContext(r.Context()),
+		KeyID: s.keyID(r),
+	}
+	if addr, ok := clientIP(r); ok {
+		rec.ClientIP = addr.String()
+	}
+	if rec.Actor == "" {
+		rec.Actor = rec.ClientIP
+	}
+	s.auditor.Audit(rec)
+}
@@ -0,0 +1,102 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKeyServer(t *testing.T) {
+	r := newTestRotator(t, 0, WithKeepPrevious(1))
+	first := r.Current()
+	second, err := r.Rotate()
+	if err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+
+	var calls int
+	s := NewKeyServer(r, WithMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			next.ServeHTTP(w, r)
+		})
+	}))
+
+	cases := []struct {
+		target string
+		status int
+		key    []byte
+	}{
+		{"/key", http.StatusOK, second.GetKey()},
+		{"/key?kid=" + first.KeyID, http.StatusOK, first.GetKey()},
+		{"/key?kid=unknown", http.StatusNotFound, nil},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.target, nil))
+		if w.Code != c.status {
+			t.Errorf("%s 期望状态码 %d，实际: %d", c.target, c.status, w.Code)
+			continue
+		}
+		if c.key != nil {
+			if !bytes.Equal(w.Body.Bytes(), c.key) {
+				t.Errorf("%s 返回的密钥不正确", c.target)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/octet-stream" {
+				t.Errorf("期望 Content-Type 为 application/octet-stream，实际: %s", ct)
+			}
+		}
+	}
+	if calls != len(cases) {
+		t.Errorf("中间件应被调用 %d 次，实际: %d", len(cases), calls)
+	}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/key", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST 请求应返回 405，实际: %d", w.Code)
+	}
+}
+
+func TestKeyServerPathKeyID(t *testing.T) {
+	r := newTestRotator(t, 0)
+	first := r.Current()
+	if _, err := r.Rotate(); err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/keys/{stream}/{keyID}", NewKeyServer(r))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/keys/channel-1/"+first.KeyID, nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), first.GetKey()) {
+		t.Errorf("应按路径中的 KeyID 返回密钥，实际: %d", w.Code)
+	}
+}
+
+func TestKeyServerAccessLog(t *testing.T) {
+	r := newTestRotator(t, 0)
+	auth := NewTokenAuth(map[string]string{"player": "secret"})
+
+	var entries []AccessLogEntry
+	s := NewKeyServer(r, WithMiddleware(auth.Middleware()), WithAccessLog(func(e AccessLogEntry) {
+		entries = append(entries, e)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/key?kid="+r.Current().KeyID+"&token=secret", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/key", nil))
+
+	if len(entries) != 2 {
+		t.Fatalf("期望 2 条访问日志，实际: %d", len(entries))
+	}
+	e := entries[0]
+	if e.KeyID != r.Current().KeyID || e.ClientIP != "10.0.0.1" || e.Subject != "player" || e.Status != http.StatusOK {
+		t.Errorf("访问日志内容不正确: %+v", e)
+	}
+	if entries[1].Status != http.StatusUnauthorized || entries[1].Subject != "" {
+		t.Errorf("被拒绝的请求应记录 401，实际: %+v", entries[1])
+	}
+}
@@ -0,0 +1,187 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// DefaultSessionTTL 默认的会话有效期
+const DefaultSessionTTL = 24 * time.Hour
+
+// sessionParam 密钥获取URL中会话令牌的查询参数名
+const sessionParam = "session"
+
+// ErrSessionNotFound 会话不存在、已过期或已撤销
+var ErrSessionNotFound = errors.New("会话不存在或已失效")
+
+// Session 观看会话
+type Session struct {
+	ID        string    // 会话 ID，由调用方指定，如用户或设备标识
+	Token     string    // 会话令牌，附带在密钥获取URL中
+	Key       []byte    // 会话密钥，开启密钥封装时用于封装内容密钥
+	ExpiresAt time.Time // 过期时间
+}
+
+// KeyURL 在密钥获取URL中附带会话令牌，用于为该会话生成播放列表
+func (s *Session) KeyURL(keyURL string) string {
+	u, err := url.Parse(keyURL)
+	if err != nil {
+		return keyURL
+	}
+	q := u.Query()
+	q.Set(sessionParam, s.Token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// SessionManager 按观看会话分发密钥，每个会话持有独立的令牌与会话密钥，可单独撤销
+// 默认向有效会话返回内容密钥本身（HLS 播放器可直接使用）；开启 WithSessionKeyWrap 时
+// 返回以会话密钥 AES-GCM 封装的内容密钥，需由定制播放器使用会话密钥解封
+type SessionManager struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session            // 会话 ID -> 会话
+	tokens   map[[sha256.Size]byte]*Session // 令牌摘要 -> 会话
+
+	ttl    time.Duration
+	wrap   bool
+	random io.Reader
+	now    func() time.Time
+}
+
+// SessionOption 会话管理器选项
+type SessionOption func(*SessionManager)
+
+// WithSessionTTL 设置会话有效期，默认 24 小时
+func WithSessionTTL(d time.Duration) SessionOption {
+	return func(m *SessionManager) {
+		m.ttl = d
+	}
+}
+
+// WithSessionKeyWrap 以会话密钥封装返回的内容密钥
+func WithSessionKeyWrap() SessionOption {
+	return func(m *SessionManager) {
+		m.wrap = true
+	}
+}
+
+// NewSessionManager 创建会话管理器
+func NewSessionManager(opts ...SessionOption) *SessionManager {
+	m := &SessionManager{
+		sessions: make(map[string]*Session),
+		tokens:   make(map[[sha256.Size]byte]*Session),
+		ttl:      DefaultSessionTTL,
+		random:   rand.Reader,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// IssueSessionKey 为会话签发新的令牌与会话密钥，会话已存在时替换原令牌
+func (m *SessionManager) IssueSessionKey(sessionID string) (*Session, error) {
+	if sessionID == "" {
+		return nil, fmt.Errorf("会话 ID 不能为空")
+	}
+	buf := make([]byte, 16+DefaultKeySize)
+	if _, err := io.ReadFull(m.random, buf); err != nil {
+		return nil, fmt.Errorf("生成会话密钥失败: %w", err)
+	}
+	s := &Session{
+		ID:        sessionID,
+		Token:     hex.EncodeToString(buf[:16]),
+		Key:       buf[16:],
+		ExpiresAt: m.now().Add(m.ttl),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeExpired()
+	if old, ok := m.sessions[sessionID]; ok {
+		delete(m.tokens, sha256.Sum256([]byte(old.Token)))
+	}
+	m.sessions[sessionID] = s
+	m.tokens[sha256.Sum256([]byte(s.Token))] = s
+	out := *s
+	return &out, nil
+}
+
+// Revoke 撤销会话，之后该会话无法再获取密钥
+func (m *SessionManager) Revoke(sessionID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[sessionID]; ok {
+		delete(m.tokens, sha256.Sum256([]byte(s.Token)))
+		delete(m.sessions, sessionID)
+	}
+}
+
+// Resolve 按令牌查找有效会话
+func (m *SessionManager) Resolve(token string) (*Session, error) {
+	if token == "" {
+		return nil, ErrSessionNotFound
+	}
+	m.mu.RLock()
+	s, ok := m.tokens[sha256.Sum256([]byte(token))]
+	m.mu.RUnlock()
+	if !ok || !m.now().Before(s.ExpiresAt) {
+		return nil, ErrSessionNotFound
+	}
+	out := *s
+	return &out, nil
+}
+
+// removeExpired 清理已过期的会话，调用方需持有写锁
+func (m *SessionManager) removeExpired() {
+	now := m.now()
+	for id, s := range m.sessions {
+		if !now.Before(s.ExpiresAt) {
+			delete(m.tokens, sha256.Sum256([]byte(s.Token)))
+			delete(m.sessions, id)
+		}
+	}
+}
+
+type sessionKey struct{}
+
+// Middleware 返回会话鉴权中间件，从 session 查询参数或 Authorization: Bearer 请求头读取会话令牌
+// 会话无效时返回 401，会话 ID 作为请求主体记录
+func (m *SessionManager) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, err := m.Resolve(requestToken(r, sessionParam))
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			r = setSubject(r, s.ID)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, s)))
+		})
+	}
+}
+
+// Handler 返回按会话分发 source 中密钥的密钥服务，opts 中的中间件位于会话鉴权之外
+func (m *SessionManager) Handler(source KeySource, opts ...ServerOption) *KeyServer {
+	opts = append(opts, WithMiddleware(m.Middleware()))
+	if m.wrap {
+		opts = append(opts, WithKeyTransform(func(r *http.Request, key []byte) ([]byte, error) {
+			s, ok := r.Context().Value(sessionKey{}).(*Session)
+			if !ok {
+				return nil, ErrSessionNotFound
+			}
+			return gcmSeal(s.Key, key)
+		}))
+	}
+	return NewKeyServer(source, opts...)
+}
@@ -0,0 +1,75 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSessionManager(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	m := NewSessionManager(WithSessionTTL(time.Hour))
+	s1, err := m.IssueSessionKey("viewer-1")
+	if err != nil {
+		t.Fatalf("签发会话失败: %v", err)
+	}
+	s2, _ := m.IssueSessionKey("viewer-2")
+	handler := m.Handler(k)
+
+	get := func(s *Session) *httptest.ResponseRecorder {
+		u, _ := url.Parse(s.KeyURL("http://localhost:4123/key"))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+		return w
+	}
+
+	if w := get(s1); w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), k.GetKey()) {
+		t.Errorf("有效会话应获取到内容密钥，实际: %d", w.Code)
+	}
+
+	// 撤销单个会话不影响其他会话
+	m.Revoke("viewer-1")
+	if w := get(s1); w.Code != http.StatusUnauthorized {
+		t.Errorf("撤销后的会话应返回 401，实际: %d", w.Code)
+	}
+	if w := get(s2); w.Code != http.StatusOK {
+		t.Errorf("其他会话不应受影响，实际: %d", w.Code)
+	}
+
+	// 过期会话失效
+	m.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := m.Resolve(s2.Token); err == nil {
+		t.Error("过期的会话应失效")
+	}
+}
+
+func TestSessionKeyWrap(t *testing.T) {
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	m := NewSessionManager(WithSessionKeyWrap())
+	s, _ := m.IssueSessionKey("viewer-1")
+	req := httptest.NewRequest(http.MethodGet, "/key", nil)
+	req.Header.Set("Authorization", "Bearer "+s.Token)
+	w := httptest.NewRecorder()
+	m.Handler(k).ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("有效会话应获取到密钥，实际: %d", w.Code)
+	}
+
+	key, err := UnwrapKey(s.Key, w.Body.Bytes())
+	if err != nil || !bytes.Equal(key, k.GetKey()) {
+		t.Errorf("会话密钥应能解封内容密钥: %v", err)
+	}
+}
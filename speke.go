@@ -0,0 +1,247 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var _ DRMProvider = &SPEKEClient{}
+
+func init() {
+	RegisterDRMProvider("speke", func(config map[string]string) (DRMProvider, error) {
+		endpoint := config["endpoint"]
+		if endpoint == "" {
+			return nil, fmt.Errorf("缺少 endpoint 配置")
+		}
+		return NewSPEKEClient(endpoint), nil
+	})
+}
+
+// HLSAES128SystemID HLS AES-128 的 DRM 系统 ID，SPEKE 据此返回 METHOD=AES-128 的 EXT-X-KEY 信令
+var HLSAES128SystemID = [16]byte{0x81, 0x37, 0x68, 0x44, 0xf9, 0x76, 0x48, 0x1e, 0xa8, 0x4e, 0xcc, 0x25, 0xd3, 0x9b, 0x0b, 0x33}
+
+// SPEKEVersion 请求头 X-Speke-Version 的值
+const SPEKEVersion = "2.0"
+
+// SPEKEClient SPEKE（Secure Packager and Encoder Key Exchange）v2 客户端，以 CPIX 文档向密钥服务请求内容密钥与 DRM 信令
+// 与 AWS Elemental MediaPackage 等使用的密钥服务兼容
+type SPEKEClient struct {
+	endpoint string
+	client   *http.Client
+	sign     func(req *http.Request, body []byte) error
+	systems  [][16]byte
+}
+
+// SPEKEOption SPEKE 客户端选项
+type SPEKEOption func(*SPEKEClient)
+
+// WithSPEKEHTTPClient 设置发送请求的 HTTP 客户端，默认超时 30 秒
+func WithSPEKEHTTPClient(c *http.Client) SPEKEOption {
+	return func(s *SPEKEClient) {
+		s.client = c
+	}
+}
+
+// WithSPEKESigner 设置请求签名，如以 AWS SDK 的 v4.Signer 对 API Gateway 端点做 SigV4 签名；body 为请求体
+func WithSPEKESigner(fn func(req *http.Request, body []byte) error) SPEKEOption {
+	return func(s *SPEKEClient) {
+		s.sign = fn
+	}
+}
+
+// WithSPEKESystems 设置 NewKeyInfo 请求信令的 DRM 系统，默认按加密方式选择 HLS AES-128 或 FairPlay
+func WithSPEKESystems(systemIDs ...[16]byte) SPEKEOption {
+	return func(s *SPEKEClient) {
+		s.systems = systemIDs
+	}
+}
+
+// NewSPEKEClient 创建 SPEKE 客户端，endpoint 为密钥服务地址
+func NewSPEKEClient(endpoint string, opts ...SPEKEOption) *SPEKEClient {
+	c := &SPEKEClient{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Exchange 发送密钥请求并返回密钥服务填充后的 CPIX 文档
+// req 中的内容密钥只需 KID 与加密方案，DRM 系统只需 KID 与系统 ID，密钥值与各项信令由密钥服务返回
+func (c *SPEKEClient) Exchange(ctx context.Context, req *CPIX) (*CPIX, error) {
+	var body bytes.Buffer
+	if _, err := writeCPIX(&body, req.document(true)); err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/xml")
+	r.Header.Set("X-Speke-Version", SPEKEVersion)
+	if c.sign != nil {
+		if err := c.sign(r, body.Bytes()); err != nil {
+			return nil, fmt.Errorf("签名 SPEKE 请求失败: %w", err)
+		}
+	}
+
+	resp, err := c.client.Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("请求 SPEKE 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("请求 SPEKE 失败: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	doc, err := ParseCPIX(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	for _, want := range req.ContentKeys {
+		if !doc.hasContentKey(want.KID) {
+			return nil, fmt.Errorf("SPEKE 响应缺少内容密钥 %s", formatUUID(want.KID))
+		}
+	}
+	return doc, nil
+}
+
+// hasContentKey 文档中是否包含指定 KID 的内容密钥
+func (c *CPIX) hasContentKey(kid [16]byte) bool {
+	for _, ck := range c.ContentKeys {
+		if ck.KID == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// request 按 DRM 密钥请求向密钥服务请求单个内容密钥及所选 DRM 系统的信令
+func (c *SPEKEClient) request(ctx context.Context, r DRMKeyRequest) (*CPIX, error) {
+	systems := c.systems
+	if len(systems) == 0 {
+		switch {
+		case r.KeyFormat == KeyFormatFairPlay:
+			systems = [][16]byte{FairPlaySystemID}
+		case r.Method == "" || r.Method == MethodAES128:
+			systems = [][16]byte{HLSAES128SystemID}
+		default:
+			return nil, fmt.Errorf("加密方式 %s 需要通过 WithSPEKESystems 指定 DRM 系统", r.Method)
+		}
+	}
+	req := &CPIX{ContentID: r.ContentID, ContentKeys: []CPIXContentKey{{KID: r.KID}}}
+	if r.Method == MethodSampleAES {
+		req.ContentKeys[0].Scheme = "cbcs"
+	}
+	for _, id := range systems {
+		req.DRMSystems = append(req.DRMSystems, CPIXDRMSystem{KID: r.KID, SystemID: id})
+	}
+	return c.Exchange(ctx, req)
+}
+
+// FetchContentKey 实现 DRMProvider 接口，密钥服务对同一内容 ID 与 KID 返回相同的密钥
+func (c *SPEKEClient) FetchContentKey(ctx context.Context, r DRMKeyRequest) (key, iv []byte, err error) {
+	doc, err := c.request(ctx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, ck := range doc.ContentKeys {
+		if ck.KID == r.KID {
+			return ck.Key, ck.IV, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("SPEKE 响应缺少内容密钥 %s", formatUUID(r.KID))
+}
+
+// SignalingTags 实现 DRMProvider 接口，返回响应中该 KID 的媒体与主播放列表信令
+func (c *SPEKEClient) SignalingTags(ctx context.Context, r DRMKeyRequest) ([]KeyTag, error) {
+	doc, err := c.request(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	var tags []KeyTag
+	for _, d := range doc.DRMSystems {
+		if d.KID != r.KID {
+			continue
+		}
+		for _, line := range []string{d.HLSSignalingData, d.HLSMasterSignalingData} {
+			if line == "" {
+				continue
+			}
+			t, err := ParseKeyTag(line)
+			if err != nil {
+				return nil, fmt.Errorf("解析 SPEKE 信令失败: %w", err)
+			}
+			tags = append(tags, t)
+		}
+	}
+	return tags, nil
+}
+
+// LicenseURL 实现 DRMProvider 接口，SPEKE 不提供许可证地址，始终返回空字符串
+func (c *SPEKEClient) LicenseURL([16]byte) string {
+	return ""
+}
+
+// signaling 返回指定 KID 的第一个媒体播放列表信令
+func (c *CPIX) signaling(kid [16]byte) (KeyTag, error) {
+	for _, d := range c.DRMSystems {
+		if d.KID != kid || d.HLSSignalingData == "" {
+			continue
+		}
+		t, err := ParseKeyTag(d.HLSSignalingData)
+		if err != nil {
+			return KeyTag{}, fmt.Errorf("解析 SPEKE 信令失败: %w", err)
+		}
+		return t, nil
+	}
+	return KeyTag{}, fmt.Errorf("SPEKE 响应缺少 HLS 信令，需要指定密钥获取URL")
+}
+
+// NewKeyInfo 以随机 KID 向密钥服务请求内容密钥并创建KeyInfo实例，KeyID 为 KID 的十六进制形式，
+// 响应包含 explicitIV 时作为显式 IV。url 为空时使用响应中 EXT-X-KEY 信令的 URI 与 KEYFORMAT
+func (c *SPEKEClient) NewKeyInfo(ctx context.Context, contentID, url string, opts ...Option) (*KeyInfo, error) {
+	settings := newKeyInfo(url, opts)
+	if settings.keySize != 16 {
+		return nil, fmt.Errorf("SPEKE 仅支持 16 字节密钥")
+	}
+	kid, err := randomKID()
+	if err != nil {
+		return nil, err
+	}
+	doc, err := c.request(ctx, DRMKeyRequest{ContentID: contentID, KID: kid, Method: settings.method(), KeyFormat: settings.KeyFormat})
+	if err != nil {
+		return nil, err
+	}
+
+	if url == "" {
+		t, err := doc.signaling(kid)
+		if err != nil {
+			return nil, err
+		}
+		url = t.URI
+		if t.KeyFormat != "" {
+			opts = append([]Option{WithKeyFormat(t.KeyFormat, t.KeyFormatVersions)}, opts...)
+		}
+	}
+
+	single := &CPIX{}
+	for _, ck := range doc.ContentKeys {
+		if ck.KID == kid {
+			single.ContentKeys = append(single.ContentKeys, ck)
+			break
+		}
+	}
+	keys, err := single.KeyInfos(url, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return keys[0], nil
+}
@@ -0,0 +1,82 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSPEKEClient(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 16)
+	iv := bytes.Repeat([]byte{0x07}, 16)
+	var signed bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Speke-Version") != SPEKEVersion || r.Header.Get("X-Test-Signature") != "ok" {
+			http.Error(w, "bad headers", http.StatusForbidden)
+			return
+		}
+		var req cpixXML
+		if err := xml.Unmarshal(body, &req); err != nil || len(req.ContentKeys) != 1 || req.ContentKeys[0].Data != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if req.ContentID != "movie-1" || len(req.DRMSystems) != 1 || req.DRMSystems[0].SystemID != formatUUID(HLSAES128SystemID) ||
+			req.DRMSystems[0].PSSH == nil || len(req.DRMSystems[0].HLSSignalingData) != 2 {
+			http.Error(w, "bad drm systems", http.StatusBadRequest)
+			return
+		}
+
+		// 按请求填充密钥、IV 与信令
+		kid, _ := parseUUID(req.ContentKeys[0].KID)
+		resp := &CPIX{
+			ContentID:   req.ContentID,
+			ContentKeys: []CPIXContentKey{{KID: kid, Key: key, IV: iv}},
+			DRMSystems: []CPIXDRMSystem{{
+				KID: kid, SystemID: HLSAES128SystemID,
+				HLSSignalingData: `#EXT-X-KEY:METHOD=AES-128,URI="https://keys.example.com/` + req.ContentKeys[0].KID + `"`,
+			}},
+		}
+		resp.WriteTo(w)
+	}))
+	defer srv.Close()
+
+	c := NewSPEKEClient(srv.URL, WithSPEKESigner(func(r *http.Request, body []byte) error {
+		signed = len(body) > 0
+		r.Header.Set("X-Test-Signature", "ok")
+		return nil
+	}))
+	k, err := c.NewKeyInfo(context.Background(), "movie-1", "", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Dispose()
+	if !signed {
+		t.Errorf("请求未签名")
+	}
+	if !bytes.Equal(k.GetKey(), key) || k.IV != "07070707070707070707070707070707" {
+		t.Errorf("密钥或 IV 不正确: %#v", k)
+	}
+	kid := k.KID()
+	if k.KeyURL() != "https://keys.example.com/"+formatUUID(kid) {
+		t.Errorf("密钥获取URL应取自 SPEKE 信令: %s", k.KeyURL())
+	}
+	if !strings.Contains(k.ExtXKey(), "METHOD=AES-128") {
+		t.Errorf("加密方式不正确: %s", k.ExtXKey())
+	}
+
+	// SAMPLE-AES 需要指定 DRM 系统
+	if _, err := c.NewKeyInfo(context.Background(), "movie-1", "", WithMethod(MethodSampleAES)); err == nil {
+		t.Errorf("未指定 DRM 系统时应返回错误")
+	}
+	// 服务端错误
+	bad := NewSPEKEClient(srv.URL)
+	if _, err := bad.NewKeyInfo(context.Background(), "movie-1", "https://keys.example.com/key"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("应返回服务端错误: %v", err)
+	}
+}
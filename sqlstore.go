@@ -0,0 +1,238 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SQLDialect SQL 方言，决定占位符、列类型与 upsert 语法
+type SQLDialect int
+
+const (
+	DialectPostgres SQLDialect = iota
+	DialectMySQL
+	DialectSQLite
+)
+
+// sqlIdentifier 合法的表名
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQLStore 基于 database/sql 的密钥存储，密钥、IV、KeyID 与使用期保存在同一张表中，
+// 可与流的元数据放在同一个数据库；驱动由调用方导入
+type SQLStore struct {
+	db      *sql.DB
+	dialect SQLDialect
+	table   string
+}
+
+var _ KeyStore = &SQLStore{}
+
+// SQLOption SQL 密钥存储选项
+type SQLOption func(*SQLStore)
+
+// WithSQLTable 设置密钥表名，默认 hls_keys，迁移记录表为 <表名>_migrations
+func WithSQLTable(name string) SQLOption {
+	return func(s *SQLStore) {
+		s.table = name
+	}
+}
+
+// NewSQLStore 创建基于 database/sql 的密钥存储，使用前需调用 Migrate 或自行执行 Migrations 中的语句
+func NewSQLStore(db *sql.DB, dialect SQLDialect, opts ...SQLOption) (*SQLStore, error) {
+	s := &SQLStore{db: db, dialect: dialect, table: "hls_keys"}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if !sqlIdentifier.MatchString(s.table) {
+		return nil, fmt.Errorf("无效的表名: %q", s.table)
+	}
+	if dialect < DialectPostgres || dialect > DialectSQLite {
+		return nil, fmt.Errorf("不支持的 SQL 方言: %d", dialect)
+	}
+	return s, nil
+}
+
+// Migrations 返回按版本排序的建表语句，供使用独立迁移工具的项目直接引用
+func (s *SQLStore) Migrations() []string {
+	blob, ts := "BYTEA", "TIMESTAMPTZ"
+	switch s.dialect {
+	case DialectMySQL:
+		blob, ts = "VARBINARY(1024)", "DATETIME(6)"
+	case DialectSQLite:
+		blob, ts = "BLOB", "TIMESTAMP"
+	}
+	return []string{
+		// 1: 密钥表
+		fmt.Sprintf(`CREATE TABLE %[1]s (
+	key_id VARCHAR(128) NOT NULL PRIMARY KEY,
+	stream VARCHAR(255) NOT NULL DEFAULT '',
+	url TEXT NOT NULL,
+	key_bytes %[2]s,
+	wrapped_key %[2]s,
+	iv VARCHAR(34) NOT NULL DEFAULT '',
+	version INTEGER NOT NULL,
+	not_before %[3]s NOT NULL,
+	not_after %[3]s NULL
+)`, s.table, blob, ts),
+		// 2: 按流查询的索引
+		fmt.Sprintf(`CREATE INDEX %[1]s_stream_idx ON %[1]s (stream, not_before)`, s.table),
+		// 3: DRM 使用的 KID
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN kid VARCHAR(36) NOT NULL DEFAULT ''`, s.table),
+	}
+}
+
+// Migrate 执行尚未应用的迁移，已应用的版本记录在 <表名>_migrations 中，可重复调用
+func (s *SQLStore) Migrate(ctx context.Context) error {
+	migrations := s.table + "_migrations"
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (version INTEGER NOT NULL PRIMARY KEY)`, migrations))
+	if err != nil {
+		return fmt.Errorf("创建迁移记录表失败: %w", err)
+	}
+
+	var current int
+	row := s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COALESCE(MAX(version), 0) FROM %s`, migrations))
+	if err := row.Scan(&current); err != nil {
+		return fmt.Errorf("读取迁移版本失败: %w", err)
+	}
+	for i, stmt := range s.Migrations() {
+		version := i + 1
+		if version <= current {
+			continue
+		}
+		// MySQL 的 DDL 会隐式提交事务，此时迁移失败需人工处理
+		err := s.tx(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, s.rebind(fmt.Sprintf(`INSERT INTO %s (version) VALUES (?)`, migrations)), version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("执行迁移 %d 失败: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// Put 实现 KeyStore 接口
+func (s *SQLStore) Put(ctx context.Context, rec KeyRecord) error {
+	if rec.KeyID == "" {
+		return fmt.Errorf("KeyID 不能为空")
+	}
+	cols := "key_id, kid, stream, url, key_bytes, wrapped_key, iv, version, not_before, not_after"
+	var query string
+	if s.dialect == DialectMySQL {
+		query = fmt.Sprintf(`INSERT INTO %s (%s) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE kid = VALUES(kid), stream = VALUES(stream), url = VALUES(url), key_bytes = VALUES(key_bytes),
+	wrapped_key = VALUES(wrapped_key), iv = VALUES(iv), version = VALUES(version),
+	not_before = VALUES(not_before), not_after = VALUES(not_after)`, s.table, cols)
+	} else {
+		query = fmt.Sprintf(`INSERT INTO %s (%s) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (key_id) DO UPDATE SET kid = excluded.kid, stream = excluded.stream, url = excluded.url, key_bytes = excluded.key_bytes,
+	wrapped_key = excluded.wrapped_key, iv = excluded.iv, version = excluded.version,
+	not_before = excluded.not_before, not_after = excluded.not_after`, s.table, cols)
+	}
+	notAfter := sql.NullTime{Time: rec.NotAfter.UTC(), Valid: !rec.NotAfter.IsZero()}
+	_, err := s.db.ExecContext(ctx, s.rebind(query),
+		rec.KeyID, rec.KID, rec.Stream, rec.URL, rec.Key, rec.WrappedKey, rec.IV, rec.Version, rec.NotBefore.UTC(), notAfter)
+	if err != nil {
+		return fmt.Errorf("写入密钥失败: %w", err)
+	}
+	return nil
+}
+
+// Get 实现 KeyStore 接口
+func (s *SQLStore) Get(ctx context.Context, keyID string) (KeyRecord, error) {
+	rows, err := s.query(ctx, "WHERE key_id = ?", keyID)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	if len(rows) == 0 {
+		return KeyRecord{}, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	return rows[0], nil
+}
+
+// Delete 实现 KeyStore 接口
+func (s *SQLStore) Delete(ctx context.Context, keyID string) error {
+	res, err := s.db.ExecContext(ctx, s.rebind(fmt.Sprintf(`DELETE FROM %s WHERE key_id = ?`, s.table)), keyID)
+	if err != nil {
+		return fmt.Errorf("删除密钥失败: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	return nil
+}
+
+// List 实现 KeyStore 接口
+func (s *SQLStore) List(ctx context.Context, stream string) ([]KeyRecord, error) {
+	if stream == "" {
+		return s.query(ctx, "ORDER BY not_before, key_id")
+	}
+	return s.query(ctx, "WHERE stream = ? ORDER BY not_before, key_id", stream)
+}
+
+// query 查询密钥记录，where 为 WHERE 与 ORDER BY 子句
+func (s *SQLStore) query(ctx context.Context, where string, args ...any) ([]KeyRecord, error) {
+	query := fmt.Sprintf(`SELECT key_id, kid, stream, url, key_bytes, wrapped_key, iv, version, not_before, not_after FROM %s %s`, s.table, where)
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询密钥失败: %w", err)
+	}
+	defer rows.Close()
+
+	var out []KeyRecord
+	for rows.Next() {
+		var rec KeyRecord
+		var notBefore time.Time
+		var notAfter sql.NullTime
+		if err := rows.Scan(&rec.KeyID, &rec.KID, &rec.Stream, &rec.URL, &rec.Key, &rec.WrappedKey, &rec.IV, &rec.Version, &notBefore, &notAfter); err != nil {
+			return nil, fmt.Errorf("读取密钥记录失败: %w", err)
+		}
+		rec.NotBefore = notBefore.Local()
+		if notAfter.Valid {
+			rec.NotAfter = notAfter.Time.Local()
+		}
+		out = append(out, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取密钥记录失败: %w", err)
+	}
+	return out, nil
+}
+
+// rebind 将 ? 占位符替换为方言使用的占位符
+func (s *SQLStore) rebind(query string) string {
+	if s.dialect != DialectPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// tx 在事务中执行 fn
+func (s *SQLStore) tx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,52 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLStore(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "keys.db"))
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	store, err := NewSQLStore(db, DialectSQLite, WithSQLTable("stream_keys"))
+	if err != nil {
+		t.Fatalf("创建 SQLStore 失败: %v", err)
+	}
+	for range 2 {
+		if err := store.Migrate(ctx); err != nil {
+			t.Fatalf("迁移失败: %v", err)
+		}
+	}
+	var version int
+	if err := db.QueryRow(`SELECT MAX(version) FROM stream_keys_migrations`).Scan(&version); err != nil || version != len(store.Migrations()) {
+		t.Errorf("迁移版本应为 %d，实际为 %d: %v", len(store.Migrations()), version, err)
+	}
+	testKeyStore(t, store)
+
+	if err := store.Delete(ctx, "key-a"); err == nil {
+		t.Error("删除不存在的密钥应返回错误")
+	}
+	if _, err := NewSQLStore(db, DialectSQLite, WithSQLTable("keys; DROP TABLE x")); err == nil {
+		t.Error("无效的表名应返回错误")
+	}
+}
+
+func TestSQLRebind(t *testing.T) {
+	s := &SQLStore{dialect: DialectPostgres}
+	if got := s.rebind("WHERE a = ? AND b = ?"); got != "WHERE a = $1 AND b = $2" {
+		t.Errorf("Postgres 占位符替换不正确: %s", got)
+	}
+	s.dialect = DialectMySQL
+	if got := s.rebind("WHERE a = ?"); got != "WHERE a = ?" {
+		t.Errorf("MySQL 不应替换占位符: %s", got)
+	}
+}
@@ -0,0 +1,263 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrKeyNotFound 密钥存储中不存在该密钥
+var ErrKeyNotFound = errors.New("密钥不存在")
+
+// KeyStore 密钥存储，使密钥可以保存在临时目录之外的后端
+// 实现需并发安全；Get 与 Delete 在密钥不存在时返回 ErrKeyNotFound（可用 errors.Is 判断）
+type KeyStore interface {
+	// Put 保存密钥记录，KeyID 已存在时覆盖
+	Put(ctx context.Context, rec KeyRecord) error
+	// Get 按 KeyID 读取密钥记录
+	Get(ctx context.Context, keyID string) (KeyRecord, error)
+	// Delete 删除密钥记录
+	Delete(ctx context.Context, keyID string) error
+	// List 列出流的全部密钥记录，stream 为空时列出所有流，按 NotBefore 排序
+	List(ctx context.Context, stream string) ([]KeyRecord, error)
+}
+
+// KeyEventType 密钥变更事件类型
+type KeyEventType string
+
+const (
+	KeyPut     KeyEventType = "put"    // 密钥记录被新增或更新
+	KeyDeleted KeyEventType = "delete" // 密钥记录被删除
+)
+
+// KeyEvent 密钥变更事件
+type KeyEvent struct {
+	Type   KeyEventType `json:"type"`
+	KeyID  string       `json:"key_id"`
+	Stream string       `json:"stream,omitempty"`
+}
+
+// KeyWatcher 可订阅密钥变更的存储，多个密钥服务副本据此及时感知轮换
+type KeyWatcher interface {
+	// Watch 订阅密钥变更，ctx 取消或订阅中断时关闭返回的通道
+	Watch(ctx context.Context) (<-chan KeyEvent, error)
+}
+
+// MemoryStore 基于内存的密钥存储，进程退出后丢失，适用于测试与单机部署
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string]KeyRecord
+}
+
+var _ KeyStore = &MemoryStore{}
+
+// NewMemoryStore 创建内存密钥存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]KeyRecord)}
+}
+
+// Put 实现 KeyStore 接口
+func (s *MemoryStore) Put(ctx context.Context, rec KeyRecord) error {
+	if rec.KeyID == "" {
+		return fmt.Errorf("KeyID 不能为空")
+	}
+	rec.Key = slices.Clone(rec.Key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[rec.KeyID] = rec
+	return nil
+}
+
+// Get 实现 KeyStore 接口
+func (s *MemoryStore) Get(ctx context.Context, keyID string) (KeyRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rec, ok := s.records[keyID]
+	if !ok {
+		return KeyRecord{}, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	rec.Key = slices.Clone(rec.Key)
+	return rec, nil
+}
+
+// Delete 实现 KeyStore 接口
+func (s *MemoryStore) Delete(ctx context.Context, keyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.records[keyID]; !ok {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	delete(s.records, keyID)
+	return nil
+}
+
+// List 实现 KeyStore 接口
+func (s *MemoryStore) List(ctx context.Context, stream string) ([]KeyRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []KeyRecord
+	for _, rec := range s.records {
+		if stream == "" || rec.Stream == stream {
+			rec.Key = slices.Clone(rec.Key)
+			out = append(out, rec)
+		}
+	}
+	sortRecords(out)
+	return out, nil
+}
+
+// sortRecords 按 NotBefore 排序，相同时按 KeyID 排序
+func sortRecords(records []KeyRecord) {
+	slices.SortFunc(records, func(a, b KeyRecord) int {
+		if c := a.NotBefore.Compare(b.NotBefore); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.KeyID, b.KeyID)
+	})
+}
+
+// CopyStore 将 src 中的密钥记录连同元数据复制到 dst，filter 为 nil 时复制全部记录，返回复制的记录数
+// 每条记录写入后从 dst 读回校验，不一致时立即返回错误，可用于在不同后端之间迁移密钥
+func CopyStore(ctx context.Context, src, dst KeyStore, filter func(KeyRecord) bool) (int, error) {
+	records, err := src.List(ctx, "")
+	if err != nil {
+		return 0, fmt.Errorf("列出源密钥失败: %w", err)
+	}
+	n := 0
+	for _, rec := range records {
+		if filter != nil && !filter(rec) {
+			continue
+		}
+		if err := dst.Put(ctx, rec); err != nil {
+			return n, fmt.Errorf("写入密钥 %s 失败: %w", rec.KeyID, err)
+		}
+		got, err := dst.Get(ctx, rec.KeyID)
+		if err != nil {
+			return n, fmt.Errorf("校验密钥 %s 失败: %w", rec.KeyID, err)
+		}
+		if !sameRecord(rec, got) {
+			return n, fmt.Errorf("校验密钥 %s 失败: 读回的记录与源记录不一致", rec.KeyID)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// sameRecord 比较两条密钥记录，时间允许存在数据库精度（如 SQL 的微秒）造成的误差
+func sameRecord(a, b KeyRecord) bool {
+	return a.KeyID == b.KeyID && a.KID == b.KID && a.Stream == b.Stream && a.URL == b.URL &&
+		bytes.Equal(a.Key, b.Key) && bytes.Equal(a.WrappedKey, b.WrappedKey) &&
+		a.IV == b.IV && a.Version == b.Version &&
+		sameTime(a.NotBefore, b.NotBefore) && sameTime(a.NotAfter, b.NotAfter)
+}
+
+// sameTime 判断两个时间是否相差不到 1 毫秒，零值只与零值相同
+func sameTime(a, b time.Time) bool {
+	if a.IsZero() || b.IsZero() {
+		return a.IsZero() == b.IsZero()
+	}
+	return a.Sub(b).Abs() < time.Millisecond
+}
+
+// SaveTo 将密钥保存到密钥存储，记录自当前时间起开始使用
+func (k *KeyInfo) SaveTo(ctx context.Context, store KeyStore) error {
+	if k.key == nil {
+		return fmt.Errorf("密钥未初始化")
+	}
+	if err := store.Put(ctx, *k.record(time.Now())); err != nil {
+		return fmt.Errorf("保存密钥失败: %w", err)
+	}
+	return nil
+}
+
+// NewKeyInfoFromStore 从密钥存储读取密钥并创建KeyInfo实例，密钥获取URL为记录中已展开的URL
+func NewKeyInfoFromStore(ctx context.Context, store KeyStore, keyID string, opts ...Option) (*KeyInfo, error) {
+	rec, err := store.Get(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	opts = append([]Option{WithStream(rec.Stream), WithKeyID(rec.KeyID)}, opts...)
+	k, err := NewKeyInfoWithKey(rec.URL, rec.Key, opts...)
+	if err != nil {
+		return nil, err
+	}
+	k.Version = rec.Version
+	if rec.IV != "" {
+		if err := k.SetIVStrict(rec.IV); err != nil {
+			k.Dispose()
+			return nil, err
+		}
+	} else {
+		k.UseSequenceIV()
+	}
+	return k, nil
+}
+
+// storeSource 以密钥存储作为密钥服务的密钥来源
+type storeSource struct {
+	store   KeyStore
+	stream  string
+	timeout time.Duration
+}
+
+// StoreSource 返回以密钥存储为后端的 KeySource，每次查询的超时时间为 timeout
+// keyID 为空时返回 stream（为空表示所有流）中当前处于使用期内、最新开始使用的密钥
+func StoreSource(store KeyStore, stream string, timeout time.Duration) KeySource {
+	return &storeSource{store: store, stream: stream, timeout: timeout}
+}
+
+// LookupKey 实现 KeySource 接口
+func (s *storeSource) LookupKey(keyID string) ([]byte, bool) {
+	ctx := context.Background()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	if keyID != "" {
+		rec, err := s.store.Get(ctx, keyID)
+		if err != nil || (s.stream != "" && rec.Stream != s.stream) {
+			return nil, false
+		}
+		return rec.Key, true
+	}
+
+	records, err := s.store.List(ctx, s.stream)
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now()
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Active(now) {
+			return records[i].Key, true
+		}
+	}
+	return nil, false
+}
+
+// WithKeyStore 设置密钥存储，轮换器保存初始密钥与每次轮换生成的新密钥，并记录旧密钥的停用时间
+func WithKeyStore(store KeyStore) RotatorOption {
+	return func(r *Rotator) {
+		r.store = store
+	}
+}
+
+// storeRotation 在密钥存储中记录一次轮换
+func (r *Rotator) storeRotation(old, next *KeyInfo, at time.Time) error {
+	ctx := context.Background()
+	rec, err := r.store.Get(ctx, old.KeyID)
+	if errors.Is(err, ErrKeyNotFound) {
+		rec, err = *old.record(time.Time{}), nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取密钥记录失败: %w", err)
+	}
+	rec.NotAfter = at
+	return errors.Join(r.store.Put(ctx, rec), r.store.Put(ctx, *next.record(at)))
+}
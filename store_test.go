@@ -0,0 +1,178 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	r := newTestRotator(t, 0, WithKeepPrevious(0), WithKeyStore(store))
+	first := r.Current()
+	firstKey := first.GetKey()
+	second, err := r.Rotate()
+	if err != nil {
+		t.Fatalf("轮换失败: %v", err)
+	}
+
+	records, err := store.List(ctx, "")
+	if err != nil || len(records) != 2 {
+		t.Fatalf("期望 2 条密钥记录，实际: %d, %v", len(records), err)
+	}
+	if records[0].KeyID != first.KeyID || records[0].NotAfter.IsZero() || !records[1].NotAfter.IsZero() {
+		t.Errorf("密钥记录的使用期不正确: %+v", records)
+	}
+
+	// 以密钥存储作为密钥服务的来源
+	source := StoreSource(store, "", 0)
+	if key, ok := source.LookupKey(first.KeyID); !ok || !bytes.Equal(key, firstKey) {
+		t.Error("应能从密钥存储查找到已退役的密钥")
+	}
+	if key, ok := source.LookupKey(""); !ok || !bytes.Equal(key, second.GetKey()) {
+		t.Error("未指定 KeyID 时应返回当前密钥")
+	}
+
+	// 从密钥存储恢复
+	restored, err := NewKeyInfoFromStore(ctx, store, first.KeyID)
+	if err != nil {
+		t.Fatalf("从密钥存储恢复失败: %v", err)
+	}
+	defer restored.Dispose()
+	if !bytes.Equal(restored.GetKey(), firstKey) || restored.IV != first.IV || restored.KeyID != first.KeyID {
+		t.Error("恢复的密钥与原密钥不一致")
+	}
+
+	if err := store.Delete(ctx, first.KeyID); err != nil {
+		t.Fatalf("删除密钥失败: %v", err)
+	}
+	if _, err := store.Get(ctx, first.KeyID); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("删除后应返回 ErrKeyNotFound，实际: %v", err)
+	}
+	if err := store.Delete(ctx, first.KeyID); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("删除不存在的密钥应返回 ErrKeyNotFound，实际: %v", err)
+	}
+}
+
+// testKeyStore 各 KeyStore 实现共用的行为测试
+func testKeyStore(t *testing.T, store KeyStore) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	a := KeyRecord{KeyID: "key-a", Stream: "channel-1", URL: "https://keys.example.com/a", Key: bytes.Repeat([]byte{1}, 16), IV: "0x" + strings.Repeat("01", 16), Version: 1, NotBefore: now}
+	b := KeyRecord{KeyID: "key-b", Stream: "channel-2", Key: bytes.Repeat([]byte{2}, 32), Version: 1, NotBefore: now.Add(time.Second)}
+	for _, rec := range []KeyRecord{a, b} {
+		if err := store.Put(ctx, rec); err != nil {
+			t.Fatalf("保存 %s 失败: %v", rec.KeyID, err)
+		}
+	}
+
+	got, err := store.Get(ctx, "key-a")
+	if err != nil {
+		t.Fatalf("读取密钥失败: %v", err)
+	}
+	if !bytes.Equal(got.Key, a.Key) || got.IV != a.IV || got.URL != a.URL || got.Stream != a.Stream || !got.NotBefore.Equal(a.NotBefore) {
+		t.Errorf("读取的密钥记录不正确: %+v", got)
+	}
+
+	// 覆盖已有记录
+	a.NotAfter = now.Add(time.Hour)
+	if err := store.Put(ctx, a); err != nil {
+		t.Fatalf("更新密钥失败: %v", err)
+	}
+	if got, _ := store.Get(ctx, "key-a"); !got.NotAfter.Equal(a.NotAfter) {
+		t.Error("Put 应覆盖已有记录")
+	}
+
+	all, err := store.List(ctx, "")
+	if err != nil || len(all) != 2 || all[0].KeyID != "key-a" {
+		t.Errorf("列出全部记录不正确: %+v, %v", all, err)
+	}
+	if list, _ := store.List(ctx, "channel-2"); len(list) != 1 || list[0].KeyID != "key-b" {
+		t.Errorf("按流列出记录不正确: %+v", list)
+	}
+
+	if err := store.Delete(ctx, "key-a"); err != nil {
+		t.Fatalf("删除密钥失败: %v", err)
+	}
+	if _, err := store.Get(ctx, "key-a"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("删除后应返回 ErrKeyNotFound，实际: %v", err)
+	}
+}
+
+func TestKeyStoreImplementations(t *testing.T) {
+	testKeyStore(t, NewMemoryStore())
+
+	fs, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("创建 FileStore 失败: %v", err)
+	}
+	testKeyStore(t, fs)
+}
+
+func TestCopyStore(t *testing.T) {
+	ctx := context.Background()
+	src, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("创建 FileStore 失败: %v", err)
+	}
+	now := time.Now()
+	for i, stream := range []string{"channel-1", "channel-1", "channel-2"} {
+		rec := KeyRecord{KeyID: fmt.Sprintf("key-%d", i), KID: formatUUID([16]byte{15: byte(i)}), Stream: stream, Key: bytes.Repeat([]byte{byte(i)}, 16), Version: i + 1, NotBefore: now.Add(time.Duration(i) * time.Second)}
+		if err := src.Put(ctx, rec); err != nil {
+			t.Fatalf("保存密钥失败: %v", err)
+		}
+	}
+
+	dst := NewMemoryStore()
+	n, err := CopyStore(ctx, src, dst, func(rec KeyRecord) bool { return rec.Stream == "channel-1" })
+	if err != nil || n != 2 {
+		t.Fatalf("应复制 2 条记录，实际: %d, %v", n, err)
+	}
+	got, err := dst.Get(ctx, "key-1")
+	if err != nil || !bytes.Equal(got.Key, bytes.Repeat([]byte{1}, 16)) || got.Version != 2 || got.KID != formatUUID([16]byte{15: 1}) {
+		t.Errorf("复制的记录不正确: %+v, %v", got, err)
+	}
+	if _, err := dst.Get(ctx, "key-2"); !errors.Is(err, ErrKeyNotFound) {
+		t.Error("不应复制被过滤的记录")
+	}
+
+	// 目标存储写入的内容与源不一致时校验失败
+	if _, err := CopyStore(ctx, src, corruptStore{NewMemoryStore()}, nil); err == nil {
+		t.Error("读回的记录不一致时应返回错误")
+	}
+	// 目标存储丢失 KID 时 DRM 无法按 KID 查找，同样校验失败
+	if _, err := CopyStore(ctx, src, kidDroppingStore{NewMemoryStore()}, nil); err == nil {
+		t.Error("读回的记录丢失 KID 时应返回错误")
+	}
+}
+
+// kidDroppingStore 读回时丢失 KID 的存储
+type kidDroppingStore struct {
+	*MemoryStore
+}
+
+func (s kidDroppingStore) Get(ctx context.Context, keyID string) (KeyRecord, error) {
+	rec, err := s.MemoryStore.Get(ctx, keyID)
+	rec.KID = ""
+	return rec, err
+}
+
+// corruptStore 读回时篡改密钥的存储
+type corruptStore struct {
+	*MemoryStore
+}
+
+func (s corruptStore) Get(ctx context.Context, keyID string) (KeyRecord, error) {
+	rec, err := s.MemoryStore.Get(ctx, keyID)
+	if len(rec.Key) > 0 {
+		rec.Key[0] ^= 0xff
+	}
+	return rec, err
+}
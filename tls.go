@@ -0,0 +1,137 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// certCheckInterval 检查证书文件是否变更的最小间隔
+const certCheckInterval = time.Second
+
+// CertReloader 证书热加载，证书或私钥文件更新后在下一次 TLS 握手时自动生效，无需重启服务
+// 新证书加载失败时继续使用旧证书
+type CertReloader struct {
+	certFile, keyFile string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+// NewCertReloader 加载证书并创建热加载器
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	c := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload 立即重新加载证书
+func (c *CertReloader) Reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	certMod, keyMod, err := c.modTimes()
+	if err != nil {
+		return err
+	}
+	return c.load(certMod, keyMod)
+}
+
+// GetCertificate 用作 tls.Config.GetCertificate，文件修改时间变化时重新加载证书
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now := time.Now(); now.Sub(c.lastCheck) >= certCheckInterval {
+		c.lastCheck = now
+		certMod, keyMod, err := c.modTimes()
+		if err == nil && (!certMod.Equal(c.certMod) || !keyMod.Equal(c.keyMod)) {
+			// 证书与私钥可能分两次写入，加载失败时保留旧证书并在下次检查时重试
+			c.load(certMod, keyMod)
+		}
+	}
+	return c.cert, nil
+}
+
+// load 加载证书，调用方需持有锁
+func (c *CertReloader) load(certMod, keyMod time.Time) error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("加载证书失败: %w", err)
+	}
+	c.cert = &cert
+	c.certMod, c.keyMod = certMod, keyMod
+	return nil
+}
+
+// modTimes 返回证书与私钥文件的修改时间
+func (c *CertReloader) modTimes() (certMod, keyMod time.Time, err error) {
+	ci, err := os.Stat(c.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("读取证书文件失败: %w", err)
+	}
+	ki, err := os.Stat(c.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("读取私钥文件失败: %w", err)
+	}
+	return ci.ModTime(), ki.ModTime(), nil
+}
+
+// ListenAndServeTLS 在 addr 上以 HTTPS 提供密钥服务，证书文件更新后自动热加载
+// 阻塞直到 ctx 取消或服务出错，ctx 取消时优雅关闭并返回 nil
+func (s *KeyServer) ListenAndServeTLS(ctx context.Context, addr, certFile, keyFile string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("监听 %s 失败: %w", addr, err)
+	}
+	return s.ServeTLS(ctx, ln, certFile, keyFile)
+}
+
+// ServeTLS 在已有的监听器上以 HTTPS 提供密钥服务，行为同 ListenAndServeTLS
+func (s *KeyServer) ServeTLS(ctx context.Context, ln net.Listener, certFile, keyFile string) error {
+	reloader, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		ln.Close()
+		return err
+	}
+
+	srv := &http.Server{
+		Handler: s,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		// 证书由 TLSConfig.GetCertificate 提供
+		errCh <- srv.ServeTLS(ln, "", "")
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("关闭密钥服务失败: %w", err)
+		}
+		if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,123 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert 生成自签名证书并写入文件，返回证书
+func writeTestCert(t *testing.T, certFile, keyFile string, serial int64) *x509.Certificate {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("生成私钥失败: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("生成证书失败: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("编码私钥失败: %v", err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("写入证书失败: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("写入私钥失败: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeTestCert(t, certFile, keyFile, 1)
+
+	c, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("创建 CertReloader 失败: %v", err)
+	}
+	cert, _ := c.GetCertificate(nil)
+
+	// 更新证书文件后重新加载
+	writeTestCert(t, certFile, keyFile, 2)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	c.lastCheck = time.Time{}
+	reloaded, _ := c.GetCertificate(nil)
+	if bytes.Equal(cert.Certificate[0], reloaded.Certificate[0]) {
+		t.Error("证书文件更新后应重新加载")
+	}
+
+	// 无效的证书不替换旧证书
+	os.WriteFile(certFile, []byte("invalid"), 0o600)
+	os.Chtimes(certFile, future.Add(time.Minute), future.Add(time.Minute))
+	c.lastCheck = time.Time{}
+	if current, _ := c.GetCertificate(nil); current != reloaded {
+		t.Error("新证书加载失败时应继续使用旧证书")
+	}
+}
+
+func TestKeyServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	cert := writeTestCert(t, certFile, keyFile, 1)
+
+	k, err := NewKeyInfo("https://127.0.0.1/key", WithTempDir(dir))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- NewKeyServer(k).ServeTLS(ctx, ln, certFile, keyFile) }()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Get("https://" + ln.Addr().String() + "/key")
+	if err != nil {
+		t.Fatalf("请求密钥失败: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !bytes.Equal(body, k.GetKey()) {
+		t.Error("HTTPS 返回的密钥不正确")
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Errorf("关闭密钥服务应返回 nil，实际: %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+package hlskeyinfo
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity 检查结果的严重程度
+type Severity int
+
+const (
+	SeverityWarning Severity = iota // 可播放，但可能不符合预期
+	SeverityError                   // 违反规范或无法解密
+)
+
+// String 实现 fmt.Stringer 接口
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "错误"
+	}
+	return "警告"
+}
+
+// Finding 播放列表检查发现的问题
+type Finding struct {
+	Line     int      // 所在行号，从 1 开始，0 表示整个播放列表
+	Severity Severity // 严重程度
+	Message  string   // 问题描述
+}
+
+// String 实现 fmt.Stringer 接口，如 "第 3 行: 错误: ..."
+func (f Finding) String() string {
+	if f.Line == 0 {
+		return fmt.Sprintf("%s: %s", f.Severity, f.Message)
+	}
+	return fmt.Sprintf("第 %d 行: %s: %s", f.Line, f.Severity, f.Message)
+}
+
+// ValidateOption ValidatePlaylist 检查选项
+type ValidateOption func(*playlistValidator)
+
+// WithKeyURICheck 请求播放列表中 http/https 的密钥URI，检查是否可达以及 AES-128 密钥是否为 16 字节
+// base 为播放列表的URL，用于解析相对URI，为空时跳过相对URI；client 为空时使用 10 秒超时的默认客户端
+func WithKeyURICheck(base string, client *http.Client) ValidateOption {
+	return func(v *playlistValidator) {
+		v.checkURI = true
+		v.base = base
+		v.client = client
+	}
+}
+
+// playlistValidator ValidatePlaylist 的检查状态
+type playlistValidator struct {
+	checkURI bool
+	base     string
+	client   *http.Client
+
+	findings []Finding
+}
+
+// add 记录一条检查结果
+func (v *playlistValidator) add(line int, severity Severity, format string, args ...any) {
+	v.findings = append(v.findings, Finding{Line: line, Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+// versionFeature 需要更高兼容版本的加密特性及其首次出现的行
+type versionFeature struct {
+	name    string
+	version int
+	line    int
+}
+
+// ValidatePlaylist 检查播放列表的加密配置，发布前用于发现配置错误，按行号顺序返回发现的问题
+// 检查 EXT-X-KEY 与 EXT-X-SESSION-KEY 的位置与属性（包括不能位于 LL-HLS 同一分片的部分分片之间）、IV 格式、EXT-X-VERSION 是否满足所用属性，
+// 配合 WithKeyURICheck 时还会请求密钥URI；仅在读取播放列表失败时返回错误
+func ValidatePlaylist(r io.Reader, opts ...ValidateOption) ([]Finding, error) {
+	v := &playlistValidator{}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxPlaylistLine)
+	var (
+		version     = 1  // 未声明 EXT-X-VERSION 时为 1
+		versionLine = -1 // EXT-X-VERSION 所在行
+		master      bool
+		media       bool
+		extinf      = -1 // 等待 URI 的 EXTINF 所在行
+		part        = -1 // 当前分片第一个 EXT-X-PART 所在行
+		pendingKeys []int
+		keyTags     []KeyTag
+		features    []versionFeature
+	)
+	need := func(name string, ver, line int) {
+		for _, f := range features {
+			if f.name == name {
+				return
+			}
+		}
+		features = append(features, versionFeature{name: name, version: ver, line: line})
+	}
+
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 && line != "#EXTM3U" {
+			v.add(1, SeverityError, "第一行应为 #EXTM3U")
+		}
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXT-X-VERSION:"):
+			ver, err := strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-VERSION:"))
+			if err != nil {
+				v.add(n, SeverityError, "无效的 EXT-X-VERSION")
+				continue
+			}
+			version, versionLine = ver, n
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"), strings.HasPrefix(line, "#EXT-X-I-FRAME-STREAM-INF:"), strings.HasPrefix(line, "#EXT-X-MEDIA:"):
+			master = true
+		case strings.HasPrefix(line, "#EXT-X-KEY:"), strings.HasPrefix(line, "#EXT-X-SESSION-KEY:"):
+			tag, err := ParseKeyTag(line)
+			if err != nil {
+				v.add(n, SeverityError, "%v", err)
+				continue
+			}
+			tag.Line = n
+			keyTags = append(keyTags, tag)
+			if !tag.Session {
+				pendingKeys = append(pendingKeys, n)
+				if part >= 0 {
+					v.add(n, SeverityError, "EXT-X-KEY 位于同一分片的部分分片之间（第 %d 行起），密钥只能在完整分片边界切换", part)
+				} else if extinf >= 0 {
+					v.add(n, SeverityWarning, "EXT-X-KEY 位于 EXTINF 与分片 URI 之间，部分播放器不支持")
+				}
+			}
+			v.checkKeyTag(tag)
+			if tag.IV != "" {
+				need("IV 属性", 2, n)
+			}
+			if tag.KeyFormat != "" || tag.KeyFormatVersions != "" {
+				need("KEYFORMAT 属性", 5, n)
+			}
+			if strings.HasPrefix(tag.Method, "SAMPLE-AES") {
+				need("METHOD="+tag.Method, 5, n)
+			}
+		case strings.HasPrefix(line, "#EXTINF:"):
+			media = true
+			extinf = n
+		case strings.HasPrefix(line, "#EXT-X-PART:"), strings.HasPrefix(line, "#EXT-X-PRELOAD-HINT:"):
+			// LL-HLS 的部分分片与预加载提示同样使用之前的密钥
+			media = true
+			pendingKeys = pendingKeys[:0]
+			if part < 0 && strings.HasPrefix(line, "#EXT-X-PART:") {
+				part = n
+			}
+		case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"), strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			media = true
+		case !strings.HasPrefix(line, "#"):
+			if !master {
+				media = true
+				pendingKeys = pendingKeys[:0]
+			}
+			extinf, part = -1, -1
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取播放列表失败: %w", err)
+	}
+	if n == 0 {
+		v.add(0, SeverityError, "播放列表为空")
+	}
+
+	for _, t := range keyTags {
+		switch {
+		case t.Session && !master && media:
+			v.add(t.Line, SeverityError, "EXT-X-SESSION-KEY 只能出现在主播放列表中")
+		case !t.Session && master:
+			v.add(t.Line, SeverityError, "主播放列表中不能使用 EXT-X-KEY，应使用 EXT-X-SESSION-KEY")
+		}
+	}
+	if media {
+		for _, line := range pendingKeys {
+			v.add(line, SeverityWarning, "EXT-X-KEY 之后没有分片")
+		}
+	}
+	for _, f := range features {
+		if version < f.version {
+			at := versionLine
+			if at < 0 {
+				at = f.line
+			}
+			v.add(at, SeverityError, "%s 需要 EXT-X-VERSION 不低于 %d，实际为 %d", f.name, f.version, version)
+		}
+	}
+	if v.checkURI {
+		v.checkKeyURIs(keyTags)
+	}
+
+	slices.SortStableFunc(v.findings, func(a, b Finding) int {
+		return cmp.Compare(a.Line, b.Line)
+	})
+	return v.findings, nil
+}
+
+// checkKeyTag 检查单个密钥标签的属性
+func (v *playlistValidator) checkKeyTag(t KeyTag) {
+	switch t.Method {
+	case "NONE":
+		if t.Session {
+			v.add(t.Line, SeverityError, "EXT-X-SESSION-KEY 的 METHOD 不能为 NONE")
+		}
+		if t.URI != "" || t.IV != "" || t.KeyFormat != "" || t.KeyFormatVersions != "" {
+			v.add(t.Line, SeverityError, "METHOD=NONE 时不能包含其他属性")
+		}
+		return
+	case MethodAES128, MethodSampleAES, "SAMPLE-AES-CTR":
+	default:
+		v.add(t.Line, SeverityError, "未知的 METHOD: %s", t.Method)
+	}
+	if t.URI == "" {
+		v.add(t.Line, SeverityError, "METHOD=%s 时必须包含 URI", t.Method)
+	}
+	if t.IV != "" {
+		if b, err := hex.DecodeString(t.IV); err != nil || len(b) != 16 {
+			v.add(t.Line, SeverityError, "IV 应为 0x 加 32 位十六进制: %s", t.IV)
+		}
+	}
+}
+
+// checkKeyURIs 请求各 http/https 密钥URI，每个URI只请求一次
+func (v *playlistValidator) checkKeyURIs(tags []KeyTag) {
+	client := v.client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	var base *url.URL
+	if v.base != "" {
+		b, err := url.Parse(v.base)
+		if err != nil {
+			v.add(0, SeverityError, "无效的播放列表URL: %v", err)
+			return
+		}
+		base = b
+	}
+
+	checked := make(map[string]bool)
+	for _, t := range tags {
+		if t.Method == "NONE" || t.URI == "" || (t.KeyFormat != "" && t.KeyFormat != "identity") {
+			continue
+		}
+		u, err := url.Parse(t.URI)
+		if err != nil {
+			v.add(t.Line, SeverityError, "无效的密钥URI: %v", err)
+			continue
+		}
+		if !u.IsAbs() {
+			if base == nil {
+				continue
+			}
+			u = base.ResolveReference(u)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || checked[u.String()] {
+			continue
+		}
+		checked[u.String()] = true
+
+		size, err := fetchKeySize(client, u.String())
+		switch {
+		case err != nil:
+			v.add(t.Line, SeverityError, "密钥URI不可达: %v", err)
+		case t.Method == MethodAES128 && size != 16:
+			v.add(t.Line, SeverityError, "AES-128 密钥应为 16 字节，%s 返回 %d 字节", u, size)
+		}
+	}
+}
+
+// fetchKeySize 请求密钥URI并返回响应体长度，响应状态码不是 200 时返回错误
+func fetchKeySize(client *http.Client, uri string) (int, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, uri, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%s 返回 %s", uri, resp.Status)
+	}
+	n, err := io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<10))
+	if err != nil {
+		return 0, fmt.Errorf("读取 %s 失败: %w", uri, err)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,122 @@
+package hlskeyinfo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidatePlaylist(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-TARGETDURATION:4
+#EXT-X-KEY:METHOD=AES-128,URI="https://example.com/key",IV=0x0123
+#EXTINF:4.0,
+#EXT-X-KEY:METHOD=NONE,URI="https://example.com/key"
+seg0.ts
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://a",KEYFORMAT="com.apple.streamingkeydelivery"
+#EXTINF:4.0,
+seg1.ts
+#EXT-X-SESSION-KEY:METHOD=AES-128,URI="https://example.com/key"
+#EXT-X-KEY:METHOD=AES-256
+`
+	findings, err := ValidatePlaylist(strings.NewReader(playlist))
+	if err != nil {
+		t.Fatalf("检查失败: %v", err)
+	}
+	want := []string{
+		"第 3 行: 错误: IV 应为 0x 加 32 位十六进制: 0123",
+		"第 3 行: 错误: IV 属性 需要 EXT-X-VERSION 不低于 2，实际为 1",
+		"第 5 行: 警告: EXT-X-KEY 位于 EXTINF 与分片 URI 之间，部分播放器不支持",
+		"第 5 行: 错误: METHOD=NONE 时不能包含其他属性",
+		"第 7 行: 错误: KEYFORMAT 属性 需要 EXT-X-VERSION 不低于 5，实际为 1",
+		"第 7 行: 错误: METHOD=SAMPLE-AES 需要 EXT-X-VERSION 不低于 5，实际为 1",
+		"第 10 行: 错误: EXT-X-SESSION-KEY 只能出现在主播放列表中",
+		"第 11 行: 错误: 未知的 METHOD: AES-256",
+		"第 11 行: 错误: METHOD=AES-256 时必须包含 URI",
+		"第 11 行: 警告: EXT-X-KEY 之后没有分片",
+	}
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("检查结果不正确:\n%s\n期望\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// 由 ExtXKey 生成的播放列表应无问题
+	k := &KeyInfo{URL: "https://example.com/key"}
+	k.RandIV()
+	playlist = "#EXTM3U\n#EXT-X-VERSION:3\n" + k.ExtXKey() + "\n#EXTINF:4.0,\nseg0.ts\n#EXT-X-ENDLIST\n"
+	if findings, err := ValidatePlaylist(strings.NewReader(playlist)); err != nil || len(findings) != 0 {
+		t.Errorf("合规的播放列表不应有问题: %v, %v", findings, err)
+	}
+
+	if findings, _ := ValidatePlaylist(strings.NewReader("#EXTM3U\n#EXT-X-KEY:METHOD=AES-128,URI=\"k\"\n#EXT-X-STREAM-INF:BANDWIDTH=1\nv.m3u8\n")); len(findings) != 1 || findings[0].Line != 2 {
+		t.Errorf("主播放列表中的 EXT-X-KEY 应报错: %v", findings)
+	}
+}
+
+func TestValidatePlaylistParts(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-KEY:METHOD=AES-128,URI="https://example.com/k1"
+#EXT-X-PART:DURATION=1.0,URI="seg0.0.mp4",INDEPENDENT=YES
+#EXT-X-KEY:METHOD=AES-128,URI="https://example.com/k2"
+#EXT-X-PART:DURATION=1.0,URI="seg0.1.mp4"
+#EXTINF:2.0,
+seg0.mp4
+#EXT-X-KEY:METHOD=AES-128,URI="https://example.com/k3"
+#EXT-X-PRELOAD-HINT:TYPE=PART,URI="seg1.0.mp4"
+`
+	findings, err := ValidatePlaylist(strings.NewReader(playlist))
+	if err != nil {
+		t.Fatalf("检查失败: %v", err)
+	}
+	// 部分分片之间的密钥切换报错，只有预加载提示的新密钥不视为之后没有分片
+	if len(findings) != 1 || findings[0].Line != 5 || findings[0].Severity != SeverityError {
+		t.Errorf("应发现部分分片之间的密钥切换: %v", findings)
+	}
+}
+
+func TestValidatePlaylistKeyURI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/key":
+			w.Write(make([]byte, 16))
+		case "/short":
+			w.Write(make([]byte, 8))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	playlist := `#EXTM3U
+#EXT-X-KEY:METHOD=AES-128,URI="key"
+#EXTINF:4.0,
+seg0.ts
+#EXT-X-KEY:METHOD=AES-128,URI="/short"
+#EXTINF:4.0,
+seg1.ts
+#EXT-X-KEY:METHOD=AES-128,URI="` + srv.URL + `/missing"
+#EXTINF:4.0,
+seg2.ts
+#EXT-X-KEY:METHOD=AES-128,URI="key"
+#EXTINF:4.0,
+seg3.ts
+`
+	findings, err := ValidatePlaylist(strings.NewReader(playlist), WithKeyURICheck(srv.URL+"/live/index.m3u8", nil))
+	if err != nil {
+		t.Fatalf("检查失败: %v", err)
+	}
+	if len(findings) != 3 || findings[0].Line != 2 || findings[1].Line != 5 || findings[2].Line != 8 {
+		t.Errorf("应发现相对URI、长度错误与不可达的密钥: %v", findings)
+	}
+
+	// 跳过相对URI时不请求
+	findings, _ = ValidatePlaylist(strings.NewReader(playlist), WithKeyURICheck("", nil))
+	if len(findings) != 1 || findings[0].Line != 8 {
+		t.Errorf("未指定播放列表URL时应跳过相对URI: %v", findings)
+	}
+}
@@ -0,0 +1,294 @@
+package hlskeyinfo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyDrift 同一媒体序列号的分片在各变体中使用了不同的密钥，通常由部分成员轮换失败导致
+type KeyDrift struct {
+	Sequence uint64              // 分片的媒体序列号
+	Keys     map[string][]KeyTag // 变体播放列表地址到该分片生效的密钥标签，明文分片为空
+}
+
+// String 实现 fmt.Stringer 接口
+func (d KeyDrift) String() string {
+	variants := make([]string, 0, len(d.Keys))
+	for v := range d.Keys {
+		variants = append(variants, v)
+	}
+	slices.Sort(variants)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "分片 %d 的密钥不一致:", d.Sequence)
+	for _, v := range variants {
+		fmt.Fprintf(&b, " %s=%s", v, keyFingerprint(d.Keys[v]))
+	}
+	return b.String()
+}
+
+// VariantCheckOption CheckVariantKeys 检查选项
+type VariantCheckOption func(*variantChecker)
+
+// WithVariantClient 设置下载播放列表使用的 HTTP 客户端，默认 10 秒超时
+func WithVariantClient(client *http.Client) VariantCheckOption {
+	return func(c *variantChecker) {
+		c.client = client
+	}
+}
+
+// WithKeyNormalizer 在比较前转换各变体的密钥标签，用于排除预期的差异，
+// 如密钥获取URL中带有流名称时将其替换为相同的值
+func WithKeyNormalizer(fn func(variant string, tag KeyTag) KeyTag) VariantCheckOption {
+	return func(c *variantChecker) {
+		c.normalize = fn
+	}
+}
+
+// variantChecker CheckVariantKeys 的检查状态
+type variantChecker struct {
+	client    *http.Client
+	normalize func(variant string, tag KeyTag) KeyTag
+}
+
+// CheckVariantKeys 读取主播放列表及其引用的各变体与音视频副本播放列表，按媒体序列号对齐各变体共有的分片，
+// 返回同一分片在不同变体中 EXT-X-KEY（URI、IV、KEYFORMAT 等）不一致的记录，按序列号排序；全部一致时返回空
+// master 可以是 http/https 地址或本地文件路径，相对引用按其所在位置解析；各变体没有共同的分片时返回错误
+func CheckVariantKeys(ctx context.Context, master string, opts ...VariantCheckOption) ([]KeyDrift, error) {
+	c := &variantChecker{client: &http.Client{Timeout: 10 * time.Second}}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	variants, err := c.masterVariants(ctx, master)
+	if err != nil {
+		return nil, err
+	}
+	if len(variants) < 2 {
+		return nil, fmt.Errorf("主播放列表中的变体少于两个: %s", master)
+	}
+
+	keys := make(map[string]map[uint64][]KeyTag, len(variants))
+	for _, v := range variants {
+		segments, err := c.variantKeys(ctx, v)
+		if err != nil {
+			return nil, fmt.Errorf("读取变体 %s 失败: %w", v, err)
+		}
+		keys[v] = segments
+	}
+
+	// 只比较所有变体都包含的分片，直播各变体的窗口可能相差一两个分片
+	var common []uint64
+	for seq := range keys[variants[0]] {
+		shared := true
+		for _, v := range variants[1:] {
+			if _, ok := keys[v][seq]; !ok {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			common = append(common, seq)
+		}
+	}
+	if len(common) == 0 {
+		return nil, fmt.Errorf("各变体播放列表没有共同的分片")
+	}
+	slices.Sort(common)
+
+	var drifts []KeyDrift
+	for _, seq := range common {
+		want := keyFingerprint(keys[variants[0]][seq])
+		for _, v := range variants[1:] {
+			if keyFingerprint(keys[v][seq]) != want {
+				d := KeyDrift{Sequence: seq, Keys: make(map[string][]KeyTag, len(variants))}
+				for _, v := range variants {
+					d.Keys[v] = keys[v][seq]
+				}
+				drifts = append(drifts, d)
+				break
+			}
+		}
+	}
+	return drifts, nil
+}
+
+// masterVariants 返回主播放列表中 EXT-X-STREAM-INF 与 EXT-X-MEDIA 引用的播放列表地址，去重并保持顺序
+func (c *variantChecker) masterVariants(ctx context.Context, master string) ([]string, error) {
+	rc, err := c.open(ctx, master)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var variants []string
+	add := func(ref string) {
+		if v := resolvePlaylistRef(master, ref); !slices.Contains(variants, v) {
+			variants = append(variants, v)
+		}
+	}
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(nil, maxPlaylistLine)
+	streamInf := false
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			streamInf = true
+		case strings.HasPrefix(line, "#EXT-X-MEDIA:"):
+			attrs, err := parseAttributes(strings.TrimPrefix(line, "#EXT-X-MEDIA:"))
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行: %w", n, err)
+			}
+			if uri := attrs["URI"]; uri != "" {
+				add(uri)
+			}
+		case strings.HasPrefix(line, "#EXTINF:"):
+			return nil, fmt.Errorf("%s 是媒体播放列表，需要主播放列表", master)
+		case !strings.HasPrefix(line, "#") && streamInf:
+			add(line)
+			streamInf = false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取主播放列表失败: %w", err)
+	}
+	return variants, nil
+}
+
+// variantKeys 返回媒体播放列表中每个分片生效的密钥标签，以媒体序列号为键
+func (c *variantChecker) variantKeys(ctx context.Context, variant string) (map[uint64][]KeyTag, error) {
+	rc, err := c.open(ctx, variant)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	out := make(map[uint64][]KeyTag)
+	var (
+		seq    uint64
+		active []KeyTag
+	)
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(nil, maxPlaylistLine)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			seq, err = strconv.ParseUint(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行: 无效的媒体序列号: %w", n, err)
+			}
+		case strings.HasPrefix(line, "#EXT-X-KEY:"):
+			tag, err := ParseKeyTag(line)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行: %w", n, err)
+			}
+			if c.normalize != nil {
+				tag = c.normalize(variant, tag)
+			}
+			active = activeKeys(active, tag)
+		case !strings.HasPrefix(line, "#"):
+			out[seq] = active
+			seq++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取播放列表失败: %w", err)
+	}
+	return out, nil
+}
+
+// activeKeys 按规范更新生效的密钥标签：METHOD=NONE 清空全部，否则替换相同 KEYFORMAT 的标签
+// 返回新的切片，不修改 keys
+func activeKeys(keys []KeyTag, tag KeyTag) []KeyTag {
+	if tag.Method == "NONE" {
+		return nil
+	}
+	out := make([]KeyTag, 0, len(keys)+1)
+	for _, k := range keys {
+		if k.KeyFormat != tag.KeyFormat {
+			out = append(out, k)
+		}
+	}
+	return append(out, tag)
+}
+
+// keyFingerprint 返回一组密钥标签与顺序无关的比较值，明文时为 NONE
+func keyFingerprint(tags []KeyTag) string {
+	if len(tags) == 0 {
+		return "NONE"
+	}
+	lines := make([]string, len(tags))
+	for i, t := range tags {
+		t.Line = 0
+		lines[i] = t.String()
+	}
+	slices.Sort(lines)
+	return strings.Join(lines, " ")
+}
+
+// open 打开 http/https 地址或本地文件
+func (c *variantChecker) open(ctx context.Context, loc string) (io.ReadCloser, error) {
+	return openLocation(ctx, c.client, loc)
+}
+
+// openLocation 打开 http/https 地址或本地文件
+func openLocation(ctx context.Context, client *http.Client, loc string) (io.ReadCloser, error) {
+	if !isHTTPURL(loc) {
+		f, err := os.Open(loc)
+		if err != nil {
+			return nil, fmt.Errorf("打开文件失败: %w", err)
+		}
+		return f, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, loc, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("下载失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("下载 %s 失败: 返回 %s", loc, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// resolvePlaylistRef 按引用方播放列表的位置解析相对引用
+func resolvePlaylistRef(base, ref string) string {
+	if isHTTPURL(base) {
+		b, err := url.Parse(base)
+		if err != nil {
+			return ref
+		}
+		r, err := url.Parse(ref)
+		if err != nil {
+			return ref
+		}
+		return b.ResolveReference(r).String()
+	}
+	if isHTTPURL(ref) || filepath.IsAbs(ref) {
+		return ref
+	}
+	return filepath.Join(filepath.Dir(base), filepath.FromSlash(ref))
+}
+
+// isHTTPURL 判断是否为 http/https 地址
+func isHTTPURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
@@ -0,0 +1,86 @@
+package hlskeyinfo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckVariantKeys(t *testing.T) {
+	master := `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud",NAME="en",URI="audio/index.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=800000,AUDIO="aud"
+720p/index.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=1600000,AUDIO="aud"
+1080p/index.m3u8
+`
+	variant := func(stream, second string) string {
+		return `#EXTM3U
+#EXT-X-MEDIA-SEQUENCE:9
+#EXT-X-KEY:METHOD=AES-128,URI="https://keys.example.com/` + stream + `/k1"
+#EXTINF:4.0,
+seg9.ts
+#EXTINF:4.0,
+seg10.ts
+#EXT-X-KEY:METHOD=AES-128,URI="https://keys.example.com/` + stream + `/` + second + `"
+#EXTINF:4.0,
+seg11.ts
+`
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"master.m3u8":      master,
+		"audio/index.m3u8": variant("audio", "k2"),
+		"720p/index.m3u8":  variant("720p", "k2"),
+		"1080p/index.m3u8": strings.Replace(variant("1080p", "k1"), "#EXT-X-MEDIA-SEQUENCE:9", "#EXT-X-MEDIA-SEQUENCE:10", 1),
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 各流的密钥URL不同，去掉流名称后比较
+	normalize := WithKeyNormalizer(func(variant string, tag KeyTag) KeyTag {
+		stream := filepath.Base(filepath.Dir(variant))
+		tag.URI = strings.Replace(tag.URI, "/"+stream+"/", "/", 1)
+		return tag
+	})
+	drifts, err := CheckVariantKeys(context.Background(), filepath.Join(dir, "master.m3u8"), normalize)
+	if err != nil {
+		t.Fatalf("检查失败: %v", err)
+	}
+	// 1080p 的序列号偏移一个分片，共同的分片为 10、11，其中 11 的 1080p 仍使用旧密钥
+	if len(drifts) != 1 || drifts[0].Sequence != 11 || len(drifts[0].Keys) != 3 {
+		t.Fatalf("应发现分片 11 的密钥不一致: %v", drifts)
+	}
+	if got := drifts[0].Keys[filepath.Join(dir, "1080p/index.m3u8")][0].URI; got != "https://keys.example.com/k1" {
+		t.Errorf("不一致记录中的密钥不正确: %s", got)
+	}
+	if !strings.Contains(drifts[0].String(), "分片 11 的密钥不一致") {
+		t.Errorf("描述不正确: %s", drifts[0])
+	}
+
+	if _, err := CheckVariantKeys(context.Background(), filepath.Join(dir, "720p/index.m3u8")); err == nil {
+		t.Error("传入媒体播放列表应返回错误")
+	}
+
+	// 通过 HTTP 读取
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer srv.Close()
+	files["1080p/index.m3u8"] = variant("1080p", "k2")
+	os.WriteFile(filepath.Join(dir, "1080p/index.m3u8"), []byte(files["1080p/index.m3u8"]), 0o644)
+	drifts, err = CheckVariantKeys(context.Background(), srv.URL+"/master.m3u8", WithKeyNormalizer(func(variant string, tag KeyTag) KeyTag {
+		tag.URI = tag.URI[strings.LastIndexByte(tag.URI, '/'):]
+		return tag
+	}))
+	if err != nil || len(drifts) != 0 {
+		t.Errorf("密钥一致时不应有记录: %v, %v", drifts, err)
+	}
+}
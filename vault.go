@@ -0,0 +1,299 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// VaultClient HashiCorp Vault HTTP API 客户端，仅实现 KV v2 与 transit 引擎所需的请求
+type VaultClient struct {
+	addr      string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+// NewVaultClient 创建 Vault 客户端，addr 如 https://vault.example.com:8200
+func NewVaultClient(addr, token string) *VaultClient {
+	return &VaultClient{
+		addr:   strings.TrimSuffix(addr, "/"),
+		token:  token,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetNamespace 设置 Vault 企业版命名空间
+func (c *VaultClient) SetNamespace(ns string) *VaultClient {
+	c.namespace = ns
+	return c
+}
+
+// do 发送请求并将响应中的 data 字段解码到 out，404 返回 ErrKeyNotFound
+func (c *VaultClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("序列化 Vault 请求失败: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.addr+"/v1/"+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", c.token)
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 Vault 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, path)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var v struct {
+			Errors []string `json:"errors"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&v)
+		return fmt.Errorf("请求 Vault 失败: %s: %s", resp.Status, strings.Join(v.Errors, "; "))
+	}
+	if out == nil {
+		return nil
+	}
+	var v struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return fmt.Errorf("解析 Vault 响应失败: %w", err)
+	}
+	if err := json.Unmarshal(v.Data, out); err != nil {
+		return fmt.Errorf("解析 Vault 响应失败: %w", err)
+	}
+	return nil
+}
+
+// VaultKVStore 基于 Vault KV v2 引擎的密钥存储，每个密钥保存为 <mount>/data/<prefix><keyID>
+// 静态加密与访问审计由 Vault 负责
+type VaultKVStore struct {
+	client *VaultClient
+	mount  string
+	prefix string
+}
+
+var _ KeyStore = &VaultKVStore{}
+
+// NewVaultKVStore 创建基于 KV v2 的密钥存储，mount 为引擎挂载路径如 secret，prefix 如 hls/keys/
+func NewVaultKVStore(client *VaultClient, mount, prefix string) *VaultKVStore {
+	return &VaultKVStore{client: client, mount: strings.Trim(mount, "/"), prefix: prefix}
+}
+
+// Put 实现 KeyStore 接口，每次写入产生一个新版本
+func (s *VaultKVStore) Put(ctx context.Context, rec KeyRecord) error {
+	path, err := s.path("data", rec.KeyID)
+	if err != nil {
+		return err
+	}
+	if err := s.client.do(ctx, http.MethodPost, path, map[string]any{"data": rec}, nil); err != nil {
+		return fmt.Errorf("写入密钥失败: %w", err)
+	}
+	return nil
+}
+
+// Get 实现 KeyStore 接口，读取最新版本
+func (s *VaultKVStore) Get(ctx context.Context, keyID string) (KeyRecord, error) {
+	path, err := s.path("data", keyID)
+	if err != nil {
+		return KeyRecord{}, err
+	}
+	var v struct {
+		Data *KeyRecord `json:"data"`
+	}
+	if err := s.client.do(ctx, http.MethodGet, path, nil, &v); err != nil {
+		return KeyRecord{}, fmt.Errorf("读取密钥失败: %w", err)
+	}
+	// 最新版本被软删除时 data 为 null
+	if v.Data == nil {
+		return KeyRecord{}, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	return *v.Data, nil
+}
+
+// Delete 实现 KeyStore 接口，删除密钥的元数据与全部版本
+func (s *VaultKVStore) Delete(ctx context.Context, keyID string) error {
+	if _, err := s.Get(ctx, keyID); err != nil {
+		return err
+	}
+	path, _ := s.path("metadata", keyID)
+	if err := s.client.do(ctx, http.MethodDelete, path, nil, nil); err != nil {
+		return fmt.Errorf("删除密钥失败: %w", err)
+	}
+	return nil
+}
+
+// List 实现 KeyStore 接口
+func (s *VaultKVStore) List(ctx context.Context, stream string) ([]KeyRecord, error) {
+	var v struct {
+		Keys []string `json:"keys"`
+	}
+	err := s.client.do(ctx, "LIST", s.mount+"/metadata/"+escapePath(s.prefix), nil, &v)
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return nil, fmt.Errorf("列出密钥失败: %w", err)
+	}
+	var out []KeyRecord
+	for _, keyID := range v.Keys {
+		// 以 / 结尾的是子目录
+		if strings.HasSuffix(keyID, "/") {
+			continue
+		}
+		rec, err := s.Get(ctx, keyID)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if stream == "" || rec.Stream == stream {
+			out = append(out, rec)
+		}
+	}
+	sortRecords(out)
+	return out, nil
+}
+
+// path 返回 KeyID 在 data 或 metadata 下的路径
+func (s *VaultKVStore) path(kind, keyID string) (string, error) {
+	if err := validatePathName(keyID); err != nil {
+		return "", err
+	}
+	return s.mount + "/" + kind + "/" + escapePath(s.prefix+keyID), nil
+}
+
+// VaultTransit Vault transit 引擎，由 Vault 生成并封装内容密钥，明文密钥只出现在内存与密钥文件中
+type VaultTransit struct {
+	client  *VaultClient
+	mount   string
+	keyName string
+}
+
+var _ KeyProvider = &VaultTransit{}
+
+// NewVaultTransit 创建 transit 引擎客户端，mount 为引擎挂载路径如 transit，keyName 为封装密钥名称
+func NewVaultTransit(client *VaultClient, mount, keyName string) *VaultTransit {
+	return &VaultTransit{client: client, mount: strings.Trim(mount, "/"), keyName: keyName}
+}
+
+// GenerateDataKey 由 Vault 生成 size 字节的内容密钥，返回明文与以 transit 密钥封装的密文（vault:v1:...）
+func (t *VaultTransit) GenerateDataKey(ctx context.Context, size int) (plaintext, wrapped []byte, err error) {
+	if err := validateKeySize(size); err != nil {
+		return nil, nil, err
+	}
+	var v struct {
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+	}
+	// Vault 只支持 128/256/512 位数据密钥，24 字节密钥截取 256 位密钥的前 24 字节后重新封装
+	bits := size * 8
+	if bits == 192 {
+		bits = 256
+	}
+	path := t.mount + "/datakey/plaintext/" + url.PathEscape(t.keyName)
+	if err := t.client.do(ctx, http.MethodPost, path, map[string]any{"bits": bits}, &v); err != nil {
+		return nil, nil, fmt.Errorf("生成数据密钥失败: %w", err)
+	}
+	if plaintext, err = base64.StdEncoding.DecodeString(v.Plaintext); err != nil {
+		return nil, nil, fmt.Errorf("解析数据密钥失败: %w", err)
+	}
+	if len(plaintext) > size {
+		plaintext = plaintext[:size]
+		if wrapped, err = t.Encrypt(ctx, plaintext); err != nil {
+			return nil, nil, err
+		}
+		return plaintext, wrapped, nil
+	}
+	return plaintext, []byte(v.Ciphertext), nil
+}
+
+// Encrypt 以 transit 密钥封装内容密钥
+func (t *VaultTransit) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	var v struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	path := t.mount + "/encrypt/" + url.PathEscape(t.keyName)
+	in := map[string]any{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}
+	if err := t.client.do(ctx, http.MethodPost, path, in, &v); err != nil {
+		return nil, fmt.Errorf("封装密钥失败: %w", err)
+	}
+	return []byte(v.Ciphertext), nil
+}
+
+// Decrypt 解封由 GenerateDataKey 或 Encrypt 生成的密文
+func (t *VaultTransit) Decrypt(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var v struct {
+		Plaintext string `json:"plaintext"`
+	}
+	path := t.mount + "/decrypt/" + url.PathEscape(t.keyName)
+	if err := t.client.do(ctx, http.MethodPost, path, map[string]any{"ciphertext": string(wrapped)}, &v); err != nil {
+		return nil, fmt.Errorf("解封密钥失败: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(v.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("解析解封结果失败: %w", err)
+	}
+	return key, nil
+}
+
+// Reader 返回由 Vault 生成随机字节的 io.Reader，配合 WithRand 使 NewKeyInfo 与轮换器的密钥和 IV 均由 Vault 生成
+// 每次读取向 Vault 发起一次请求，超时时间为 timeout
+func (t *VaultTransit) Reader(timeout time.Duration) io.Reader {
+	return &vaultRandReader{t: t, timeout: timeout}
+}
+
+// vaultRandReader 基于 transit random 接口的随机数来源
+type vaultRandReader struct {
+	t       *VaultTransit
+	timeout time.Duration
+}
+
+// Read 实现 io.Reader 接口
+func (r *vaultRandReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	ctx := context.Background()
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	// 单次请求上限 128KiB
+	n := min(len(p), 128<<10)
+	var v struct {
+		RandomBytes string `json:"random_bytes"`
+	}
+	path := fmt.Sprintf("%s/random/%d", r.t.mount, n)
+	if err := r.t.client.do(ctx, http.MethodPost, path, map[string]any{"format": "base64"}, &v); err != nil {
+		return 0, fmt.Errorf("获取随机数失败: %w", err)
+	}
+	b, err := base64.StdEncoding.DecodeString(v.RandomBytes)
+	if err != nil {
+		return 0, fmt.Errorf("解析随机数失败: %w", err)
+	}
+	return copy(p, b), nil
+}
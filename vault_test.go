@@ -0,0 +1,138 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newFakeVault 模拟 KV v2 与 transit 引擎，transit 的“密文”为 vault:v1: 加明文的 Base64
+func newFakeVault(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	kv := make(map[string]json.RawMessage)
+	reply := func(w http.ResponseWriter, data any) {
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{"errors": []string{"permission denied"}})
+			return
+		}
+		var in map[string]json.RawMessage
+		json.NewDecoder(r.Body).Decode(&in)
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case strings.HasPrefix(path, "secret/data/"):
+			name := strings.TrimPrefix(path, "secret/data/")
+			if r.Method == http.MethodPost {
+				kv[name] = in["data"]
+				return
+			}
+			data, ok := kv[name]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			reply(w, map[string]any{"data": data})
+		case strings.HasPrefix(path, "secret/metadata/"):
+			name := strings.TrimPrefix(path, "secret/metadata/")
+			if r.Method == http.MethodDelete {
+				delete(kv, name)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			var keys []string
+			for k := range kv {
+				if rest, ok := strings.CutPrefix(k, name); ok {
+					if dir, _, ok := strings.Cut(rest, "/"); ok {
+						rest = dir + "/"
+					}
+					keys = append(keys, rest)
+				}
+			}
+			if len(keys) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			reply(w, map[string]any{"keys": keys})
+		case strings.HasPrefix(path, "transit/datakey/plaintext/hls"):
+			var bits int
+			json.Unmarshal(in["bits"], &bits)
+			key := bytes.Repeat([]byte{7}, bits/8)
+			b64 := base64.StdEncoding.EncodeToString(key)
+			reply(w, map[string]any{"plaintext": b64, "ciphertext": "vault:v1:" + b64})
+		case path == "transit/encrypt/hls":
+			var plaintext string
+			json.Unmarshal(in["plaintext"], &plaintext)
+			reply(w, map[string]any{"ciphertext": "vault:v1:" + plaintext})
+		case path == "transit/decrypt/hls":
+			var ciphertext string
+			json.Unmarshal(in["ciphertext"], &ciphertext)
+			reply(w, map[string]any{"plaintext": strings.TrimPrefix(ciphertext, "vault:v1:")})
+		case strings.HasPrefix(path, "transit/random/"):
+			n, _ := strconv.Atoi(strings.TrimPrefix(path, "transit/random/"))
+			reply(w, map[string]any{"random_bytes": base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{9}, n))})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVaultKVStore(t *testing.T) {
+	srv := newFakeVault(t)
+	testKeyStore(t, NewVaultKVStore(NewVaultClient(srv.URL, "root"), "secret", "hls/keys/"))
+
+	// 前缀下没有任何密钥时返回空列表
+	empty := NewVaultKVStore(NewVaultClient(srv.URL, "root"), "secret", "other/")
+	if list, err := empty.List(context.Background(), ""); err != nil || len(list) != 0 {
+		t.Errorf("空前缀应返回空列表: %v, %v", list, err)
+	}
+
+	denied := NewVaultKVStore(NewVaultClient(srv.URL, "wrong"), "secret", "hls/keys/")
+	if _, err := denied.Get(context.Background(), "key-a"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("应返回 Vault 的错误信息: %v", err)
+	}
+}
+
+func TestVaultTransit(t *testing.T) {
+	ctx := context.Background()
+	transit := NewVaultTransit(NewVaultClient(newFakeVault(t).URL, "root"), "transit", "hls")
+
+	for _, size := range []int{16, 24, 32} {
+		plaintext, wrapped, err := transit.GenerateDataKey(ctx, size)
+		if err != nil {
+			t.Fatalf("生成 %d 字节数据密钥失败: %v", size, err)
+		}
+		if len(plaintext) != size {
+			t.Errorf("数据密钥长度应为 %d，实际为 %d", size, len(plaintext))
+		}
+		key, err := transit.Decrypt(ctx, wrapped)
+		if err != nil || !bytes.Equal(key, plaintext) {
+			t.Errorf("%d 字节数据密钥解封结果不一致: %v", size, err)
+		}
+	}
+
+	// Vault 作为密钥与 IV 的随机数来源
+	k, err := NewKeyInfo("https://example.com/key", WithRand(transit.Reader(time.Second)))
+	if err != nil {
+		t.Fatalf("创建KeyInfo失败: %v", err)
+	}
+	defer k.Dispose()
+	if !bytes.Equal(k.GetKey(), bytes.Repeat([]byte{9}, 16)) {
+		t.Error("密钥应由 Vault 生成")
+	}
+}
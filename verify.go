@@ -0,0 +1,157 @@
+package hlskeyinfo
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSegmentMismatch 分片解密后既不是 MPEG-TS 也不是 fMP4，通常表示播放列表中的密钥或 IV 不正确
+var ErrSegmentMismatch = errors.New("分片解密后不是 MPEG-TS 或 fMP4 数据")
+
+// verifyBytes 校验时解密的字节数，覆盖三个 TS 包
+const verifyBytes = 36 * aes.BlockSize
+
+// fmp4Boxes 分片开头可能出现的 fMP4 box 类型
+var fmp4Boxes = map[string]bool{
+	"ftyp": true, "styp": true, "moof": true, "moov": true, "sidx": true,
+	"emsg": true, "prft": true, "free": true, "skip": true, "mdat": true,
+}
+
+// VerifySegment 解密分片开头的若干块，检查是否为 MPEG-TS（每 188 字节一个 0x47 同步字节）或 fMP4（合法的 box 头），
+// 不匹配时返回 ErrSegmentMismatch；只读取开头少量数据，不校验填充
+// CBC 模式下 IV 错误只影响第一个块，仅当其改变了同步字节、TS 包头或 box 头时才能发现，密钥错误则总能发现
+func (k *KeyInfo) VerifySegment(r io.Reader, seq uint64) error {
+	block, iv, err := k.segmentKey(seq)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, verifyBytes)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("读取分片失败: %w", err)
+	}
+	n -= n % aes.BlockSize
+	if n == 0 {
+		return fmt.Errorf("%w: 分片不足一个块", ErrSegmentMismatch)
+	}
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(buf[:n], buf[:n])
+	if !looksLikeMedia(buf[:n]) {
+		return ErrSegmentMismatch
+	}
+	return nil
+}
+
+// looksLikeMedia 判断解密后的数据开头是否为 MPEG-TS 或 fMP4
+func looksLikeMedia(data []byte) bool {
+	if data[0] == 0x47 {
+		for i := 0; i+4 <= len(data); i += 188 {
+			// 同步字节、传输错误标志为 0、adaptation_field_control 不为保留值 0
+			if data[i] != 0x47 || data[i+1]&0x80 != 0 || data[i+3]&0x30 == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	if len(data) < 8 {
+		return false
+	}
+	size := binary.BigEndian.Uint32(data)
+	return (size == 1 || size >= 8) && fmp4Boxes[string(data[4:8])]
+}
+
+// VerifyOption VerifyPlaylist 校验选项
+type VerifyOption func(*playlistVerifier)
+
+// WithVerifyClient 设置下载播放列表与分片使用的 HTTP 客户端，默认 10 秒超时
+func WithVerifyClient(client *http.Client) VerifyOption {
+	return func(v *playlistVerifier) {
+		v.client = client
+	}
+}
+
+// playlistVerifier VerifyPlaylist 的校验状态
+type playlistVerifier struct {
+	client *http.Client
+}
+
+// VerifyPlaylist 按播放列表中的 EXT-X-KEY 逐个校验已加密分片能否被正确解密，用于确认发布的密钥与 IV 和媒体一致
+// playlist 可以是 http/https 地址或本地文件路径；keyFor 按密钥标签返回密钥，每个不同的标签只调用一次；
+// 每个分片只读取开头少量数据。每个无法解密或无法获取的分片返回一条 Finding，全部通过时返回空；
+// 仅支持 KEYFORMAT 为 identity 的 AES-128，读取播放列表失败时返回错误
+func VerifyPlaylist(ctx context.Context, playlist string, keyFor func(tag KeyTag) ([]byte, error), opts ...VerifyOption) ([]Finding, error) {
+	v := &playlistVerifier{client: &http.Client{Timeout: 10 * time.Second}}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	rc, err := openLocation(ctx, v.client, playlist)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var (
+		findings []Finding
+		seq      uint64
+		active   *KeyInfo
+		keyErr   error // 当前密钥标签无法使用的原因
+		keys     = make(map[string]*KeyInfo)
+	)
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(nil, maxPlaylistLine)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			return nil, fmt.Errorf("不支持主播放列表，请对各媒体播放列表分别校验")
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			seq, err = strconv.ParseUint(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行: 无效的媒体序列号: %w", n, err)
+			}
+		case strings.HasPrefix(line, "#EXT-X-KEY:"):
+			tag, err := ParseKeyTag(line)
+			if err == nil {
+				active, err = tagKeyInfo(tag, keys, keyFor)
+			}
+			if keyErr = err; err != nil {
+				active = nil
+				findings = append(findings, Finding{Line: n, Severity: SeverityError, Message: err.Error()})
+			}
+		case !strings.HasPrefix(line, "#"):
+			if active != nil {
+				if err := v.verify(ctx, active, resolvePlaylistRef(playlist, line), seq); err != nil {
+					findings = append(findings, Finding{Line: n, Severity: SeverityError, Message: fmt.Sprintf("分片 %s: %v", line, err)})
+				}
+			} else if keyErr != nil {
+				findings = append(findings, Finding{Line: n, Severity: SeverityError, Message: fmt.Sprintf("分片 %s 的密钥不可用", line)})
+			}
+			seq++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取播放列表失败: %w", err)
+	}
+	return findings, nil
+}
+
+// verify 下载并校验单个分片
+func (v *playlistVerifier) verify(ctx context.Context, k *KeyInfo, loc string, seq uint64) error {
+	rc, err := openLocation(ctx, v.client, loc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return k.VerifySegment(rc, seq)
+}
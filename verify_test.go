@@ -0,0 +1,70 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyPlaylist(t *testing.T) {
+	dir := t.TempDir()
+	k, err := NewKeyInfoWithKey("https://keys.example.com/1", bytes.Repeat([]byte{1}, 16), WithTempDir(dir))
+	if err != nil {
+		t.Fatalf("创建失败: %v", err)
+	}
+	defer k.Dispose()
+	k.UseSequenceIV()
+
+	ts := bytes.Repeat(append([]byte{0x47, 0x40, 0x00, 0x10}, make([]byte, 184)...), 4)
+	fmp4 := make([]byte, 24)
+	binary.BigEndian.PutUint32(fmp4, 24)
+	copy(fmp4[4:], "styp")
+	other, _ := NewKeyInfoWithKey("https://keys.example.com/1", bytes.Repeat([]byte{2}, 16), WithTempDir(dir))
+	defer other.Dispose()
+	other.UseSequenceIV()
+	write := func(name string, k *KeyInfo, plain []byte, seq uint64) {
+		var buf bytes.Buffer
+		if err := k.EncryptSegment(&buf, bytes.NewReader(plain), seq); err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0o644)
+	}
+	write("0.ts", k, ts, 0)
+	write("1.m4s", k, fmp4, 1)
+	write("2.ts", other, ts, 2) // 使用了其他密钥
+	os.WriteFile(filepath.Join(dir, "3.ts"), ts, 0o644)
+
+	playlist := filepath.Join(dir, "index.m3u8")
+	os.WriteFile(playlist, []byte("#EXTM3U\n"+k.ExtXKey()+"\n#EXTINF:4,\n0.ts\n#EXTINF:4,\n1.m4s\n#EXTINF:4,\n2.ts\n"+
+		"#EXT-X-KEY:METHOD=NONE\n#EXTINF:4,\n3.ts\n#EXT-X-KEY:METHOD=AES-128,URI=\"https://keys.example.com/2\"\n#EXTINF:4,\n4.ts\n"), 0o644)
+
+	findings, err := VerifyPlaylist(context.Background(), playlist, func(tag KeyTag) ([]byte, error) {
+		if tag.URI != k.KeyURL() {
+			return nil, errors.New("密钥不存在")
+		}
+		return k.GetKey(), nil
+	})
+	if err != nil {
+		t.Fatalf("校验失败: %v", err)
+	}
+	// 2.ts 无法解密；密钥 2 不可用，其后的 4.ts 无法校验
+	if len(findings) != 3 || findings[0].Line != 8 || findings[1].Line != 12 || findings[2].Line != 14 {
+		t.Errorf("校验结果不正确: %v", findings)
+	}
+
+	// 播放列表中的 IV 与加密时不一致
+	var enc bytes.Buffer
+	k.SetIV("0xa0a1a2a3a4a5a6a7a8a9aaabacadaeaf")
+	k.EncryptSegment(&enc, bytes.NewReader(ts), 3)
+	if err := k.VerifySegment(bytes.NewReader(enc.Bytes()), 3); err != nil {
+		t.Errorf("正确的密钥与 IV 应通过校验: %v", err)
+	}
+	k.UseSequenceIV()
+	if err := k.VerifySegment(bytes.NewReader(enc.Bytes()), 3); !errors.Is(err, ErrSegmentMismatch) {
+		t.Errorf("IV 不正确时应返回 ErrSegmentMismatch: %v", err)
+	}
+}
@@ -0,0 +1,415 @@
+package hlskeyinfo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// EncryptVOD 使用该密钥就地加密未加密的 HLS 点播：加密播放列表引用的每个分片，并在第一个分片前插入 EXT-X-KEY 标签
+// playlist 为媒体播放列表路径，分片须为相对于播放列表所在目录的本地路径；不需要 ffmpeg
+// 显式 IV 模式下所有分片共用该 IV，否则按媒体序列号计算 IV；EXT-X-MAP 初始化分片保持明文
+// 加密方式为 SAMPLE-AES 时按 EncryptSampleAES 加密 MPEG-TS 分片，不支持带 EXT-X-MAP 的 fMP4
+// 所有分片先加密到临时文件，全部成功后才替换原文件与播放列表，途中失败时原文件不变
+func (k *KeyInfo) EncryptVOD(playlist string) error {
+	data, err := os.ReadFile(playlist)
+	if err != nil {
+		return fmt.Errorf("读取播放列表失败: %w", err)
+	}
+	dir := filepath.Dir(playlist)
+
+	text := string(data)
+	eol := "\n"
+	if strings.Contains(text, "\r\n") {
+		eol = "\r\n"
+	}
+	lines := strings.Split(strings.TrimRight(text, "\r\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "#EXTM3U" {
+		return fmt.Errorf("不是有效的播放列表: %s", playlist)
+	}
+
+	var (
+		seq      uint64
+		segments []vodSegment
+		out      []string
+		seen     = make(map[string]bool)
+		keyAt    = -1 // EXT-X-KEY 插入的位置
+		version  = -1 // EXT-X-VERSION 所在行
+	)
+	for n, raw := range lines {
+		line := strings.TrimSpace(raw)
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-KEY:"):
+			return fmt.Errorf("播放列表已包含 EXT-X-KEY（第 %d 行）", n+1)
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			return fmt.Errorf("不支持主播放列表，请对各媒体播放列表分别加密")
+		case strings.HasPrefix(line, "#EXT-X-PART:"):
+			return fmt.Errorf("不支持包含 LL-HLS 部分分片的播放列表（第 %d 行）", n+1)
+		case strings.HasPrefix(line, "#EXT-X-BYTERANGE:"):
+			return fmt.Errorf("不支持按字节范围引用的分片（第 %d 行）", n+1)
+		case strings.HasPrefix(line, "#EXT-X-MAP:") && k.method() == MethodSampleAES:
+			return fmt.Errorf("SAMPLE-AES 不支持 fMP4 点播（第 %d 行），请使用 EncryptCMAFInit 与 EncryptCMAFSegment", n+1)
+		case strings.HasPrefix(line, "#EXT-X-MAP:") && keyAt >= 0:
+			// 位于 EXT-X-KEY 之后的初始化分片按规范也需加密
+			return fmt.Errorf("不支持位于分片之间的 EXT-X-MAP（第 %d 行）", n+1)
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			seq, err = strconv.ParseUint(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"), 10, 64)
+			if err != nil {
+				return fmt.Errorf("无效的媒体序列号（第 %d 行）: %w", n+1, err)
+			}
+		case strings.HasPrefix(line, "#EXT-X-VERSION:"):
+			version = len(out)
+		case strings.HasPrefix(line, "#EXTINF:") && keyAt < 0:
+			keyAt = len(out)
+		case line != "" && !strings.HasPrefix(line, "#"):
+			path, err := vodSegmentPath(dir, line)
+			if err != nil {
+				return fmt.Errorf("第 %d 行: %w", n+1, err)
+			}
+			// 就地加密时重复引用的分片会被加密两次
+			if seen[path] {
+				return fmt.Errorf("第 %d 行: 分片 %s 被多次引用", n+1, line)
+			}
+			seen[path] = true
+			segments = append(segments, vodSegment{path: path, seq: seq})
+			seq++
+		}
+		out = append(out, strings.TrimRight(raw, "\r"))
+	}
+	if len(segments) == 0 || keyAt < 0 {
+		return fmt.Errorf("播放列表中没有分片: %s", playlist)
+	}
+
+	out, inserted := k.requireVersion(out, version)
+	if inserted {
+		keyAt++
+	}
+	out = append(out[:keyAt], append([]string{k.ExtXKey()}, out[keyAt:]...)...)
+
+	encrypt := k.EncryptSegment
+	if k.method() == MethodSampleAES {
+		encrypt = k.EncryptSampleAES
+	}
+	err = rewriteSegments(segments, func(dst io.Writer, src io.Reader, s vodSegment) error {
+		return encrypt(dst, src, s.seq)
+	})
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(playlist)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(playlist, []byte(strings.Join(out, eol)+eol), info.Mode().Perm())
+}
+
+// DecryptVOD 就地解密 HLS AES-128 点播，用于质检与导出明文：解密播放列表引用的每个加密分片并移除 EXT-X-KEY 标签
+// keyFor 按密钥标签返回 16 字节密钥，如通过 KeyStore 或 KeySource 查找；每个不同的标签只调用一次
+// 位于 EXT-X-KEY 之后的 EXT-X-MAP 初始化分片同样解密，此时标签须带显式 IV；其余限制与 EncryptVOD 相同
+// 所有分片先解密到临时文件，全部成功后才替换原文件与播放列表，途中失败时原文件不变
+func DecryptVOD(playlist string, keyFor func(tag KeyTag) ([]byte, error)) error {
+	vod, err := readEncryptedVOD(playlist, keyFor, func(KeyTag) string { return "" })
+	if err != nil {
+		return err
+	}
+	err = rewriteSegments(vod.segments, func(dst io.Writer, src io.Reader, s vodSegment) error {
+		return s.key.DecryptSegment(dst, src, s.seq)
+	})
+	if err != nil {
+		return err
+	}
+	return vod.writePlaylist(playlist)
+}
+
+// ReEncryptVOD 使用该密钥就地重新加密已加密的 HLS 点播，用于密钥疑似泄露时迁移到新密钥：
+// 以旧密钥解密每个加密分片后用该密钥重新加密，并将播放列表中的 EXT-X-KEY 替换为该密钥的标签
+// keyFor 按旧的密钥标签返回 16 字节密钥，规则与 DecryptVOD 相同；METHOD=NONE 的明文区间与第一个密钥之前的分片保持明文
+// 加密规则与 EncryptVOD 相同，加密的 EXT-X-MAP 要求该密钥设置显式 IV；所有分片全部成功后才替换原文件与播放列表
+func (k *KeyInfo) ReEncryptVOD(playlist string, keyFor func(tag KeyTag) ([]byte, error)) error {
+	encrypted := false // 输出中该密钥的标签是否已生效
+	vod, err := readEncryptedVOD(playlist, keyFor, func(tag KeyTag) string {
+		switch {
+		case tag.Method == "NONE" && encrypted:
+			encrypted = false
+			return "#EXT-X-KEY:METHOD=NONE"
+		case tag.Method != "NONE" && !encrypted:
+			encrypted = true
+			return k.ExtXKey()
+		}
+		return ""
+	})
+	if err != nil {
+		return err
+	}
+	for _, s := range vod.segments {
+		if s.init && !k.HasIV() {
+			return fmt.Errorf("加密的 EXT-X-MAP 要求新密钥设置显式 IV: %s", s.path)
+		}
+		if s.init && k.method() == MethodSampleAES {
+			return fmt.Errorf("SAMPLE-AES 不支持 fMP4 点播，请使用 EncryptCMAFInit 与 EncryptCMAFSegment")
+		}
+	}
+	vod.lines, _ = k.requireVersion(vod.lines, vod.version)
+
+	encrypt := k.EncryptSegment
+	if k.method() == MethodSampleAES {
+		encrypt = k.EncryptSampleAES
+	}
+	err = rewriteSegments(vod.segments, func(dst io.Writer, src io.Reader, s vodSegment) error {
+		r, err := NewDecryptReader(src, s.key, s.seq)
+		if err != nil {
+			return err
+		}
+		return encrypt(dst, r, s.seq)
+	})
+	if err != nil {
+		return err
+	}
+	return vod.writePlaylist(playlist)
+}
+
+// encryptedVOD 解析后的已加密点播播放列表
+type encryptedVOD struct {
+	eol      string
+	lines    []string // 输出的播放列表行
+	version  int      // EXT-X-VERSION 在 lines 中的位置，不存在时为 -1
+	segments []vodSegment
+}
+
+// readEncryptedVOD 解析已加密的点播播放列表，返回各加密分片及其密钥
+// 每个 EXT-X-KEY 标签替换为 keyLine 返回的行，返回空时移除该标签
+func readEncryptedVOD(playlist string, keyFor func(tag KeyTag) ([]byte, error), keyLine func(tag KeyTag) string) (*encryptedVOD, error) {
+	data, err := os.ReadFile(playlist)
+	if err != nil {
+		return nil, fmt.Errorf("读取播放列表失败: %w", err)
+	}
+	dir := filepath.Dir(playlist)
+
+	text := string(data)
+	vod := &encryptedVOD{eol: "\n", version: -1}
+	if strings.Contains(text, "\r\n") {
+		vod.eol = "\r\n"
+	}
+	lines := strings.Split(strings.TrimRight(text, "\r\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "#EXTM3U" {
+		return nil, fmt.Errorf("不是有效的播放列表: %s", playlist)
+	}
+
+	var (
+		seq    uint64
+		active *KeyInfo // 当前生效的密钥，明文时为 nil
+		keys   = make(map[string]*KeyInfo)
+		seen   = make(map[string]bool)
+		tagged bool
+	)
+	add := func(n int, uri string, init bool) error {
+		path, err := vodSegmentPath(dir, uri)
+		if err != nil {
+			return fmt.Errorf("第 %d 行: %w", n+1, err)
+		}
+		if seen[path] {
+			return fmt.Errorf("第 %d 行: 分片 %s 被多次引用", n+1, uri)
+		}
+		seen[path] = true
+		vod.segments = append(vod.segments, vodSegment{path: path, seq: seq, key: active, init: init})
+		return nil
+	}
+	for n, raw := range lines {
+		line := strings.TrimSpace(raw)
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			return nil, fmt.Errorf("不支持主播放列表，请对各媒体播放列表分别处理")
+		case strings.HasPrefix(line, "#EXT-X-PART:"):
+			return nil, fmt.Errorf("不支持包含 LL-HLS 部分分片的播放列表（第 %d 行）", n+1)
+		case strings.HasPrefix(line, "#EXT-X-BYTERANGE:"):
+			return nil, fmt.Errorf("不支持按字节范围引用的分片（第 %d 行）", n+1)
+		case strings.HasPrefix(line, "#EXT-X-VERSION:"):
+			vod.version = len(vod.lines)
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			seq, err = strconv.ParseUint(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("无效的媒体序列号（第 %d 行）: %w", n+1, err)
+			}
+		case strings.HasPrefix(line, "#EXT-X-KEY:"):
+			tag, err := ParseKeyTag(line)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行: %w", n+1, err)
+			}
+			tagged = true
+			if active, err = tagKeyInfo(tag, keys, keyFor); err != nil {
+				return nil, fmt.Errorf("第 %d 行: %w", n+1, err)
+			}
+			if l := keyLine(tag); l != "" {
+				vod.lines = append(vod.lines, l)
+			}
+			continue
+		case strings.HasPrefix(line, "#EXT-X-MAP:") && active != nil:
+			if !active.HasIV() {
+				return nil, fmt.Errorf("第 %d 行: 加密的 EXT-X-MAP 要求 EXT-X-KEY 带显式 IV", n+1)
+			}
+			attrs, err := parseAttributes(strings.TrimPrefix(line, "#EXT-X-MAP:"))
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行: %w", n+1, err)
+			}
+			if err := add(n, attrs["URI"], true); err != nil {
+				return nil, err
+			}
+		case line != "" && !strings.HasPrefix(line, "#"):
+			if active != nil {
+				if err := add(n, line, false); err != nil {
+					return nil, err
+				}
+			}
+			seq++
+		}
+		vod.lines = append(vod.lines, strings.TrimRight(raw, "\r"))
+	}
+	if !tagged {
+		return nil, fmt.Errorf("播放列表未加密: %s", playlist)
+	}
+	return vod, nil
+}
+
+// writePlaylist 以原有的换行符与权限替换播放列表
+func (v *encryptedVOD) writePlaylist(playlist string) error {
+	info, err := os.Stat(playlist)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(playlist, []byte(strings.Join(v.lines, v.eol)+v.eol), info.Mode().Perm())
+}
+
+// requireVersion 确保播放列表的 EXT-X-VERSION 满足该密钥所用的属性：IV 需要 2，SAMPLE-AES 需要 5
+// version 为 EXT-X-VERSION 所在行，不存在时为 -1；需要时在第二行插入，返回新的行与是否插入
+func (k *KeyInfo) requireVersion(lines []string, version int) ([]string, bool) {
+	minVersion := 0
+	if k.HasIV() {
+		minVersion = 2
+	}
+	if k.method() == MethodSampleAES {
+		minVersion = 5
+	}
+	if minVersion == 0 {
+		return lines, false
+	}
+	tag := "#EXT-X-VERSION:" + strconv.Itoa(minVersion)
+	if version < 0 {
+		return append(lines[:1], append([]string{tag}, lines[1:]...)...), true
+	}
+	if v, _ := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(lines[version]), "#EXT-X-VERSION:")); v < minVersion {
+		lines[version] = tag
+	}
+	return lines, false
+}
+
+// tagKeyInfo 返回密钥标签对应的 KeyInfo，METHOD=NONE 时返回 nil；相同的标签复用 keys 中已获取的密钥
+func tagKeyInfo(tag KeyTag, keys map[string]*KeyInfo, keyFor func(tag KeyTag) ([]byte, error)) (*KeyInfo, error) {
+	if tag.Method == "NONE" {
+		return nil, nil
+	}
+	if tag.Method != MethodAES128 || (tag.KeyFormat != "" && tag.KeyFormat != "identity") {
+		return nil, fmt.Errorf("仅支持解密 KEYFORMAT 为 identity 的 AES-128 分片: %s", tag)
+	}
+	id := tag.String()
+	if k, ok := keys[id]; ok {
+		return k, nil
+	}
+	key, err := keyFor(tag)
+	if err != nil {
+		return nil, fmt.Errorf("获取密钥 %s 失败: %w", tag.URI, err)
+	}
+	if len(key) != 16 {
+		return nil, fmt.Errorf("AES-128 密钥应为 16 字节，%s 为 %d 字节", tag.URI, len(key))
+	}
+	k := &KeyInfo{URL: tag.URI, key: key, keySize: len(key)}
+	if tag.IV != "" {
+		k.SetIV(tag.IV)
+	} else {
+		k.UseSequenceIV()
+	}
+	keys[id] = k
+	return k, nil
+}
+
+// vodSegment 待加密或解密的点播分片
+type vodSegment struct {
+	path string
+	seq  uint64
+	key  *KeyInfo // 解密使用的密钥
+	init bool     // 是否为 EXT-X-MAP 初始化分片
+	tmp  string
+}
+
+// vodSegmentPath 将播放列表中的分片 URI 解析为本地路径
+func vodSegmentPath(dir, uri string) (string, error) {
+	rel := filepath.FromSlash(uri)
+	if strings.Contains(uri, "://") || strings.ContainsAny(uri, "?#") || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("分片须为播放列表目录下的相对路径: %q", uri)
+	}
+	return filepath.Join(dir, rel), nil
+}
+
+// rewriteSegments 先将 fn 转换后的所有分片写入临时文件，全部成功后再替换原文件
+func rewriteSegments(segments []vodSegment, fn func(dst io.Writer, src io.Reader, s vodSegment) error) error {
+	defer func() {
+		for _, s := range segments {
+			if s.tmp != "" {
+				os.Remove(s.tmp)
+			}
+		}
+	}()
+
+	for i := range segments {
+		s := &segments[i]
+		if err := s.rewrite(fn); err != nil {
+			return err
+		}
+	}
+
+	for i := range segments {
+		s := &segments[i]
+		if err := os.Rename(s.tmp, s.path); err != nil {
+			return fmt.Errorf("替换分片 %s 失败: %w", s.path, err)
+		}
+		s.tmp = ""
+	}
+	return nil
+}
+
+// rewrite 将 fn 转换后的分片写入同目录的临时文件，保留原文件权限
+func (s *vodSegment) rewrite(fn func(dst io.Writer, src io.Reader, s vodSegment) error) error {
+	src, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("读取分片失败: %w", err)
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	s.tmp = tmp.Name()
+
+	w := bufio.NewWriter(tmp)
+	err = fn(w, bufio.NewReader(src), *s)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("分片 %s: %w", s.path, err)
+	}
+	if err := os.Chmod(s.tmp, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("设置文件权限失败: %w", err)
+	}
+	return nil
+}
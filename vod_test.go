@@ -0,0 +1,274 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// decryptTestSegment 按媒体序列号 IV 解密分片并去除填充
+func decryptTestSegment(t *testing.T, k *KeyInfo, data []byte, seq uint64) []byte {
+	t.Helper()
+	iv, _ := k.SegmentIV(seq)
+	block, _ := aes.NewCipher(k.GetKey())
+	if len(data)%aes.BlockSize != 0 {
+		t.Fatalf("密文长度应为块大小的整数倍: %d", len(data))
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+	return out[:len(out)-int(out[len(out)-1])]
+}
+
+func TestEncryptVOD(t *testing.T) {
+	dir := t.TempDir()
+	playlist := filepath.Join(dir, "index.m3u8")
+	content := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:4\n#EXT-X-MEDIA-SEQUENCE:10\n" +
+		"#EXTINF:4.0,\nseg/0.ts\n#EXTINF:4.0,\nseg/1.ts\n#EXT-X-ENDLIST\n"
+	os.MkdirAll(filepath.Join(dir, "seg"), 0o755)
+	segments := [][]byte{bytes.Repeat([]byte{0x47}, 188*3), bytes.Repeat([]byte{0x47, 1}, 100)}
+	for i, seg := range segments {
+		os.WriteFile(filepath.Join(dir, "seg", fmt.Sprintf("%d.ts", i)), seg, 0o644)
+	}
+	os.WriteFile(playlist, []byte(content), 0o644)
+
+	k, err := NewKeyInfo("https://example.com/key", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建KeyInfo失败: %v", err)
+	}
+	defer k.Dispose()
+	if err := k.EncryptVOD(playlist); err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+
+	got, _ := os.ReadFile(playlist)
+	want := strings.Replace(content, "#EXTINF:4.0,\nseg/0.ts", k.ExtXKey()+"\n#EXTINF:4.0,\nseg/0.ts", 1)
+	if string(got) != want {
+		t.Errorf("播放列表不正确:\n%s", got)
+	}
+	for i, seg := range segments {
+		enc, _ := os.ReadFile(filepath.Join(dir, "seg", fmt.Sprintf("%d.ts", i)))
+		if plain := decryptTestSegment(t, k, enc, uint64(10+i)); !bytes.Equal(plain, seg) {
+			t.Errorf("分片 %d 解密后与原内容不一致", i)
+		}
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "seg", ".*")); len(matches) > 0 {
+		t.Errorf("不应残留临时文件: %v", matches)
+	}
+
+	if err := k.EncryptVOD(playlist); err == nil {
+		t.Error("已加密的播放列表应返回错误")
+	}
+}
+
+func TestEncryptVODSequenceIV(t *testing.T) {
+	dir := t.TempDir()
+	playlist := filepath.Join(dir, "index.m3u8")
+	os.WriteFile(playlist, []byte("#EXTM3U\r\n#EXTINF:2.0,\r\na.ts\r\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "a.ts"), []byte("segment"), 0o644)
+
+	k, err := NewKeyInfo("https://example.com/key", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建KeyInfo失败: %v", err)
+	}
+	defer k.Dispose()
+	k.UseSequenceIV()
+	if err := k.EncryptVOD(playlist); err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	got, _ := os.ReadFile(playlist)
+	if want := "#EXTM3U\r\n" + k.ExtXKey() + "\r\n#EXTINF:2.0,\r\na.ts\r\n"; string(got) != want {
+		t.Errorf("应保留 CRLF 且不改动版本号:\n%q", got)
+	}
+	enc, _ := os.ReadFile(filepath.Join(dir, "a.ts"))
+	if plain := decryptTestSegment(t, k, enc, 0); string(plain) != "segment" {
+		t.Errorf("解密结果不正确: %q", plain)
+	}
+
+	// 显式 IV 需要 EXT-X-VERSION:2
+	os.WriteFile(playlist, []byte("#EXTM3U\n#EXTINF:2.0,\na.ts\n"), 0o644)
+	k.RandIV()
+	if err := k.EncryptVOD(playlist); err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	if got, _ := os.ReadFile(playlist); !strings.HasPrefix(string(got), "#EXTM3U\n#EXT-X-VERSION:2\n#EXT-X-KEY:") {
+		t.Errorf("应插入 EXT-X-VERSION:2:\n%s", got)
+	}
+
+	for _, bad := range []string{
+		"#EXTM3U\n#EXTINF:2.0,\nhttps://cdn.example.com/a.ts\n",
+		"#EXTM3U\n#EXTINF:2.0,\n../a.ts\n",
+		"#EXTM3U\n#EXTINF:2.0,\nmissing.ts\n",
+		"#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=1\nlow.m3u8\n",
+		"#EXTM3U\n#EXT-X-PART:DURATION=1.0,URI=\"a.0.ts\"\n#EXTINF:2.0,\na.ts\n",
+	} {
+		os.WriteFile(playlist, []byte(bad), 0o644)
+		if err := k.EncryptVOD(playlist); err == nil {
+			t.Errorf("应返回错误: %q", bad)
+		}
+		if got, _ := os.ReadFile(playlist); string(got) != bad {
+			t.Errorf("失败时不应修改播放列表: %q", got)
+		}
+	}
+}
+
+func TestEncryptVODDuplicateSegment(t *testing.T) {
+	dir := t.TempDir()
+	playlist := filepath.Join(dir, "index.m3u8")
+	content := "#EXTM3U\n#EXTINF:2.0,\na.ts\n#EXTINF:2.0,\n./a.ts\n"
+	os.WriteFile(playlist, []byte(content), 0o644)
+	os.WriteFile(filepath.Join(dir, "a.ts"), []byte("segment"), 0o644)
+
+	k, err := NewKeyInfo("https://example.com/key", WithTempDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("创建KeyInfo失败: %v", err)
+	}
+	defer k.Dispose()
+	if err := k.EncryptVOD(playlist); err == nil || !strings.Contains(err.Error(), "多次引用") {
+		t.Errorf("重复引用的分片应返回错误: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "a.ts")); string(got) != "segment" {
+		t.Error("失败时不应加密分片")
+	}
+	if got, _ := os.ReadFile(playlist); string(got) != content {
+		t.Errorf("失败时不应修改播放列表: %q", got)
+	}
+}
+
+func TestDecryptVOD(t *testing.T) {
+	dir := t.TempDir()
+	k1, _ := NewKeyInfoWithKey("https://keys.example.com/1", bytes.Repeat([]byte{1}, 16), WithTempDir(dir))
+	k2, _ := NewKeyInfoWithKey("https://keys.example.com/2", bytes.Repeat([]byte{2}, 16), WithTempDir(dir))
+	defer k1.Dispose()
+	defer k2.Dispose()
+	k1.UseSequenceIV()
+	k2.SetIV("0x000102030405060708090a0b0c0d0e0f")
+
+	segments := map[string]*KeyInfo{"0.ts": k1, "1.ts": k2, "2.ts": nil}
+	for i, name := range []string{"0.ts", "1.ts", "2.ts"} {
+		plain := bytes.Repeat([]byte{0x47, byte(i)}, 200)
+		data := plain
+		if k := segments[name]; k != nil {
+			var buf bytes.Buffer
+			k.EncryptSegment(&buf, bytes.NewReader(plain), uint64(5+i))
+			data = buf.Bytes()
+		}
+		os.WriteFile(filepath.Join(dir, name), data, 0o644)
+	}
+	playlist := filepath.Join(dir, "index.m3u8")
+	os.WriteFile(playlist, []byte("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-MEDIA-SEQUENCE:5\n"+
+		k1.ExtXKey()+"\n#EXTINF:4.0,\n0.ts\n"+
+		k2.ExtXKey()+"\n#EXTINF:4.0,\n1.ts\n"+
+		"#EXT-X-KEY:METHOD=NONE\n#EXTINF:4.0,\n2.ts\n#EXT-X-ENDLIST\n"), 0o644)
+
+	var calls int
+	err := DecryptVOD(playlist, func(tag KeyTag) ([]byte, error) {
+		calls++
+		for _, k := range []*KeyInfo{k1, k2} {
+			if k.KeyURL() == tag.URI {
+				return k.GetKey(), nil
+			}
+		}
+		return nil, fmt.Errorf("未知密钥 %s", tag.URI)
+	})
+	if err != nil {
+		t.Fatalf("解密失败: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("应为每个密钥获取一次，实际 %d 次", calls)
+	}
+	for i, name := range []string{"0.ts", "1.ts", "2.ts"} {
+		if got, _ := os.ReadFile(filepath.Join(dir, name)); !bytes.Equal(got, bytes.Repeat([]byte{0x47, byte(i)}, 200)) {
+			t.Errorf("分片 %s 解密后不一致", name)
+		}
+	}
+	if got, _ := os.ReadFile(playlist); strings.Contains(string(got), "EXT-X-KEY") || !strings.Contains(string(got), "#EXT-X-MEDIA-SEQUENCE:5\n#EXTINF:4.0,\n0.ts\n") {
+		t.Errorf("应移除密钥标签:\n%s", got)
+	}
+
+	// 播放列表未加密
+	if err := DecryptVOD(playlist, nil); err == nil {
+		t.Error("未加密的播放列表应返回错误")
+	}
+}
+
+func TestReEncryptVOD(t *testing.T) {
+	dir := t.TempDir()
+	old1, _ := NewKeyInfoWithKey("https://keys.example.com/1", bytes.Repeat([]byte{1}, 16), WithTempDir(dir))
+	old2, _ := NewKeyInfoWithKey("https://keys.example.com/2", bytes.Repeat([]byte{2}, 16), WithTempDir(dir))
+	k, _ := NewKeyInfoWithKey("https://keys.example.com/new", bytes.Repeat([]byte{3}, 16), WithTempDir(dir))
+	defer old1.Dispose()
+	defer old2.Dispose()
+	defer k.Dispose()
+	old1.UseSequenceIV()
+	old2.UseSequenceIV()
+	k.UseSequenceIV()
+
+	keys := []*KeyInfo{nil, old1, old2, nil, old2}
+	for i, old := range keys {
+		plain := bytes.Repeat([]byte{0x47, byte(i)}, 300)
+		data := plain
+		if old != nil {
+			var buf bytes.Buffer
+			old.EncryptSegment(&buf, bytes.NewReader(plain), uint64(i))
+			data = buf.Bytes()
+		}
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.ts", i)), data, 0o644)
+	}
+	playlist := filepath.Join(dir, "index.m3u8")
+	os.WriteFile(playlist, []byte("#EXTM3U\n#EXTINF:4.0,\n0.ts\n"+
+		old1.ExtXKey()+"\n#EXTINF:4.0,\n1.ts\n"+
+		old2.ExtXKey()+"\n#EXTINF:4.0,\n2.ts\n"+
+		"#EXT-X-KEY:METHOD=NONE\n#EXTINF:4.0,\n3.ts\n"+
+		old2.ExtXKey()+"\n#EXTINF:4.0,\n4.ts\n#EXT-X-ENDLIST\n"), 0o644)
+
+	keyFor := func(tag KeyTag) ([]byte, error) {
+		for _, k := range []*KeyInfo{old1, old2} {
+			if k.KeyURL() == tag.URI {
+				return k.GetKey(), nil
+			}
+		}
+		return nil, fmt.Errorf("未知密钥 %s", tag.URI)
+	}
+	if err := k.ReEncryptVOD(playlist, keyFor); err != nil {
+		t.Fatalf("重新加密失败: %v", err)
+	}
+
+	want := "#EXTM3U\n#EXTINF:4.0,\n0.ts\n" +
+		k.ExtXKey() + "\n#EXTINF:4.0,\n1.ts\n#EXTINF:4.0,\n2.ts\n" +
+		"#EXT-X-KEY:METHOD=NONE\n#EXTINF:4.0,\n3.ts\n" +
+		k.ExtXKey() + "\n#EXTINF:4.0,\n4.ts\n#EXT-X-ENDLIST\n"
+	if got, _ := os.ReadFile(playlist); string(got) != want {
+		t.Errorf("播放列表不正确:\n%s", got)
+	}
+	for i, old := range keys {
+		plain := bytes.Repeat([]byte{0x47, byte(i)}, 300)
+		got, _ := os.ReadFile(filepath.Join(dir, fmt.Sprintf("%d.ts", i)))
+		if old == nil {
+			if !bytes.Equal(got, plain) {
+				t.Errorf("明文分片 %d 不应改变", i)
+			}
+			continue
+		}
+		if dec := decryptTestSegment(t, k, got, uint64(i)); !bytes.Equal(dec, plain) {
+			t.Errorf("分片 %d 应使用新密钥加密", i)
+		}
+	}
+
+	// 旧密钥不正确时不修改任何文件
+	before, _ := os.ReadFile(playlist)
+	err := old1.ReEncryptVOD(playlist, func(KeyTag) ([]byte, error) { return bytes.Repeat([]byte{9}, 16), nil })
+	if err == nil {
+		t.Fatal("旧密钥错误时应返回错误")
+	}
+	if got, _ := os.ReadFile(playlist); !bytes.Equal(got, before) {
+		t.Errorf("失败时不应修改播放列表")
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "1.ts")); !bytes.Equal(decryptTestSegment(t, k, got, 1), bytes.Repeat([]byte{0x47, 1}, 300)) {
+		t.Errorf("失败时不应修改分片")
+	}
+}
@@ -0,0 +1,67 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestWidevineKeyTag(t *testing.T) {
+	k, err := NewKeyInfo("https://keys.example.com/key", WithTempDir(t.TempDir()), WithMethod(MethodSampleAES))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Dispose()
+	kid, _ := hex.DecodeString(k.KeyID)
+
+	tag, err := k.WidevineKeyTag(WidevinePSSH{Provider: "example", ContentID: []byte("movie-1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag.KeyFormat != KeyFormatWidevine || tag.KeyFormatVersions != "1" || tag.KeyID != k.KeyID {
+		t.Errorf("标签属性不正确: %+v", tag)
+	}
+
+	// URI 为 pssh box 的 data URI
+	box, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(tag.URI, "data:text/plain;base64,"))
+	if err != nil {
+		t.Fatalf("URI 不是 Base64 data URI: %s", tag.URI)
+	}
+	boxes, err := parseMP4Boxes(box)
+	if err != nil || len(boxes) != 1 || boxes[0].typ != "pssh" {
+		t.Fatalf("URI 不是 pssh box: %v", err)
+	}
+	p := boxes[0].payload
+	if !bytes.Equal(p[4:20], WidevineSystemID[:]) || binary.BigEndian.Uint32(p[20:]) != 1 || !bytes.Equal(p[24:40], kid) {
+		t.Errorf("pssh 的系统 ID 或 KID 不正确")
+	}
+	data := p[44:]
+	want := append([]byte{0x12, 16}, kid...)
+	want = append(want, 0x1a, 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0x22, 7, 'm', 'o', 'v', 'i', 'e', '-', '1')
+	want = append(want, 0x48, 0xf3, 0xc6, 0x89, 0x9b, 0x06) // cbcs = 0x63626373
+	if !bytes.Equal(data, want) {
+		t.Errorf("WidevinePsshData 编码不正确: %x", data)
+	}
+
+	// 往返解析
+	tag.Session = true
+	parsed, err := ParseKeyTag(tag.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed != tag {
+		t.Errorf("解析结果不一致: %+v", parsed)
+	}
+	if !strings.HasPrefix(tag.String(), "#EXT-X-SESSION-KEY:METHOD=SAMPLE-AES,") {
+		t.Errorf("应为 EXT-X-SESSION-KEY: %s", tag)
+	}
+
+	aes128, _ := NewKeyInfo("https://keys.example.com/key", WithTempDir(t.TempDir()))
+	defer aes128.Dispose()
+	if _, err := aes128.WidevineKeyTag(WidevinePSSH{}); err == nil {
+		t.Errorf("AES-128 不应生成 Widevine 标签")
+	}
+}
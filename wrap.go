@@ -0,0 +1,79 @@
+package hlskeyinfo
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+)
+
+// WrapKey 使用密钥加密密钥（KEK）以 AES-GCM 封装内容密钥
+// 返回值格式为 nonce || 密文 || 认证标签，可安全地存储在密钥文件之外
+func (k *KeyInfo) WrapKey(kek []byte) ([]byte, error) {
+	if k.key == nil {
+		return nil, fmt.Errorf("密钥未初始化")
+	}
+	return gcmSeal(kek, k.key)
+}
+
+// UnwrapKey 使用 KEK 解封由 WrapKey 生成的数据，返回内容密钥
+func UnwrapKey(kek, wrapped []byte) ([]byte, error) {
+	return gcmOpen(kek, wrapped)
+}
+
+// NewKeyInfoFromWrapped 解封 KEK 封装的内容密钥并创建KeyInfo实例
+func NewKeyInfoFromWrapped(url string, kek, wrapped []byte, opts ...Option) (*KeyInfo, error) {
+	key, err := UnwrapKey(kek, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyInfoWithKey(url, key, opts...)
+}
+
+// newGCM 使用 KEK 创建 AES-GCM 实例
+func newGCM(kek []byte) (cipher.AEAD, error) {
+	if err := validateKeySize(len(kek)); err != nil {
+		return nil, fmt.Errorf("KEK %w", err)
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, fmt.Errorf("创建 AES 实例失败: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("创建 GCM 实例失败: %w", err)
+	}
+	return gcm, nil
+}
+
+// gcmSeal 以 AES-GCM 加密数据，随机 nonce 置于密文之前
+// nonce 始终取自 crypto/rand，不使用 WithRand 设置的随机数来源，确定性来源会导致同一 KEK 下 nonce 重复
+func gcmSeal(kek, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("生成 nonce 失败: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// gcmOpen 解密 gcmSeal 生成的数据
+func gcmOpen(kek, data []byte) ([]byte, error) {
+	gcm, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, fmt.Errorf("封装数据长度不足")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("解封密钥失败: %w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,64 @@
+package hlskeyinfo
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWrapKey(t *testing.T) {
+	kek := bytes.Repeat([]byte{0x07}, 32)
+
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo")
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	wrapped, err := k.WrapKey(kek)
+	if err != nil {
+		t.Fatalf("WrapKey 失败: %v", err)
+	}
+	if bytes.Contains(wrapped, k.GetKey()) {
+		t.Error("封装数据中不应包含明文密钥")
+	}
+
+	restored, err := NewKeyInfoFromWrapped("http://localhost:4123/keyinfo", kek, wrapped)
+	if err != nil {
+		t.Fatalf("NewKeyInfoFromWrapped 失败: %v", err)
+	}
+	defer restored.Dispose()
+	if !bytes.Equal(restored.GetKey(), k.GetKey()) {
+		t.Error("解封后的密钥与原密钥不一致")
+	}
+
+	// 错误的 KEK 或被篡改的数据无法解封
+	if _, err := UnwrapKey(bytes.Repeat([]byte{0x08}, 32), wrapped); err == nil {
+		t.Error("错误的 KEK 应解封失败")
+	}
+	wrapped[len(wrapped)-1] ^= 0xff
+	if _, err := UnwrapKey(kek, wrapped); err == nil {
+		t.Error("被篡改的数据应解封失败")
+	}
+}
+
+func TestWrapKeyNonceIgnoresRand(t *testing.T) {
+	kek := bytes.Repeat([]byte{0x07}, 32)
+	// 确定性随机数来源只用于密钥与 IV，封装的 nonce 仍需各不相同
+	k, err := NewKeyInfo("http://localhost:4123/keyinfo", WithRand(bytes.NewReader(bytes.Repeat([]byte{0x42}, 64))))
+	if err != nil {
+		t.Fatalf("创建 KeyInfo 失败: %v", err)
+	}
+	defer k.Dispose()
+
+	a, err := k.WrapKey(kek)
+	if err != nil {
+		t.Fatalf("WrapKey 失败: %v", err)
+	}
+	b, err := k.WrapKey(kek)
+	if err != nil {
+		t.Fatalf("WrapKey 失败: %v", err)
+	}
+	if bytes.Equal(a[:12], b[:12]) {
+		t.Error("同一 KEK 下的 nonce 不应重复")
+	}
+}